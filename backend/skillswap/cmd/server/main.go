@@ -2,8 +2,15 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	_ "time/tzdata" // Timezone validation must not depend on the host's zoneinfo
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jobs"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/database"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
@@ -12,11 +19,22 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(configCommand(os.Args[2:]))
+	}
+
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	gin.SetMode(cfg.Server.Mode)
 
 	// Initialize database
-	db, err := database.Initialize(cfg.DBUrl)
+	db, err := database.Initialize(cfg.Database)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
@@ -26,30 +44,44 @@ func main() {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
+	// Search and statistics read from the replica, if there is one
+	replica, err := database.OpenReplica(cfg.Database, db)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
 	// Initialize Gin router
 	router := gin.New()
 
+	// Forwarding headers are only read by RealIP, which checks them against
+	// TRUSTED_PROXIES
+	if err := router.SetTrustedProxies(nil); err != nil {
+		log.Fatal("Failed to configure trusted proxies:", err)
+	}
+
 	// Add middleware
+	router.Use(middleware.RealIP(cfg))
 	router.Use(middleware.Logger())
 	router.Use(middleware.Recovery())
-	router.Use(middleware.CORS())
-	router.Use(middleware.SecurityHeaders())
+	router.Use(middleware.RequestTimeout(cfg))
+	router.Use(middleware.CORS(cfg))
+	router.Use(middleware.SecurityHeaders(cfg))
 
 	// Setup health routes
 	apirouter.SetupHealthRoutes(router)
+	apirouter.SetupWellKnownRoutes(router, &cfg)
 
 	// Setup API routes
+	scheduler := jobs.NewScheduler(db, cfg.Jobs.PollInterval)
 	api := router.Group("/api/v1")
-	apirouter.SetupRoutes(api, db, &cfg)
+	apirouter.SetupRoutes(api, db, replica, &cfg, scheduler)
 
-	// Start server
-	port := cfg.Port
-	if port == "" {
-		port = "8080"
-	}
+	// Run background jobs once their handlers are registered
+	scheduler.Start(context.Background())
 
-	log.Printf("Server starting on port %s", port)
-	if err := router.Run(":" + port); err != nil {
+	// Start server
+	log.Printf("Server starting on port %d", cfg.Server.Port)
+	if err := router.Run(fmt.Sprintf(":%d", cfg.Server.Port)); err != nil {
 		log.Fatal("Server failed to start:", err)
 	}
 }
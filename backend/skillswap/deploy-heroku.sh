@@ -44,6 +44,12 @@ JWT_SECRET=$(openssl rand -base64 32)
 echo "🔐 Setting JWT secret..."
 heroku config:set JWT_SECRET="$JWT_SECRET" -a $APP_NAME
 
+# Generate the key that signs access tokens, unless the app has one
+if [ -z "$(heroku config:get JWT_SIGNING_KEY -a $APP_NAME)" ]; then
+    echo "🔑 Setting JWT signing key..."
+    heroku config:set JWT_SIGNING_KEY="$(openssl genpkey -algorithm ed25519)" -a $APP_NAME
+fi
+
 # Set production BASE_URL
 echo "🌐 Setting BASE_URL..."
 heroku config:set BASE_URL="https://$APP_NAME.herokuapp.com" -a $APP_NAME
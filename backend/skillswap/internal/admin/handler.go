@@ -4,7 +4,9 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -27,7 +29,8 @@ func NewHandler(adminService service.AdminService) *Handler {
 // @Produce json
 // @Param search query string false "Search by name or email"
 // @Param is_banned query bool false "Filter by banned status"
-// @Param is_admin query bool false "Filter by admin status"
+// @Param role query string false "Filter by role, e.g. moderator"
+// @Param is_admin query bool false "Only admins; same as role=admin"
 // @Param sort_by query string false "Sort by field (created_at, name, email)"
 // @Param sort_order query string false "Sort order (asc, desc)"
 // @Param limit query int false "Limit results"
@@ -38,9 +41,15 @@ func NewHandler(adminService service.AdminService) *Handler {
 // @Failure 500 {object} gin.H
 // @Router /api/v1/admin/users [get]
 func (h *Handler) GetAllUsers(c *gin.Context) {
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
 	// Parse query parameters
 	filter := service.AdminUserFilter{
 		Search:    c.Query("search"),
+		Role:      c.Query("role"),
 		SortBy:    c.Query("sort_by"),
 		SortOrder: c.Query("sort_order"),
 	}
@@ -51,10 +60,8 @@ func (h *Handler) GetAllUsers(c *gin.Context) {
 		}
 	}
 
-	if isAdmin := c.Query("is_admin"); isAdmin != "" {
-		if val, err := strconv.ParseBool(isAdmin); err == nil {
-			filter.IsAdmin = &val
-		}
+	if isAdmin, err := strconv.ParseBool(c.Query("is_admin")); err == nil && isAdmin {
+		filter.Role = rbac.RoleAdmin
 	}
 
 	if limit := c.Query("limit"); limit != "" {
@@ -69,9 +76,9 @@ func (h *Handler) GetAllUsers(c *gin.Context) {
 		}
 	}
 
-	users, total, err := h.adminService.GetAllUsers(filter)
+	users, total, err := h.adminService.GetAllUsers(c.Request.Context(), adminID, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -106,23 +113,14 @@ func (h *Handler) BanUser(c *gin.Context) {
 	}
 
 	// Get admin ID from JWT token
-	adminID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	err = h.adminService.BanUser(adminID.(uuid.UUID), userID)
+	err = h.adminService.BanUser(c.Request.Context(), adminID, userID)
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
-		}
-		if err.Error() == "cannot ban an admin user" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Cannot ban an admin user"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -150,15 +148,14 @@ func (h *Handler) UnbanUser(c *gin.Context) {
 		return
 	}
 
-	adminID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	err = h.adminService.UnbanUser(adminID.(uuid.UUID), userID)
+	err = h.adminService.UnbanUser(c.Request.Context(), adminID, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -187,23 +184,14 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	adminID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	err = h.adminService.DeleteUser(adminID.(uuid.UUID), userID)
+	err = h.adminService.DeleteUser(c.Request.Context(), adminID, userID)
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
-		}
-		if err.Error() == "cannot delete an admin user" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Cannot delete an admin user"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -231,15 +219,14 @@ func (h *Handler) MakeUserAdmin(c *gin.Context) {
 		return
 	}
 
-	adminID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	err = h.adminService.MakeUserAdmin(adminID.(uuid.UUID), userID)
+	err = h.adminService.GrantRole(c.Request.Context(), adminID, userID, rbac.RoleAdmin)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -267,19 +254,14 @@ func (h *Handler) RemoveUserAdmin(c *gin.Context) {
 		return
 	}
 
-	adminID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	err = h.adminService.RemoveUserAdmin(adminID.(uuid.UUID), userID)
+	err = h.adminService.RevokeRole(c.Request.Context(), adminID, userID, rbac.RoleAdmin)
 	if err != nil {
-		if err.Error() == "cannot remove admin privileges from yourself" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Cannot remove admin privileges from yourself"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -305,6 +287,11 @@ func (h *Handler) RemoveUserAdmin(c *gin.Context) {
 // @Failure 500 {object} gin.H
 // @Router /api/v1/admin/swaps [get]
 func (h *Handler) GetAllSwaps(c *gin.Context) {
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
 	filter := service.AdminSwapFilter{
 		SortBy:    c.Query("sort_by"),
 		SortOrder: c.Query("sort_order"),
@@ -338,9 +325,9 @@ func (h *Handler) GetAllSwaps(c *gin.Context) {
 		}
 	}
 
-	swaps, total, err := h.adminService.GetAllSwaps(filter)
+	swaps, total, err := h.adminService.GetAllSwaps(c.Request.Context(), adminID, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -382,15 +369,14 @@ func (h *Handler) CancelSwap(c *gin.Context) {
 		return
 	}
 
-	adminID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	err = h.adminService.CancelSwap(adminID.(uuid.UUID), swapID, req.Reason)
+	err = h.adminService.CancelSwap(c.Request.Context(), adminID, swapID, req.Reason)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -409,9 +395,14 @@ func (h *Handler) CancelSwap(c *gin.Context) {
 // @Failure 500 {object} gin.H
 // @Router /api/v1/admin/stats [get]
 func (h *Handler) GetPlatformStats(c *gin.Context) {
-	stats, err := h.adminService.GetPlatformStats()
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.adminService.GetPlatformStats(c.Request.Context(), adminID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -430,11 +421,216 @@ func (h *Handler) GetPlatformStats(c *gin.Context) {
 // @Failure 500 {object} gin.H
 // @Router /api/v1/admin/reports [get]
 func (h *Handler) GetReportedContent(c *gin.Context) {
-	reports, err := h.adminService.GetReportedContent()
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	reports, err := h.adminService.GetReportedContent(c.Request.Context(), adminID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"reports": reports})
 }
+
+// ListRoles lists the roles that can be granted
+// @Summary List roles (admin only)
+// @Description List the staff roles and the permissions each grants
+// @Tags admin
+// @Produce json
+// @Success 200 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Router /api/v1/admin/roles [get]
+func (h *Handler) ListRoles(c *gin.Context) {
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	roles, err := h.adminService.ListRoles(c.Request.Context(), adminID)
+	if err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// GetUserRoles lists the roles granted to a user
+// @Summary Get a user's roles (admin only)
+// @Description List the roles granted to a user, with who granted them
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1/admin/users/{id}/roles [get]
+func (h *Handler) GetUserRoles(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	roles, err := h.adminService.GetUserRoles(c.Request.Context(), adminID, userID)
+	if err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// GrantRole grants a role to a user
+// @Summary Grant a role (admin only)
+// @Description Grant a role to a user. It shows in their tokens once they refresh.
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Param role path string true "Role, e.g. moderator"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1/admin/users/{id}/roles/{role} [put]
+func (h *Handler) GrantRole(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.GrantRole(c.Request.Context(), adminID, userID, c.Param("role")); err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeRole revokes a role from a user
+// @Summary Revoke a role (admin only)
+// @Description Revoke a role from a user. Admin endpoints refuse it at once.
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Param role path string true "Role, e.g. moderator"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Router /api/v1/admin/users/{id}/roles/{role} [delete]
+func (h *Handler) RevokeRole(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.RevokeRole(c.Request.Context(), adminID, userID, c.Param("role")); err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetLockouts lists addresses with recent failed logins
+// @Summary Get login lockouts (admin only)
+// @Description List email addresses with failed logins in the last day, most recent first
+// @Tags admin
+// @Produce json
+// @Param locked query bool false "Only addresses that are locked now"
+// @Success 200 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/admin/lockouts [get]
+func (h *Handler) GetLockouts(c *gin.Context) {
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	lockedOnly := c.Query("locked") == "true"
+	lockouts, err := h.adminService.GetLockouts(c.Request.Context(), adminID, lockedOnly)
+	if err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lockouts": lockouts})
+}
+
+// ClearLockout unlocks a user's account
+// @Summary Clear a login lockout (admin only)
+// @Description Forget a user's failed logins, so they can sign in again at once
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1/admin/users/{id}/lockout [delete]
+func (h *Handler) ClearLockout(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.ClearLockout(c.Request.Context(), adminID, userID); err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// adminErrorStatus maps admin service errors to HTTP status codes
+func adminErrorStatus(err error) int {
+	switch err.Error() {
+	case "permission denied",
+		"cannot ban a user with a staff role",
+		"cannot delete a user with a staff role",
+		"cannot remove admin privileges from yourself":
+		return http.StatusForbidden
+	case "user not found":
+		return http.StatusNotFound
+	case "unknown role":
+		return http.StatusBadRequest
+	case "cannot grant a role to a banned user":
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,114 @@
+// Package authtoken defines the access and refresh tokens we issue and the
+// single verifier that checks them, wherever they are presented.
+package authtoken
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jwtkeys"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Token types, carried in the token_type claim
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+)
+
+// clockSkew tolerates clocks of other services being slightly off
+const clockSkew = 30 * time.Second
+
+var (
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrWrongTokenType = errors.New("invalid token type") // e.g. a refresh token presented as an access token
+	ErrRevoked        = errors.New("token revoked")
+)
+
+type Claims struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Roles     []string  `json:"roles,omitempty"` // Staff roles, see package rbac
+	TokenType string    `json:"token_type"`      // TypeAccess or TypeRefresh
+	// TokenVersion must match the user's; bumping it revokes all sessions,
+	// once verifiers with a VersionSource see the new version
+	TokenVersion int `json:"token_version"`
+	// MFA is set when the session passed two-factor authentication
+	MFA bool `json:"mfa,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Principal returns who the token was issued to
+func (c *Claims) Principal() *Principal {
+	return &Principal{
+		UserID: c.UserID,
+		Email:  c.Email,
+		Roles:  c.Roles,
+		MFA:    c.MFA,
+	}
+}
+
+// Verifier checks the signature, expiry, issuer, audience and type of
+// tokens, and with a VersionSource that they have not been revoked. It is
+// the only place tokens are parsed.
+type Verifier struct {
+	keys     *jwtkeys.Keyring
+	issuer   string
+	audience string
+	versions VersionSource
+}
+
+func NewVerifier(keys *jwtkeys.Keyring, issuer, audience string) *Verifier {
+	return &Verifier{keys: keys, issuer: issuer, audience: audience}
+}
+
+// NewVerifierFromConfig returns a verifier for the tokens this server issues
+func NewVerifierFromConfig(cfg config.Config) *Verifier {
+	return NewVerifier(cfg.Auth.Keys, cfg.Auth.Issuer, cfg.Auth.Audience)
+}
+
+// WithVersions returns a copy of v that rejects tokens whose version is
+// behind the user's current one in versions
+func (v *Verifier) WithVersions(versions VersionSource) *Verifier {
+	copied := *v
+	copied.versions = versions
+	return &copied
+}
+
+// Verify parses raw and checks that it is a valid token of tokenType
+func (v *Verifier) Verify(ctx context.Context, raw, tokenType string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims, v.keys.Keyfunc,
+		jwt.WithValidMethods(v.keys.ValidMethods()),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.TokenType != tokenType {
+		return nil, ErrWrongTokenType
+	}
+	if claims.UserID == uuid.Nil || claims.Subject != claims.UserID.String() {
+		return nil, ErrInvalidToken
+	}
+
+	if v.versions != nil {
+		version, err := v.versions.TokenVersion(ctx, claims.UserID)
+		if err != nil {
+			// Deleted users, or a lookup that failed; either way the token
+			// cannot be shown to be current
+			return nil, ErrRevoked
+		}
+		if claims.TokenVersion != version {
+			return nil, ErrRevoked
+		}
+	}
+	return claims, nil
+}
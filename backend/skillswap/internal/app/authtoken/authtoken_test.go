@@ -0,0 +1,212 @@
+package authtoken
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jwtkeys"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	testIssuer   = "https://skillswap.test"
+	testAudience = "skillswap"
+)
+
+func testKeyring(t *testing.T) *jwtkeys.Keyring {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := jwtkeys.New(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// validClaims returns access token claims this verifier accepts
+func validClaims(userID uuid.UUID) *Claims {
+	now := time.Now()
+	return &Claims{
+		UserID:       userID,
+		Email:        "user@example.com",
+		TokenType:    TypeAccess,
+		TokenVersion: 3,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(15 * time.Minute)),
+		},
+	}
+}
+
+// fakeVersions serves token versions from a map and counts lookups
+type fakeVersions struct {
+	versions map[uuid.UUID]int
+	lookups  int
+}
+
+func (f *fakeVersions) TokenVersion(_ context.Context, userID uuid.UUID) (int, error) {
+	f.lookups++
+	version, ok := f.versions[userID]
+	if !ok {
+		return 0, errors.New("user not found")
+	}
+	return version, nil
+}
+
+func TestVerify(t *testing.T) {
+	keys := testKeyring(t)
+	verifier := NewVerifier(keys, testIssuer, testAudience)
+	userID := uuid.New()
+
+	signed := func(claims *Claims) string {
+		token, err := keys.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name      string
+		token     func() string
+		tokenType string
+		want      error
+	}{
+		{name: "valid", token: func() string { return signed(validClaims(userID)) }, tokenType: TypeAccess},
+		{name: "wrong alg", tokenType: TypeAccess, want: ErrInvalidToken, token: func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims(userID))
+			token.Header["kid"] = keys.SigningKeyID()
+			raw, _ := token.SignedString([]byte(keys.SigningKeyID()))
+			return raw
+		}},
+		{name: "alg none", tokenType: TypeAccess, want: ErrInvalidToken, token: func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims(userID))
+			token.Header["kid"] = keys.SigningKeyID()
+			raw, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return raw
+		}},
+		{name: "signed by another key", tokenType: TypeAccess, want: ErrInvalidToken, token: func() string {
+			raw, _ := testKeyring(t).Sign(validClaims(userID))
+			return raw
+		}},
+		{name: "wrong issuer", tokenType: TypeAccess, want: ErrInvalidToken, token: func() string {
+			claims := validClaims(userID)
+			claims.Issuer = "https://elsewhere.test"
+			return signed(claims)
+		}},
+		{name: "wrong audience", tokenType: TypeAccess, want: ErrInvalidToken, token: func() string {
+			claims := validClaims(userID)
+			claims.Audience = jwt.ClaimStrings{"another-service"}
+			return signed(claims)
+		}},
+		{name: "expired", tokenType: TypeAccess, want: ErrInvalidToken, token: func() string {
+			claims := validClaims(userID)
+			claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			return signed(claims)
+		}},
+		{name: "expired within the clock skew", tokenType: TypeAccess, token: func() string {
+			claims := validClaims(userID)
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-clockSkew / 2))
+			return signed(claims)
+		}},
+		{name: "no expiry", tokenType: TypeAccess, want: ErrInvalidToken, token: func() string {
+			claims := validClaims(userID)
+			claims.ExpiresAt = nil
+			return signed(claims)
+		}},
+		{name: "issued in the future", tokenType: TypeAccess, want: ErrInvalidToken, token: func() string {
+			claims := validClaims(userID)
+			claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(10 * time.Minute))
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+			return signed(claims)
+		}},
+		{name: "refresh token presented as access token", tokenType: TypeAccess, want: ErrWrongTokenType, token: func() string {
+			claims := validClaims(userID)
+			claims.TokenType = TypeRefresh
+			return signed(claims)
+		}},
+		{name: "access token presented as refresh token", tokenType: TypeRefresh, want: ErrWrongTokenType, token: func() string {
+			return signed(validClaims(userID))
+		}},
+		{name: "sub and user_id differ", tokenType: TypeAccess, want: ErrInvalidToken, token: func() string {
+			claims := validClaims(userID)
+			claims.Subject = uuid.NewString()
+			return signed(claims)
+		}},
+		{name: "no user_id", tokenType: TypeAccess, want: ErrInvalidToken, token: func() string {
+			claims := validClaims(uuid.Nil)
+			return signed(claims)
+		}},
+		{name: "garbage", tokenType: TypeAccess, want: ErrInvalidToken, token: func() string { return "not.a.token" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), tt.token(), tt.tokenType)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && (claims.UserID != userID || claims.Principal().UserID != userID) {
+				t.Errorf("claims for %s, want %s", claims.UserID, userID)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenVersion(t *testing.T) {
+	keys := testKeyring(t)
+	userID := uuid.New()
+	token, err := keys.Sign(validClaims(userID)) // version 3
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	source := &fakeVersions{versions: map[uuid.UUID]int{userID: 3}}
+	cache := NewVersionCache(source)
+	verifier := NewVerifier(keys, testIssuer, testAudience).WithVersions(cache)
+
+	for i := 0; i < 3; i++ {
+		if _, err := verifier.Verify(ctx, token, TypeAccess); err != nil {
+			t.Fatalf("current token rejected: %v", err)
+		}
+	}
+	if source.lookups != 1 {
+		t.Errorf("%d version lookups, want 1 while cached", source.lookups)
+	}
+
+	// Revoking bumps the version; once the cached one expires the token stops working
+	source.versions[userID] = 4
+	cache.mu.Lock()
+	cached := cache.versions[userID]
+	cached.expires = time.Now().Add(-time.Second)
+	cache.versions[userID] = cached
+	cache.mu.Unlock()
+	if _, err := verifier.Verify(ctx, token, TypeAccess); !errors.Is(err, ErrRevoked) {
+		t.Errorf("revoked token: error = %v, want ErrRevoked", err)
+	}
+
+	// Deleted users' tokens are revoked as well
+	delete(source.versions, userID)
+	uncached := NewVerifier(keys, testIssuer, testAudience).WithVersions(source)
+	if _, err := uncached.Verify(ctx, token, TypeAccess); !errors.Is(err, ErrRevoked) {
+		t.Errorf("deleted user: error = %v, want ErrRevoked", err)
+	}
+
+	// Without a version source the token is only checked cryptographically
+	if _, err := NewVerifier(keys, testIssuer, testAudience).Verify(ctx, token, TypeAccess); err != nil {
+		t.Errorf("verifier without versions: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package authtoken
+
+import (
+	"context"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
+	"github.com/google/uuid"
+)
+
+// Principal is the authenticated user of a request
+type Principal struct {
+	UserID uuid.UUID
+	Email  string
+	Roles  []string
+	MFA    bool // Signed in with two-factor authentication
+}
+
+// Can reports whether the principal's roles grant perm. Services check again
+// against the stored roles, which may have changed since the token was issued.
+func (p *Principal) Can(perm rbac.Permission) bool {
+	return rbac.Allows(p.Roles, perm)
+}
+
+// IsStaff reports whether the principal holds any role
+func (p *Principal) IsStaff() bool {
+	return len(p.Roles) > 0
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx that carries p
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal in ctx, if the request is authenticated
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
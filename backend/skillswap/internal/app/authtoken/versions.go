@@ -0,0 +1,74 @@
+package authtoken
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VersionCacheTTL is how long a user's token version is cached, and so how
+// long tokens keep working after their sessions are revoked
+const VersionCacheTTL = 5 * time.Second
+
+// VersionSource looks up a user's current token version. Users that no
+// longer exist should return an error.
+type VersionSource interface {
+	TokenVersion(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+// VersionCache remembers versions from a source for VersionCacheTTL, so
+// the several checks of one request, and requests in quick succession, cost
+// one lookup
+type VersionCache struct {
+	source VersionSource
+
+	mu       sync.Mutex
+	versions map[uuid.UUID]cachedVersion
+	swept    time.Time
+}
+
+type cachedVersion struct {
+	version int
+	expires time.Time
+}
+
+func NewVersionCache(source VersionSource) *VersionCache {
+	return &VersionCache{source: source, versions: make(map[uuid.UUID]cachedVersion)}
+}
+
+func (c *VersionCache) TokenVersion(ctx context.Context, userID uuid.UUID) (int, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	cached, ok := c.versions[userID]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.version, nil
+	}
+
+	version, err := c.source.TokenVersion(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+	c.versions[userID] = cachedVersion{version: version, expires: now.Add(VersionCacheTTL)}
+	return version, nil
+}
+
+// sweep drops expired versions at most once a minute
+func (c *VersionCache) sweep(now time.Time) {
+	if now.Sub(c.swept) < time.Minute {
+		return
+	}
+	c.swept = now
+	for userID, cached := range c.versions {
+		if !now.Before(cached.expires) {
+			delete(c.versions, userID)
+		}
+	}
+}
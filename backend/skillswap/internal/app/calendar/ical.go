@@ -0,0 +1,443 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// iCalendar (RFC 5545) support limited to what availability needs: VEVENTs
+// with a start, an end, a summary and an optional weekly or daily RRULE.
+// Time zones are written and read as IANA TZIDs, which the common calendar
+// apps accept without VTIMEZONE definitions.
+
+const (
+	icalDateTime    = "20060102T150405"
+	icalDateTimeUTC = "20060102T150405Z"
+	icalDate        = "20060102"
+
+	// maxICalEvents bounds how many events one import may contain
+	maxICalEvents = 5000
+)
+
+// Event is one VEVENT. Location is the zone Start and End are written in;
+// nil writes UTC. Transparent events do not block time.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Location    *time.Location
+	RRule       *RRule
+	Transparent bool
+	Cancelled   bool
+}
+
+// RRule is the subset of a recurrence rule used here. Days uses the same
+// bitmask as availability slots; zero means the weekday of the start.
+type RRule struct {
+	Freq     string // "DAILY", "WEEKLY", ...
+	Interval int
+	Days     int32
+	Count    int
+	Until    *time.Time
+}
+
+var icalDays = []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// EncodeICal writes a VCALENDAR with the given events
+func EncodeICal(w io.Writer, name string, events []Event) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		writeFolded(bw, s)
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Skill Swap//Availability//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	if name != "" {
+		line("X-WR-CALNAME:" + escapeText(name))
+	}
+
+	stamp := time.Now().UTC().Format(icalDateTimeUTC)
+	for _, event := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + escapeText(event.UID))
+		line("DTSTAMP:" + stamp)
+		line(formatICalTime("DTSTART", event.Start, event.Location, event.AllDay))
+		line(formatICalTime("DTEND", event.End, event.Location, event.AllDay))
+		if event.RRule != nil {
+			line("RRULE:" + event.RRule.String())
+		}
+		line("SUMMARY:" + escapeText(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION:" + escapeText(event.Description))
+		}
+		if event.Transparent {
+			line("TRANSP:TRANSPARENT")
+		} else {
+			line("TRANSP:OPAQUE")
+		}
+		if event.Cancelled {
+			line("STATUS:CANCELLED")
+		}
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// String formats the rule as an RRULE value
+func (r RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Days != 0 {
+		var days []string
+		for i, day := range icalDays {
+			if r.Days&(1<<i) != 0 {
+				days = append(days, day)
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(icalDateTimeUTC))
+	}
+	return strings.Join(parts, ";")
+}
+
+func formatICalTime(name string, t time.Time, loc *time.Location, allDay bool) string {
+	switch {
+	case allDay:
+		return name + ";VALUE=DATE:" + t.Format(icalDate)
+	case loc == nil || loc == time.UTC:
+		return name + ":" + t.UTC().Format(icalDateTimeUTC)
+	default:
+		return name + ";TZID=" + loc.String() + ":" + t.In(loc).Format(icalDateTime)
+	}
+}
+
+// writeFolded writes a content line, folding it at 75 octets without
+// splitting a UTF-8 sequence
+func writeFolded(w *bufio.Writer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = 74 // the leading space counts
+	}
+	w.WriteString(s + "\r\n")
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// ParseICal reads the VEVENTs of a VCALENDAR. Floating times and dates are
+// read in defaultLoc, as are TZIDs that are not IANA zone names.
+func ParseICal(r io.Reader, defaultLoc *time.Location) ([]Event, error) {
+	if defaultLoc == nil {
+		defaultLoc = time.UTC
+	}
+
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current *Event
+	var hasEnd bool
+	var duration time.Duration
+	var stack []string
+	sawCalendar := false
+
+	for _, raw := range lines {
+		name, params, value, ok := parseContentLine(raw)
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "BEGIN":
+			component := strings.ToUpper(value)
+			stack = append(stack, component)
+			if component == "VCALENDAR" {
+				sawCalendar = true
+			}
+			if component == "VEVENT" && len(stack) == 2 {
+				current = &Event{}
+				hasEnd, duration = false, 0
+			}
+			continue
+		case "END":
+			if len(stack) == 0 {
+				return nil, errors.New("invalid calendar: unexpected END")
+			}
+			component := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if component == "VEVENT" && current != nil && len(stack) == 1 {
+				if current.Start.IsZero() {
+					current = nil
+					continue
+				}
+				if !hasEnd {
+					switch {
+					case duration > 0:
+						current.End = current.Start.Add(duration)
+					case current.AllDay:
+						current.End = current.Start.AddDate(0, 0, 1)
+					default:
+						current.End = current.Start
+					}
+				}
+				events = append(events, *current)
+				if len(events) > maxICalEvents {
+					return nil, fmt.Errorf("calendar has more than %d events", maxICalEvents)
+				}
+				current = nil
+			}
+			continue
+		}
+
+		// Only properties directly inside a VEVENT matter; alarms and
+		// time zone definitions are skipped
+		if current == nil || len(stack) != 2 || stack[1] != "VEVENT" {
+			continue
+		}
+
+		switch name {
+		case "UID":
+			current.UID = unescapeText(value)
+		case "SUMMARY":
+			current.Summary = unescapeText(value)
+		case "DESCRIPTION":
+			current.Description = unescapeText(value)
+		case "DTSTART":
+			t, loc, allDay, err := parseICalTime(value, params, defaultLoc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DTSTART %q", value)
+			}
+			current.Start, current.Location, current.AllDay = t, loc, allDay
+		case "DTEND":
+			t, _, _, err := parseICalTime(value, params, defaultLoc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DTEND %q", value)
+			}
+			current.End, hasEnd = t, true
+		case "DURATION":
+			d, err := parseICalDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DURATION %q", value)
+			}
+			duration = d
+		case "RRULE":
+			rule, err := parseRRule(value)
+			if err != nil {
+				return nil, err
+			}
+			current.RRule = rule
+		case "TRANSP":
+			current.Transparent = strings.EqualFold(value, "TRANSPARENT")
+		case "STATUS":
+			current.Cancelled = strings.EqualFold(value, "CANCELLED")
+		}
+	}
+
+	if !sawCalendar {
+		return nil, errors.New("invalid calendar: missing VCALENDAR")
+	}
+	return events, nil
+}
+
+// unfoldLines splits the input into content lines, joining folded ones
+func unfoldLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseContentLine splits "NAME;PARAM=VALUE:value", honouring quoted
+// parameter values that contain colons
+func parseContentLine(line string) (name string, params map[string]string, value string, ok bool) {
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	name = strings.ToUpper(parts[0])
+	params = make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		if key, val, found := strings.Cut(part, "="); found {
+			params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+		}
+	}
+	return name, params, value, true
+}
+
+func parseICalTime(value string, params map[string]string, defaultLoc *time.Location) (time.Time, *time.Location, bool, error) {
+	if strings.EqualFold(params["VALUE"], "DATE") || len(value) == len(icalDate) {
+		t, err := time.ParseInLocation(icalDate, value, defaultLoc)
+		return t, nil, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icalDateTimeUTC, value)
+		return t, time.UTC, false, err
+	}
+
+	loc := defaultLoc
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(icalDateTime, value, loc)
+	return t, loc, false, err
+}
+
+// parseICalDuration parses durations such as "PT1H30M", "P1D" or "P1W"
+func parseICalDuration(value string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	if s == value || s == "" {
+		return 0, errors.New("invalid duration")
+	}
+
+	var total time.Duration
+	inTime := false
+	number := ""
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			number += string(r)
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, errors.New("invalid duration")
+		}
+		number = ""
+
+		switch {
+		case r == 'W' && !inTime:
+			total += time.Duration(n) * 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			total += time.Duration(n) * 24 * time.Hour
+		case r == 'H' && inTime:
+			total += time.Duration(n) * time.Hour
+		case r == 'M' && inTime:
+			total += time.Duration(n) * time.Minute
+		case r == 'S' && inTime:
+			total += time.Duration(n) * time.Second
+		default:
+			return 0, errors.New("invalid duration")
+		}
+	}
+	if number != "" {
+		return 0, errors.New("invalid duration")
+	}
+	return total, nil
+}
+
+func parseRRule(value string) (*RRule, error) {
+	rule := &RRule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE %q", value)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE %q", value)
+			}
+			rule.Count = n
+		case "UNTIL":
+			var until time.Time
+			var err error
+			if len(val) == len(icalDate) {
+				until, err = time.Parse(icalDate, val)
+			} else {
+				until, err = time.Parse(icalDateTimeUTC, val)
+				if err != nil {
+					until, err = time.Parse(icalDateTime, val)
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE %q", value)
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				// Ordinals such as "1MO" only make sense for monthly rules;
+				// keep the weekday and let the caller reject the frequency
+				day = strings.ToUpper(strings.TrimLeft(day, "+-0123456789"))
+				for i, name := range icalDays {
+					if day == name {
+						rule.Days |= 1 << i
+					}
+				}
+			}
+		}
+	}
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("invalid RRULE %q", value)
+	}
+	return rule, nil
+}
@@ -0,0 +1,371 @@
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// vcalendar wraps content lines in a VCALENDAR, joined with CRLF
+func vcalendar(lines ...string) string {
+	all := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...)
+	all = append(all, "END:VCALENDAR")
+	return strings.Join(all, "\r\n") + "\r\n"
+}
+
+func locationName(loc *time.Location) string {
+	if loc == nil {
+		return ""
+	}
+	return loc.String()
+}
+
+func assertEvents(t *testing.T, got, want []Event) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(got), got, len(want))
+	}
+	for i := range got {
+		g, w := got[i], want[i]
+		if g.UID != w.UID || g.Summary != w.Summary || g.Description != w.Description {
+			t.Errorf("event %d text = %q %q %q, want %q %q %q", i, g.UID, g.Summary, g.Description, w.UID, w.Summary, w.Description)
+		}
+		if !g.Start.Equal(w.Start) || !g.End.Equal(w.End) {
+			t.Errorf("event %d = %s - %s, want %s - %s", i, g.Start, g.End, w.Start, w.End)
+		}
+		if g.AllDay != w.AllDay || g.Transparent != w.Transparent || g.Cancelled != w.Cancelled {
+			t.Errorf("event %d flags = %v %v %v, want %v %v %v", i, g.AllDay, g.Transparent, g.Cancelled, w.AllDay, w.Transparent, w.Cancelled)
+		}
+		if locationName(g.Location) != locationName(w.Location) {
+			t.Errorf("event %d location = %q, want %q", i, locationName(g.Location), locationName(w.Location))
+		}
+		assertRRule(t, g.RRule, w.RRule)
+	}
+}
+
+func assertRRule(t *testing.T, got, want *RRule) {
+	t.Helper()
+	if (got == nil) != (want == nil) {
+		t.Fatalf("rrule = %+v, want %+v", got, want)
+	}
+	if got == nil {
+		return
+	}
+	if got.Freq != want.Freq || got.Interval != want.Interval || got.Days != want.Days || got.Count != want.Count {
+		t.Errorf("rrule = %+v, want %+v", *got, *want)
+	}
+	if (got.Until == nil) != (want.Until == nil) || (got.Until != nil && !got.Until.Equal(*want.Until)) {
+		t.Errorf("rrule until = %v, want %v", got.Until, want.Until)
+	}
+}
+
+func TestParseICal(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	berlin := mustLoad(t, "Europe/Berlin")
+	until := utc(2024, 6, 30, 23, 59).Add(59 * time.Second)
+
+	tests := []struct {
+		name  string
+		input string
+		want  []Event
+	}{
+		{
+			name: "UTC event",
+			input: vcalendar(
+				"BEGIN:VEVENT", "UID:a", "SUMMARY:Lesson",
+				"DTSTART:20240603T090000Z", "DTEND:20240603T100000Z",
+				"END:VEVENT",
+			),
+			want: []Event{{UID: "a", Summary: "Lesson", Start: utc(2024, 6, 3, 9, 0), End: utc(2024, 6, 3, 10, 0), Location: time.UTC}},
+		},
+		{
+			name: "folded lines are joined",
+			input: vcalendar(
+				"BEGIN:VEVENT", "UID:a", "SUMMARY:Guitar ",
+				" lesson", "DESCRIPTION:Bring",
+				"\t your own",
+				"DTSTART:20240603T090000Z", "END:VEVENT",
+			),
+			want: []Event{{UID: "a", Summary: "Guitar lesson", Description: "Bring your own", Start: utc(2024, 6, 3, 9, 0), End: utc(2024, 6, 3, 9, 0), Location: time.UTC}},
+		},
+		{
+			name: "escaped text",
+			input: vcalendar(
+				"BEGIN:VEVENT", `SUMMARY:One\, two\; three\nfour \\ five`,
+				"DTSTART:20240603T090000Z", "END:VEVENT",
+			),
+			want: []Event{{Summary: "One, two; three\nfour \\ five", Start: utc(2024, 6, 3, 9, 0), End: utc(2024, 6, 3, 9, 0), Location: time.UTC}},
+		},
+		{
+			name: "quoted parameter containing a colon",
+			input: vcalendar(
+				"BEGIN:VEVENT",
+				`DTSTART;X-NOTE="see: notes";TZID=America/New_York:20240603T090000`,
+				`DTEND;TZID="America/New_York":20240603T100000`,
+				"END:VEVENT",
+			),
+			want: []Event{{Start: utc(2024, 6, 3, 13, 0), End: utc(2024, 6, 3, 14, 0), Location: newYork}},
+		},
+		{
+			name: "unknown TZID falls back to the default zone",
+			input: vcalendar(
+				"BEGIN:VEVENT",
+				"DTSTART;TZID=Customized Time Zone:20240603T090000",
+				"DTEND;TZID=Customized Time Zone:20240603T100000",
+				"END:VEVENT",
+			),
+			want: []Event{{Start: utc(2024, 6, 3, 7, 0), End: utc(2024, 6, 3, 8, 0), Location: berlin}},
+		},
+		{
+			name: "floating time is read in the default zone",
+			input: vcalendar(
+				"BEGIN:VEVENT", "DTSTART:20240603T090000", "DTEND:20240603T100000", "END:VEVENT",
+			),
+			want: []Event{{Start: utc(2024, 6, 3, 7, 0), End: utc(2024, 6, 3, 8, 0), Location: berlin}},
+		},
+		{
+			name: "DATE without DTEND lasts one day",
+			input: vcalendar(
+				"BEGIN:VEVENT", "DTSTART;VALUE=DATE:20240603", "END:VEVENT",
+			),
+			want: []Event{{Start: time.Date(2024, 6, 3, 0, 0, 0, 0, berlin), End: time.Date(2024, 6, 4, 0, 0, 0, 0, berlin), AllDay: true}},
+		},
+		{
+			name: "DATE without a VALUE parameter",
+			input: vcalendar(
+				"BEGIN:VEVENT", "DTSTART:20240603", "DTEND:20240605", "END:VEVENT",
+			),
+			want: []Event{{Start: time.Date(2024, 6, 3, 0, 0, 0, 0, berlin), End: time.Date(2024, 6, 5, 0, 0, 0, 0, berlin), AllDay: true}},
+		},
+		{
+			name: "DURATION without DTEND",
+			input: vcalendar(
+				"BEGIN:VEVENT", "DTSTART:20240603T090000Z", "DURATION:PT1H30M", "END:VEVENT",
+			),
+			want: []Event{{Start: utc(2024, 6, 3, 9, 0), End: utc(2024, 6, 3, 10, 30), Location: time.UTC}},
+		},
+		{
+			name: "DTEND wins over DURATION",
+			input: vcalendar(
+				"BEGIN:VEVENT", "DTSTART:20240603T090000Z", "DURATION:PT3H", "DTEND:20240603T100000Z", "END:VEVENT",
+			),
+			want: []Event{{Start: utc(2024, 6, 3, 9, 0), End: utc(2024, 6, 3, 10, 0), Location: time.UTC}},
+		},
+		{
+			name: "nested VALARM and VTIMEZONE are skipped",
+			input: vcalendar(
+				"BEGIN:VTIMEZONE", "TZID:Europe/Berlin",
+				"BEGIN:STANDARD", "DTSTART:19701025T030000", "END:STANDARD",
+				"END:VTIMEZONE",
+				"BEGIN:VEVENT", "UID:a", "SUMMARY:Lesson",
+				"DTSTART:20240603T090000Z", "DTEND:20240603T100000Z",
+				"BEGIN:VALARM", "SUMMARY:Reminder", "DESCRIPTION:Alarm", "TRIGGER:-PT15M", "END:VALARM",
+				"END:VEVENT",
+			),
+			want: []Event{{UID: "a", Summary: "Lesson", Start: utc(2024, 6, 3, 9, 0), End: utc(2024, 6, 3, 10, 0), Location: time.UTC}},
+		},
+		{
+			name: "event without DTSTART is dropped",
+			input: vcalendar(
+				"BEGIN:VEVENT", "UID:a", "END:VEVENT",
+				"BEGIN:VEVENT", "UID:b", "DTSTART:20240603T090000Z", "END:VEVENT",
+			),
+			want: []Event{{UID: "b", Start: utc(2024, 6, 3, 9, 0), End: utc(2024, 6, 3, 9, 0), Location: time.UTC}},
+		},
+		{
+			name: "transparency, status and recurrence",
+			input: vcalendar(
+				"BEGIN:VEVENT", "DTSTART:20240603T090000Z", "DTEND:20240603T100000Z",
+				"TRANSP:TRANSPARENT", "STATUS:CANCELLED",
+				"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20240630T235959Z",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				Start: utc(2024, 6, 3, 9, 0), End: utc(2024, 6, 3, 10, 0), Location: time.UTC,
+				Transparent: true, Cancelled: true,
+				RRule: &RRule{Freq: "WEEKLY", Interval: 1, Days: Monday | Wednesday, Until: &until},
+			}},
+		},
+		{
+			name: "lowercase names and LF line endings",
+			input: strings.Join([]string{
+				"begin:vcalendar", "begin:vevent", "dtstart:20240603T090000Z", "end:vevent", "end:vcalendar",
+			}, "\n"),
+			want: []Event{{Start: utc(2024, 6, 3, 9, 0), End: utc(2024, 6, 3, 9, 0), Location: time.UTC}},
+		},
+		{
+			name:  "empty calendar",
+			input: vcalendar(),
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseICal(strings.NewReader(tt.input), berlin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEvents(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseICalRejectsMalformedInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "END without BEGIN", input: "END:VEVENT\r\n"},
+		{name: "END after the calendar closed", input: vcalendar() + "END:VCALENDAR\r\n"},
+		{name: "missing VCALENDAR", input: "BEGIN:VEVENT\r\nDTSTART:20240603T090000Z\r\nEND:VEVENT\r\n"},
+		{name: "empty input", input: ""},
+		{name: "invalid DTSTART", input: vcalendar("BEGIN:VEVENT", "DTSTART:2024-06-03T09:00:00Z", "END:VEVENT")},
+		{name: "invalid DTEND", input: vcalendar("BEGIN:VEVENT", "DTSTART:20240603T090000Z", "DTEND:tomorrow", "END:VEVENT")},
+		{name: "invalid DURATION", input: vcalendar("BEGIN:VEVENT", "DTSTART:20240603T090000Z", "DURATION:1H", "END:VEVENT")},
+		{name: "RRULE without FREQ", input: vcalendar("BEGIN:VEVENT", "DTSTART:20240603T090000Z", "RRULE:BYDAY=MO", "END:VEVENT")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if events, err := ParseICal(strings.NewReader(tt.input), nil); err == nil {
+				t.Errorf("ParseICal succeeded with %+v", events)
+			}
+		})
+	}
+}
+
+func TestParseICalEventLimit(t *testing.T) {
+	build := func(n int) string {
+		lines := make([]string, 0, 3*n)
+		for i := 0; i < n; i++ {
+			lines = append(lines, "BEGIN:VEVENT", fmt.Sprintf("DTSTART:20240603T%02d0000Z", i%24), "END:VEVENT")
+		}
+		return vcalendar(lines...)
+	}
+
+	events, err := ParseICal(strings.NewReader(build(maxICalEvents)), nil)
+	if err != nil || len(events) != maxICalEvents {
+		t.Fatalf("ParseICal(%d events) = %d events, %v", maxICalEvents, len(events), err)
+	}
+	if _, err := ParseICal(strings.NewReader(build(maxICalEvents+1)), nil); err == nil {
+		t.Errorf("ParseICal accepted %d events", maxICalEvents+1)
+	}
+}
+
+func TestICalRoundTrip(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	until := utc(2024, 9, 1, 0, 0)
+
+	events := []Event{
+		{
+			UID:         "slot-1@example.com",
+			Summary:     "Available; evenings, mostly",
+			Description: strings.Repeat("Ünïcödé text that needs folding. ", 5) + "\nSecond line",
+			Start:       utc(2024, 6, 3, 22, 0),
+			End:         utc(2024, 6, 4, 1, 0),
+			Location:    newYork,
+			RRule:       &RRule{Freq: "WEEKLY", Interval: 2, Days: Monday | Wednesday | Sunday, Until: &until},
+			Transparent: true,
+		},
+		{
+			UID:      "slot-2@example.com",
+			Summary:  "Daily",
+			Start:    utc(2024, 6, 3, 9, 0),
+			End:      utc(2024, 6, 3, 10, 0),
+			Location: time.UTC,
+			RRule:    &RRule{Freq: "DAILY", Interval: 1, Count: 10},
+		},
+		{
+			UID:       "booking-3@example.com",
+			Summary:   "Cancelled",
+			Start:     time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC),
+			End:       time.Date(2024, 6, 6, 0, 0, 0, 0, time.UTC),
+			AllDay:    true,
+			Cancelled: true,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeICal(&buf, "Availability", events); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+
+	got, err := ParseICal(&buf, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEvents(t, got, events)
+}
+
+func TestParseICalDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"PT1H30M", 90 * time.Minute},
+		{"PT45S", 45 * time.Second},
+		{"+PT15M", 15 * time.Minute},
+		{"P1D", 24 * time.Hour},
+		{"P2W", 14 * 24 * time.Hour},
+		{"P1DT2H", 26 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got, err := parseICalDuration(tt.value); err != nil || got != tt.want {
+			t.Errorf("parseICalDuration(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "P", "1H", "-PT15M", "PT1H5", "P1H", "PT1D", "PTXM"} {
+		if _, err := parseICalDuration(bad); err == nil {
+			t.Errorf("parseICalDuration(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestParseRRule(t *testing.T) {
+	untilDate := utc(2024, 6, 30, 0, 0)
+	untilUTC := utc(2024, 6, 30, 12, 0)
+
+	tests := []struct {
+		value string
+		want  RRule
+	}{
+		{"FREQ=WEEKLY", RRule{Freq: "WEEKLY", Interval: 1}},
+		{"freq=weekly;byday=mo,we;interval=2", RRule{Freq: "WEEKLY", Interval: 2, Days: Monday | Wednesday}},
+		{"FREQ=MONTHLY;BYDAY=1MO,-1FR", RRule{Freq: "MONTHLY", Interval: 1, Days: Monday | Friday}},
+		{"FREQ=DAILY;COUNT=3", RRule{Freq: "DAILY", Interval: 1, Count: 3}},
+		{"FREQ=WEEKLY;UNTIL=20240630", RRule{Freq: "WEEKLY", Interval: 1, Until: &untilDate}},
+		{"FREQ=WEEKLY;UNTIL=20240630T120000Z", RRule{Freq: "WEEKLY", Interval: 1, Until: &untilUTC}},
+		{"FREQ=WEEKLY;UNTIL=20240630T120000", RRule{Freq: "WEEKLY", Interval: 1, Until: &untilUTC}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseRRule(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertRRule(t, got, &tt.want)
+
+			// Our own export reads back unchanged
+			again, err := parseRRule(got.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertRRule(t, again, got)
+		})
+	}
+
+	for _, bad := range []string{"", "BYDAY=MO", "FREQ=WEEKLY;INTERVAL=0", "FREQ=WEEKLY;COUNT=x", "FREQ=WEEKLY;UNTIL=soon"} {
+		if _, err := parseRRule(bad); err == nil {
+			t.Errorf("parseRRule(%q) succeeded", bad)
+		}
+	}
+}
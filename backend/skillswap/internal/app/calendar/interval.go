@@ -0,0 +1,121 @@
+package calendar
+
+import (
+	"sort"
+	"time"
+)
+
+// Interval is a half-open span of absolute time [Start, End)
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Duration returns the length of the interval
+func (i Interval) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// Empty reports whether the interval contains no time
+func (i Interval) Empty() bool {
+	return !i.Start.Before(i.End)
+}
+
+// In returns the interval with both ends shown in loc
+func (i Interval) In(loc *time.Location) Interval {
+	return Interval{Start: i.Start.In(loc), End: i.End.In(loc)}
+}
+
+// Normalize sorts intervals and merges the ones that overlap or touch, so
+// 09:00-10:00 and 10:00-11:00 become 09:00-11:00.
+// Empty intervals are dropped. The input slice is not modified.
+func Normalize(intervals []Interval) []Interval {
+	sorted := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		if !interval.Empty() {
+			sorted = append(sorted, interval)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	merged := make([]Interval, 0, len(sorted))
+	for _, interval := range sorted {
+		if n := len(merged); n > 0 && !interval.Start.After(merged[n-1].End) {
+			if interval.End.After(merged[n-1].End) {
+				merged[n-1].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// Intersect returns the time covered by both a and b
+func Intersect(a, b []Interval) []Interval {
+	a, b = Normalize(a), Normalize(b)
+
+	var result []Interval
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start := latest(a[i].Start, b[j].Start)
+		end := earliest(a[i].End, b[j].End)
+		if start.Before(end) {
+			result = append(result, Interval{Start: start, End: end})
+		}
+
+		if a[i].End.Before(b[j].End) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+// Clip restricts intervals to the window
+func Clip(intervals []Interval, window Interval) []Interval {
+	return Intersect(intervals, []Interval{window})
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// Subtract returns the parts of a not covered by b
+func Subtract(a, b []Interval) []Interval {
+	a, b = Normalize(a), Normalize(b)
+
+	var result []Interval
+	j := 0
+	for _, interval := range a {
+		start := interval.Start
+		// Skip holes that end before this interval starts
+		for j < len(b) && !b[j].End.After(start) {
+			j++
+		}
+		for k := j; k < len(b) && b[k].Start.Before(interval.End); k++ {
+			if b[k].Start.After(start) {
+				result = append(result, Interval{Start: start, End: b[k].Start})
+			}
+			if b[k].End.After(start) {
+				start = b[k].End
+			}
+		}
+		if start.Before(interval.End) {
+			result = append(result, Interval{Start: start, End: interval.End})
+		}
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+// hours returns an interval between two hours of 3 June 2024, UTC
+func hours(start, end int) Interval {
+	return Interval{Start: utc(2024, 6, 3, start, 0), End: utc(2024, 6, 3, end, 0)}
+}
+
+func assertIntervals(t *testing.T, got, want []Interval) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	got := Normalize([]Interval{hours(13, 14), hours(9, 10), hours(10, 11), hours(12, 12), hours(9, 10)})
+	assertIntervals(t, got, []Interval{hours(9, 11), hours(13, 14)})
+}
+
+func TestIntersect(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []Interval
+		want []Interval
+	}{
+		{name: "overlap", a: []Interval{hours(9, 12)}, b: []Interval{hours(11, 14)}, want: []Interval{hours(11, 12)}},
+		{name: "touching", a: []Interval{hours(9, 11)}, b: []Interval{hours(11, 13)}, want: nil},
+		{name: "contained", a: []Interval{hours(9, 17)}, b: []Interval{hours(10, 11), hours(13, 14)}, want: []Interval{hours(10, 11), hours(13, 14)}},
+		{name: "unsorted and overlapping input", a: []Interval{hours(14, 16), hours(9, 11), hours(10, 12)}, b: []Interval{hours(11, 15)}, want: []Interval{hours(11, 12), hours(14, 15)}},
+		{name: "empty side", a: []Interval{hours(9, 17)}, b: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertIntervals(t, Intersect(tt.a, tt.b), tt.want)
+			assertIntervals(t, Intersect(tt.b, tt.a), tt.want)
+		})
+	}
+}
+
+func TestIntersectAcrossTimezones(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	// 09:00-17:00 in New York and 15:00-23:00 in Berlin, on a Monday when
+	// only the US has moved its clocks: 9 and 15 o'clock are 13:00 and 14:00 UTC
+	window := Interval{Start: utc(2024, 3, 11, 0, 0), End: utc(2024, 3, 12, 0, 0)}
+	ny := WeeklyRule{Days: Monday, Start: 9 * 60, End: 17 * 60, Location: newYork}.Expand(window)
+	de := WeeklyRule{Days: Monday, Start: 15 * 60, End: 23 * 60, Location: berlin}.Expand(window)
+
+	assertIntervals(t, Intersect(ny, de), []Interval{{Start: utc(2024, 3, 11, 14, 0), End: utc(2024, 3, 11, 21, 0)}})
+}
+
+func TestIntervalDurationAcrossDST(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	day := Interval{
+		Start: time.Date(2024, 3, 10, 0, 0, 0, 0, newYork),
+		End:   time.Date(2024, 3, 11, 0, 0, 0, 0, newYork),
+	}
+	if day.Duration() != 23*time.Hour {
+		t.Errorf("spring forward day lasts %s, want 23h", day.Duration())
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []Interval
+		want []Interval
+	}{
+		{name: "nothing to remove", a: []Interval{hours(9, 17)}, b: nil, want: []Interval{hours(9, 17)}},
+		{name: "hole in the middle", a: []Interval{hours(9, 17)}, b: []Interval{hours(12, 13)}, want: []Interval{hours(9, 12), hours(13, 17)}},
+		{name: "overlapping the start", a: []Interval{hours(9, 17)}, b: []Interval{hours(8, 10)}, want: []Interval{hours(10, 17)}},
+		{name: "overlapping the end", a: []Interval{hours(9, 17)}, b: []Interval{hours(16, 18)}, want: []Interval{hours(9, 16)}},
+		{name: "touching", a: []Interval{hours(9, 12)}, b: []Interval{hours(12, 13), hours(7, 9)}, want: []Interval{hours(9, 12)}},
+		{name: "covered entirely", a: []Interval{hours(9, 12)}, b: []Interval{hours(8, 13)}, want: nil},
+		{name: "several holes", a: []Interval{hours(8, 18)}, b: []Interval{hours(15, 16), hours(9, 10), hours(9, 11)}, want: []Interval{hours(8, 9), hours(11, 15), hours(16, 18)}},
+		{name: "one hole across two intervals", a: []Interval{hours(8, 10), hours(11, 14)}, b: []Interval{hours(9, 12)}, want: []Interval{hours(8, 9), hours(12, 14)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertIntervals(t, Subtract(tt.a, tt.b), tt.want)
+		})
+	}
+}
+
+func TestSubtractAcrossDST(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+
+	// Evenings from 8 to 11 March, minus a blackout for the local day of
+	// 10 March, which is 23 hours long because clocks go forward
+	available := WeeklyRule{Days: AllDays, Start: 20 * 60, End: 22 * 60, Location: newYork}.
+		Expand(Interval{Start: utc(2024, 3, 9, 0, 0), End: utc(2024, 3, 12, 0, 0)})
+	blackout := Interval{
+		Start: time.Date(2024, 3, 10, 0, 0, 0, 0, newYork),
+		End:   time.Date(2024, 3, 11, 0, 0, 0, 0, newYork),
+	}
+
+	assertIntervals(t, Subtract(available, []Interval{blackout}), []Interval{
+		{Start: utc(2024, 3, 9, 1, 0), End: utc(2024, 3, 9, 3, 0)},   // 8 March, EST
+		{Start: utc(2024, 3, 10, 1, 0), End: utc(2024, 3, 10, 3, 0)}, // 9 March, EST
+	})
+}
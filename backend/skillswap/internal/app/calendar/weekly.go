@@ -0,0 +1,115 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Day bits used by availability bitmasks (Monday=1, Tuesday=2, ..., Sunday=64)
+const (
+	Monday int32 = 1 << iota
+	Tuesday
+	Wednesday
+	Thursday
+	Friday
+	Saturday
+	Sunday
+
+	AllDays = Monday | Tuesday | Wednesday | Thursday | Friday | Saturday | Sunday
+)
+
+// DayBit returns the bitmask bit for a weekday
+func DayBit(day time.Weekday) int32 {
+	return 1 << ((int(day) + 6) % 7)
+}
+
+// Clock is a wall-clock time of day in minutes after midnight
+type Clock int
+
+// ParseClock parses "15:04"
+func ParseClock(s string) (Clock, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.New("invalid time format, use HH:MM")
+	}
+	return ClockOf(t), nil
+}
+
+// ClockOf returns the wall-clock time of t in its own location
+func ClockOf(t time.Time) Clock {
+	return Clock(t.Hour()*60 + t.Minute())
+}
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", int(c)/60, int(c)%60)
+}
+
+// WeeklyRule repeats a wall-clock window on the days in Days, in Location.
+// When End is not after Start the window runs overnight and ends on the
+// following day, so Monday 23:00-01:00 ends early on Tuesday.
+type WeeklyRule struct {
+	Days     int32
+	Start    Clock
+	End      Clock
+	Location *time.Location
+}
+
+// Overnight reports whether the window crosses midnight
+func (r WeeklyRule) Overnight() bool {
+	return r.End <= r.Start
+}
+
+// Expand returns the absolute occurrences of the rule that overlap window,
+// clipped to it. Wall-clock times are resolved per date, so an occurrence
+// keeps its local time across DST changes and its real length may differ by
+// the DST shift. A time inside a DST gap is resolved by time.Date to one
+// gap's length earlier, so 02:30 on the night clocks go forward is 01:30.
+func (r WeeklyRule) Expand(window Interval) []Interval {
+	loc := r.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	// Start a day early so an overnight occurrence from the previous day is
+	// included
+	first := window.Start.In(loc).AddDate(0, 0, -1)
+	day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+
+	var occurrences []Interval
+	for ; day.Before(window.End); day = day.AddDate(0, 0, 1) {
+		if r.Days&DayBit(day.Weekday()) == 0 {
+			continue
+		}
+
+		start := r.at(day, r.Start)
+		endDay := day
+		if r.Overnight() {
+			endDay = day.AddDate(0, 0, 1)
+		}
+		end := r.at(endDay, r.End)
+
+		occurrences = append(occurrences, Interval{Start: start, End: end})
+	}
+
+	return Clip(occurrences, window)
+}
+
+func (r WeeklyRule) at(day time.Time, clock Clock) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(clock)/60, int(clock)%60, 0, 0, day.Location())
+}
+
+// WeekStart returns midnight on the Monday of the week containing t, in loc
+func WeekStart(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	offset := (int(t.Weekday()) + 6) % 7
+	monday := t.AddDate(0, 0, -offset)
+	return time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, loc)
+}
+
+// Week returns the seven calendar days starting at WeekStart(t, loc). The
+// interval is 167 or 169 hours long in weeks with a DST change.
+func Week(t time.Time, loc *time.Location) Interval {
+	start := WeekStart(t, loc)
+	return Interval{Start: start, End: start.AddDate(0, 0, 7)}
+}
@@ -0,0 +1,142 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func utc(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestWeeklyRuleExpand(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	tests := []struct {
+		name   string
+		rule   WeeklyRule
+		window Interval
+		want   []Interval
+	}{
+		{
+			name:   "local time kept across spring forward",
+			rule:   WeeklyRule{Days: Monday, Start: 9 * 60, End: 10 * 60, Location: newYork},
+			window: Interval{Start: utc(2024, 3, 4, 0, 0), End: utc(2024, 3, 12, 0, 0)},
+			want: []Interval{
+				{Start: utc(2024, 3, 4, 14, 0), End: utc(2024, 3, 4, 15, 0)},   // EST
+				{Start: utc(2024, 3, 11, 13, 0), End: utc(2024, 3, 11, 14, 0)}, // EDT
+			},
+		},
+		{
+			name:   "occurrence spanning spring forward is an hour shorter",
+			rule:   WeeklyRule{Days: Sunday, Start: 1 * 60, End: 4 * 60, Location: newYork},
+			window: Interval{Start: utc(2024, 3, 10, 0, 0), End: utc(2024, 3, 11, 0, 0)},
+			want:   []Interval{{Start: utc(2024, 3, 10, 6, 0), End: utc(2024, 3, 10, 8, 0)}},
+		},
+		{
+			name:   "start inside the spring forward gap",
+			rule:   WeeklyRule{Days: Sunday, Start: 2*60 + 30, End: 5 * 60, Location: newYork},
+			window: Interval{Start: utc(2024, 3, 10, 0, 0), End: utc(2024, 3, 11, 0, 0)},
+			want:   []Interval{{Start: utc(2024, 3, 10, 6, 30), End: utc(2024, 3, 10, 9, 0)}},
+		},
+		{
+			name:   "occurrence spanning fall back is an hour longer",
+			rule:   WeeklyRule{Days: Sunday, Start: 0, End: 3 * 60, Location: newYork},
+			window: Interval{Start: utc(2024, 11, 3, 0, 0), End: utc(2024, 11, 4, 0, 0)},
+			want:   []Interval{{Start: utc(2024, 11, 3, 4, 0), End: utc(2024, 11, 3, 8, 0)}},
+		},
+		{
+			name:   "overnight across spring forward",
+			rule:   WeeklyRule{Days: Saturday, Start: 23 * 60, End: 3 * 60, Location: newYork},
+			window: Interval{Start: utc(2024, 3, 9, 0, 0), End: utc(2024, 3, 11, 0, 0)},
+			want:   []Interval{{Start: utc(2024, 3, 10, 4, 0), End: utc(2024, 3, 10, 7, 0)}},
+		},
+		{
+			name:   "overnight from the day before the window",
+			rule:   WeeklyRule{Days: Monday, Start: 23 * 60, End: 1 * 60, Location: time.UTC},
+			window: Interval{Start: utc(2024, 6, 4, 0, 0), End: utc(2024, 6, 5, 0, 0)},
+			want:   []Interval{{Start: utc(2024, 6, 4, 0, 0), End: utc(2024, 6, 4, 1, 0)}},
+		},
+		{
+			name:   "clipped to the window",
+			rule:   WeeklyRule{Days: AllDays, Start: 9 * 60, End: 17 * 60},
+			window: Interval{Start: utc(2024, 6, 3, 12, 0), End: utc(2024, 6, 4, 10, 0)},
+			want: []Interval{
+				{Start: utc(2024, 6, 3, 12, 0), End: utc(2024, 6, 3, 17, 0)},
+				{Start: utc(2024, 6, 4, 9, 0), End: utc(2024, 6, 4, 10, 0)},
+			},
+		},
+		{
+			name:   "only the chosen days",
+			rule:   WeeklyRule{Days: Tuesday | Thursday, Start: 9 * 60, End: 10 * 60, Location: time.UTC},
+			window: Interval{Start: utc(2024, 6, 3, 0, 0), End: utc(2024, 6, 10, 0, 0)},
+			want: []Interval{
+				{Start: utc(2024, 6, 4, 9, 0), End: utc(2024, 6, 4, 10, 0)},
+				{Start: utc(2024, 6, 6, 9, 0), End: utc(2024, 6, 6, 10, 0)},
+			},
+		},
+		{
+			name:   "weekday taken in the rule's location",
+			rule:   WeeklyRule{Days: Monday, Start: 0, End: 60, Location: berlin},
+			window: Interval{Start: utc(2024, 6, 2, 0, 0), End: utc(2024, 6, 4, 0, 0)},
+			want:   []Interval{{Start: utc(2024, 6, 2, 22, 0), End: utc(2024, 6, 2, 23, 0)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertIntervals(t, tt.rule.Expand(tt.window), tt.want)
+		})
+	}
+}
+
+func TestWeekAcrossDST(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		at    time.Time
+		hours float64
+	}{
+		{utc(2024, 3, 6, 12, 0), 167},
+		{utc(2024, 11, 1, 12, 0), 169},
+		{utc(2024, 6, 5, 12, 0), 168},
+	}
+	for _, tt := range tests {
+		week := Week(tt.at, newYork)
+		if got := week.Duration().Hours(); got != tt.hours {
+			t.Errorf("Week(%s) lasts %v hours, want %v", tt.at, got, tt.hours)
+		}
+		if start := week.Start.In(newYork); start.Weekday() != time.Monday || start.Hour() != 0 {
+			t.Errorf("Week(%s) starts %s, want Monday midnight", tt.at, start)
+		}
+	}
+}
+
+func TestDayBit(t *testing.T) {
+	if DayBit(time.Monday) != Monday || DayBit(time.Sunday) != Sunday || DayBit(time.Saturday) != Saturday {
+		t.Error("DayBit does not match the day constants")
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	clock, err := ParseClock("09:05")
+	if err != nil || clock != 9*60+5 || clock.String() != "09:05" {
+		t.Errorf("ParseClock(09:05) = %v, %v", clock, err)
+	}
+	for _, bad := range []string{"9am", "24:00", "12:60", ""} {
+		if _, err := ParseClock(bad); err == nil {
+			t.Errorf("ParseClock(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package clientip finds the address of the client behind reverse proxies.
+// Forwarding headers are only believed as far as they were written by
+// trusted proxies: the chain is read from the right, and the first address
+// that is not a trusted proxy is the client.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Headers a Resolver can read the forwarding chain from
+const (
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderForwarded     = "Forwarded" // RFC 7239
+)
+
+// Aliases that ParsePrefixes accepts for common ranges
+var aliases = map[string][]string{
+	"loopback": {"127.0.0.0/8", "::1/128"},
+	"private":  {"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"},
+}
+
+// ParsePrefixes parses a comma-separated list of CIDRs, addresses, and the
+// aliases "loopback" and "private"
+func ParsePrefixes(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if cidrs, ok := aliases[strings.ToLower(part)]; ok {
+			for _, cidr := range cidrs {
+				prefixes = append(prefixes, netip.MustParsePrefix(cidr))
+			}
+			continue
+		}
+		if strings.Contains(part, "/") {
+			prefix, err := netip.ParsePrefix(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", part)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q", part)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// Resolver finds client addresses. With no trusted proxies it ignores the
+// headers and returns the peer address.
+type Resolver struct {
+	trusted []netip.Prefix
+	header  string
+}
+
+// NewResolver returns a resolver that trusts the proxies in trusted to
+// append to header, one of HeaderXForwardedFor and HeaderForwarded
+func NewResolver(trusted []netip.Prefix, header string) (*Resolver, error) {
+	switch header {
+	case HeaderXForwardedFor, HeaderForwarded:
+	default:
+		return nil, fmt.Errorf("unsupported client IP header %q", header)
+	}
+	return &Resolver{trusted: trusted, header: header}, nil
+}
+
+// Resolve returns the client address of r. It is invalid only when
+// RemoteAddr is not an address, which the net/http server never does.
+func (res *Resolver) Resolve(r *http.Request) netip.Addr {
+	peer := parseAddr(r.RemoteAddr)
+	if !peer.IsValid() || !res.isTrusted(peer) {
+		return peer
+	}
+
+	var chain []string
+	if res.header == HeaderForwarded {
+		chain = forwardedFor(r.Header.Values(HeaderForwarded))
+	} else {
+		chain = splitList(r.Header.Values(HeaderXForwardedFor))
+	}
+
+	// Walk back from the nearest proxy until an untrusted address. An entry
+	// that is not an address, such as "unknown" or an obfuscated node, ends
+	// the walk at the proxy that wrote it.
+	client := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr := parseAddr(chain[i])
+		if !addr.IsValid() {
+			break
+		}
+		client = addr
+		if !res.isTrusted(addr) {
+			break
+		}
+	}
+	return client
+}
+
+func (res *Resolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range res.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddr parses an address with or without a port, IPv6 possibly in
+// brackets
+func parseAddr(s string) netip.Addr {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// splitList joins the comma-separated values of all header lines, in order
+func splitList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+	}
+	return list
+}
+
+// forwardedFor returns the for= node of each element of Forwarded headers,
+// such as `for=192.0.2.60;proto=https, for="[2001:db8::1]:4711"`. Elements
+// without one yield "", which stops the walk like any unparsable node.
+func forwardedFor(values []string) []string {
+	var nodes []string
+	for _, value := range values {
+		for _, element := range splitOutsideQuotes(value, ',') {
+			node := ""
+			for _, pair := range splitOutsideQuotes(element, ';') {
+				name, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(name, "for") {
+					node = strings.Trim(v, `"`)
+				}
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// splitOutsideQuotes splits s at sep, except inside quoted strings
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			if quoted {
+				i++
+			}
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,208 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestParsePrefixes(t *testing.T) {
+	prefixes, err := ParsePrefixes(" loopback, 10.1.2.3 ,2001:db8::/32,, 192.168.1.7/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"127.0.0.0/8", "::1/128", "10.1.2.3/32", "2001:db8::/32", "192.168.1.0/24"}
+	if len(prefixes) != len(want) {
+		t.Fatalf("got %v, want %v", prefixes, want)
+	}
+	for i := range want {
+		if prefixes[i].String() != want[i] {
+			t.Fatalf("got %v, want %v", prefixes, want)
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "example.com", "private,nope"} {
+		if _, err := ParsePrefixes(bad); err == nil {
+			t.Errorf("ParsePrefixes(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestNewResolverHeader(t *testing.T) {
+	if _, err := NewResolver(nil, "X-Real-IP"); err == nil {
+		t.Error("NewResolver accepted an unsupported header")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	trusted, err := ParsePrefixes("private, loopback")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		trusted []netip.Prefix
+		remote  string
+		values  []string
+		want    string
+	}{
+		{
+			name:   "no trusted proxies ignores the header",
+			header: HeaderXForwardedFor,
+			remote: "10.0.0.1:1234",
+			values: []string{"203.0.113.9"},
+			want:   "10.0.0.1",
+		},
+		{
+			name:    "untrusted peer ignores the header",
+			header:  HeaderXForwardedFor,
+			trusted: trusted,
+			remote:  "198.51.100.4:1234",
+			values:  []string{"203.0.113.9"},
+			want:    "198.51.100.4",
+		},
+		{
+			name:    "client behind one proxy",
+			header:  HeaderXForwardedFor,
+			trusted: trusted,
+			remote:  "10.0.0.1:1234",
+			values:  []string{"203.0.113.9"},
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "spoofed entries left of the client are ignored",
+			header:  HeaderXForwardedFor,
+			trusted: trusted,
+			remote:  "10.0.0.1:1234",
+			values:  []string{"1.1.1.1, 203.0.113.9, 10.0.0.2"},
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "chain split over header lines",
+			header:  HeaderXForwardedFor,
+			trusted: trusted,
+			remote:  "10.0.0.1:1234",
+			values:  []string{"1.1.1.1, 203.0.113.9", "10.0.0.2"},
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "every hop trusted",
+			header:  HeaderXForwardedFor,
+			trusted: trusted,
+			remote:  "127.0.0.1:1234",
+			values:  []string{"10.0.0.3, 10.0.0.2"},
+			want:    "10.0.0.3",
+		},
+		{
+			name:    "unparsable entry stops at the proxy that wrote it",
+			header:  HeaderXForwardedFor,
+			trusted: trusted,
+			remote:  "10.0.0.1:1234",
+			values:  []string{"203.0.113.9, unknown, 10.0.0.2"},
+			want:    "10.0.0.2",
+		},
+		{
+			name:    "no header",
+			header:  HeaderXForwardedFor,
+			trusted: trusted,
+			remote:  "10.0.0.1:1234",
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "IPv4-mapped addresses are unmapped",
+			header:  HeaderXForwardedFor,
+			trusted: trusted,
+			remote:  "[::ffff:10.0.0.1]:1234",
+			values:  []string{"::ffff:203.0.113.9"},
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "Forwarded with IPv6 and a port",
+			header:  HeaderForwarded,
+			trusted: trusted,
+			remote:  "10.0.0.1:1234",
+			values:  []string{`for="[2001:db8::1]:4711";proto=https, for=10.0.0.2`},
+			want:    "2001:db8::1",
+		},
+		{
+			name:    "Forwarded element without for",
+			header:  HeaderForwarded,
+			trusted: trusted,
+			remote:  "10.0.0.1:1234",
+			values:  []string{"for=203.0.113.9, proto=https"},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "Forwarded ignores X-Forwarded-For",
+			header:  HeaderForwarded,
+			trusted: trusted,
+			remote:  "10.0.0.1:1234",
+			want:    "10.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := NewResolver(tt.trusted, tt.header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.values {
+				r.Header.Add(tt.header, v)
+			}
+			if tt.header == HeaderForwarded {
+				r.Header.Set(HeaderXForwardedFor, "1.1.1.1")
+			}
+			if got := res.Resolve(r); got.String() != tt.want {
+				t.Errorf("Resolve = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardedFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{
+			name:   "several elements",
+			values: []string{`for=192.0.2.60;proto=https;by=203.0.113.43, for="[2001:db8::1]:4711"`},
+			want:   []string{"192.0.2.60", "[2001:db8::1]:4711"},
+		},
+		{
+			name:   "case-insensitive parameter name",
+			values: []string{"For=192.0.2.60"},
+			want:   []string{"192.0.2.60"},
+		},
+		{
+			name:   "separators inside quotes",
+			values: []string{`for="_a,b;c", for=192.0.2.60`},
+			want:   []string{"_a,b;c", "192.0.2.60"},
+		},
+		{
+			name:   "element without for",
+			values: []string{"proto=https", "for=192.0.2.60"},
+			want:   []string{"", "192.0.2.60"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := forwardedFor(tt.values)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package event
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Type identifies a domain event
+type Type string
+
+const (
+	UserChanged  Type = "user.changed"
+	UserDeleted  Type = "user.deleted"
+	SkillChanged Type = "skill.changed"
+	SkillDeleted Type = "skill.deleted"
+	SwapChanged  Type = "swap.changed"
+	SwapDeleted  Type = "swap.deleted"
+
+	BookingChanged Type = "booking.changed"
+)
+
+// Event is published by services after a change has been committed
+type Event struct {
+	Type       Type
+	EntityID   uuid.UUID
+	OccurredAt time.Time
+}
+
+// Handler reacts to a published event
+type Handler func(Event)
+
+// Bus is a synchronous in-process publish/subscribe dispatcher.
+// A nil *Bus is valid and drops every event, so services can be
+// constructed without one.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[Type][]Handler
+	all      []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[Type][]Handler)}
+}
+
+// Subscribe registers a handler for the given event types, or for every
+// event when no type is given
+func (b *Bus) Subscribe(handler Handler, types ...Type) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(types) == 0 {
+		b.all = append(b.all, handler)
+		return
+	}
+	for _, t := range types {
+		b.handlers[t] = append(b.handlers[t], handler)
+	}
+}
+
+// Publish delivers an event to all matching handlers. A panicking handler is
+// logged and does not affect the publisher or other handlers.
+func (b *Bus) Publish(eventType Type, entityID uuid.UUID) {
+	if b == nil {
+		return
+	}
+
+	evt := Event{Type: eventType, EntityID: entityID, OccurredAt: time.Now()}
+
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers[eventType])+len(b.all))
+	handlers = append(handlers, b.handlers[eventType]...)
+	handlers = append(handlers, b.all...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		dispatch(handler, evt)
+	}
+}
+
+func dispatch(handler Handler, evt Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Warning: event handler for %s panicked: %v", evt.Type, r)
+		}
+	}()
+	handler(evt)
+}
@@ -0,0 +1,177 @@
+package geo
+
+import "strings"
+
+// GazetteerEntry is one known city. Aliases are extra names it can be
+// found by, such as "NYC" or "Bombay".
+type GazetteerEntry struct {
+	City        string
+	Country     string
+	CountryCode string
+	Latitude    float64
+	Longitude   float64
+	Timezone    string
+	Aliases     []string
+}
+
+// Gazetteer is an offline Geocoder backed by a fixed list of cities. It
+// needs no network access, which makes it suitable for tests and as a
+// fallback; cities it does not know resolve to ErrNotFound.
+type Gazetteer struct {
+	byName    map[string][]GazetteerEntry
+	countries map[string]string // normalized country name, code or alias -> code
+}
+
+func NewGazetteer(entries []GazetteerEntry) *Gazetteer {
+	g := &Gazetteer{
+		byName:    make(map[string][]GazetteerEntry),
+		countries: make(map[string]string),
+	}
+
+	for code, aliases := range countryAliases {
+		g.countries[Normalize(code)] = code
+		for _, alias := range aliases {
+			g.countries[Normalize(alias)] = code
+		}
+	}
+
+	for _, entry := range entries {
+		g.countries[Normalize(entry.Country)] = entry.CountryCode
+		g.countries[Normalize(entry.CountryCode)] = entry.CountryCode
+
+		names := append([]string{entry.City, entry.City + " " + entry.Country}, entry.Aliases...)
+		for _, name := range names {
+			key := Normalize(name)
+			g.byName[key] = append(g.byName[key], entry)
+		}
+	}
+
+	return g
+}
+
+// DefaultGazetteer returns a gazetteer of major cities
+func DefaultGazetteer() *Gazetteer {
+	return NewGazetteer(defaultCities)
+}
+
+// Geocode matches the longest leading run of words against known city
+// names and aliases. Any words after it are used to pick between cities of
+// the same name by country ("Paris, FR"), and are otherwise ignored
+// ("New York, NY").
+func (g *Gazetteer) Geocode(query string) (*Place, error) {
+	words := strings.Fields(Normalize(query))
+
+	for n := len(words); n > 0; n-- {
+		candidates := g.byName[strings.Join(words[:n], " ")]
+		if len(candidates) == 0 {
+			continue
+		}
+
+		entry := candidates[0]
+		hints := append([]string{strings.Join(words[n:], " ")}, words[n:]...)
+		for _, hint := range hints {
+			if code, ok := g.countries[hint]; ok {
+				for _, candidate := range candidates {
+					if candidate.CountryCode == code {
+						entry = candidate
+						break
+					}
+				}
+			}
+		}
+
+		return &Place{
+			Name:      entry.City + ", " + entry.Country,
+			Latitude:  entry.Latitude,
+			Longitude: entry.Longitude,
+			Timezone:  entry.Timezone,
+		}, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+// countryAliases are common alternative country names, keyed by ISO code
+var countryAliases = map[string][]string{
+	"US": {"usa", "united states of america", "america"},
+	"GB": {"uk", "england", "scotland", "great britain", "britain"},
+	"AE": {"uae"},
+	"NL": {"holland", "the netherlands"},
+	"KR": {"korea"},
+	"CZ": {"czech republic", "czechia"},
+}
+
+// defaultCities lists major cities, most populous first where names clash
+var defaultCities = []GazetteerEntry{
+	// North America
+	{City: "New York", Country: "United States", CountryCode: "US", Latitude: 40.7128, Longitude: -74.0060, Timezone: "America/New_York", Aliases: []string{"NYC", "New York City", "Manhattan", "Brooklyn"}},
+	{City: "Los Angeles", Country: "United States", CountryCode: "US", Latitude: 34.0522, Longitude: -118.2437, Timezone: "America/Los_Angeles", Aliases: []string{"LA"}},
+	{City: "San Francisco", Country: "United States", CountryCode: "US", Latitude: 37.7749, Longitude: -122.4194, Timezone: "America/Los_Angeles", Aliases: []string{"SF", "San Fran", "Bay Area"}},
+	{City: "Chicago", Country: "United States", CountryCode: "US", Latitude: 41.8781, Longitude: -87.6298, Timezone: "America/Chicago"},
+	{City: "Seattle", Country: "United States", CountryCode: "US", Latitude: 47.6062, Longitude: -122.3321, Timezone: "America/Los_Angeles"},
+	{City: "Austin", Country: "United States", CountryCode: "US", Latitude: 30.2672, Longitude: -97.7431, Timezone: "America/Chicago", Aliases: []string{"ATX"}},
+	{City: "Boston", Country: "United States", CountryCode: "US", Latitude: 42.3601, Longitude: -71.0589, Timezone: "America/New_York"},
+	{City: "Washington", Country: "United States", CountryCode: "US", Latitude: 38.9072, Longitude: -77.0369, Timezone: "America/New_York", Aliases: []string{"DC", "Washington DC", "Washington D.C."}},
+	{City: "Miami", Country: "United States", CountryCode: "US", Latitude: 25.7617, Longitude: -80.1918, Timezone: "America/New_York"},
+	{City: "Denver", Country: "United States", CountryCode: "US", Latitude: 39.7392, Longitude: -104.9903, Timezone: "America/Denver"},
+	{City: "Toronto", Country: "Canada", CountryCode: "CA", Latitude: 43.6532, Longitude: -79.3832, Timezone: "America/Toronto"},
+	{City: "Vancouver", Country: "Canada", CountryCode: "CA", Latitude: 49.2827, Longitude: -123.1207, Timezone: "America/Vancouver"},
+	{City: "Montreal", Country: "Canada", CountryCode: "CA", Latitude: 45.5017, Longitude: -73.5673, Timezone: "America/Toronto", Aliases: []string{"Montréal"}},
+	{City: "Mexico City", Country: "Mexico", CountryCode: "MX", Latitude: 19.4326, Longitude: -99.1332, Timezone: "America/Mexico_City", Aliases: []string{"CDMX"}},
+
+	// South America
+	{City: "São Paulo", Country: "Brazil", CountryCode: "BR", Latitude: -23.5505, Longitude: -46.6333, Timezone: "America/Sao_Paulo", Aliases: []string{"Sao Paulo"}},
+	{City: "Buenos Aires", Country: "Argentina", CountryCode: "AR", Latitude: -34.6037, Longitude: -58.3816, Timezone: "America/Argentina/Buenos_Aires"},
+
+	// Europe
+	{City: "London", Country: "United Kingdom", CountryCode: "GB", Latitude: 51.5074, Longitude: -0.1278, Timezone: "Europe/London", Aliases: []string{"LDN"}},
+	{City: "Manchester", Country: "United Kingdom", CountryCode: "GB", Latitude: 53.4808, Longitude: -2.2426, Timezone: "Europe/London"},
+	{City: "Dublin", Country: "Ireland", CountryCode: "IE", Latitude: 53.3498, Longitude: -6.2603, Timezone: "Europe/Dublin"},
+	{City: "Paris", Country: "France", CountryCode: "FR", Latitude: 48.8566, Longitude: 2.3522, Timezone: "Europe/Paris"},
+	{City: "Berlin", Country: "Germany", CountryCode: "DE", Latitude: 52.5200, Longitude: 13.4050, Timezone: "Europe/Berlin"},
+	{City: "Munich", Country: "Germany", CountryCode: "DE", Latitude: 48.1351, Longitude: 11.5820, Timezone: "Europe/Berlin", Aliases: []string{"München", "Muenchen"}},
+	{City: "Amsterdam", Country: "Netherlands", CountryCode: "NL", Latitude: 52.3676, Longitude: 4.9041, Timezone: "Europe/Amsterdam"},
+	{City: "Madrid", Country: "Spain", CountryCode: "ES", Latitude: 40.4168, Longitude: -3.7038, Timezone: "Europe/Madrid"},
+	{City: "Barcelona", Country: "Spain", CountryCode: "ES", Latitude: 41.3851, Longitude: 2.1734, Timezone: "Europe/Madrid", Aliases: []string{"BCN"}},
+	{City: "Lisbon", Country: "Portugal", CountryCode: "PT", Latitude: 38.7223, Longitude: -9.1393, Timezone: "Europe/Lisbon", Aliases: []string{"Lisboa"}},
+	{City: "Rome", Country: "Italy", CountryCode: "IT", Latitude: 41.9028, Longitude: 12.4964, Timezone: "Europe/Rome", Aliases: []string{"Roma"}},
+	{City: "Milan", Country: "Italy", CountryCode: "IT", Latitude: 45.4642, Longitude: 9.1900, Timezone: "Europe/Rome", Aliases: []string{"Milano"}},
+	{City: "Zurich", Country: "Switzerland", CountryCode: "CH", Latitude: 47.3769, Longitude: 8.5417, Timezone: "Europe/Zurich", Aliases: []string{"Zürich"}},
+	{City: "Vienna", Country: "Austria", CountryCode: "AT", Latitude: 48.2082, Longitude: 16.3738, Timezone: "Europe/Vienna", Aliases: []string{"Wien"}},
+	{City: "Stockholm", Country: "Sweden", CountryCode: "SE", Latitude: 59.3293, Longitude: 18.0686, Timezone: "Europe/Stockholm"},
+	{City: "Copenhagen", Country: "Denmark", CountryCode: "DK", Latitude: 55.6761, Longitude: 12.5683, Timezone: "Europe/Copenhagen", Aliases: []string{"København"}},
+	{City: "Warsaw", Country: "Poland", CountryCode: "PL", Latitude: 52.2297, Longitude: 21.0122, Timezone: "Europe/Warsaw", Aliases: []string{"Warszawa"}},
+	{City: "Prague", Country: "Czechia", CountryCode: "CZ", Latitude: 50.0755, Longitude: 14.4378, Timezone: "Europe/Prague", Aliases: []string{"Praha"}},
+	{City: "Istanbul", Country: "Turkey", CountryCode: "TR", Latitude: 41.0082, Longitude: 28.9784, Timezone: "Europe/Istanbul"},
+
+	// Africa and the Middle East
+	{City: "Cairo", Country: "Egypt", CountryCode: "EG", Latitude: 30.0444, Longitude: 31.2357, Timezone: "Africa/Cairo"},
+	{City: "Lagos", Country: "Nigeria", CountryCode: "NG", Latitude: 6.5244, Longitude: 3.3792, Timezone: "Africa/Lagos"},
+	{City: "Nairobi", Country: "Kenya", CountryCode: "KE", Latitude: -1.2921, Longitude: 36.8219, Timezone: "Africa/Nairobi"},
+	{City: "Johannesburg", Country: "South Africa", CountryCode: "ZA", Latitude: -26.2041, Longitude: 28.0473, Timezone: "Africa/Johannesburg", Aliases: []string{"Joburg", "Jozi"}},
+	{City: "Cape Town", Country: "South Africa", CountryCode: "ZA", Latitude: -33.9249, Longitude: 18.4241, Timezone: "Africa/Johannesburg"},
+	{City: "Dubai", Country: "United Arab Emirates", CountryCode: "AE", Latitude: 25.2048, Longitude: 55.2708, Timezone: "Asia/Dubai"},
+
+	// Asia
+	{City: "Mumbai", Country: "India", CountryCode: "IN", Latitude: 19.0760, Longitude: 72.8777, Timezone: "Asia/Kolkata", Aliases: []string{"Bombay"}},
+	{City: "Delhi", Country: "India", CountryCode: "IN", Latitude: 28.6139, Longitude: 77.2090, Timezone: "Asia/Kolkata", Aliases: []string{"New Delhi", "NCR"}},
+	{City: "Bengaluru", Country: "India", CountryCode: "IN", Latitude: 12.9716, Longitude: 77.5946, Timezone: "Asia/Kolkata", Aliases: []string{"Bangalore", "BLR"}},
+	{City: "Hyderabad", Country: "India", CountryCode: "IN", Latitude: 17.3850, Longitude: 78.4867, Timezone: "Asia/Kolkata"},
+	{City: "Chennai", Country: "India", CountryCode: "IN", Latitude: 13.0827, Longitude: 80.2707, Timezone: "Asia/Kolkata", Aliases: []string{"Madras"}},
+	{City: "Pune", Country: "India", CountryCode: "IN", Latitude: 18.5204, Longitude: 73.8567, Timezone: "Asia/Kolkata"},
+	{City: "Kolkata", Country: "India", CountryCode: "IN", Latitude: 22.5726, Longitude: 88.3639, Timezone: "Asia/Kolkata", Aliases: []string{"Calcutta"}},
+	{City: "Singapore", Country: "Singapore", CountryCode: "SG", Latitude: 1.3521, Longitude: 103.8198, Timezone: "Asia/Singapore"},
+	{City: "Hong Kong", Country: "Hong Kong", CountryCode: "HK", Latitude: 22.3193, Longitude: 114.1694, Timezone: "Asia/Hong_Kong", Aliases: []string{"HK"}},
+	{City: "Shanghai", Country: "China", CountryCode: "CN", Latitude: 31.2304, Longitude: 121.4737, Timezone: "Asia/Shanghai"},
+	{City: "Beijing", Country: "China", CountryCode: "CN", Latitude: 39.9042, Longitude: 116.4074, Timezone: "Asia/Shanghai", Aliases: []string{"Peking"}},
+	{City: "Tokyo", Country: "Japan", CountryCode: "JP", Latitude: 35.6762, Longitude: 139.6503, Timezone: "Asia/Tokyo"},
+	{City: "Seoul", Country: "South Korea", CountryCode: "KR", Latitude: 37.5665, Longitude: 126.9780, Timezone: "Asia/Seoul"},
+	{City: "Bangkok", Country: "Thailand", CountryCode: "TH", Latitude: 13.7563, Longitude: 100.5018, Timezone: "Asia/Bangkok"},
+	{City: "Jakarta", Country: "Indonesia", CountryCode: "ID", Latitude: -6.2088, Longitude: 106.8456, Timezone: "Asia/Jakarta"},
+	{City: "Manila", Country: "Philippines", CountryCode: "PH", Latitude: 14.5995, Longitude: 120.9842, Timezone: "Asia/Manila"},
+
+	// Oceania
+	{City: "Sydney", Country: "Australia", CountryCode: "AU", Latitude: -33.8688, Longitude: 151.2093, Timezone: "Australia/Sydney"},
+	{City: "Melbourne", Country: "Australia", CountryCode: "AU", Latitude: -37.8136, Longitude: 144.9631, Timezone: "Australia/Melbourne"},
+	{City: "Auckland", Country: "New Zealand", CountryCode: "NZ", Latitude: -36.8485, Longitude: 174.7633, Timezone: "Pacific/Auckland"},
+}
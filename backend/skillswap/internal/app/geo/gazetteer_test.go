@@ -0,0 +1,54 @@
+package geo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGazetteerGeocode(t *testing.T) {
+	g := NewGazetteer(append([]GazetteerEntry{
+		{City: "Paris", Country: "France", CountryCode: "FR", Latitude: 48.8566, Longitude: 2.3522, Timezone: "Europe/Paris"},
+		{City: "Paris", Country: "United States", CountryCode: "US", Latitude: 33.6609, Longitude: -95.5555, Timezone: "America/Chicago"},
+	}, defaultCities...))
+
+	tests := []struct {
+		query    string
+		name     string
+		timezone string
+	}{
+		{query: "Berlin", name: "Berlin, Germany", timezone: "Europe/Berlin"},
+		{query: "berlin, de", name: "Berlin, Germany", timezone: "Europe/Berlin"},
+		{query: "NYC", name: "New York, United States", timezone: "America/New_York"},
+		{query: "New York, NY", name: "New York, United States", timezone: "America/New_York"},
+		{query: "new york city", name: "New York, United States", timezone: "America/New_York"},
+		{query: "Washington D.C.", name: "Washington, United States", timezone: "America/New_York"},
+		{query: "Bombay", name: "Mumbai, India", timezone: "Asia/Kolkata"},
+		{query: "München", name: "Munich, Germany", timezone: "Europe/Berlin"},
+		{query: "Sao Paulo, Brazil", name: "São Paulo, Brazil", timezone: "America/Sao_Paulo"},
+		{query: "London United Kingdom", name: "London, United Kingdom", timezone: "Europe/London"},
+		// Clashing names: first entry by default, the country picks otherwise
+		{query: "Paris", name: "Paris, France", timezone: "Europe/Paris"},
+		{query: "Paris, Texas", name: "Paris, France", timezone: "Europe/Paris"},
+		{query: "Paris, US", name: "Paris, United States", timezone: "America/Chicago"},
+		{query: "Paris USA", name: "Paris, United States", timezone: "America/Chicago"},
+		{query: "paris united states of america", name: "Paris, United States", timezone: "America/Chicago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			place, err := g.Geocode(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if place.Name != tt.name || place.Timezone != tt.timezone {
+				t.Errorf("Geocode(%q) = %q in %s, want %q in %s", tt.query, place.Name, place.Timezone, tt.name, tt.timezone)
+			}
+		})
+	}
+
+	for _, query := range []string{"", "Atlantis", "York", "United States"} {
+		if place, err := g.Geocode(query); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Geocode(%q) = %+v, %v, want ErrNotFound", query, place, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// EarthRadiusKm is the mean Earth radius used for distance calculations
+const EarthRadiusKm = 6371.0
+
+var ErrNotFound = errors.New("location not found")
+
+// Place is a resolved location. Name is the canonical display name, e.g.
+// "New York, United States"; Timezone is an IANA zone name.
+type Place struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Timezone  string  `json:"timezone"`
+}
+
+// Geocoder resolves free-text locations such as "NYC" or "Berlin, DE".
+// Implementations return ErrNotFound when nothing matches.
+type Geocoder interface {
+	Geocode(query string) (*Place, error)
+}
+
+// DistanceKm returns the great-circle distance between two points
+func DistanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLng := radians(lng2 - lng1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// BoundingBox returns the latitude/longitude ranges that contain every point
+// within radiusKm of the center. It is used as an index-friendly prefilter
+// before the exact distance check. Near the poles or across the antimeridian
+// the longitude range is widened to the full circle.
+func BoundingBox(lat, lng, radiusKm float64) (minLat, maxLat, minLng, maxLng float64) {
+	dLat := degrees(radiusKm / EarthRadiusKm)
+	minLat, maxLat = math.Max(lat-dLat, -90), math.Min(lat+dLat, 90)
+
+	if maxLat >= 90 || minLat <= -90 {
+		return minLat, maxLat, -180, 180
+	}
+
+	// The widest longitude reach is where the circle touches a meridian,
+	// not at the center's latitude
+	ratio := math.Sin(radiusKm/EarthRadiusKm) / math.Cos(radians(lat))
+	if ratio >= 1 {
+		return minLat, maxLat, -180, 180
+	}
+	dLng := degrees(math.Asin(ratio))
+	minLng, maxLng = lng-dLng, lng+dLng
+	if minLng < -180 || maxLng > 180 {
+		return minLat, maxLat, -180, 180
+	}
+	return minLat, maxLat, minLng, maxLng
+}
+
+// Normalize lower-cases a place query and reduces punctuation to single
+// spaces, so "New York, NY" and "new york ny" compare equal
+func Normalize(query string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
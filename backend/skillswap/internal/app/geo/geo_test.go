@@ -0,0 +1,110 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		want                   float64
+	}{
+		{name: "same point", lat1: 51.5074, lng1: -0.1278, lat2: 51.5074, lng2: -0.1278, want: 0},
+		{name: "London to Paris", lat1: 51.5074, lng1: -0.1278, lat2: 48.8566, lng2: 2.3522, want: 343.6},
+		{name: "New York to London", lat1: 40.7128, lng1: -74.0060, lat2: 51.5074, lng2: -0.1278, want: 5570.2},
+		{name: "across the antimeridian", lat1: 0, lng1: 179, lat2: 0, lng2: -179, want: 222.4},
+		{name: "antipodes", lat1: 10, lng1: 20, lat2: -10, lng2: -160, want: math.Pi * EarthRadiusKm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DistanceKm(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			if math.Abs(got-tt.want) > 0.5 {
+				t.Errorf("DistanceKm = %.1f, want %.1f", got, tt.want)
+			}
+			if back := DistanceKm(tt.lat2, tt.lng2, tt.lat1, tt.lng1); math.Abs(back-got) > 1e-9 {
+				t.Errorf("DistanceKm is not symmetric: %v and %v", got, back)
+			}
+		})
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	tests := []struct {
+		name                           string
+		lat, lng, radiusKm             float64
+		minLat, maxLat, minLng, maxLng float64
+	}{
+		{name: "equator", lat: 0, lng: 0, radiusKm: 111.195, minLat: -1, maxLat: 1, minLng: -1, maxLng: 1},
+		// r/(R cos lat) would give 35.97 degrees
+		{name: "high latitude", lat: 60, lng: 10, radiusKm: 2000, minLat: 42.01, maxLat: 77.99, minLng: -28.14, maxLng: 48.14},
+		{name: "southern hemisphere", lat: -60, lng: 10, radiusKm: 2000, minLat: -77.99, maxLat: -42.01, minLng: -28.14, maxLng: 48.14},
+		{name: "near the north pole", lat: 89, lng: 45, radiusKm: 200, minLat: 87.20, maxLat: 90, minLng: -180, maxLng: 180},
+		{name: "near the south pole", lat: -88.5, lng: 45, radiusKm: 200, minLat: -90, maxLat: -86.70, minLng: -180, maxLng: 180},
+		{name: "across the antimeridian", lat: -36.8, lng: 179, radiusKm: 300, minLat: -39.50, maxLat: -34.10, minLng: -180, maxLng: 180},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minLat, maxLat, minLng, maxLng := BoundingBox(tt.lat, tt.lng, tt.radiusKm)
+			got := []float64{minLat, maxLat, minLng, maxLng}
+			want := []float64{tt.minLat, tt.maxLat, tt.minLng, tt.maxLng}
+			for i := range got {
+				if math.Abs(got[i]-want[i]) > 0.01 {
+					t.Fatalf("BoundingBox = %.2f, want %.2f", got, want)
+				}
+			}
+		})
+	}
+}
+
+// destination returns the point distKm from lat/lng along bearing (degrees)
+func destination(lat, lng, bearing, distKm float64) (float64, float64) {
+	phi, lambda, theta := radians(lat), radians(lng), radians(bearing)
+	delta := distKm / EarthRadiusKm
+
+	phi2 := math.Asin(math.Sin(phi)*math.Cos(delta) + math.Cos(phi)*math.Sin(delta)*math.Cos(theta))
+	lambda2 := lambda + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(phi), math.Cos(delta)-math.Sin(phi)*math.Sin(phi2))
+	lng2 := math.Mod(degrees(lambda2)+540, 360) - 180
+	return degrees(phi2), lng2
+}
+
+func TestBoundingBoxContainsCircle(t *testing.T) {
+	centers := []struct{ lat, lng float64 }{
+		{0, 0}, {40.7, -74}, {60, 10}, {-60, 10}, {70, 100}, {-45, -170}, {84, 0},
+	}
+	radii := []float64{1, 50, 500, 2000}
+
+	for _, c := range centers {
+		for _, r := range radii {
+			minLat, maxLat, minLng, maxLng := BoundingBox(c.lat, c.lng, r)
+			for bearing := 0.0; bearing < 360; bearing += 0.5 {
+				for _, frac := range []float64{0.5, 0.999} {
+					lat, lng := destination(c.lat, c.lng, bearing, r*frac)
+					if lat < minLat || lat > maxLat || lng < minLng || lng > maxLng {
+						t.Fatalf("center %v radius %v: point (%.4f, %.4f) at bearing %v is outside [%.4f, %.4f] x [%.4f, %.4f]",
+							c, r, lat, lng, bearing, minLat, maxLat, minLng, maxLng)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"New York, NY":     "new york ny",
+		"  new   york ny ": "new york ny",
+		"São-Paulo!!":      "são paulo",
+		"Washington D.C.":  "washington d c",
+		"":                 "",
+		"...":              "",
+	}
+	for query, want := range tests {
+		if got := Normalize(query); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package jobs runs deferred work stored in the scheduled_jobs table.
+//
+// Jobs are claimed with SELECT ... FOR UPDATE SKIP LOCKED, so any number of
+// server instances can poll the same table and each due job runs once. A
+// claimed job holds a lease; if the instance dies mid-run the lease expires
+// and another instance picks the job up again.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	batchSize     = 20
+	leaseDuration = 5 * time.Minute
+	retryBase     = 30 * time.Second
+	retryMax      = time.Hour
+
+	// Finished jobs are kept this long so their keys keep deduplicating
+	retention     = 30 * 24 * time.Hour
+	purgeInterval = time.Hour
+)
+
+// MaxAttempts is how often a job is tried before it is marked failed
+const MaxAttempts = 5
+
+// Handler runs a claimed job. A returned error retries the job with
+// exponential backoff until it has been attempted MaxAttempts times. ctx is
+// cancelled when the scheduler stops.
+type Handler func(ctx context.Context, job models.ScheduledJob) error
+
+// Job describes work to enqueue
+type Job struct {
+	Kind      string
+	Key       string     // Optional; a second job with the same key is ignored
+	SubjectID *uuid.UUID // Optional; lets Cancel find the job
+	RunAt     time.Time
+	Payload   interface{} // Encoded as JSON
+}
+
+type Scheduler struct {
+	db           *gorm.DB
+	pollInterval time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+func NewScheduler(db *gorm.DB, pollInterval time.Duration) *Scheduler {
+	return &Scheduler{db: db, pollInterval: pollInterval, handlers: make(map[string]Handler)}
+}
+
+// Register sets the handler for a kind of job
+func (s *Scheduler) Register(kind string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[kind] = handler
+}
+
+// Enqueue stores a job. Jobs whose key already exists, whether pending or
+// finished, are silently skipped.
+func (s *Scheduler) Enqueue(ctx context.Context, job Job) error {
+	payload := "{}"
+	if job.Payload != nil {
+		b, err := json.Marshal(job.Payload)
+		if err != nil {
+			return err
+		}
+		payload = string(b)
+	}
+
+	row := &models.ScheduledJob{
+		Kind:      job.Kind,
+		SubjectID: job.SubjectID,
+		Payload:   payload,
+		Status:    models.JobPending,
+		RunAt:     job.RunAt,
+	}
+	if job.Key != "" {
+		row.Key = &job.Key
+	}
+
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoNothing: true,
+	}).Create(row).Error
+}
+
+// Cancel deletes pending jobs of a kind for a subject. Jobs already running
+// are left to finish.
+func (s *Scheduler) Cancel(ctx context.Context, kind string, subjectID uuid.UUID) error {
+	return s.db.WithContext(ctx).Where("kind = ? AND subject_id = ? AND status = ?", kind, subjectID, models.JobPending).
+		Delete(&models.ScheduledJob{}).Error
+}
+
+// Start polls for due jobs until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	go s.run(ctx)
+	log.Printf("✓ Job scheduler started (polling every %s)", s.pollInterval)
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+	lastPurge := time.Now()
+
+	for {
+		// Keep draining while full batches come back
+		for {
+			n, err := s.RunDue(ctx)
+			if err != nil {
+				log.Printf("Warning: failed to run scheduled jobs: %v", err)
+				break
+			}
+			if n < batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		if time.Since(lastPurge) >= purgeInterval {
+			if err := s.purge(ctx); err != nil {
+				log.Printf("Warning: failed to purge finished jobs: %v", err)
+			}
+			lastPurge = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue claims one batch of due jobs and runs them, returning how many were
+// claimed
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	jobs, err := s.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, job := range jobs {
+		s.execute(ctx, job)
+	}
+	return len(jobs), nil
+}
+
+// claim leases due pending jobs, and running jobs whose lease has expired
+func (s *Scheduler) claim(ctx context.Context) ([]models.ScheduledJob, error) {
+	now := time.Now()
+	var jobs []models.ScheduledJob
+	err := s.db.WithContext(ctx).Raw(`
+		UPDATE scheduled_jobs
+		SET status = ?, attempts = attempts + 1, locked_until = ?, updated_at = ?
+		WHERE job_id IN (
+			SELECT job_id FROM scheduled_jobs
+			WHERE (status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)
+			ORDER BY run_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		models.JobRunning, now.Add(leaseDuration), now,
+		models.JobPending, now, models.JobRunning, now,
+		batchSize,
+	).Scan(&jobs).Error
+	return jobs, err
+}
+
+func (s *Scheduler) execute(ctx context.Context, job models.ScheduledJob) {
+	s.mu.RLock()
+	handler, ok := s.handlers[job.Kind]
+	s.mu.RUnlock()
+
+	var err error
+	switch {
+	case !ok:
+		err = fmt.Errorf("no handler registered for %q", job.Kind)
+		job.Attempts = MaxAttempts // Retrying will not help
+	case job.Attempts > MaxAttempts:
+		err = fmt.Errorf("lease expired %d times", job.Attempts-1)
+	default:
+		err = runHandler(ctx, handler, job)
+	}
+
+	updates := map[string]interface{}{"locked_until": nil}
+	switch {
+	case err == nil:
+		updates["status"] = models.JobDone
+		updates["last_error"] = nil
+	case job.Attempts >= MaxAttempts:
+		log.Printf("Warning: job %s (%s) failed permanently: %v", job.JobID, job.Kind, err)
+		updates["status"] = models.JobFailed
+		updates["last_error"] = err.Error()
+	default:
+		updates["status"] = models.JobPending
+		updates["run_at"] = time.Now().Add(backoff(job.Attempts))
+		updates["last_error"] = err.Error()
+	}
+
+	// The result is recorded even when stopping, so finished work is not redone
+	if err := s.db.WithContext(context.WithoutCancel(ctx)).Model(&models.ScheduledJob{}).Where("job_id = ?", job.JobID).Updates(updates).Error; err != nil {
+		log.Printf("Warning: failed to record result of job %s: %v", job.JobID, err)
+	}
+}
+
+// runHandler turns a panicking handler into a failed attempt
+func runHandler(ctx context.Context, handler Handler, job models.ScheduledJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// backoff doubles the retry delay after every failed attempt
+func backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	if delay > retryMax {
+		delay = retryMax
+	}
+	return delay
+}
+
+func (s *Scheduler) purge(ctx context.Context) error {
+	return s.db.WithContext(ctx).Where("status IN ? AND updated_at < ?", []models.JobStatus{models.JobDone, models.JobFailed}, time.Now().Add(-retention)).
+		Delete(&models.ScheduledJob{}).Error
+}
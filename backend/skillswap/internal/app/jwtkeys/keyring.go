@@ -0,0 +1,276 @@
+// Package jwtkeys holds the asymmetric keys that sign and verify our JWTs.
+// One key signs; others are only trusted for verification, so keys can be
+// rotated with an overlap in which tokens signed by the old key still work.
+// The public keys are published as a JWK set for other services.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSABits = 2048
+
+// Key is a public key we trust, with the method it verifies
+type Key struct {
+	ID     string // RFC 7638 thumbprint, sent as the "kid" header
+	Method jwt.SigningMethod
+	Public crypto.PublicKey
+}
+
+// Keyring signs with one private key and verifies with any trusted key
+type Keyring struct {
+	signer  crypto.Signer
+	signing *Key
+	keys    map[string]*Key
+	order   []string // Key IDs, signing key first
+}
+
+// New returns a keyring that signs with signer and also accepts tokens
+// signed by the keys in verifyOnly
+func New(signer crypto.Signer, verifyOnly ...crypto.PublicKey) (*Keyring, error) {
+	signing, err := newKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	k := &Keyring{signer: signer, signing: signing, keys: make(map[string]*Key)}
+	k.add(signing)
+	for _, pub := range verifyOnly {
+		key, err := newKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		k.add(key)
+	}
+	return k, nil
+}
+
+// Generate returns a keyring with a new Ed25519 key. Its tokens stop working
+// when the process exits, so it is only for development.
+func Generate() (*Keyring, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return New(private)
+}
+
+// Load reads the signing key and verification-only keys. Each value is PEM
+// text or the path of a PEM file; verify may hold several keys.
+func Load(signing, verify string) (*Keyring, error) {
+	data, err := readPEM(signing)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parsePEM(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) != 1 {
+		return nil, fmt.Errorf("signing key must be a single private key, found %d keys", len(keys))
+	}
+	signer, ok := keys[0].(crypto.Signer)
+	if !ok {
+		return nil, errors.New("signing key must be a private key")
+	}
+
+	var verifyOnly []crypto.PublicKey
+	if verify != "" {
+		data, err := readPEM(verify)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := parsePEM(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if signer, ok := key.(crypto.Signer); ok {
+				key = signer.Public()
+			}
+			verifyOnly = append(verifyOnly, key)
+		}
+	}
+	return New(signer, verifyOnly...)
+}
+
+// SigningKeyID returns the ID of the key new tokens are signed with
+func (k *Keyring) SigningKeyID() string { return k.signing.ID }
+
+// Sign returns a token for claims signed with the signing key
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.Method, claims)
+	token.Header["kid"] = k.signing.ID
+	return token.SignedString(k.signer)
+}
+
+// Keyfunc finds the key for a token by its "kid" header, for jwt.Parse. The
+// token's algorithm must be the one that key is used with.
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+// ValidMethods lists the algorithms of the trusted keys, for
+// jwt.WithValidMethods
+func (k *Keyring) ValidMethods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, id := range k.order {
+		alg := k.keys[id].Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWKS returns the trusted public keys as a JWK set (RFC 7517)
+func (k *Keyring) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(k.order))}
+	for _, id := range k.order {
+		set.Keys = append(set.Keys, toJWK(k.keys[id]))
+	}
+	return set
+}
+
+func (k *Keyring) add(key *Key) {
+	if _, ok := k.keys[key.ID]; ok {
+		return
+	}
+	k.keys[key.ID] = key
+	k.order = append(k.order, key.ID)
+}
+
+// JSONWebKeySet is a JWK set as served at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func newKey(pub crypto.PublicKey) (*Key, error) {
+	key := &Key{Public: pub}
+	switch p := pub.(type) {
+	case *rsa.PublicKey:
+		if p.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA keys must have at least %d bits, got %d", minRSABits, p.N.BitLen())
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T; use RSA or Ed25519", pub)
+	}
+	key.ID = thumbprint(toJWK(key))
+	return key, nil
+}
+
+func toJWK(key *Key) JSONWebKey {
+	jwk := JSONWebKey{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+	switch p := key.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(p.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(p)
+	}
+	return jwk
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint, which identifies a key
+// without configuring IDs
+func thumbprint(jwk JSONWebKey) string {
+	var canonical string
+	switch jwk.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"OKP","x":"%s"}`, jwk.Crv, jwk.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// readPEM returns value if it is PEM text, or else the file it names
+func readPEM(value string) ([]byte, error) {
+	if strings.Contains(value, "-----BEGIN") {
+		// Some platforms cannot hold newlines in variables
+		return []byte(strings.ReplaceAll(value, `\n`, "\n")), nil
+	}
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	return data, nil
+}
+
+// parsePEM returns the keys in data: private keys as crypto.Signer, public
+// keys as crypto.PublicKey
+func parsePEM(data []byte) ([]interface{}, error) {
+	var keys []interface{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var key interface{}
+		var err error
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		default:
+			return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", strings.ToLower(block.Type), err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no PEM keys found")
+	}
+	return keys, nil
+}
@@ -0,0 +1,266 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newEd25519(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return private
+}
+
+func newRSA(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return private
+}
+
+func mustNew(t *testing.T, signer crypto.Signer, verifyOnly ...crypto.PublicKey) *Keyring {
+	t.Helper()
+	k, err := New(signer, verifyOnly...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func sign(t *testing.T, k *Keyring) string {
+	t.Helper()
+	token, err := k.Sign(jwt.RegisteredClaims{Subject: "user", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func verify(k *Keyring, token string) error {
+	_, err := jwt.Parse(token, k.Keyfunc, jwt.WithValidMethods(k.ValidMethods()))
+	return err
+}
+
+func TestSignWithTheSigningKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		signer crypto.Signer
+		alg    string
+	}{
+		{name: "Ed25519", signer: newEd25519(t), alg: "EdDSA"},
+		{name: "RSA", signer: newRSA(t, 2048), alg: "RS256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := mustNew(t, tt.signer, newEd25519(t).Public())
+			token := sign(t, k)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kid := parsed.Header["kid"]; kid != k.SigningKeyID() {
+				t.Errorf("kid = %v, want %s", kid, k.SigningKeyID())
+			}
+			if alg := parsed.Header["alg"]; alg != tt.alg {
+				t.Errorf("alg = %v, want %s", alg, tt.alg)
+			}
+			if err := verify(k, token); err != nil {
+				t.Errorf("own token rejected: %v", err)
+			}
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	oldKey, newKey := newEd25519(t), newRSA(t, 2048)
+	before := mustNew(t, oldKey)
+	oldToken := sign(t, before)
+
+	// During the grace period the old key is still trusted for verification
+	during := mustNew(t, newKey, oldKey.Public())
+	if err := verify(during, oldToken); err != nil {
+		t.Errorf("token from the rotated-out key rejected during the grace period: %v", err)
+	}
+	newToken := sign(t, during)
+	if err := verify(during, newToken); err != nil {
+		t.Errorf("token from the new key rejected: %v", err)
+	}
+	if err := verify(before, newToken); err == nil {
+		t.Error("old keyring accepted a token from a key it does not know")
+	}
+	if got := during.ValidMethods(); strings.Join(got, ",") != "RS256,EdDSA" {
+		t.Errorf("ValidMethods = %v, want the signing key's first", got)
+	}
+
+	// Afterwards it is dropped
+	after := mustNew(t, newKey)
+	if err := verify(after, oldToken); err == nil {
+		t.Error("token from a dropped key accepted")
+	}
+	if err := verify(after, newToken); err != nil {
+		t.Errorf("token from the new key rejected: %v", err)
+	}
+}
+
+func TestKeyfuncRejectsUnknownKeys(t *testing.T) {
+	k := mustNew(t, newEd25519(t))
+	other := mustNew(t, newEd25519(t))
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{name: "unknown kid", token: func() string { return sign(t, other) }},
+		{name: "no kid", token: func() string {
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{}).SignedString(newEd25519(t))
+			return token
+		}},
+		{name: "known kid, different algorithm", token: func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{})
+			token.Header["kid"] = k.SigningKeyID()
+			signed, _ := token.SignedString([]byte("secret"))
+			return signed
+		}},
+		{name: "known kid, signed by another key", token: func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{})
+			token.Header["kid"] = k.SigningKeyID()
+			signed, _ := token.SignedString(newEd25519(t))
+			return signed
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verify(k, tt.token()); err == nil {
+				t.Error("token accepted")
+			}
+		})
+	}
+}
+
+func TestNewRejectsWeakOrUnsupportedKeys(t *testing.T) {
+	if _, err := New(newRSA(t, 1024)); err == nil {
+		t.Error("1024-bit RSA key accepted")
+	}
+	if _, err := New(newEd25519(t), "not a key"); err == nil {
+		t.Error("unsupported verification key accepted")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	edKey, rsaKey := newEd25519(t), newRSA(t, 2048)
+	k := mustNew(t, rsaKey, edKey.Public(), rsaKey.Public())
+
+	data, err := json.Marshal(k.JWKS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 2 {
+		t.Fatalf("got %d keys, want the signing key and one verification key without duplicates", len(set.Keys))
+	}
+
+	rsaJWK, edJWK := set.Keys[0], set.Keys[1]
+	if rsaJWK["kid"] != k.SigningKeyID() || rsaJWK["kty"] != "RSA" || rsaJWK["alg"] != "RS256" || rsaJWK["use"] != "sig" {
+		t.Errorf("signing key = %v", rsaJWK)
+	}
+	if edJWK["kty"] != "OKP" || edJWK["crv"] != "Ed25519" || edJWK["alg"] != "EdDSA" || edJWK["use"] != "sig" {
+		t.Errorf("verification key = %v", edJWK)
+	}
+	for _, jwk := range set.Keys {
+		if _, ok := jwk["d"]; ok {
+			t.Errorf("JWKS contains private key material: %v", jwk)
+		}
+	}
+
+	// The published keys are the real public keys
+	n, _ := base64.RawURLEncoding.DecodeString(rsaJWK["n"])
+	e, _ := base64.RawURLEncoding.DecodeString(rsaJWK["e"])
+	published := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if !published.Equal(rsaKey.Public()) {
+		t.Error("published RSA key does not match")
+	}
+	x, _ := base64.RawURLEncoding.DecodeString(edJWK["x"])
+	if !ed25519.PublicKey(x).Equal(edKey.Public()) {
+		t.Error("published Ed25519 key does not match")
+	}
+
+	// Key IDs are RFC 7638 thumbprints of the required members in
+	// lexicographic order
+	for _, jwk := range set.Keys {
+		required := map[string]string{"kty": jwk["kty"]}
+		for _, member := range []string{"crv", "e", "n", "x"} {
+			if v, ok := jwk[member]; ok {
+				required[member] = v
+			}
+		}
+		canonical, _ := json.Marshal(required)
+		sum := sha256.Sum256(canonical)
+		if want := base64.RawURLEncoding.EncodeToString(sum[:]); jwk["kid"] != want {
+			t.Errorf("kid = %s, want thumbprint %s", jwk["kid"], want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	signing, verifyOnly := newEd25519(t), newRSA(t, 2048)
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(signing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(verifyOnly.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	verifyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})) +
+		string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(verifyOnly)}))
+
+	// Newlines may be escaped when the value comes from the environment
+	k, err := Load(strings.ReplaceAll(signingPEM, "\n", `\n`), verifyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mustNew(t, signing).SigningKeyID(); k.SigningKeyID() != want {
+		t.Errorf("SigningKeyID = %s, want %s", k.SigningKeyID(), want)
+	}
+	if err := verify(k, sign(t, mustNew(t, verifyOnly))); err != nil {
+		t.Errorf("token from a verification key rejected: %v", err)
+	}
+	if len(k.JWKS().Keys) != 2 {
+		t.Errorf("got %d keys, want a private and public copy of the same key to count once", len(k.JWKS().Keys))
+	}
+
+	if _, err := Load(verifyPEM, ""); err == nil {
+		t.Error("signing key with several PEM blocks accepted")
+	}
+	if _, err := Load("-----BEGIN NOTHING-----", ""); err == nil {
+		t.Error("text without keys accepted")
+	}
+}
@@ -0,0 +1,73 @@
+// Package mail sends email through a pluggable transport.
+//
+// SMTP delivers for real. The log and file transports are for development:
+// they print messages or write them as .eml files that any mail client can
+// open.
+package mail
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Transports selectable with Settings.Transport
+const (
+	TransportSMTP = "smtp"
+	TransportFile = "file"
+	TransportLog  = "log"
+	TransportNone = "none"
+)
+
+// Message is one email with a plain text and an HTML body
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string // Extra headers, e.g. List-Unsubscribe
+}
+
+// Mailer delivers messages. A returned error means the message may be
+// retried.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Settings configures New
+type Settings struct {
+	Transport string
+	From      string
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
+	Dir string // Output directory of the file transport
+}
+
+// New creates the mailer for settings.Transport. It returns nil for
+// TransportNone, which turns email off.
+func New(settings Settings) (Mailer, error) {
+	switch settings.Transport {
+	case TransportSMTP:
+		if settings.SMTPHost == "" {
+			return nil, fmt.Errorf("the smtp mail transport needs a host")
+		}
+		return NewSMTPMailer(settings.SMTPHost, settings.SMTPPort, settings.SMTPUsername, settings.SMTPPassword, settings.From), nil
+	case TransportFile:
+		return NewFileMailer(settings.Dir, settings.From)
+	case TransportLog:
+		return NewLogMailer(settings.From), nil
+	case TransportNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", settings.Transport)
+	}
+}
+
+// sanitizeHeader keeps header values on one line so user-supplied text,
+// e.g. a name in a subject, cannot add headers
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
@@ -0,0 +1,129 @@
+package mail
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parse reads a built message back with the standard library
+func parse(t *testing.T, raw []byte) (*netmail.Message, map[string]string) {
+	t.Helper()
+	msg, err := netmail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+
+	bodies := map[string]string{}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(part) // Decodes quoted-printable
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[contentType] = string(body)
+	}
+	return msg, bodies
+}
+
+func TestBuild(t *testing.T) {
+	long := strings.Repeat("Grüße aus dem Café. ", 10)
+	raw, err := build("Skill Swap <noreply@skillswap.test>", Message{
+		To:      "Ada <ada@example.com>",
+		Subject: "Héllo\r\nBcc: eve@evil.test",
+		Text:    long,
+		HTML:    "<p>" + long + "</p>",
+		Headers: map[string]string{
+			"list-unsubscribe":      "<https://skillswap.test/unsubscribe?token=abc>",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click\nBcc: eve@evil.test",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, bodies := parse(t, raw)
+	header := msg.Header
+
+	if header.Get("Bcc") != "" {
+		t.Error("header injected through a header value")
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(header.Get("Subject")); subject != "Héllo  Bcc: eve@evil.test" {
+		t.Errorf("Subject = %q", subject)
+	}
+	if got := header.Get("List-Unsubscribe"); got != "<https://skillswap.test/unsubscribe?token=abc>" {
+		t.Errorf("List-Unsubscribe = %q", got)
+	}
+	if got := header.Get("List-Unsubscribe-Post"); got != "List-Unsubscribe=One-Click Bcc: eve@evil.test" {
+		t.Errorf("List-Unsubscribe-Post = %q", got)
+	}
+	if !strings.HasSuffix(header.Get("Message-ID"), "@skillswap.test>") {
+		t.Errorf("Message-ID = %q, want one in the sender's domain", header.Get("Message-ID"))
+	}
+	if to, _ := header.AddressList("To"); len(to) != 1 || to[0].Address != "ada@example.com" {
+		t.Errorf("To = %v", to)
+	}
+
+	if bodies["text/plain"] != long || bodies["text/html"] != "<p>"+long+"</p>" {
+		t.Errorf("bodies do not round-trip: %q", bodies)
+	}
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("line of %d characters", len(line))
+		}
+	}
+}
+
+func TestBuildRejectsInvalidAddresses(t *testing.T) {
+	if _, err := build("not an address", Message{To: "ada@example.com"}); err == nil {
+		t.Error("invalid sender accepted")
+	}
+	if _, err := build("noreply@skillswap.test", Message{To: "ada@example.com, eve@evil.test"}); err == nil {
+		t.Error("several recipients accepted")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := New(Settings{Transport: TransportFile, Dir: dir, From: "noreply@skillswap.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mailer.Send(Message{To: "ada@example.com", Subject: "Hi", Text: "Hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("got files %v (%v), want one .eml file", files, err)
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, bodies := parse(t, raw)
+	if msg.Header.Get("Subject") != "Hi" || bodies["text/plain"] != "Hello" {
+		t.Errorf("file holds %q with bodies %q", msg.Header.Get("Subject"), bodies)
+	}
+	if _, ok := bodies["text/html"]; ok {
+		t.Error("empty HTML body written")
+	}
+}
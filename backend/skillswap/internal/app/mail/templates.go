@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html"))
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt"))
+)
+
+// NotificationData fills the notification email templates
+type NotificationData struct {
+	To                string
+	RecipientName     string
+	Type              string // Selects the template; unknown types use a generic one
+	Title             string
+	Message           string
+	UnsubscribeURL    string // Stops emails of this type; empty if they cannot be turned off
+	UnsubscribeAllURL string // Stops all optional emails
+}
+
+// AccountEmailData fills the account email templates, which are sent
+// regardless of notification preferences
+type AccountEmailData struct {
+	To            string
+	RecipientName string
+	Kind          string // Selects the template, e.g. "verify_email"
+	Title         string
+	Link          string // Optional call to action
+	Action        string // Label of the link
+	ExpiresIn     string // How long the link works, e.g. "24 hours"
+	Email         string // Address the email is about, e.g. the new one in an email change
+}
+
+// RenderNotification renders the email for a notification
+func RenderNotification(data NotificationData) (Message, error) {
+	name := data.Type
+	if htmlTemplates.Lookup(name) == nil || textTemplates.Lookup(name) == nil {
+		name = "default"
+	}
+	return render("notification_layout", name, data.To, data.Title, data)
+}
+
+// RenderAccountEmail renders an account email such as a verification link
+func RenderAccountEmail(data AccountEmailData) (Message, error) {
+	if htmlTemplates.Lookup(data.Kind) == nil || textTemplates.Lookup(data.Kind) == nil {
+		return Message{}, fmt.Errorf("no template for account email %q", data.Kind)
+	}
+	return render("account_layout", data.Kind, data.To, data.Title, data)
+}
+
+// render executes the named body template and places it in layout
+func render(layout, name, to, subject string, data interface{}) (Message, error) {
+	var htmlContent, textContent bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&htmlContent, name, data); err != nil {
+		return Message{}, err
+	}
+	if err := textTemplates.ExecuteTemplate(&textContent, name, data); err != nil {
+		return Message{}, err
+	}
+
+	var html, text bytes.Buffer
+	err := htmlTemplates.ExecuteTemplate(&html, layout, struct {
+		Data    interface{}
+		Content htmltemplate.HTML // Already escaped by the body template
+	}{data, htmltemplate.HTML(htmlContent.String())})
+	if err != nil {
+		return Message{}, err
+	}
+	err = textTemplates.ExecuteTemplate(&text, layout, struct {
+		Data    interface{}
+		Content string
+	}{data, textContent.String()})
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{To: to, Subject: subject, Text: text.String(), HTML: html.String()}, nil
+}
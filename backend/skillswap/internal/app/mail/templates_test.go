@@ -0,0 +1,113 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderNotification(t *testing.T) {
+	data := NotificationData{
+		To:                "ada@example.com",
+		RecipientName:     "Ada <Lovelace>",
+		Type:              "swap_request",
+		Title:             "New swap request",
+		Message:           `Grace wants to learn "Go" & <b>Rust</b>`,
+		UnsubscribeURL:    "https://skillswap.test/api/v1/notifications/unsubscribe?token=one&x=1",
+		UnsubscribeAllURL: "https://skillswap.test/api/v1/notifications/unsubscribe?token=all",
+	}
+
+	msg, err := RenderNotification(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.To != data.To || msg.Subject != data.Title {
+		t.Errorf("To %q and subject %q, want %q and %q", msg.To, msg.Subject, data.To, data.Title)
+	}
+
+	for _, want := range []string{
+		"Hi Ada <Lovelace>,",
+		`Grace wants to learn "Go" & <b>Rust</b>.`,
+		"Open Skill Swap to accept or decline the request.",
+		"Stop these emails: " + data.UnsubscribeURL,
+		"Unsubscribe from all optional emails: " + data.UnsubscribeAllURL,
+	} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("text body lacks %q:\n%s", want, msg.Text)
+		}
+	}
+
+	// User-supplied text is escaped in HTML
+	for _, want := range []string{
+		"<p>Hi Ada &lt;Lovelace&gt;,</p>",
+		"<p>Grace wants to learn &#34;Go&#34; &amp; &lt;b&gt;Rust&lt;/b&gt;.</p>",
+		`<a href="https://skillswap.test/api/v1/notifications/unsubscribe?token=one&amp;x=1"`,
+		"<title>New swap request</title>",
+	} {
+		if !strings.Contains(msg.HTML, want) {
+			t.Errorf("HTML body lacks %q:\n%s", want, msg.HTML)
+		}
+	}
+	if strings.Contains(msg.HTML, "<b>Rust</b>") {
+		t.Error("HTML body contains unescaped markup from the message")
+	}
+}
+
+func TestRenderNotificationWithoutTypeUnsubscribe(t *testing.T) {
+	msg, err := RenderNotification(NotificationData{
+		To:                "ada@example.com",
+		RecipientName:     "Ada",
+		Type:              "something_new",
+		Title:             "Hello",
+		Message:           "A message",
+		UnsubscribeAllURL: "https://skillswap.test/unsubscribe-all",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unknown types use the generic template
+	if !strings.Contains(msg.Text, "Hi Ada,\n\nA message\n") || !strings.Contains(msg.HTML, "<p>A message</p>") {
+		t.Errorf("generic template not used:\n%s\n%s", msg.Text, msg.HTML)
+	}
+	if strings.Contains(msg.Text, "Stop these emails") || strings.Contains(msg.HTML, "Stop these emails") {
+		t.Error("link to stop a type that cannot be turned off")
+	}
+	if !strings.Contains(msg.Text, "https://skillswap.test/unsubscribe-all") {
+		t.Error("unsubscribe-all link missing")
+	}
+}
+
+func TestRenderAccountEmail(t *testing.T) {
+	msg, err := RenderAccountEmail(AccountEmailData{
+		To:            "ada@example.com",
+		RecipientName: "Ada",
+		Kind:          "confirm_email_change",
+		Title:         "Confirm your new email",
+		Link:          "https://skillswap.test/confirm?token=abc",
+		Action:        "Confirm",
+		ExpiresIn:     "24 hours",
+		Email:         "new@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"use new@example.com for your Skill Swap account",
+		"The link expires in 24 hours.",
+		"Confirm: https://skillswap.test/confirm?token=abc",
+	} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("text body lacks %q:\n%s", want, msg.Text)
+		}
+	}
+	if !strings.Contains(msg.HTML, "https://skillswap.test/confirm?token=abc") {
+		t.Errorf("HTML body lacks the link:\n%s", msg.HTML)
+	}
+	if strings.Contains(msg.Text, "Unsubscribe") {
+		t.Error("account email offers to unsubscribe")
+	}
+
+	if _, err := RenderAccountEmail(AccountEmailData{To: "ada@example.com", Kind: "no_such_email"}); err == nil {
+		t.Error("unknown account email rendered")
+	}
+}
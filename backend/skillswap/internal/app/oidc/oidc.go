@@ -0,0 +1,419 @@
+// Package oidc is an OpenID Connect relying party for the authorization
+// code flow with PKCE. Provider metadata and signing keys are discovered
+// from the issuer and cached.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	discoveryTTL = 24 * time.Hour
+	// keyRefreshInterval limits refetching signing keys when a token names
+	// an unknown key, so bad tokens cannot make us hammer the provider
+	keyRefreshInterval = time.Minute
+	clockSkew          = time.Minute
+	maxResponseSize    = 1 << 20
+)
+
+// Config describes a provider registration
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string // Empty for public clients
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Discovery is the subset of the provider metadata that is used
+type Discovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// Claims are the ID token claims that are used
+type Claims struct {
+	Nonce           string       `json:"nonce"`
+	AuthorizedParty string       `json:"azp,omitempty"`
+	Email           string       `json:"email"`
+	EmailVerified   flexibleBool `json:"email_verified"`
+	Name            string       `json:"name"`
+	Picture         string       `json:"picture"`
+	jwt.RegisteredClaims
+}
+
+// Provider talks to one identity provider. It is safe for concurrent use.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	// fetches runs one discovery or key set request at a time, shared by
+	// everyone waiting for it; mu is not held across requests
+	fetches singleflight.Group
+
+	mu            sync.Mutex
+	discovery     *Discovery
+	discoveredAt  time.Time
+	keys          map[string]interface{} // By key ID
+	keysFetchedAt time.Time
+}
+
+// NewProvider returns a provider. Discovery happens on first use, so an
+// unreachable provider does not stop the server from starting.
+func NewProvider(cfg Config, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+func (p *Provider) Name() string { return p.cfg.Name }
+
+// NewPKCEVerifier returns a random PKCE code verifier (RFC 7636)
+func NewPKCEVerifier() (string, error) {
+	return randomString(32)
+}
+
+// NewNonce returns a random value for state or nonce parameters
+func NewNonce() (string, error) {
+	return randomString(24)
+}
+
+// AuthCodeURL returns the URL to send the user to. The verifier's S256
+// challenge is sent; the verifier itself goes with the code exchange.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	d, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.cfg.ClientID)
+	params.Set("redirect_uri", p.cfg.RedirectURL)
+	params.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified ID token
+// claims. nonce must be the one sent with the authorization request.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	d, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+
+	useBasic := p.cfg.ClientSecret != "" && !onlyPostAuth(d.TokenEndpointAuthMethodsSupported)
+	if !useBasic {
+		form.Set("client_id", p.cfg.ClientID)
+		if p.cfg.ClientSecret != "" {
+			form.Set("client_secret", p.cfg.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		// RFC 6749 section 2.3.1: credentials are form-encoded first
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &token)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("token endpoint returned %d %s %s", status, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return p.VerifyIDToken(ctx, token.IDToken, nonce)
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// an ID token
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	d, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	algs := d.IDTokenSigningAlgValuesSupported
+	if len(algs) == 0 {
+		algs = []string{"RS256"}
+	}
+	var allowed []string
+	for _, alg := range algs {
+		// Symmetric and unsigned tokens are never accepted
+		if alg != "none" && !strings.HasPrefix(alg, "HS") {
+			allowed = append(allowed, alg)
+		}
+	}
+
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, d, kid)
+	},
+		jwt.WithValidMethods(allowed),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("invalid id token: missing subject")
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, errors.New("invalid id token: wrong authorized party")
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("invalid id token: nonce mismatch")
+	}
+	return claims, nil
+}
+
+// metadata returns the cached discovery document, fetching it when needed
+func (p *Provider) metadata(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+	d, discoveredAt := p.discovery, p.discoveredAt
+	p.mu.Unlock()
+	if d != nil && time.Since(discoveredAt) < discoveryTTL {
+		return d, nil
+	}
+
+	v, err := p.shared(ctx, "discovery", p.discover)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Discovery), nil
+}
+
+func (p *Provider) discover(ctx context.Context) (interface{}, error) {
+	p.mu.Lock()
+	old, discoveredAt := p.discovery, p.discoveredAt
+	p.mu.Unlock()
+	if old != nil && time.Since(discoveredAt) < discoveryTTL {
+		// Fetched by a request that finished just before this one started
+		return old, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var d Discovery
+	status, err := p.doJSON(req, &d)
+	if err != nil || status != http.StatusOK {
+		if old != nil {
+			// Keep using the old document while the provider is down
+			return old, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("status %d", status)
+		}
+		return nil, fmt.Errorf("oidc discovery for %s failed: %w", p.cfg.Name, err)
+	}
+
+	// OpenID Connect Discovery section 4.3
+	if d.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery for %s returned issuer %q, want %q", p.cfg.Name, d.Issuer, p.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery for %s is missing endpoints", p.cfg.Name)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.discovery = &d
+	p.discoveredAt = time.Now()
+	return &d, nil
+}
+
+// key returns the signing key with the given ID, refetching the key set
+// when it is unknown because the provider may have rotated keys
+func (p *Provider) key(ctx context.Context, d *Discovery, kid string) (interface{}, error) {
+	p.mu.Lock()
+	key, ok := p.lookupKey(kid)
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	_, err := p.shared(ctx, "keys", func(ctx context.Context) (interface{}, error) {
+		p.mu.Lock()
+		if time.Since(p.keysFetchedAt) < keyRefreshInterval {
+			p.mu.Unlock()
+			return nil, nil
+		}
+		p.keysFetchedAt = time.Now()
+		p.mu.Unlock()
+
+		keys, err := p.fetchKeys(ctx, d.JWKSURI)
+		if err != nil {
+			return nil, err
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.keys = keys
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// shared runs fetch once for all callers waiting on the same key. The
+// request is not cancelled when one caller gives up, since others may still
+// be waiting; the client timeout bounds it.
+func (p *Provider) shared(ctx context.Context, key string, fetch func(context.Context) (interface{}, error)) (interface{}, error) {
+	result := p.fetches.DoChan(key, func() (interface{}, error) {
+		return fetch(context.WithoutCancel(ctx))
+	})
+	select {
+	case r := <-result:
+		return r.Val, r.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// lookupKey finds a key by ID. Tokens without a key ID are accepted when
+// the set has a single key.
+func (p *Provider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, jwksURI string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set jsonWebKeySet
+	status, err := p.doJSON(req, &set)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("jwks endpoint returned %d", status)
+	}
+	return set.publicKeys(), nil
+}
+
+func (p *Provider) doJSON(req *http.Request, v interface{}) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(body, v); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("invalid response from %s: %w", req.URL.Host, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// onlyPostAuth reports whether the token endpoint wants the client secret
+// in the form rather than in a basic auth header, which is the default
+func onlyPostAuth(methods []string) bool {
+	basic, post := false, false
+	for _, m := range methods {
+		switch m {
+		case "client_secret_basic":
+			basic = true
+		case "client_secret_post":
+			post = true
+		}
+	}
+	return post && !basic
+}
+
+func randomString(size int) (string, error) {
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// flexibleBool accepts both true and "true", since some providers send
+// email_verified as a string
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID = "skillswap"
+	testNonce    = "nonce-1"
+)
+
+func testProvider(tp *oidctest.Provider) *Provider {
+	return NewProvider(Config{
+		Name:        "test",
+		Issuer:      tp.Issuer(),
+		ClientID:    testClientID,
+		RedirectURL: "https://app.example/callback",
+		Scopes:      []string{"openid", "email"},
+	}, tp.Client())
+}
+
+// testClaims returns valid claims for an ID token from tp
+func testClaims(tp *oidctest.Provider) jwt.MapClaims {
+	claims := tp.Claims("subject-1", "ada@example.com")
+	claims["nonce"] = testNonce
+	return claims
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	tp := oidctest.NewProvider(t, testClientID)
+
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims(tp)).SignedString([]byte(testClientID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims(tp)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+		kid    string
+		raw    string // Used instead of signing the claims
+		nonce  string
+		ok     bool
+	}{
+		{name: "valid", kid: oidctest.KeyID, nonce: testNonce, ok: true},
+		{name: "without key ID", nonce: testNonce, ok: true},
+		{name: "wrong nonce", kid: oidctest.KeyID, nonce: "other-nonce"},
+		{name: "no nonce expected", kid: oidctest.KeyID, modify: func(c jwt.MapClaims) { c["nonce"] = "" }},
+		{name: "missing nonce", kid: oidctest.KeyID, nonce: testNonce, modify: func(c jwt.MapClaims) { delete(c, "nonce") }},
+		{name: "wrong issuer", kid: oidctest.KeyID, nonce: testNonce, modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }},
+		{name: "wrong audience", kid: oidctest.KeyID, nonce: testNonce, modify: func(c jwt.MapClaims) { c["aud"] = "another-client" }},
+		{name: "several audiences without azp", kid: oidctest.KeyID, nonce: testNonce, modify: func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "another-client"}
+		}},
+		{name: "several audiences with azp", kid: oidctest.KeyID, nonce: testNonce, ok: true, modify: func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "another-client"}
+			c["azp"] = testClientID
+		}},
+		{name: "expired", kid: oidctest.KeyID, nonce: testNonce, modify: func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+		}},
+		{name: "no expiry", kid: oidctest.KeyID, nonce: testNonce, modify: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "no subject", kid: oidctest.KeyID, nonce: testNonce, modify: func(c jwt.MapClaims) { delete(c, "sub") }},
+		{name: "unknown key ID", kid: "key-2", nonce: testNonce},
+		{name: "HMAC with the client ID", raw: hmacToken, nonce: testNonce},
+		{name: "unsigned", raw: unsigned, nonce: testNonce},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := tt.raw
+			if raw == "" {
+				claims := testClaims(tp)
+				if tt.modify != nil {
+					tt.modify(claims)
+				}
+				raw = tp.Sign(t, claims, tt.kid)
+			}
+
+			claims, err := testProvider(tp).VerifyIDToken(context.Background(), raw, tt.nonce)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("VerifyIDToken: %v", err)
+				}
+				if claims.Subject != "subject-1" || claims.Email != "ada@example.com" {
+					t.Errorf("claims = %+v", claims)
+				}
+			} else if err == nil {
+				t.Fatal("VerifyIDToken accepted the token")
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenEmailVerified(t *testing.T) {
+	tp := oidctest.NewProvider(t, testClientID)
+	p := testProvider(tp)
+
+	tests := []struct {
+		value    interface{}
+		verified bool
+	}{
+		{true, true},
+		{"true", true},
+		{false, false},
+		{"false", false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		claims := testClaims(tp)
+		if tt.value == nil {
+			delete(claims, "email_verified")
+		} else {
+			claims["email_verified"] = tt.value
+		}
+
+		got, err := p.VerifyIDToken(context.Background(), tp.Sign(t, claims, oidctest.KeyID), testNonce)
+		if err != nil {
+			t.Fatalf("email_verified %#v: %v", tt.value, err)
+		}
+		if bool(got.EmailVerified) != tt.verified {
+			t.Errorf("email_verified %#v: EmailVerified = %v, want %v", tt.value, got.EmailVerified, tt.verified)
+		}
+	}
+}
+
+func TestUnknownKeyRefetchIsLimited(t *testing.T) {
+	tp := oidctest.NewProvider(t, testClientID)
+	p := testProvider(tp)
+
+	for i := 0; i < 5; i++ {
+		raw := tp.Sign(t, testClaims(tp), "key-2")
+		if _, err := p.VerifyIDToken(context.Background(), raw, testNonce); err == nil {
+			t.Fatal("VerifyIDToken accepted an unknown key")
+		}
+	}
+	if n := tp.KeyFetches.Load(); n != 1 {
+		t.Errorf("key set fetched %d times, want 1", n)
+	}
+
+	// Known keys keep working from the cache
+	if _, err := p.VerifyIDToken(context.Background(), tp.Sign(t, testClaims(tp), oidctest.KeyID), testNonce); err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if n := tp.KeyFetches.Load(); n != 1 {
+		t.Errorf("key set fetched %d times, want 1", n)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	tp := oidctest.NewProvider(t, testClientID)
+	p := testProvider(tp)
+	ctx := context.Background()
+
+	verifier, err := NewPKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := p.AuthCodeURL(ctx, "state-1", testNonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	if got := u.Query().Get("code_challenge"); got != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+		t.Errorf("code_challenge = %q", got)
+	}
+	if got := u.Query().Get("nonce"); got != testNonce {
+		t.Errorf("nonce = %q", got)
+	}
+
+	claims := tp.Claims("subject-1", "ada@example.com")
+	tp.Authorize(t, authURL, "good-code", claims)
+	got, err := p.Exchange(ctx, "good-code", verifier, testNonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if got.Subject != "subject-1" {
+		t.Errorf("Subject = %q", got.Subject)
+	}
+	if _, err := p.Exchange(ctx, "good-code", verifier, testNonce); err == nil {
+		t.Error("Exchange redeemed a code twice")
+	}
+
+	tp.Authorize(t, authURL, "good-code", claims)
+	if _, err := p.Exchange(ctx, "good-code", verifier, "other-nonce"); err == nil {
+		t.Error("Exchange accepted a token for another nonce")
+	}
+	tp.Authorize(t, authURL, "good-code", claims)
+	if _, err := p.Exchange(ctx, "good-code", "other-verifier", testNonce); err == nil {
+		t.Error("Exchange succeeded with the wrong code verifier")
+	}
+	if _, err := p.Exchange(ctx, "bad-code", verifier, testNonce); err == nil {
+		t.Error("Exchange succeeded with a bad code")
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	tp := oidctest.NewProvider(t, testClientID)
+	p := NewProvider(Config{Name: "test", Issuer: tp.Issuer() + "/", ClientID: testClientID}, tp.Client())
+
+	if _, err := p.AuthCodeURL(context.Background(), "state", "nonce", "verifier"); err == nil || !strings.Contains(err.Error(), "issuer") {
+		t.Fatalf("AuthCodeURL error = %v, want an issuer mismatch", err)
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	tp := oidctest.NewProvider(t, testClientID)
+	p := testProvider(tp)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Exchange(ctx, "good-code", "verifier", testNonce); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Exchange error = %v, want context.Canceled", err)
+	}
+}
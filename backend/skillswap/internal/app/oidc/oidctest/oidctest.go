@@ -0,0 +1,157 @@
+// Package oidctest runs a fake OpenID Connect provider for tests. It serves
+// discovery, an RSA key set and a token endpoint that checks PKCE.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyID identifies the provider's signing key in its key set
+const KeyID = "key-1"
+
+// Provider is a fake identity provider. Codes are granted with Authorize
+// instead of a browser visiting the authorization endpoint.
+type Provider struct {
+	Server     *httptest.Server
+	ClientID   string
+	Key        *rsa.PrivateKey
+	KeyFetches atomic.Int32 // Requests for the key set
+
+	mu     sync.Mutex
+	grants map[string]grant // By authorization code
+}
+
+type grant struct {
+	challenge string // PKCE S256 challenge the verifier must match
+	idToken   string
+}
+
+// NewProvider starts a provider for clientID, stopped when the test ends
+func NewProvider(t testing.TB, clientID string) *Provider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Provider{ClientID: clientID, Key: key, grants: make(map[string]grant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.Issuer(),
+			"authorization_endpoint":                p.Issuer() + "/authorize",
+			"token_endpoint":                        p.Issuer() + "/token",
+			"jwks_uri":                              p.Issuer() + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256", "HS256", "none"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.KeyFetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": KeyID,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Server.Close)
+	return p
+}
+
+// Issuer is the provider's issuer URL
+func (p *Provider) Issuer() string { return p.Server.URL }
+
+// Client returns an HTTP client for the provider's server
+func (p *Provider) Client() *http.Client { return p.Server.Client() }
+
+// Claims returns valid ID token claims for an account with a verified email
+func (p *Provider) Claims(subject, email string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            p.Issuer(),
+		"aud":            p.ClientID,
+		"sub":            subject,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"email":          email,
+		"email_verified": true,
+	}
+}
+
+// Sign signs claims with the provider's key. An empty kid leaves the key ID
+// out of the header.
+func (p *Provider) Sign(t testing.TB, claims jwt.MapClaims, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(p.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// Authorize answers the authorization request authURL as if the user had
+// signed in. code can then be redeemed once, with the request's PKCE
+// verifier, for an ID token with claims and the request's nonce.
+func (p *Provider) Authorize(t testing.TB, authURL, code string, claims jwt.MapClaims) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization request without an S256 code challenge: %s", authURL)
+	}
+
+	signed := jwt.MapClaims{}
+	for name, value := range claims {
+		signed[name] = value
+	}
+	signed["nonce"] = query.Get("nonce")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.grants[code] = grant{challenge: query.Get("code_challenge"), idToken: p.Sign(t, signed, KeyID)}
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	code, verifier := r.PostForm.Get("code"), r.PostForm.Get("code_verifier")
+	challenge := sha256.Sum256([]byte(verifier))
+
+	p.mu.Lock()
+	g, ok := p.grants[code]
+	ok = ok && base64.RawURLEncoding.EncodeToString(challenge[:]) == g.challenge
+	if ok {
+		delete(p.grants, code)
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": g.idToken})
+}
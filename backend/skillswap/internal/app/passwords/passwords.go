@@ -0,0 +1,62 @@
+// Package passwords rejects passwords known from data breaches. A list of
+// the most common ones is bundled; a larger one can be loaded from a file.
+package passwords
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed common.txt
+var common []byte
+
+// List is a set of breached passwords. Matching ignores case, since
+// attackers try the common variants anyway.
+type List struct {
+	set map[string]struct{}
+}
+
+// Load returns the bundled list, extended with the passwords in the file at
+// path, one per line, if path is not empty
+func Load(path string) (*List, error) {
+	l := &List{set: make(map[string]struct{})}
+	if err := l.read(bytes.NewReader(common)); err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open breached password list: %w", err)
+		}
+		defer f.Close()
+		if err := l.read(f); err != nil {
+			return nil, fmt.Errorf("failed to read breached password list: %w", err)
+		}
+	}
+	return l, nil
+}
+
+// Contains reports whether password is on the list
+func (l *List) Contains(password string) bool {
+	_, ok := l.set[strings.ToLower(password)]
+	return ok
+}
+
+// Len returns the number of passwords on the list
+func (l *List) Len() int { return len(l.set) }
+
+func (l *List) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			l.set[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return scanner.Err()
+}
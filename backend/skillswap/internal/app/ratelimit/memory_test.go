@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+	policy := Policy{Limit: 2, Period: time.Hour}
+
+	for i := 0; i < 2; i++ {
+		if r, err := m.Take(ctx, "a", policy); err != nil || !r.Allowed {
+			t.Fatalf("take %d: %+v, %v", i, r, err)
+		}
+	}
+	if r, _ := m.Take(ctx, "a", policy); r.Allowed {
+		t.Error("took a third token from a bucket of two")
+	}
+	if r, _ := m.Take(ctx, "b", policy); !r.Allowed {
+		t.Error("keys share a bucket")
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	m := NewMemoryStore()
+	now := time.Now()
+	m.buckets["full"] = &memoryBucket{fullAt: now}
+	m.buckets["draining"] = &memoryBucket{fullAt: now.Add(time.Hour)}
+
+	m.sweep(now)
+	if _, ok := m.buckets["full"]; ok {
+		t.Error("sweep kept a full bucket")
+	}
+	if _, ok := m.buckets["draining"]; !ok {
+		t.Error("sweep dropped a bucket that is not full")
+	}
+
+	// Sweeps run at most once a minute
+	m.buckets["full"] = &memoryBucket{fullAt: now}
+	m.sweep(now.Add(time.Second))
+	if _, ok := m.buckets["full"]; !ok {
+		t.Error("sweep ran again within a minute")
+	}
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table, so limits
+// hold across every server using the database. Each request locks its
+// bucket's row for one short transaction.
+type PostgresStore struct {
+	db *gorm.DB
+
+	mu    sync.Mutex
+	swept time.Time
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (p *PostgresStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	now := time.Now()
+	var result Result
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Create the bucket full if it is new, so there is a row to lock
+		err := tx.Exec(`
+			INSERT INTO rate_limit_buckets (key, tokens, updated_at, expires_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (key) DO NOTHING`,
+			key, float64(policy.Limit), now, now,
+		).Error
+		if err != nil {
+			return err
+		}
+
+		var b bucket
+		row := tx.Raw("SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = ? FOR UPDATE", key).Row()
+		if err := row.Scan(&b.tokens, &b.updatedAt); err != nil {
+			return err
+		}
+
+		result = b.take(policy, now)
+		return tx.Exec(
+			"UPDATE rate_limit_buckets SET tokens = ?, updated_at = ?, expires_at = ? WHERE key = ?",
+			b.tokens, b.updatedAt, now.Add(result.ResetAfter), key,
+		).Error
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	p.sweep(ctx, now)
+	return result, nil
+}
+
+// sweep deletes full buckets, which behave like missing ones, at most once
+// a minute per server
+func (p *PostgresStore) sweep(ctx context.Context, now time.Time) {
+	p.mu.Lock()
+	due := now.Sub(p.swept) >= time.Minute
+	if due {
+		p.swept = now
+	}
+	p.mu.Unlock()
+	if !due {
+		return
+	}
+
+	if err := p.db.WithContext(ctx).Exec("DELETE FROM rate_limit_buckets WHERE expires_at < ?", now).Error; err != nil {
+		log.Printf("Warning: failed to delete full rate limit buckets: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+// Package ratelimit limits how often a client may call the API, with token
+// buckets kept in a Store. A bucket holds up to Policy.Limit tokens and
+// refills at Limit tokens per Period; each request takes one.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Backend names accepted by New
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// Built-in policy names
+const (
+	PolicyAPI  = "api"  // Every API request
+	PolicyAuth = "auth" // Password reset, two-factor and other abuse-prone auth routes
+)
+
+// DefaultPolicies returns the built-in policies, which RATE_LIMIT_POLICIES
+// can override
+func DefaultPolicies() map[string]Policy {
+	return map[string]Policy{
+		PolicyAPI:  {Limit: 100, Period: time.Minute},
+		PolicyAuth: {Limit: 5, Period: time.Minute},
+	}
+}
+
+// Policy allows bursts of up to Limit requests, and Limit per Period on
+// average
+type Policy struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParsePolicy parses a policy written as "limit/period", e.g. "100/1m"
+func ParsePolicy(s string) (Policy, error) {
+	limit, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %q is not of the form limit/period", s)
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q needs a positive limit", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q needs a positive period such as \"1m\"", s)
+	}
+	return Policy{Limit: n, Period: d}, nil
+}
+
+func (p Policy) String() string {
+	return fmt.Sprintf("%d/%s", p.Limit, p.Period)
+}
+
+// rate is how many tokens are added per second
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed    bool
+	Remaining  int           // Whole tokens left in the bucket
+	ResetAfter time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until a token is available, when not allowed
+}
+
+// Store keeps the buckets. Take must be atomic for a key, across every
+// server sharing the store; a Redis store, for example, would run it as a
+// script.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// New creates the store for the configured backend
+func New(backend string, db *gorm.DB) (Store, error) {
+	switch backend {
+	case "", BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendPostgres:
+		return NewPostgresStore(db), nil
+	}
+	return nil, fmt.Errorf("unknown rate limit backend %q", backend)
+}
+
+// bucket is the state stores keep per key
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take refills the bucket up to now and takes a token if there is one. A
+// zero bucket is full.
+func (b *bucket) take(p Policy, now time.Time) Result {
+	capacity := float64(p.Limit)
+	if b.updatedAt.IsZero() {
+		b.tokens, b.updatedAt = capacity, now
+	} else if now.After(b.updatedAt) {
+		// Servers sharing a store may disagree slightly on the time; a
+		// clock behind the last update just adds nothing
+		b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updatedAt).Seconds()*p.rate())
+		b.updatedAt = now
+	}
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / p.rate())
+	}
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = seconds((capacity - b.tokens) / p.rate())
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// threePerThree refills one token a second, which keeps the arithmetic exact
+var threePerThree = Policy{Limit: 3, Period: 3 * time.Second}
+
+func TestBucketTake(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	b := &bucket{}
+
+	steps := []struct {
+		name string
+		at   time.Duration // After start
+		want Result
+	}{
+		{name: "new bucket is full", at: 0, want: Result{Allowed: true, Remaining: 2, ResetAfter: time.Second}},
+		{name: "burst", at: 0, want: Result{Allowed: true, Remaining: 1, ResetAfter: 2 * time.Second}},
+		{name: "last token", at: 0, want: Result{Allowed: true, Remaining: 0, ResetAfter: 3 * time.Second}},
+		{name: "empty", at: 0, want: Result{Remaining: 0, ResetAfter: 3 * time.Second, RetryAfter: time.Second}},
+		{name: "partly refilled", at: 500 * time.Millisecond, want: Result{Remaining: 0, ResetAfter: 2500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+		{name: "refilled one token", at: time.Second, want: Result{Allowed: true, Remaining: 0, ResetAfter: 3 * time.Second}},
+		{name: "clock behind adds nothing", at: 0, want: Result{Remaining: 0, ResetAfter: 3 * time.Second, RetryAfter: time.Second}},
+		{name: "refill stops at the limit", at: time.Minute, want: Result{Allowed: true, Remaining: 2, ResetAfter: time.Second}},
+	}
+
+	for _, step := range steps {
+		if got := b.take(threePerThree, start.Add(step.at)); got != step.want {
+			t.Fatalf("%s: got %+v, want %+v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy(" 100/1m ")
+	if err != nil || p != (Policy{Limit: 100, Period: time.Minute}) {
+		t.Errorf("ParsePolicy(100/1m) = %v, %v", p, err)
+	}
+	for _, bad := range []string{"100", "0/1m", "-1/1m", "x/1m", "100/0s", "100/minute"} {
+		if _, err := ParsePolicy(bad); err == nil {
+			t.Errorf("ParsePolicy(%q) succeeded", bad)
+		}
+	}
+}
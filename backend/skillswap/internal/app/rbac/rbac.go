@@ -0,0 +1,89 @@
+// Package rbac defines the staff roles and what each may do. Roles are part
+// of the code; which users hold them is stored in user_roles.
+package rbac
+
+import "sort"
+
+// Permission allows one kind of staff action
+type Permission string
+
+const (
+	PermUsersRead         Permission = "users:read"
+	PermUsersBan          Permission = "users:ban"
+	PermUsersDelete       Permission = "users:delete"
+	PermLockoutsManage    Permission = "lockouts:manage" // View and clear login lockouts
+	PermRolesManage       Permission = "roles:manage"    // Grant and revoke roles, including admin
+	PermSwapsRead         Permission = "swaps:read"
+	PermSwapsCancel       Permission = "swaps:cancel"
+	PermReportsManage     Permission = "reports:manage"
+	PermSkillsManage      Permission = "skills:manage"
+	PermStatsRead         Permission = "stats:read"
+	PermSearchReindex     Permission = "search:reindex"
+	PermNotificationsSend Permission = "notifications:send"
+)
+
+// Built-in roles
+const (
+	RoleAdmin        = "admin"
+	RoleModerator    = "moderator"
+	RoleSkillCurator = "skill_curator"
+)
+
+type Role struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+var roles = map[string]Role{
+	RoleAdmin: {
+		Name:        RoleAdmin,
+		Description: "Full access, including granting roles",
+		Permissions: []Permission{
+			PermUsersRead, PermUsersBan, PermUsersDelete, PermLockoutsManage, PermRolesManage,
+			PermSwapsRead, PermSwapsCancel, PermReportsManage, PermSkillsManage,
+			PermStatsRead, PermSearchReindex, PermNotificationsSend,
+		},
+	},
+	RoleModerator: {
+		Name:        RoleModerator,
+		Description: "Handles reports, bans and unlocks users and cancels swaps",
+		Permissions: []Permission{
+			PermUsersRead, PermUsersBan, PermLockoutsManage, PermSwapsRead, PermSwapsCancel, PermReportsManage,
+		},
+	},
+	RoleSkillCurator: {
+		Name:        RoleSkillCurator,
+		Description: "Manages the skill catalog",
+		Permissions: []Permission{PermSkillsManage},
+	},
+}
+
+// Roles lists the roles by name
+func Roles() []Role {
+	list := make([]Role, 0, len(roles))
+	for _, role := range roles {
+		list = append(list, role)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Lookup returns the role called name
+func Lookup(name string) (Role, bool) {
+	role, ok := roles[name]
+	return role, ok
+}
+
+// Allows reports whether any of the named roles grants p. Unknown names,
+// e.g. of a role since removed, grant nothing.
+func Allows(names []string, p Permission) bool {
+	for _, name := range names {
+		for _, granted := range roles[name].Permissions {
+			if granted == p {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package rbac
+
+import "testing"
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		roles []string
+		perm  Permission
+		want  bool
+	}{
+		{roles: []string{RoleAdmin}, perm: PermRolesManage, want: true},
+		{roles: []string{RoleAdmin}, perm: PermUsersDelete, want: true},
+		{roles: []string{RoleModerator}, perm: PermUsersBan, want: true},
+		{roles: []string{RoleModerator}, perm: PermLockoutsManage, want: true},
+		{roles: []string{RoleModerator}, perm: PermUsersDelete, want: false},
+		{roles: []string{RoleModerator}, perm: PermRolesManage, want: false},
+		{roles: []string{RoleModerator}, perm: PermSkillsManage, want: false},
+		{roles: []string{RoleSkillCurator}, perm: PermSkillsManage, want: true},
+		{roles: []string{RoleSkillCurator}, perm: PermUsersRead, want: false},
+		{roles: []string{RoleSkillCurator, RoleModerator}, perm: PermSkillsManage, want: true},
+		{roles: []string{RoleSkillCurator, RoleModerator}, perm: PermSwapsCancel, want: true},
+		// Roles that no longer exist, and no roles at all, grant nothing
+		{roles: []string{"superuser"}, perm: PermUsersRead, want: false},
+		{roles: nil, perm: PermUsersRead, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := Allows(tt.roles, tt.perm); got != tt.want {
+			t.Errorf("Allows(%v, %s) = %v, want %v", tt.roles, tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestAdminHoldsEveryPermission(t *testing.T) {
+	seen := make(map[Permission]bool)
+	for _, role := range Roles() {
+		for _, perm := range role.Permissions {
+			seen[perm] = true
+		}
+	}
+	for perm := range seen {
+		if !Allows([]string{RoleAdmin}, perm) {
+			t.Errorf("admin lacks %s", perm)
+		}
+	}
+}
+
+func TestRolesAndLookup(t *testing.T) {
+	roles := Roles()
+	for i := 1; i < len(roles); i++ {
+		if roles[i-1].Name >= roles[i].Name {
+			t.Fatalf("Roles not sorted by name: %v", roles)
+		}
+	}
+	for _, role := range roles {
+		found, ok := Lookup(role.Name)
+		if !ok || found.Name != role.Name || role.Description == "" {
+			t.Errorf("Lookup(%q) = %+v, %v", role.Name, found, ok)
+		}
+	}
+	if _, ok := Lookup("superuser"); ok {
+		t.Error("Lookup found an unknown role")
+	}
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -10,12 +11,14 @@ import (
 )
 
 type UserRepository interface {
-	Create(user *models.User) error
-	GetByID(id uuid.UUID) (*models.User, error)
-	GetByEmail(email string) (*models.User, error)
-	Update(user *models.User) error
-	Delete(id uuid.UUID) error
-	List(limit, offset int, filters UserFilters) ([]*models.User, int64, error)
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, limit, offset int, filters UserFilters) ([]*models.User, int64, error)
+	// TokenVersion returns the version that the user's tokens must carry
+	TokenVersion(ctx context.Context, id uuid.UUID) (int, error)
 }
 
 type UserFilters struct {
@@ -32,16 +35,16 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) Create(user *models.User) error {
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 	user.UserID = uuid.New()
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
-	return r.db.Create(user).Error
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *userRepository) GetByID(id uuid.UUID) (*models.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	var user models.User
-	err := r.db.Preload("SkillsOffered.Skill").
+	err := r.db.WithContext(ctx).Preload("SkillsOffered.Skill").
 		Preload("SkillsWanted.Skill").
 		Where("user_id = ?", id).
 		First(&user).Error
@@ -55,9 +58,21 @@ func (r *userRepository) GetByID(id uuid.UUID) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *userRepository) GetByEmail(email string) (*models.User, error) {
+func (r *userRepository) TokenVersion(ctx context.Context, id uuid.UUID) (int, error) {
 	var user models.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Select("token_version").Where("user_id = ?", id).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.New("user not found")
+		}
+		return 0, err
+	}
+	return user.TokenVersion, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
@@ -67,20 +82,20 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *userRepository) Update(user *models.User) error {
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 	user.UpdatedAt = time.Now()
-	return r.db.Save(user).Error
+	return r.db.WithContext(ctx).Save(user).Error
 }
 
-func (r *userRepository) Delete(id uuid.UUID) error {
-	return r.db.Where("user_id = ?", id).Delete(&models.User{}).Error
+func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", id).Delete(&models.User{}).Error
 }
 
-func (r *userRepository) List(limit, offset int, filters UserFilters) ([]*models.User, int64, error) {
+func (r *userRepository) List(ctx context.Context, limit, offset int, filters UserFilters) ([]*models.User, int64, error) {
 	var users []*models.User
 	var total int64
 
-	query := r.db.Model(&models.User{})
+	query := r.db.WithContext(ctx).Model(&models.User{})
 
 	// Apply filters
 	if filters.IsPublic != nil {
@@ -88,7 +103,7 @@ func (r *userRepository) List(limit, offset int, filters UserFilters) ([]*models
 	}
 
 	if filters.Location != "" {
-		query = query.Where("location ILIKE ?", "%"+filters.Location+"%")
+		query = query.Where("(location ILIKE ? OR location_place ILIKE ?)", "%"+filters.Location+"%", "%"+filters.Location+"%")
 	}
 
 	if filters.SearchTerm != "" {
@@ -0,0 +1,76 @@
+package searchindex
+
+import (
+	"strings"
+
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+)
+
+// UserDocument builds a user's document. SkillsOffered.Skill and
+// SkillsWanted.Skill must be preloaded for the skill fields to be filled.
+func UserDocument(user *models.User) Document {
+	location := ""
+	if user.Location != nil {
+		location = *user.Location
+	}
+
+	offers := make([]string, 0, len(user.SkillsOffered))
+	for _, us := range user.SkillsOffered {
+		offers = append(offers, us.Skill.Name)
+	}
+	wants := make([]string, 0, len(user.SkillsWanted))
+	for _, us := range user.SkillsWanted {
+		wants = append(wants, us.Skill.Name)
+	}
+
+	title := user.Name
+	if location != "" {
+		title += " · " + location
+	}
+
+	// The resolved place is searchable too, so "New York" finds "NYC"
+	if user.LocationPlace != nil && *user.LocationPlace != location {
+		location = strings.TrimSpace(location + "\n" + *user.LocationPlace)
+	}
+
+	return Document{
+		Kind:  KindUser,
+		ID:    user.UserID,
+		Title: title,
+		Fields: map[string]string{
+			"name":     user.Name,
+			"location": location,
+			"offers":   strings.Join(offers, "\n"),
+			"wants":    strings.Join(wants, "\n"),
+			"skill":    strings.Join(append(offers, wants...), "\n"),
+		},
+	}
+}
+
+// SkillDocument builds a skill's document
+func SkillDocument(skill *models.Skill) Document {
+	return Document{
+		Kind:   KindSkill,
+		ID:     skill.SkillID,
+		Title:  skill.Name,
+		Fields: map[string]string{"name": skill.Name},
+	}
+}
+
+// SwapDocument builds a swap request's document from its skill and
+// participant names, which must be preloaded
+func SwapDocument(swap *models.SwapRequest) Document {
+	offered, wanted := swap.OfferedSkill.Name, swap.WantedSkill.Name
+
+	return Document{
+		Kind:  KindSwap,
+		ID:    swap.SwapID,
+		Title: offered + " ⇄ " + wanted,
+		Fields: map[string]string{
+			"offered": offered,
+			"wanted":  wanted,
+			"skill":   offered + "\n" + wanted,
+			"user":    swap.Requester.Name + "\n" + swap.Responder.Name,
+		},
+	}
+}
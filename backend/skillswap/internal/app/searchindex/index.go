@@ -0,0 +1,96 @@
+package searchindex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Kind identifies which entity a document describes
+type Kind string
+
+const (
+	KindUser  Kind = "user"
+	KindSkill Kind = "skill"
+	KindSwap  Kind = "swap"
+)
+
+// Kinds lists every indexed entity kind
+var Kinds = []Kind{KindUser, KindSkill, KindSwap}
+
+// Backend names accepted by New
+const (
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+// textFields lists, per kind, the document fields that free text is matched
+// against together with their weight. Other fields can only be targeted with
+// field filters such as `skill:go`.
+var textFields = map[Kind]map[string]float64{
+	KindUser:  {"name": 1.0, "location": 0.4},
+	KindSkill: {"name": 1.0},
+	KindSwap:  {"offered": 1.0, "wanted": 1.0, "user": 0.4},
+}
+
+// Document is the searchable representation of a user, skill or swap.
+// Fields holds the text of each searchable field; Title is the text that
+// highlight snippets are cut from.
+type Document struct {
+	Kind   Kind
+	ID     uuid.UUID
+	Title  string
+	Fields map[string]string
+}
+
+// Query describes a search against one kind of document. Text is matched
+// against the kind's text fields with prefix and typo tolerance, while every
+// value in Fields must fuzzily match the named field.
+type Query struct {
+	Kind   Kind
+	Text   string
+	Fields map[string][]string
+	Limit  int
+}
+
+// Hit is a matching document ID with its relevance score. Highlight holds
+// the document title with matched words wrapped in <mark> tags.
+type Hit struct {
+	ID        uuid.UUID
+	Score     float64
+	Highlight string
+}
+
+// Indexer is the storage and retrieval backend used by the search service.
+// Search returns hits ordered by descending relevance, at most Limit of them.
+type Indexer interface {
+	Index(docs ...Document) error
+	Delete(kind Kind, ids ...uuid.UUID) error
+	Search(ctx context.Context, query Query) ([]Hit, error)
+	// Reset drops everything indexed for a kind ahead of a full rebuild
+	Reset(kind Kind) error
+}
+
+// LiveIndexer is implemented by indexers that search the live tables. They
+// need no reindexing, and their hits can be joined with further conditions
+// in SQL rather than fetched first.
+type LiveIndexer interface {
+	Indexer
+	// HitsQuery returns the hits for q as an unordered query with id, score
+	// and highlight columns. q.Limit is ignored.
+	HitsQuery(ctx context.Context, q Query) (*gorm.DB, error)
+}
+
+// New creates the indexer for the configured backend. Searches read from
+// read, which may be a replica.
+func New(backend string, read *gorm.DB) (Indexer, error) {
+	switch backend {
+	case "", BackendPostgres:
+		return NewPostgresIndexer(read), nil
+	case BackendMemory:
+		return NewMemoryIndexer(), nil
+	}
+	return nil, fmt.Errorf("unknown search backend %q", backend)
+}
@@ -0,0 +1,360 @@
+package searchindex
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Trigram similarity thresholds. Field filters use pg_trgm's default so both
+// backends agree; single words need a closer match to avoid noise.
+const (
+	termSimilarityThreshold  = 0.4
+	fieldSimilarityThreshold = 0.3
+)
+
+// MemoryIndexer is a pure-Go inverted index. It holds everything in memory
+// and is meant for tests and small single-instance deployments; it has to
+// be filled with a full reindex on startup.
+type MemoryIndexer struct {
+	mu    sync.RWMutex
+	kinds map[Kind]*memoryKind
+}
+
+type memoryKind struct {
+	docs map[uuid.UUID]*memoryDoc
+	// postings maps a token to the documents containing it and the highest
+	// field weight it appears under in each
+	postings map[string]map[uuid.UUID]float64
+}
+
+type memoryDoc struct {
+	title  string
+	fields map[string]string // lower-cased field text, for field filters
+	tokens map[string]float64
+}
+
+func NewMemoryIndexer() *MemoryIndexer {
+	return &MemoryIndexer{kinds: make(map[Kind]*memoryKind)}
+}
+
+// Index adds or replaces documents
+func (m *MemoryIndexer) Index(docs ...Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, doc := range docs {
+		weights, ok := textFields[doc.Kind]
+		if !ok {
+			return fmt.Errorf("unknown search kind %q", doc.Kind)
+		}
+
+		k := m.kind(doc.Kind)
+		k.remove(doc.ID)
+
+		entry := &memoryDoc{
+			title:  doc.Title,
+			fields: make(map[string]string, len(doc.Fields)),
+			tokens: make(map[string]float64),
+		}
+		for field, text := range doc.Fields {
+			entry.fields[field] = strings.ToLower(text)
+
+			weight, searchable := weights[field]
+			if !searchable {
+				continue
+			}
+			for _, token := range tokenize(text) {
+				if weight > entry.tokens[token] {
+					entry.tokens[token] = weight
+				}
+			}
+		}
+
+		for token, weight := range entry.tokens {
+			if k.postings[token] == nil {
+				k.postings[token] = make(map[uuid.UUID]float64)
+			}
+			k.postings[token][doc.ID] = weight
+		}
+		k.docs[doc.ID] = entry
+	}
+
+	return nil
+}
+
+// Delete removes documents; unknown IDs are ignored
+func (m *MemoryIndexer) Delete(kind Kind, ids ...uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := m.kind(kind)
+	for _, id := range ids {
+		k.remove(id)
+	}
+	return nil
+}
+
+// Reset drops every document of a kind
+func (m *MemoryIndexer) Reset(kind Kind) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.kinds, kind)
+	return nil
+}
+
+// Search scores documents by how well each query word matches their tokens:
+// exact matches score highest, then prefix matches, then close misspellings.
+// Every word has to match something, like the Postgres prefix tsquery.
+func (m *MemoryIndexer) Search(ctx context.Context, q Query) ([]Hit, error) {
+	if _, ok := textFields[q.Kind]; !ok {
+		return nil, fmt.Errorf("unknown search kind %q", q.Kind)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	k, ok := m.kinds[q.Kind]
+	if !ok {
+		return []Hit{}, nil
+	}
+
+	var scores map[uuid.UUID]float64
+	matched := make(map[string]bool)
+
+	if terms := tokenize(q.Text); len(terms) > 0 {
+		for i, term := range terms {
+			termScores := make(map[uuid.UUID]float64)
+			for token, postings := range k.postings {
+				quality := termMatch(term, token)
+				if quality == 0 {
+					continue
+				}
+				matched[token] = true
+				for id, weight := range postings {
+					if s := quality * weight; s > termScores[id] {
+						termScores[id] = s
+					}
+				}
+			}
+
+			if i == 0 {
+				scores = termScores
+				continue
+			}
+			for id := range scores {
+				if s, ok := termScores[id]; ok {
+					scores[id] += s
+				} else {
+					delete(scores, id)
+				}
+			}
+		}
+	} else {
+		scores = make(map[uuid.UUID]float64, len(k.docs))
+		for id := range k.docs {
+			scores[id] = 0
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		doc := k.docs[id]
+		if !matchesFields(doc, q.Fields) {
+			continue
+		}
+
+		hit := Hit{ID: id, Score: score}
+		if len(matched) > 0 {
+			hit.Highlight = highlight(doc.title, matched)
+		}
+		hits = append(hits, hit)
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID.String() < hits[j].ID.String()
+	})
+
+	if q.Limit > 0 && len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+
+	return hits, nil
+}
+
+// kind returns the index for a kind, creating it on first use. Callers must
+// hold the write lock.
+func (m *MemoryIndexer) kind(kind Kind) *memoryKind {
+	k, ok := m.kinds[kind]
+	if !ok {
+		k = &memoryKind{
+			docs:     make(map[uuid.UUID]*memoryDoc),
+			postings: make(map[string]map[uuid.UUID]float64),
+		}
+		m.kinds[kind] = k
+	}
+	return k
+}
+
+func (k *memoryKind) remove(id uuid.UUID) {
+	doc, ok := k.docs[id]
+	if !ok {
+		return
+	}
+
+	for token := range doc.tokens {
+		delete(k.postings[token], id)
+		if len(k.postings[token]) == 0 {
+			delete(k.postings, token)
+		}
+	}
+	delete(k.docs, id)
+}
+
+// termMatch rates how well an indexed token matches a query word, from 0 (no
+// match) to 1 (exact match)
+func termMatch(term, token string) float64 {
+	switch {
+	case term == token:
+		return 1.0
+	case strings.HasPrefix(token, term):
+		return 0.8
+	}
+
+	if sim := similarity(term, token); sim >= termSimilarityThreshold {
+		return 0.6 * sim
+	}
+
+	// Trigrams miss swapped letters in short words ("pyhton"), so allow one
+	// edit for words of four or more letters
+	if len([]rune(term)) >= 4 && editDistance(term, token) <= 1 {
+		return 0.5
+	}
+	return 0
+}
+
+// editDistance is the optimal string alignment distance: insertions,
+// deletions, substitutions and swaps of adjacent runes each count as one edit
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > 1 || d < -1 {
+		return 2 // more than one edit apart; the caller only cares about <= 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(rb)]
+}
+
+// matchesFields checks every field filter by substring or trigram similarity
+func matchesFields(doc *memoryDoc, filters map[string][]string) bool {
+	for field, values := range filters {
+		text := doc.fields[field]
+		for _, value := range values {
+			value = strings.ToLower(value)
+			if strings.Contains(text, value) {
+				continue
+			}
+			if similarity(text, value) < fieldSimilarityThreshold {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// highlight wraps the words of title whose lower-cased form was matched
+func highlight(title string, matched map[string]bool) string {
+	var out, word strings.Builder
+
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := word.String()
+		if matched[strings.ToLower(w)] {
+			out.WriteString("<mark>" + w + "</mark>")
+		} else {
+			out.WriteString(w)
+		}
+		word.Reset()
+	}
+
+	for _, r := range title {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word.WriteRune(r)
+			continue
+		}
+		flush()
+		out.WriteRune(r)
+	}
+	flush()
+
+	return out.String()
+}
+
+// tokenize lower-cases text and splits it into runs of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// similarity is the pg_trgm similarity of two strings: the number of shared
+// trigrams divided by the number of distinct trigrams in either
+func similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// trigrams extracts pg_trgm style trigrams: each word is padded with two
+// spaces in front and one behind before being cut into three-rune windows
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range tokenize(s) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,273 @@
+package searchindex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func skillDoc(name string) Document {
+	return Document{Kind: KindSkill, ID: uuid.New(), Title: name, Fields: map[string]string{"name": name}}
+}
+
+func userDoc(name, location, skills string) Document {
+	title := name
+	if location != "" {
+		title += " · " + location
+	}
+	return Document{Kind: KindUser, ID: uuid.New(), Title: title, Fields: map[string]string{
+		"name":     name,
+		"location": location,
+		"skill":    skills,
+	}}
+}
+
+// searchTitles returns the titles of the hits, in order
+func searchTitles(t *testing.T, m *MemoryIndexer, docs []Document, q Query) []string {
+	t.Helper()
+	titles := make(map[uuid.UUID]string, len(docs))
+	for _, doc := range docs {
+		titles[doc.ID] = doc.Title
+	}
+
+	hits, err := m.Search(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]string, len(hits))
+	for i, hit := range hits {
+		out[i] = titles[hit.ID]
+	}
+	return out
+}
+
+func TestMemorySearch(t *testing.T) {
+	skills := []Document{
+		skillDoc("Go"),
+		skillDoc("Golang Concurrency"),
+		skillDoc("Python"),
+		skillDoc("Machine Learning"),
+		skillDoc("Watercolor Painting"),
+	}
+	users := []Document{
+		userDoc("Ada Lovelace", "London", "Mathematics"),
+		userDoc("Grace Hopper", "New York", "Compilers\nGo"),
+		userDoc("London Jones", "Paris", ""),
+	}
+
+	all := append(skills, users...)
+	m := NewMemoryIndexer()
+	if err := m.Index(all...); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{
+			name: "exact match before prefix match",
+			q:    Query{Kind: KindSkill, Text: "go"},
+			want: []string{"Go", "Golang Concurrency"},
+		},
+		{
+			name: "prefix",
+			q:    Query{Kind: KindSkill, Text: "mach learn"},
+			want: []string{"Machine Learning"},
+		},
+		{
+			name: "swapped letters",
+			q:    Query{Kind: KindSkill, Text: "pyhton"},
+			want: []string{"Python"},
+		},
+		{
+			name: "misspelling",
+			q:    Query{Kind: KindSkill, Text: "watercolour"},
+			want: []string{"Watercolor Painting"},
+		},
+		{
+			name: "every word must match",
+			q:    Query{Kind: KindSkill, Text: "python painting"},
+			want: []string{},
+		},
+		{
+			name: "name outranks location",
+			q:    Query{Kind: KindUser, Text: "london"},
+			want: []string{"London Jones · Paris", "Ada Lovelace · London"},
+		},
+		{
+			name: "words across fields",
+			q:    Query{Kind: KindUser, Text: "ada london"},
+			want: []string{"Ada Lovelace · London"},
+		},
+		{
+			name: "field filter only",
+			q:    Query{Kind: KindUser, Fields: map[string][]string{"skill": {"go"}}},
+			want: []string{"Grace Hopper · New York"},
+		},
+		{
+			name: "text and field filter",
+			q:    Query{Kind: KindUser, Text: "london", Fields: map[string][]string{"location": {"paris"}}},
+			want: []string{"London Jones · Paris"},
+		},
+		{
+			name: "field filter with a typo",
+			q:    Query{Kind: KindUser, Fields: map[string][]string{"location": {"new yrok"}}},
+			want: []string{"Grace Hopper · New York"},
+		},
+		{
+			name: "limit",
+			q:    Query{Kind: KindSkill, Text: "go", Limit: 1},
+			want: []string{"Go"},
+		},
+		{
+			name: "other kinds are not searched",
+			q:    Query{Kind: KindSwap, Text: "go"},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := searchTitles(t, m, all, tt.q)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemorySearchHighlight(t *testing.T) {
+	m := NewMemoryIndexer()
+	doc := userDoc("Ada Lovelace", "London", "")
+	if err := m.Index(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := m.Search(context.Background(), Query{Kind: KindUser, Text: "ada lond"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("got %d hits, want 1", len(hits))
+	}
+	if want := "<mark>Ada</mark> Lovelace · <mark>London</mark>"; hits[0].Highlight != want {
+		t.Errorf("Highlight = %q, want %q", hits[0].Highlight, want)
+	}
+	if hits[0].Score <= 0 {
+		t.Errorf("Score = %v, want > 0", hits[0].Score)
+	}
+}
+
+func TestMemoryIndexUpdates(t *testing.T) {
+	m := NewMemoryIndexer()
+	doc := skillDoc("Rust")
+	if err := m.Index(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(text string) int {
+		t.Helper()
+		hits, err := m.Search(context.Background(), Query{Kind: KindSkill, Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(hits)
+	}
+
+	// Re-indexing replaces the old text
+	doc.Title, doc.Fields["name"] = "Elixir", "Elixir"
+	if err := m.Index(doc); err != nil {
+		t.Fatal(err)
+	}
+	if count("rust") != 0 || count("elixir") != 1 {
+		t.Fatal("re-indexed document still matches its old text")
+	}
+
+	if err := m.Delete(KindSkill, doc.ID); err != nil {
+		t.Fatal(err)
+	}
+	if count("elixir") != 0 {
+		t.Fatal("deleted document still matches")
+	}
+
+	if err := m.Index(doc, skillDoc("Erlang")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Reset(KindSkill); err != nil {
+		t.Fatal(err)
+	}
+	if count("elixir") != 0 || count("erlang") != 0 {
+		t.Fatal("documents survived Reset")
+	}
+}
+
+func TestMemoryUnknownKind(t *testing.T) {
+	m := NewMemoryIndexer()
+	if err := m.Index(Document{Kind: "event", ID: uuid.New()}); err == nil {
+		t.Error("Index accepted an unknown kind")
+	}
+	if _, err := m.Search(context.Background(), Query{Kind: "event"}); err == nil {
+		t.Error("Search accepted an unknown kind")
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"python", "python", 0},
+		{"python", "pyhton", 1},
+		{"python", "pythn", 1},
+		{"python", "pythons", 1},
+		{"python", "pithon", 1},
+		{"python", "pyhtno", 2},
+		{"go", "golang", 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	if got := similarity("word", "word"); got != 1 {
+		t.Errorf("similarity of equal words = %v, want 1", got)
+	}
+	if got := similarity("abc", "xyz"); got != 0 {
+		t.Errorf("similarity of unrelated words = %v, want 0", got)
+	}
+	// 4 shared of 7 distinct trigrams, as pg_trgm reports
+	if got := similarity("word", "words"); got != 4.0/7 {
+		t.Errorf("similarity(word, words) = %v, want 4/7", got)
+	}
+	if got := similarity("", "word"); got != 0 {
+		t.Errorf("similarity with an empty string = %v, want 0", got)
+	}
+}
+
+func TestToPrefixTSQuery(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"mach learn", "mach:* & learn:*"},
+		{"C++ & Go!", "c:* & go:*"},
+		{"it's", "its:*"},
+		{"& | !", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := toPrefixTSQuery(tt.text); got != tt.want {
+			t.Errorf("toPrefixTSQuery(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
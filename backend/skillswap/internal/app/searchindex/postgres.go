@@ -0,0 +1,230 @@
+package searchindex
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// headlineOptions marks matched words in ts_headline snippets
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+
+// PostgresIndexer searches the live tables through the generated
+// search_vector columns and pg_trgm indexes. Postgres keeps those up to date
+// on every write, so Index and Delete have nothing to do. Searches run on
+// read, which may be a replica.
+type PostgresIndexer struct {
+	read *gorm.DB
+}
+
+func NewPostgresIndexer(read *gorm.DB) *PostgresIndexer {
+	return &PostgresIndexer{read: read}
+}
+
+// Index is a no-op; the generated columns are maintained by Postgres
+func (p *PostgresIndexer) Index(docs ...Document) error {
+	return nil
+}
+
+// Delete is a no-op; deleted rows are filtered out by the search queries
+func (p *PostgresIndexer) Delete(kind Kind, ids ...uuid.UUID) error {
+	return nil
+}
+
+// Reset is a no-op; there is no copy of the tables to drop
+func (p *PostgresIndexer) Reset(kind Kind) error {
+	return nil
+}
+
+// browseOrder is the order of hits for queries without free text, which
+// all score 0
+var browseOrder = map[Kind]string{
+	KindUser:  "users.created_at DESC",
+	KindSkill: "skills.name ASC",
+	KindSwap:  "swap_requests.created_at DESC",
+}
+
+// Search runs a ranked full-text and trigram query for one kind
+func (p *PostgresIndexer) Search(ctx context.Context, q Query) ([]Hit, error) {
+	query, err := p.HitsQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if q.Text == "" {
+		query = query.Order(browseOrder[q.Kind])
+	} else {
+		query = query.Order("score DESC")
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+
+	var hits []Hit
+	if err := query.Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// HitsQuery returns the unordered full-text and trigram query for one kind.
+// q.Limit is ignored.
+func (p *PostgresIndexer) HitsQuery(ctx context.Context, q Query) (*gorm.DB, error) {
+	db := p.read.WithContext(ctx)
+	switch q.Kind {
+	case KindUser:
+		return userQuery(db, q), nil
+	case KindSkill:
+		return skillQuery(db, q), nil
+	case KindSwap:
+		return swapQuery(db, q), nil
+	}
+	return nil, fmt.Errorf("unknown search kind %q", q.Kind)
+}
+
+func userQuery(db *gorm.DB, q Query) *gorm.DB {
+	query := db.Table("users").Where("users.deleted_at IS NULL")
+
+	if q.Text == "" {
+		query = query.
+			Select("users.user_id AS id, 0::float8 AS score, '' AS highlight")
+	} else {
+		tsQuery := toPrefixTSQuery(q.Text)
+		query = query.
+			Select("users.user_id AS id, "+
+				"ts_rank(users.search_vector, to_tsquery('simple', ?)) + similarity(users.name, ?) AS score, "+
+				"ts_headline('simple', users.name || COALESCE(' · ' || users.location, ''), to_tsquery('simple', ?), '"+headlineOptions+"') AS highlight",
+				tsQuery, q.Text, tsQuery).
+			Where("(users.search_vector @@ to_tsquery('simple', ?) OR users.name % ? OR users.location % ?)",
+				tsQuery, q.Text, q.Text)
+	}
+
+	for _, name := range q.Fields["name"] {
+		query = query.Where(fuzzyMatch("users.name"), likePattern(name), name)
+	}
+	for _, location := range q.Fields["location"] {
+		query = query.Where(fuzzyMatch("users.location"), likePattern(location), location)
+	}
+	for _, skill := range q.Fields["skill"] {
+		query = query.Where("("+userHasSkillByName("user_skills_offered")+" OR "+userHasSkillByName("user_skills_wanted")+")",
+			likePattern(skill), skill, likePattern(skill), skill)
+	}
+	for _, skill := range q.Fields["offers"] {
+		query = query.Where(userHasSkillByName("user_skills_offered"), likePattern(skill), skill)
+	}
+	for _, skill := range q.Fields["wants"] {
+		query = query.Where(userHasSkillByName("user_skills_wanted"), likePattern(skill), skill)
+	}
+
+	return query
+}
+
+func skillQuery(db *gorm.DB, q Query) *gorm.DB {
+	query := db.Table("skills")
+
+	if q.Text == "" {
+		query = query.
+			Select("skills.skill_id AS id, 0::float8 AS score, '' AS highlight")
+	} else {
+		tsQuery := toPrefixTSQuery(q.Text)
+		query = query.
+			Select("skills.skill_id AS id, "+
+				"ts_rank(skills.search_vector, to_tsquery('simple', ?)) + similarity(skills.name, ?) AS score, "+
+				"ts_headline('simple', skills.name, to_tsquery('simple', ?), '"+headlineOptions+"') AS highlight",
+				tsQuery, q.Text, tsQuery).
+			Where("(skills.search_vector @@ to_tsquery('simple', ?) OR skills.name % ?)", tsQuery, q.Text)
+	}
+
+	for _, name := range q.Fields["name"] {
+		query = query.Where(fuzzyMatch("skills.name"), likePattern(name), name)
+	}
+
+	return query
+}
+
+// swapQuery matches swap requests through the offered/wanted skill names
+// and the participants' names, since swaps carry no free text of their own
+func swapQuery(db *gorm.DB, q Query) *gorm.DB {
+	query := db.Table("swap_requests").
+		Joins("JOIN skills os ON os.skill_id = swap_requests.offered_skill_id").
+		Joins("JOIN skills ws ON ws.skill_id = swap_requests.wanted_skill_id").
+		Joins("JOIN users req ON req.user_id = swap_requests.requester_id").
+		Joins("JOIN users resp ON resp.user_id = swap_requests.responder_id").
+		Where("swap_requests.deleted_at IS NULL")
+
+	if q.Text == "" {
+		query = query.
+			Select("swap_requests.swap_id AS id, 0::float8 AS score, '' AS highlight")
+	} else {
+		tsQuery := toPrefixTSQuery(q.Text)
+		query = query.
+			Select("swap_requests.swap_id AS id, "+
+				"GREATEST(ts_rank(os.search_vector, to_tsquery('simple', ?)), ts_rank(ws.search_vector, to_tsquery('simple', ?)), similarity(os.name, ?), similarity(ws.name, ?)) AS score, "+
+				"ts_headline('simple', os.name || ' ⇄ ' || ws.name, to_tsquery('simple', ?), '"+headlineOptions+"') AS highlight",
+				tsQuery, tsQuery, q.Text, q.Text, tsQuery).
+			Where(`(os.search_vector @@ to_tsquery('simple', ?) OR ws.search_vector @@ to_tsquery('simple', ?)
+				OR to_tsvector('simple', req.name) @@ to_tsquery('simple', ?) OR to_tsvector('simple', resp.name) @@ to_tsquery('simple', ?)
+				OR os.name % ? OR ws.name % ?)`,
+				tsQuery, tsQuery, tsQuery, tsQuery, q.Text, q.Text)
+	}
+
+	for _, skill := range q.Fields["skill"] {
+		query = query.Where("("+fuzzyMatch("os.name")+" OR "+fuzzyMatch("ws.name")+")",
+			likePattern(skill), skill, likePattern(skill), skill)
+	}
+	for _, skill := range q.Fields["offered"] {
+		query = query.Where(fuzzyMatch("os.name"), likePattern(skill), skill)
+	}
+	for _, skill := range q.Fields["wanted"] {
+		query = query.Where(fuzzyMatch("ws.name"), likePattern(skill), skill)
+	}
+	for _, name := range q.Fields["user"] {
+		query = query.Where("("+fuzzyMatch("req.name")+" OR "+fuzzyMatch("resp.name")+")",
+			likePattern(name), name, likePattern(name), name)
+	}
+
+	return query
+}
+
+// fuzzyMatch matches a column by substring or trigram similarity. Both forms
+// are served by the column's gin_trgm_ops index. Takes (likePattern, value).
+func fuzzyMatch(column string) string {
+	return fmt.Sprintf("(%s ILIKE ? OR %s %% ?)", column, column)
+}
+
+// userHasSkillByName matches users linked through the given junction table
+// to a skill whose name fuzzily matches. Takes (likePattern, value).
+func userHasSkillByName(junctionTable string) string {
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM %s us JOIN skills sk ON sk.skill_id = us.skill_id WHERE us.user_id = users.user_id AND %s)",
+		junctionTable, fuzzyMatch("sk.name"))
+}
+
+// toPrefixTSQuery converts free text into a to_tsquery expression where every
+// word is prefix-matched ("mach learn" -> "mach:* & learn:*"). Characters that
+// have a meaning in tsquery syntax are dropped so user input can never produce
+// a syntax error. An empty string means there was nothing searchable.
+func toPrefixTSQuery(text string) string {
+	var parts []string
+	for _, word := range strings.Fields(text) {
+		cleaned := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, word)
+		if cleaned != "" {
+			parts = append(parts, cleaned+":*")
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+// likePattern escapes LIKE wildcards in the user's input and wraps it for a
+// substring match
+func likePattern(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(value) + "%"
+}
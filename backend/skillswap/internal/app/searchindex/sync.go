@@ -0,0 +1,211 @@
+package searchindex
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// reindexBatchSize is how many rows are loaded per query during a full reindex
+const reindexBatchSize = 500
+
+// ReindexStats summarises a full reindex
+type ReindexStats struct {
+	Users    int    `json:"users"`
+	Skills   int    `json:"skills"`
+	Swaps    int    `json:"swaps"`
+	Duration string `json:"duration"`
+}
+
+// Syncer keeps an Indexer in step with the database by reloading the
+// affected rows whenever a domain event is published
+type Syncer struct {
+	db      *gorm.DB
+	indexer Indexer
+}
+
+func NewSyncer(db *gorm.DB, indexer Indexer) *Syncer {
+	return &Syncer{db: db, indexer: indexer}
+}
+
+// Subscribe registers the syncer for all user, skill and swap events
+func (s *Syncer) Subscribe(bus *event.Bus) {
+	bus.Subscribe(s.Handle,
+		event.UserChanged, event.UserDeleted,
+		event.SkillChanged, event.SkillDeleted,
+		event.SwapChanged, event.SwapDeleted)
+}
+
+// Handle updates the index for a single event. Failures are logged rather
+// than returned because the change that triggered the event has already
+// been committed; a full reindex repairs any drift.
+func (s *Syncer) Handle(evt event.Event) {
+	var err error
+
+	switch evt.Type {
+	case event.UserChanged:
+		// Swap documents carry participant names
+		_, err = s.indexUsers(s.db.Where("user_id = ?", evt.EntityID), evt.EntityID)
+		if err == nil {
+			_, err = s.indexSwaps(s.db.Where("requester_id = ? OR responder_id = ?", evt.EntityID, evt.EntityID))
+		}
+	case event.UserDeleted:
+		err = s.indexer.Delete(KindUser, evt.EntityID)
+	case event.SkillChanged:
+		// User and swap documents carry skill names
+		_, err = s.indexSkills(s.db.Where("skill_id = ?", evt.EntityID), evt.EntityID)
+		if err == nil {
+			_, err = s.indexUsers(s.db.Where(
+				"user_id IN (SELECT user_id FROM user_skills_offered WHERE skill_id = ?) OR user_id IN (SELECT user_id FROM user_skills_wanted WHERE skill_id = ?)",
+				evt.EntityID, evt.EntityID))
+		}
+		if err == nil {
+			_, err = s.indexSwaps(s.db.Where("offered_skill_id = ? OR wanted_skill_id = ?", evt.EntityID, evt.EntityID))
+		}
+	case event.SkillDeleted:
+		err = s.indexer.Delete(KindSkill, evt.EntityID)
+	case event.SwapChanged:
+		_, err = s.indexSwaps(s.db.Where("swap_id = ?", evt.EntityID), evt.EntityID)
+	case event.SwapDeleted:
+		err = s.indexer.Delete(KindSwap, evt.EntityID)
+	}
+
+	if err != nil {
+		log.Printf("Warning: failed to update search index for %s %s: %v", evt.Type, evt.EntityID, err)
+	}
+}
+
+// ReindexAll drops and rebuilds every kind from the database. Searches made
+// while it runs may miss documents that have not been re-added yet. A
+// LiveIndexer has nothing to rebuild, so its rows are only counted.
+func (s *Syncer) ReindexAll(ctx context.Context) (*ReindexStats, error) {
+	started := time.Now()
+	stats := &ReindexStats{}
+	db := s.db.WithContext(ctx)
+
+	if _, ok := s.indexer.(LiveIndexer); ok {
+		if err := s.countAll(db, stats); err != nil {
+			return nil, err
+		}
+		stats.Duration = time.Since(started).Round(time.Millisecond).String()
+		return stats, nil
+	}
+
+	for _, kind := range Kinds {
+		if err := s.indexer.Reset(kind); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if stats.Users, err = s.indexUsers(db); err != nil {
+		return nil, err
+	}
+	if stats.Skills, err = s.indexSkills(db); err != nil {
+		return nil, err
+	}
+	if stats.Swaps, err = s.indexSwaps(db); err != nil {
+		return nil, err
+	}
+
+	stats.Duration = time.Since(started).Round(time.Millisecond).String()
+	return stats, nil
+}
+
+func (s *Syncer) countAll(db *gorm.DB, stats *ReindexStats) error {
+	var users, skills, swaps int64
+	if err := db.Model(&models.User{}).Count(&users).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.Skill{}).Count(&skills).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.SwapRequest{}).Count(&swaps).Error; err != nil {
+		return err
+	}
+	stats.Users, stats.Skills, stats.Swaps = int(users), int(skills), int(swaps)
+	return nil
+}
+
+// indexUsers indexes the users matched by query. IDs in expected that are
+// no longer found (deleted since the event) are removed from the index.
+func (s *Syncer) indexUsers(query *gorm.DB, expected ...uuid.UUID) (int, error) {
+	seen := make(map[uuid.UUID]bool)
+
+	var batch []models.User
+	err := query.Model(&models.User{}).
+		Preload("SkillsOffered.Skill").Preload("SkillsWanted.Skill").
+		FindInBatches(&batch, reindexBatchSize, func(tx *gorm.DB, _ int) error {
+			docs := make([]Document, len(batch))
+			for i := range batch {
+				docs[i] = UserDocument(&batch[i])
+				seen[batch[i].UserID] = true
+			}
+			return s.indexer.Index(docs...)
+		}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return len(seen), s.deleteMissing(KindUser, seen, expected)
+}
+
+func (s *Syncer) indexSkills(query *gorm.DB, expected ...uuid.UUID) (int, error) {
+	seen := make(map[uuid.UUID]bool)
+
+	var batch []models.Skill
+	err := query.Model(&models.Skill{}).
+		FindInBatches(&batch, reindexBatchSize, func(tx *gorm.DB, _ int) error {
+			docs := make([]Document, len(batch))
+			for i := range batch {
+				docs[i] = SkillDocument(&batch[i])
+				seen[batch[i].SkillID] = true
+			}
+			return s.indexer.Index(docs...)
+		}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return len(seen), s.deleteMissing(KindSkill, seen, expected)
+}
+
+func (s *Syncer) indexSwaps(query *gorm.DB, expected ...uuid.UUID) (int, error) {
+	seen := make(map[uuid.UUID]bool)
+
+	var batch []models.SwapRequest
+	err := query.Model(&models.SwapRequest{}).
+		Preload("Requester").Preload("Responder").
+		Preload("OfferedSkill").Preload("WantedSkill").
+		FindInBatches(&batch, reindexBatchSize, func(tx *gorm.DB, _ int) error {
+			docs := make([]Document, len(batch))
+			for i := range batch {
+				docs[i] = SwapDocument(&batch[i])
+				seen[batch[i].SwapID] = true
+			}
+			return s.indexer.Index(docs...)
+		}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return len(seen), s.deleteMissing(KindSwap, seen, expected)
+}
+
+func (s *Syncer) deleteMissing(kind Kind, seen map[uuid.UUID]bool, expected []uuid.UUID) error {
+	var missing []uuid.UUID
+	for _, id := range expected {
+		if !seen[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return s.indexer.Delete(kind, missing...)
+}
@@ -1,39 +1,55 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// AdminService holds staff actions. Each checks that the acting user's
+// roles grant the permission it needs.
 type AdminService interface {
 	// User management
-	GetAllUsers(filter AdminUserFilter) ([]models.User, int64, error)
-	BanUser(adminID, userID uuid.UUID) error
-	UnbanUser(adminID, userID uuid.UUID) error
-	DeleteUser(adminID, userID uuid.UUID) error
-	MakeUserAdmin(adminID, userID uuid.UUID) error
-	RemoveUserAdmin(adminID, userID uuid.UUID) error
+	GetAllUsers(ctx context.Context, adminID uuid.UUID, filter AdminUserFilter) ([]models.User, int64, error)
+	BanUser(ctx context.Context, adminID, userID uuid.UUID) error
+	UnbanUser(ctx context.Context, adminID, userID uuid.UUID) error
+	DeleteUser(ctx context.Context, adminID, userID uuid.UUID) error
+
+	// Login lockouts
+	GetLockouts(ctx context.Context, adminID uuid.UUID, lockedOnly bool) ([]AccountLockout, error)
+	// ClearLockout forgets a user's failed logins, unlocking the account
+	ClearLockout(ctx context.Context, adminID, userID uuid.UUID) error
+
+	// Roles
+	ListRoles(ctx context.Context, adminID uuid.UUID) ([]rbac.Role, error)
+	GetUserRoles(ctx context.Context, adminID, userID uuid.UUID) ([]models.UserRole, error)
+	GrantRole(ctx context.Context, adminID, userID uuid.UUID, role string) error
+	// RevokeRole takes a role away; admins cannot revoke their own admin role
+	RevokeRole(ctx context.Context, adminID, userID uuid.UUID, role string) error
 
 	// Swap management
-	GetAllSwaps(filter AdminSwapFilter) ([]models.SwapRequest, int64, error)
-	CancelSwap(adminID, swapID uuid.UUID, reason string) error
+	GetAllSwaps(ctx context.Context, adminID uuid.UUID, filter AdminSwapFilter) ([]models.SwapRequest, int64, error)
+	CancelSwap(ctx context.Context, adminID, swapID uuid.UUID, reason string) error
 
 	// Platform statistics
-	GetPlatformStats() (*PlatformStats, error)
+	GetPlatformStats(ctx context.Context, adminID uuid.UUID) (*PlatformStats, error)
 
 	// Content moderation
-	GetReportedContent() ([]ReportedContent, error)
+	GetReportedContent(ctx context.Context, adminID uuid.UUID) ([]ReportedContent, error)
 }
 
 // DTOs and filters
 type AdminUserFilter struct {
 	Search    string `json:"search,omitempty"`
 	IsBanned  *bool  `json:"is_banned,omitempty"`
-	IsAdmin   *bool  `json:"is_admin,omitempty"`
+	Role      string `json:"role,omitempty"`       // Only users holding this role
 	SortBy    string `json:"sort_by,omitempty"`    // "created_at", "name", "email"
 	SortOrder string `json:"sort_order,omitempty"` // "asc", "desc"
 	Limit     int    `json:"limit,omitempty"`
@@ -50,6 +66,16 @@ type AdminSwapFilter struct {
 	Offset      int        `json:"offset,omitempty"`
 }
 
+// AccountLockout is an email address with recent failed logins. UserID is
+// nil when no account uses the address.
+type AccountLockout struct {
+	Email          string     `json:"email"`
+	UserID         *uuid.UUID `json:"user_id"`
+	FailedAttempts int        `json:"failed_attempts"`
+	LastFailedAt   time.Time  `json:"last_failed_at"`
+	LockedUntil    *time.Time `json:"locked_until"`
+}
+
 type PlatformStats struct {
 	TotalUsers        int64   `json:"total_users"`
 	ActiveUsers       int64   `json:"active_users"` // Users with activity in last 30 days
@@ -74,17 +100,24 @@ type ReportedContent struct {
 	CreatedAt   string    `json:"created_at"`
 }
 
+// adminService reads platform statistics from read, which may be a replica
 type adminService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	read   *gorm.DB
+	events *event.Bus
 }
 
-func NewAdminService(db *gorm.DB) AdminService {
-	return &adminService{db: db}
+func NewAdminService(db, read *gorm.DB, events *event.Bus) AdminService {
+	return &adminService{db: db, read: read, events: events}
 }
 
 // GetAllUsers retrieves all users with filtering and pagination
-func (a *adminService) GetAllUsers(filter AdminUserFilter) ([]models.User, int64, error) {
-	query := a.db.Model(&models.User{})
+func (a *adminService) GetAllUsers(ctx context.Context, adminID uuid.UUID, filter AdminUserFilter) ([]models.User, int64, error) {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermUsersRead); err != nil {
+		return nil, 0, err
+	}
+
+	query := a.db.WithContext(ctx).Model(&models.User{})
 
 	// Apply filters
 	if filter.Search != "" {
@@ -96,8 +129,8 @@ func (a *adminService) GetAllUsers(filter AdminUserFilter) ([]models.User, int64
 		query = query.Where("is_banned = ?", *filter.IsBanned)
 	}
 
-	if filter.IsAdmin != nil {
-		query = query.Where("is_admin = ?", *filter.IsAdmin)
+	if filter.Role != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.user_id AND user_roles.role = ?)", filter.Role)
 	}
 
 	// Get total count
@@ -128,90 +161,179 @@ func (a *adminService) GetAllUsers(filter AdminUserFilter) ([]models.User, int64
 	}
 
 	var users []models.User
-	err := query.Find(&users).Error
+	err := query.Preload("Roles").Find(&users).Error
 	return users, total, err
 }
 
-// BanUser bans a user (only admins can do this)
-func (a *adminService) BanUser(adminID, userID uuid.UUID) error {
-	// Verify admin permissions
-	if err := a.verifyAdminPermissions(adminID); err != nil {
+// BanUser bans a user
+func (a *adminService) BanUser(ctx context.Context, adminID, userID uuid.UUID) error {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermUsersBan); err != nil {
 		return err
 	}
 
-	// Cannot ban another admin
-	var targetUser models.User
-	if err := a.db.First(&targetUser, "user_id = ?", userID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
-		}
+	roles, err := a.targetRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := checkNotStaff(roles, "ban"); err != nil {
 		return err
 	}
 
-	if targetUser.IsAdmin {
-		return errors.New("cannot ban an admin user")
+	if err := a.db.WithContext(ctx).Model(&models.User{}).Where("user_id = ?", userID).Update("is_banned", true).Error; err != nil {
+		return err
 	}
 
-	return a.db.Model(&models.User{}).Where("user_id = ?", userID).Update("is_banned", true).Error
+	a.events.Publish(event.UserChanged, userID)
+	return nil
 }
 
 // UnbanUser unbans a user
-func (a *adminService) UnbanUser(adminID, userID uuid.UUID) error {
-	if err := a.verifyAdminPermissions(adminID); err != nil {
+func (a *adminService) UnbanUser(ctx context.Context, adminID, userID uuid.UUID) error {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermUsersBan); err != nil {
+		return err
+	}
+
+	if err := a.db.WithContext(ctx).Model(&models.User{}).Where("user_id = ?", userID).Update("is_banned", false).Error; err != nil {
 		return err
 	}
 
-	return a.db.Model(&models.User{}).Where("user_id = ?", userID).Update("is_banned", false).Error
+	a.events.Publish(event.UserChanged, userID)
+	return nil
 }
 
 // DeleteUser soft deletes a user
-func (a *adminService) DeleteUser(adminID, userID uuid.UUID) error {
-	if err := a.verifyAdminPermissions(adminID); err != nil {
+func (a *adminService) DeleteUser(ctx context.Context, adminID, userID uuid.UUID) error {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermUsersDelete); err != nil {
+		return err
+	}
+
+	roles, err := a.targetRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := checkNotStaff(roles, "delete"); err != nil {
+		return err
+	}
+
+	if err := a.db.WithContext(ctx).Delete(&models.User{}, "user_id = ?", userID).Error; err != nil {
+		return err
+	}
+
+	a.events.Publish(event.UserDeleted, userID)
+	return nil
+}
+
+// GetLockouts lists addresses with failed logins in the last day, most
+// recent first
+func (a *adminService) GetLockouts(ctx context.Context, adminID uuid.UUID, lockedOnly bool) ([]AccountLockout, error) {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermLockoutsManage); err != nil {
+		return nil, err
+	}
+
+	query := a.db.WithContext(ctx).Table("login_failures").
+		Select("login_failures.*, users.user_id").
+		Joins("LEFT JOIN users ON LOWER(users.email) = login_failures.email AND users.deleted_at IS NULL").
+		Where("login_failures.last_failed_at > ?", time.Now().Add(-loginFailureWindow))
+	if lockedOnly {
+		query = query.Where("login_failures.locked_until > ?", time.Now())
+	}
+
+	lockouts := []AccountLockout{}
+	err := query.Order("login_failures.last_failed_at DESC").Limit(100).Scan(&lockouts).Error
+	return lockouts, err
+}
+
+func (a *adminService) ClearLockout(ctx context.Context, adminID, userID uuid.UUID) error {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermLockoutsManage); err != nil {
 		return err
 	}
 
-	// Cannot delete another admin
-	var targetUser models.User
-	if err := a.db.First(&targetUser, "user_id = ?", userID).Error; err != nil {
+	var user models.User
+	if err := a.db.WithContext(ctx).First(&user, "user_id = ?", userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("user not found")
 		}
 		return err
 	}
+	return clearLoginFailures(a.db.WithContext(ctx), user.Email)
+}
+
+// ListRoles lists the roles that can be granted
+func (a *adminService) ListRoles(ctx context.Context, adminID uuid.UUID) ([]rbac.Role, error) {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermRolesManage); err != nil {
+		return nil, err
+	}
+	return rbac.Roles(), nil
+}
 
-	if targetUser.IsAdmin {
-		return errors.New("cannot delete an admin user")
+func (a *adminService) GetUserRoles(ctx context.Context, adminID, userID uuid.UUID) ([]models.UserRole, error) {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermUsersRead); err != nil {
+		return nil, err
+	}
+	if err := a.db.WithContext(ctx).First(&models.User{}, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
 	}
 
-	return a.db.Delete(&models.User{}, "user_id = ?", userID).Error
+	roles := []models.UserRole{}
+	err := a.db.WithContext(ctx).Where("user_id = ?", userID).Order("role").Find(&roles).Error
+	return roles, err
 }
 
-// MakeUserAdmin grants admin privileges to a user
-func (a *adminService) MakeUserAdmin(adminID, userID uuid.UUID) error {
-	if err := a.verifyAdminPermissions(adminID); err != nil {
+// GrantRole gives a user a role. Granting a role the user holds does
+// nothing. The user's tokens list the role once they are refreshed.
+func (a *adminService) GrantRole(ctx context.Context, adminID, userID uuid.UUID, role string) error {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermRolesManage); err != nil {
+		return err
+	}
+	if _, ok := rbac.Lookup(role); !ok {
+		return errors.New("unknown role")
+	}
+
+	var user models.User
+	if err := a.db.WithContext(ctx).First(&user, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
 		return err
 	}
+	if user.IsBanned {
+		return errors.New("cannot grant a role to a banned user")
+	}
 
-	return a.db.Model(&models.User{}).Where("user_id = ?", userID).Update("is_admin", true).Error
+	return a.db.WithContext(ctx).Exec(`
+		INSERT INTO user_roles (user_id, role, granted_by, created_at)
+		VALUES (?, ?, ?, NOW())
+		ON CONFLICT (user_id, role) DO NOTHING`,
+		userID, role, adminID,
+	).Error
 }
 
-// RemoveUserAdmin removes admin privileges from a user
-func (a *adminService) RemoveUserAdmin(adminID, userID uuid.UUID) error {
-	if err := a.verifyAdminPermissions(adminID); err != nil {
+func (a *adminService) RevokeRole(ctx context.Context, adminID, userID uuid.UUID, role string) error {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermRolesManage); err != nil {
 		return err
 	}
+	if _, ok := rbac.Lookup(role); !ok {
+		return errors.New("unknown role")
+	}
 
-	// Cannot remove admin from self
-	if adminID == userID {
+	// An admin always remains, so admin access cannot be lost
+	if role == rbac.RoleAdmin && adminID == userID {
 		return errors.New("cannot remove admin privileges from yourself")
 	}
 
-	return a.db.Model(&models.User{}).Where("user_id = ?", userID).Update("is_admin", false).Error
+	return a.db.WithContext(ctx).Where("user_id = ? AND role = ?", userID, role).Delete(&models.UserRole{}).Error
 }
 
 // GetAllSwaps retrieves all swaps with filtering and pagination
-func (a *adminService) GetAllSwaps(filter AdminSwapFilter) ([]models.SwapRequest, int64, error) {
-	query := a.db.Model(&models.SwapRequest{}).
+func (a *adminService) GetAllSwaps(ctx context.Context, adminID uuid.UUID, filter AdminSwapFilter) ([]models.SwapRequest, int64, error) {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermSwapsRead); err != nil {
+		return nil, 0, err
+	}
+
+	query := a.db.WithContext(ctx).Model(&models.SwapRequest{}).
 		Preload("Requester").
 		Preload("Responder").
 		Preload("OfferedSkill").
@@ -263,31 +385,42 @@ func (a *adminService) GetAllSwaps(filter AdminSwapFilter) ([]models.SwapRequest
 }
 
 // CancelSwap cancels a swap (admin intervention)
-func (a *adminService) CancelSwap(adminID, swapID uuid.UUID, reason string) error {
-	if err := a.verifyAdminPermissions(adminID); err != nil {
+func (a *adminService) CancelSwap(ctx context.Context, adminID, swapID uuid.UUID, reason string) error {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermSwapsCancel); err != nil {
 		return err
 	}
 
 	// TODO: Add audit log for admin actions
-	return a.db.Model(&models.SwapRequest{}).
+	err := a.db.WithContext(ctx).Model(&models.SwapRequest{}).
 		Where("swap_id = ?", swapID).
 		Updates(map[string]interface{}{
 			"status": models.StatusCancelled,
 			// TODO: Add admin_notes field to store the reason
 		}).Error
+	if err != nil {
+		return err
+	}
+
+	a.events.Publish(event.SwapChanged, swapID)
+	return nil
 }
 
 // GetPlatformStats retrieves platform-wide statistics
-func (a *adminService) GetPlatformStats() (*PlatformStats, error) {
+func (a *adminService) GetPlatformStats(ctx context.Context, adminID uuid.UUID) (*PlatformStats, error) {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermStatsRead); err != nil {
+		return nil, err
+	}
+
 	stats := &PlatformStats{}
+	db := a.read.WithContext(ctx)
 
 	// Total users
-	if err := a.db.Model(&models.User{}).Count(&stats.TotalUsers).Error; err != nil {
+	if err := db.Model(&models.User{}).Count(&stats.TotalUsers).Error; err != nil {
 		return nil, err
 	}
 
 	// Active users (users with swaps in last 30 days)
-	if err := a.db.Model(&models.User{}).
+	if err := db.Model(&models.User{}).
 		Joins("JOIN swap_requests ON users.user_id = swap_requests.requester_id OR users.user_id = swap_requests.responder_id").
 		Where("swap_requests.created_at > NOW() - INTERVAL '30 days'").
 		Distinct("users.user_id").
@@ -296,37 +429,37 @@ func (a *adminService) GetPlatformStats() (*PlatformStats, error) {
 	}
 
 	// Total swaps
-	if err := a.db.Model(&models.SwapRequest{}).Count(&stats.TotalSwaps).Error; err != nil {
+	if err := db.Model(&models.SwapRequest{}).Count(&stats.TotalSwaps).Error; err != nil {
 		return nil, err
 	}
 
 	// Completed swaps
-	if err := a.db.Model(&models.SwapRequest{}).
+	if err := db.Model(&models.SwapRequest{}).
 		Where("status = ?", models.StatusAccepted).
 		Count(&stats.CompletedSwaps).Error; err != nil {
 		return nil, err
 	}
 
 	// Pending swaps
-	if err := a.db.Model(&models.SwapRequest{}).
+	if err := db.Model(&models.SwapRequest{}).
 		Where("status = ?", models.StatusPending).
 		Count(&stats.PendingSwaps).Error; err != nil {
 		return nil, err
 	}
 
 	// Total skills
-	if err := a.db.Model(&models.Skill{}).Count(&stats.TotalSkills).Error; err != nil {
+	if err := db.Model(&models.Skill{}).Count(&stats.TotalSkills).Error; err != nil {
 		return nil, err
 	}
 
 	// Total ratings
-	if err := a.db.Model(&models.SwapRating{}).Count(&stats.TotalRatings).Error; err != nil {
+	if err := db.Model(&models.SwapRating{}).Count(&stats.TotalRatings).Error; err != nil {
 		return nil, err
 	}
 
 	// Average rating
 	if stats.TotalRatings > 0 {
-		if err := a.db.Model(&models.SwapRating{}).
+		if err := db.Model(&models.SwapRating{}).
 			Select("AVG(score)").
 			Scan(&stats.AverageRating).Error; err != nil {
 			return nil, err
@@ -334,14 +467,14 @@ func (a *adminService) GetPlatformStats() (*PlatformStats, error) {
 	}
 
 	// New users this month
-	if err := a.db.Model(&models.User{}).
+	if err := db.Model(&models.User{}).
 		Where("created_at > DATE_TRUNC('month', NOW())").
 		Count(&stats.NewUsersThisMonth).Error; err != nil {
 		return nil, err
 	}
 
 	// Swaps this month
-	if err := a.db.Model(&models.SwapRequest{}).
+	if err := db.Model(&models.SwapRequest{}).
 		Where("created_at > DATE_TRUNC('month', NOW())").
 		Count(&stats.SwapsThisMonth).Error; err != nil {
 		return nil, err
@@ -351,25 +484,23 @@ func (a *adminService) GetPlatformStats() (*PlatformStats, error) {
 }
 
 // GetReportedContent retrieves reported content (placeholder implementation)
-func (a *adminService) GetReportedContent() ([]ReportedContent, error) {
+func (a *adminService) GetReportedContent(ctx context.Context, adminID uuid.UUID) ([]ReportedContent, error) {
+	if err := authorize(a.db.WithContext(ctx), adminID, rbac.PermReportsManage); err != nil {
+		return nil, err
+	}
+
 	// TODO: Implement reporting system
 	// For now, return empty slice
 	return []ReportedContent{}, nil
 }
 
-// verifyAdminPermissions checks if the user has admin privileges
-func (a *adminService) verifyAdminPermissions(userID uuid.UUID) error {
-	var user models.User
-	if err := a.db.First(&user, "user_id = ? AND is_admin = true", userID).Error; err != nil {
+// targetRoles returns the roles of a user a staff action is aimed at
+func (a *adminService) targetRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	if err := a.db.WithContext(ctx).First(&models.User{}, "user_id = ?", userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("unauthorized: admin privileges required")
+			return nil, errors.New("user not found")
 		}
-		return err
-	}
-
-	if user.IsBanned {
-		return errors.New("unauthorized: admin account is banned")
+		return nil, err
 	}
-
-	return nil
+	return userRoles(a.db.WithContext(ctx), userID)
 }
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/mail"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// sqlStateUniqueViolation is raised when users.email is already taken
+const sqlStateUniqueViolation = "23505"
+
+func (s *authService) ChangePassword(ctx context.Context, userID uuid.UUID, req *ChangePasswordRequest) (*AuthResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		return nil, errors.New("current password is incorrect")
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return nil, errors.New("new password must be different from the current password")
+	}
+	if err := s.checkPasswordPolicy(req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), s.cfg.Auth.BcryptCost)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"password_hash": string(hashedPassword),
+			"token_version": gorm.Expr("token_version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+		// A pending reset link must not undo the change
+		return tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, models.TokenPasswordReset).
+			Delete(&models.UserToken{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.sendAccountNotice(ctx, user, user.Email, "password_changed", "Your password was changed", "")
+
+	// Fresh tokens carry the new token version
+	user, err = s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.generateAuthResponse(ctx, user)
+}
+
+func (s *authService) RequestEmailChange(ctx context.Context, userID uuid.UUID, req *ChangeEmailRequest) error {
+	if !s.notifications.EmailEnabled() {
+		return errors.New("email is not configured")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return errors.New("password is incorrect")
+	}
+	if strings.EqualFold(req.NewEmail, user.Email) {
+		return errors.New("new email is the same as the current one")
+	}
+	if existing, _ := s.userRepo.GetByEmail(ctx, req.NewEmail); existing != nil {
+		return errors.New("email is already in use")
+	}
+
+	token, err := issueUserToken(s.db.WithContext(ctx), user.UserID, models.TokenEmailChange, &req.NewEmail, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.notifications.QueueAccountEmail(ctx, user.UserID, mail.AccountEmailData{
+		To:            req.NewEmail,
+		RecipientName: user.Name,
+		Kind:          "confirm_email_change",
+		Title:         "Confirm your new email address",
+		Link:          s.cfg.Server.AppURL + "/confirm-email-change?token=" + url.QueryEscape(token),
+		Action:        "Confirm new email address",
+		ExpiresIn:     formatLeadTime(emailVerificationTTL),
+		Email:         req.NewEmail,
+	})
+}
+
+func (s *authService) ConfirmEmailChange(ctx context.Context, token string) error {
+	var user models.User
+	var newEmail string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		used, err := consumeUserToken(tx, token, models.TokenEmailChange)
+		if err != nil {
+			return err
+		}
+		if used.Email == nil {
+			return errors.New("invalid or expired token")
+		}
+		newEmail = *used.Email
+
+		if err := tx.Select("user_id", "name", "email").Where("user_id = ?", used.UserID).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("invalid or expired token")
+			}
+			return err
+		}
+
+		// Someone may have registered the address since the link was sent
+		var taken int64
+		if err := tx.Model(&models.User{}).Where("email = ? AND user_id <> ?", newEmail, user.UserID).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return errors.New("email is already in use")
+		}
+
+		err = tx.Model(&models.User{}).Where("user_id = ?", user.UserID).Updates(map[string]interface{}{
+			"email":             newEmail,
+			"email_verified_at": time.Now(),
+			"token_version":     gorm.Expr("token_version + 1"),
+		}).Error
+		if err != nil {
+			// Taken by a concurrent sign-up or change since the check above
+			var state interface{ SQLState() string }
+			if errors.As(err, &state) && state.SQLState() == sqlStateUniqueViolation {
+				return errors.New("email is already in use")
+			}
+			return err
+		}
+
+		// Links sent to the old address no longer apply
+		return tx.Where("user_id = ? AND used_at IS NULL", user.UserID).Delete(&models.UserToken{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.events.Publish(event.UserChanged, user.UserID)
+	s.sendAccountNotice(ctx, &user, user.Email, "email_changed", "Your email address was changed", newEmail)
+	return nil
+}
+
+// upgradePasswordHash re-hashes a just-verified password whose hash uses a
+// lower cost than configured
+func (s *authService) upgradePasswordHash(ctx context.Context, user *models.User, password string) {
+	cost, err := bcrypt.Cost([]byte(user.PasswordHash))
+	if err != nil || cost >= s.cfg.Auth.BcryptCost {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), s.cfg.Auth.BcryptCost)
+	if err != nil {
+		log.Printf("Warning: failed to upgrade password hash of user %s: %v", user.UserID, err)
+		return
+	}
+	// Only replace the hash that was verified, not one changed meanwhile
+	err = s.db.WithContext(ctx).Model(&models.User{}).
+		Where("user_id = ? AND password_hash = ?", user.UserID, user.PasswordHash).
+		Update("password_hash", string(hashedPassword)).Error
+	if err != nil {
+		log.Printf("Warning: failed to upgrade password hash of user %s: %v", user.UserID, err)
+		return
+	}
+	user.PasswordHash = string(hashedPassword)
+}
+
+// sendAccountNotice tells a user about a security-relevant change to their
+// account. It is best effort: the change has already happened.
+func (s *authService) sendAccountNotice(ctx context.Context, user *models.User, to, kind, title, email string) {
+	if !s.notifications.EmailEnabled() {
+		return
+	}
+	// Not cancelled with the request, for the same reason
+	err := s.notifications.QueueAccountEmail(context.WithoutCancel(ctx), user.UserID, mail.AccountEmailData{
+		To:            to,
+		RecipientName: user.Name,
+		Kind:          kind,
+		Title:         title,
+		Link:          s.cfg.Server.AppURL + "/forgot-password",
+		Action:        "Reset your password",
+		Email:         email,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to send %s email to user %s: %v", kind, user.UserID, err)
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jobs"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"gorm.io/gorm"
+)
+
+// Failed logins are counted per email address, whichever IPs they come
+// from. After loginDelayAfter failures each attempt has to wait twice as
+// long as the one before, and every loginLockoutAfter failures lock the
+// address for loginLockoutDuration.
+const (
+	loginDelayAfter      = 3
+	maxLoginDelay        = 30 * time.Second
+	loginLockoutAfter    = 10
+	loginLockoutDuration = 15 * time.Minute
+	loginFailureWindow   = 24 * time.Hour // Failures are forgotten after a quiet day
+)
+
+// JobLoginFailurePurge is the scheduled job kind that deletes forgotten
+// failures. Each run schedules the next, loginFailurePurgeInterval later.
+const (
+	JobLoginFailurePurge      = "login_failure_purge"
+	loginFailurePurgeInterval = time.Hour
+)
+
+// LoginThrottledError is returned for logins attempted too soon after
+// failed ones, or while the account is locked
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "account is temporarily locked after too many failed login attempts"
+	}
+	return "too many failed login attempts, try again later"
+}
+
+// checkPasswordPolicy refuses new passwords that are known from breaches
+func (s *authService) checkPasswordPolicy(password string) error {
+	if s.cfg.Auth.BreachedPasswords != nil && s.cfg.Auth.BreachedPasswords.Contains(password) {
+		return errors.New("this password has appeared in a data breach, please choose another")
+	}
+	return nil
+}
+
+// checkLoginThrottle refuses attempts while the address is locked or its
+// delay has not passed, before the password is checked
+func (s *authService) checkLoginThrottle(ctx context.Context, email string) error {
+	var failure models.LoginFailure
+	err := s.db.WithContext(ctx).Where("email = ? AND last_failed_at > ?", normalizeEmail(email), time.Now().Add(-loginFailureWindow)).
+		First(&failure).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if throttled := loginThrottle(failure, time.Now()); throttled != nil {
+		return throttled
+	}
+	return nil
+}
+
+// loginThrottle decides whether a login may be attempted at now, given the
+// failures recorded for its address. It returns nil when it may.
+func loginThrottle(failure models.LoginFailure, now time.Time) *LoginThrottledError {
+	if !failure.LastFailedAt.After(now.Add(-loginFailureWindow)) {
+		return nil
+	}
+	if failure.LockedUntil != nil && failure.LockedUntil.After(now) {
+		return &LoginThrottledError{RetryAfter: failure.LockedUntil.Sub(now), Locked: true}
+	}
+	if next := failure.LastFailedAt.Add(loginDelay(failure.FailedAttempts)); next.After(now) {
+		return &LoginThrottledError{RetryAfter: next.Sub(now)}
+	}
+	return nil
+}
+
+// loginLockout returns when the lockout started by the attempts-th failure
+// at now ends, or nil when that failure does not lock the address
+func loginLockout(attempts int, now time.Time) *time.Time {
+	if attempts == 0 || attempts%loginLockoutAfter != 0 {
+		return nil
+	}
+	until := now.Add(loginLockoutDuration)
+	return &until
+}
+
+// recordLoginFailure counts a failed attempt. When it locks the address,
+// the owner of the account, if there is one, is told.
+func (s *authService) recordLoginFailure(ctx context.Context, email string, user *models.User) {
+	now := time.Now()
+	var failures []models.LoginFailure
+	err := s.db.WithContext(ctx).Raw(`
+		INSERT INTO login_failures (email, failed_attempts, last_failed_at)
+		VALUES (?, 1, ?)
+		ON CONFLICT (email) DO UPDATE SET
+			failed_attempts = CASE WHEN login_failures.last_failed_at > ? THEN login_failures.failed_attempts + 1 ELSE 1 END,
+			last_failed_at = EXCLUDED.last_failed_at,
+			locked_until = CASE WHEN login_failures.last_failed_at > ? THEN login_failures.locked_until END
+		RETURNING *`,
+		normalizeEmail(email), now, now.Add(-loginFailureWindow), now.Add(-loginFailureWindow),
+	).Scan(&failures).Error
+	if err != nil || len(failures) == 0 {
+		log.Printf("Warning: failed to record a failed login: %v", err)
+		return
+	}
+
+	lockedUntil := loginLockout(failures[0].FailedAttempts, now)
+	if lockedUntil == nil {
+		return
+	}
+	err = s.db.WithContext(ctx).Model(&models.LoginFailure{}).
+		Where("email = ?", failures[0].Email).
+		Update("locked_until", *lockedUntil).Error
+	if err != nil {
+		log.Printf("Warning: failed to lock an address after failed logins: %v", err)
+		return
+	}
+	if user != nil {
+		s.sendAccountNotice(ctx, user, user.Email, "account_locked", "Sign-in to your account was paused", "")
+	}
+}
+
+// clearLoginFailures forgets the failed attempts for an address after a
+// successful login or password reset
+func clearLoginFailures(db *gorm.DB, email string) error {
+	return db.Where("email = ?", normalizeEmail(email)).Delete(&models.LoginFailure{}).Error
+}
+
+func (s *authService) RegisterJobs(ctx context.Context, scheduler *jobs.Scheduler) error {
+	scheduler.Register(JobLoginFailurePurge, func(ctx context.Context, job models.ScheduledJob) error {
+		// Rows are created for any address submitted, so they must not pile up
+		err := s.db.WithContext(ctx).Where("last_failed_at <= ?", time.Now().Add(-loginFailureWindow)).
+			Delete(&models.LoginFailure{}).Error
+		if err != nil {
+			return err
+		}
+		return scheduleLoginFailurePurge(ctx, scheduler, job.RunAt.Add(loginFailurePurgeInterval))
+	})
+	// Runs at once unless this interval's purge exists already, e.g. from
+	// another instance or the previous run
+	return scheduleLoginFailurePurge(ctx, scheduler, time.Now())
+}
+
+// scheduleLoginFailurePurge enqueues the purge for the interval containing
+// at. The key lets only one purge per interval exist.
+func scheduleLoginFailurePurge(ctx context.Context, scheduler *jobs.Scheduler, at time.Time) error {
+	runAt := at.Truncate(loginFailurePurgeInterval)
+	return scheduler.Enqueue(ctx, jobs.Job{
+		Kind:  JobLoginFailurePurge,
+		Key:   fmt.Sprintf("%s:%d", JobLoginFailurePurge, runAt.Unix()),
+		RunAt: runAt,
+	})
+}
+
+// loginDelay is how long to wait after the failures-th failed attempt
+func loginDelay(failures int) time.Duration {
+	if failures < loginDelayAfter {
+		return 0
+	}
+	shift := failures - loginDelayAfter
+	if shift > 5 {
+		return maxLoginDelay
+	}
+	if delay := time.Second << shift; delay < maxLoginDelay {
+		return delay
+	}
+	return maxLoginDelay
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/repository"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{7, 16 * time.Second},
+		{8, maxLoginDelay},
+		{9, maxLoginDelay},
+		{100, maxLoginDelay},
+	}
+	for _, tt := range tests {
+		if got := loginDelay(tt.failures); got != tt.want {
+			t.Errorf("loginDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name    string
+		failure models.LoginFailure
+		retry   time.Duration // zero when the attempt may go ahead
+		locked  bool
+	}{
+		{name: "two failures", failure: models.LoginFailure{FailedAttempts: 2, LastFailedAt: now}},
+		{name: "third failure starts the delay", failure: models.LoginFailure{FailedAttempts: 3, LastFailedAt: now.Add(-400 * time.Millisecond)}, retry: 600 * time.Millisecond},
+		{name: "delay has passed", failure: models.LoginFailure{FailedAttempts: 3, LastFailedAt: now.Add(-time.Second)}},
+		{name: "delay doubles", failure: models.LoginFailure{FailedAttempts: 5, LastFailedAt: now.Add(-time.Second)}, retry: 3 * time.Second},
+		{name: "delay is capped", failure: models.LoginFailure{FailedAttempts: 9, LastFailedAt: now}, retry: maxLoginDelay},
+		{
+			name:    "locked",
+			failure: models.LoginFailure{FailedAttempts: 10, LastFailedAt: now.Add(-time.Minute), LockedUntil: at(14 * time.Minute)},
+			retry:   14 * time.Minute,
+			locked:  true,
+		},
+		{
+			name:    "lockout over, delay still applies",
+			failure: models.LoginFailure{FailedAttempts: 10, LastFailedAt: now.Add(-15 * time.Second), LockedUntil: at(-time.Second)},
+			retry:   15 * time.Second,
+		},
+		{
+			name:    "failures older than the window are forgotten",
+			failure: models.LoginFailure{FailedAttempts: 10, LastFailedAt: now.Add(-loginFailureWindow), LockedUntil: at(time.Hour)},
+		},
+		{
+			name:    "failures just inside the window count",
+			failure: models.LoginFailure{FailedAttempts: 10, LastFailedAt: now.Add(-loginFailureWindow + time.Second), LockedUntil: at(time.Hour)},
+			retry:   time.Hour,
+			locked:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := loginThrottle(tt.failure, now)
+			if tt.retry == 0 {
+				if got != nil {
+					t.Fatalf("attempt refused: %+v", got)
+				}
+				return
+			}
+			if got == nil || got.RetryAfter != tt.retry || got.Locked != tt.locked {
+				t.Fatalf("loginThrottle = %+v, want retry after %v, locked %v", got, tt.retry, tt.locked)
+			}
+		})
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	for attempts := 0; attempts <= 31; attempts++ {
+		got := loginLockout(attempts, now)
+		locks := attempts == 10 || attempts == 20 || attempts == 30
+		if (got != nil) != locks {
+			t.Errorf("loginLockout(%d) = %v, want lock %v", attempts, got, locks)
+		}
+		if got != nil && !got.Equal(now.Add(15*time.Minute)) {
+			t.Errorf("loginLockout(%d) locks until %v, want 15 minutes", attempts, got)
+		}
+	}
+}
+
+func TestResetPasswordClearsLoginFailures(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	s := &authService{db: db, cfg: config.Config{Auth: config.AuthConfig{BcryptCost: bcrypt.MinCost}}}
+
+	email := "Reset-" + uuid.NewString() + "@Example.com"
+	user := &models.User{Name: "Reset", Email: email, PasswordHash: "x"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Where("user_id = ?", user.UserID).Delete(&models.UserToken{})
+		db.Where("email = ?", strings.ToLower(email)).Delete(&models.LoginFailure{})
+		db.Unscoped().Delete(user)
+	})
+
+	lockedUntil := time.Now().Add(loginLockoutDuration)
+	failure := models.LoginFailure{Email: strings.ToLower(email), FailedAttempts: 10, LastFailedAt: time.Now(), LockedUntil: &lockedUntil}
+	if err := db.Create(&failure).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.checkLoginThrottle(ctx, email); err == nil {
+		t.Fatal("locked address was not throttled")
+	}
+
+	token, err := issueUserToken(db, user.UserID, models.TokenPasswordReset, &user.Email, passwordResetTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ResetPassword(ctx, &ResetPasswordRequest{Token: token, Password: "a new long password"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int64
+	db.Model(&models.LoginFailure{}).Where("email = ?", strings.ToLower(email)).Count(&count)
+	if count != 0 {
+		t.Error("login failures kept after a password reset")
+	}
+	if err := s.checkLoginThrottle(ctx, email); err != nil {
+		t.Errorf("login still throttled after a password reset: %v", err)
+	}
+}
+
+func TestMFAChangesAreThrottled(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	s := &authService{db: db, userRepo: repository.NewUserRepository(db), notifications: &NotificationService{}}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := "JBSWY3DPEHPK3PXP"
+	enabledAt := time.Now()
+	email := "mfa-" + uuid.NewString() + "@example.com"
+	user := &models.User{Name: "MFA", Email: email, PasswordHash: string(hash), MFASecret: &secret, MFAEnabledAt: &enabledAt}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Where("email = ?", email).Delete(&models.LoginFailure{})
+		db.Unscoped().Delete(user)
+	})
+
+	// Wrong passwords and wrong codes on either endpoint share one count
+	attempts := []func() error{
+		func() error {
+			_, err := s.DisableMFA(ctx, user.UserID, &DisableMFARequest{Password: "wrong password", Code: "000000"})
+			return err
+		},
+		func() error {
+			_, err := s.DisableMFA(ctx, user.UserID, &DisableMFARequest{Password: "correct password", Code: "000000"})
+			return err
+		},
+		func() error {
+			_, err := s.RegenerateRecoveryCodes(ctx, user.UserID, &MFACodeRequest{Code: "000000"})
+			return err
+		},
+	}
+	for i, attempt := range attempts {
+		err := attempt()
+		var throttled *LoginThrottledError
+		if err == nil || errors.As(err, &throttled) {
+			t.Fatalf("attempt %d: error = %v, want a wrong password or code", i+1, err)
+		}
+	}
+
+	var throttled *LoginThrottledError
+	if _, err := s.RegenerateRecoveryCodes(ctx, user.UserID, &MFACodeRequest{Code: "000000"}); !errors.As(err, &throttled) {
+		t.Errorf("fourth attempt: error = %v, want it throttled", err)
+	}
+	if _, err := s.DisableMFA(ctx, user.UserID, &DisableMFARequest{Password: "correct password", Code: "000000"}); !errors.As(err, &throttled) {
+		t.Errorf("fourth attempt: error = %v, want it throttled", err)
+	}
+}
@@ -0,0 +1,421 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/authtoken"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/totp"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	mfaIssuer          = "Skill Swap" // Shown in authenticator apps
+	mfaChallengeTTL    = 5 * time.Minute
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10 // Base32 characters, 50 bits
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// errInvalidMFACode is returned for wrong, reused and unusable codes alike
+var errInvalidMFACode = errors.New("invalid authentication code")
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+type SetupMFARequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+// MFAChallenge is returned by Login instead of tokens when the account uses
+// two-factor authentication
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+	Required               bool       `json:"required"` // Staff must use two-factor authentication
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// MFASetup holds a new TOTP secret. ProvisioningURI is meant to be shown as
+// a QR code; Secret is for typing in by hand.
+type MFASetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type EnableMFAResponse struct {
+	AuthResponse
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (s *authService) VerifyMFA(ctx context.Context, req *VerifyMFARequest) (*AuthResponse, error) {
+	token, err := jwt.ParseWithClaims(req.MFAToken, &authtoken.Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return mfaChallengeKey(s.cfg.Auth.JWTSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+	claims, ok := token.Claims.(*authtoken.Claims)
+	if !ok || claims.TokenType != "mfa_challenge" {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil || claims.TokenVersion != user.TokenVersion || user.MFAEnabledAt == nil {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	if err := s.checkLoginThrottle(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	if err := s.checkSecondFactor(ctx, user, req.Code); err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			s.recordLoginFailure(ctx, user.Email, user)
+		}
+		return nil, err
+	}
+	if err := clearLoginFailures(s.db.WithContext(ctx), user.Email); err != nil {
+		return nil, err
+	}
+	return s.generateAuthResponse(ctx, user)
+}
+
+func (s *authService) GetMFAStatus(ctx context.Context, userID uuid.UUID) (*MFAStatus, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := userRoles(s.db.WithContext(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MFAStatus{
+		Enabled:   user.MFAEnabledAt != nil,
+		EnabledAt: user.MFAEnabledAt,
+		Required:  len(roles) > 0,
+	}
+	if status.Enabled {
+		err := s.db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Count(&status.RecoveryCodesRemaining).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+func (s *authService) SetupMFA(ctx context.Context, userID uuid.UUID, req *SetupMFARequest) (*MFASetup, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, errors.New("password is incorrect")
+	}
+	if user.MFAEnabledAt != nil {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	// Replaces the secret of an unfinished enrollment
+	err = s.db.WithContext(ctx).Model(&models.User{}).
+		Where("user_id = ? AND mfa_enabled_at IS NULL", userID).
+		Update("mfa_secret", secret).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFASetup{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+func (s *authService) EnableMFA(ctx context.Context, userID uuid.UUID, req *MFACodeRequest) (*EnableMFAResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabledAt != nil {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.MFASecret == nil {
+		return nil, errors.New("two-factor setup has not been started")
+	}
+
+	step, ok := totp.Validate(*user.MFASecret, req.Code, time.Now())
+	if !ok {
+		return nil, errInvalidMFACode
+	}
+
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("user_id = ? AND mfa_enabled_at IS NULL AND mfa_secret = ?", userID, *user.MFASecret).
+			Updates(map[string]interface{}{
+				"mfa_enabled_at": time.Now(),
+				"mfa_last_step":  step,
+				// Sessions from before did not pass two-factor authentication
+				"token_version": gorm.Expr("token_version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Enabled or set up again meanwhile
+			return errInvalidMFACode
+		}
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.sendAccountNotice(ctx, user, user.Email, "mfa_enabled", "Two-factor authentication was turned on", "")
+
+	user, err = s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.generateAuthResponse(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &EnableMFAResponse{AuthResponse: *response, RecoveryCodes: codes}, nil
+}
+
+func (s *authService) DisableMFA(ctx context.Context, userID uuid.UUID, req *DisableMFARequest) (*AuthResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabledAt == nil {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	roles, err := userRoles(s.db.WithContext(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) > 0 {
+		return nil, errors.New("two-factor authentication is required for staff")
+	}
+	// Guesses here count toward the same lockout as logins
+	if err := s.checkLoginThrottle(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		s.recordLoginFailure(ctx, user.Email, user)
+		return nil, errors.New("password is incorrect")
+	}
+	if err := s.checkSecondFactor(ctx, user, req.Code); err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			s.recordLoginFailure(ctx, user.Email, user)
+		}
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"mfa_secret":     nil,
+			"mfa_enabled_at": nil,
+			"mfa_last_step":  0,
+			"token_version":  gorm.Expr("token_version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.sendAccountNotice(ctx, user, user.Email, "mfa_disabled", "Two-factor authentication was turned off", "")
+
+	user, err = s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.generateAuthResponse(ctx, user)
+}
+
+func (s *authService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, req *MFACodeRequest) (*RecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabledAt == nil {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	if err := s.checkLoginThrottle(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	if err := s.checkSecondFactor(ctx, user, req.Code); err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			s.recordLoginFailure(ctx, user.Email, user)
+		}
+		return nil, err
+	}
+
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// issueMFAChallenge returns a short-lived token that proves the password was
+// checked. It is signed with its own key, so it never passes as an access
+// token.
+func (s *authService) issueMFAChallenge(user *models.User) (*MFAChallenge, error) {
+	claims := authtoken.Claims{
+		UserID:       user.UserID,
+		TokenType:    "mfa_challenge",
+		TokenVersion: user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(mfaChallengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   user.UserID.String(),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(mfaChallengeKey(s.cfg.Auth.JWTSecret))
+	if err != nil {
+		return nil, errors.New("failed to generate mfa token")
+	}
+	return &MFAChallenge{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int64(mfaChallengeTTL.Seconds()),
+	}, nil
+}
+
+// checkSecondFactor accepts a current TOTP code or an unused recovery code.
+// Either can only be used once.
+func (s *authService) checkSecondFactor(ctx context.Context, user *models.User, code string) error {
+	if user.MFASecret == nil {
+		return errInvalidMFACode
+	}
+
+	if step, ok := totp.Validate(*user.MFASecret, code, time.Now()); ok {
+		result := s.db.WithContext(ctx).Model(&models.User{}).
+			Where("user_id = ? AND mfa_last_step < ?", user.UserID, step).
+			Update("mfa_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidMFACode
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return errInvalidMFACode
+	}
+	result := s.db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("code_hash = ? AND user_id = ? AND used_at IS NULL", hashSecretToken(normalized), user.UserID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errInvalidMFACode
+	}
+
+	s.sendAccountNotice(ctx, user, user.Email, "recovery_code_used", "A recovery code was used", "")
+	return nil
+}
+
+// newRecoveryCodes returns codes formatted for display, e.g. "abcde-fghij"
+func newRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	raw := make([]byte, 7) // 56 bits, trimmed to 50
+	for i := range codes {
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))[:recoveryCodeLength]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// replaceRecoveryCodes stores the hashes of codes in place of the user's
+// previous ones
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID, codes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+	rows := make([]models.MFARecoveryCode, len(codes))
+	for i, code := range codes {
+		rows[i] = models.MFARecoveryCode{
+			CodeHash: hashSecretToken(normalizeRecoveryCode(code)),
+			UserID:   userID,
+		}
+	}
+	return tx.Create(&rows).Error
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func mfaChallengeKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("skillswap mfa challenge"))
+	return mac.Sum(nil)
+}
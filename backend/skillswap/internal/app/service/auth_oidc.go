@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/oidc"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+)
+
+const (
+	oidcLoginTTL    = 10 * time.Minute // Time to sign in at the provider
+	oidcExchangeTTL = time.Minute      // Time for the app to exchange the code
+)
+
+type OIDCExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// OIDCLogin is a started provider sign-in. State must be stored in the
+// browser and handed back with the callback, which binds the sign-in to it.
+type OIDCLogin struct {
+	AuthURL string
+	State   string
+}
+
+// OIDCCallback holds the query parameters the provider redirects back with
+type OIDCCallback struct {
+	State        string
+	Code         string
+	Error        string
+	BrowserState string // State stored in the browser by BeginOIDCLogin
+}
+
+func newOIDCProviders(cfg config.Config) map[string]*oidc.Provider {
+	providers := make(map[string]*oidc.Provider)
+	for _, p := range cfg.OIDC.Providers {
+		providers[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  cfg.Server.BaseURL + "/api/v1/auth/oidc/" + p.Name + "/callback",
+			Scopes:       p.Scopes,
+		}, nil)
+	}
+	return providers
+}
+
+func (s *authService) OIDCProviders() []string {
+	names := make([]string, 0, len(s.oidc))
+	for name := range s.oidc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *authService) BeginOIDCLogin(ctx context.Context, provider string) (*OIDCLogin, error) {
+	p, ok := s.oidc[provider]
+	if !ok {
+		return nil, errors.New("unknown identity provider")
+	}
+
+	state, err := oidc.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.NewPKCEVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("Warning: OIDC provider %s is unavailable: %v", provider, err)
+		return nil, errors.New("identity provider is unavailable")
+	}
+
+	err = s.identities.SaveState(ctx, &models.OIDCLoginState{
+		StateHash:    hashSecretToken(state),
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCLogin{AuthURL: authURL, State: state}, nil
+}
+
+// CompleteOIDCLogin returns where to send the browser: the app's callback
+// page with a one-time code to exchange for tokens, or with an error
+func (s *authService) CompleteOIDCLogin(ctx context.Context, provider string, callback *OIDCCallback) string {
+	code, err := s.completeOIDCLogin(ctx, provider, callback)
+	if err != nil {
+		return s.cfg.Server.AppURL + "/oauth/callback?error=" + url.QueryEscape(err.Error())
+	}
+	return s.cfg.Server.AppURL + "/oauth/callback?code=" + url.QueryEscape(code)
+}
+
+func (s *authService) completeOIDCLogin(ctx context.Context, provider string, callback *OIDCCallback) (string, error) {
+	p, ok := s.oidc[provider]
+	if !ok {
+		return "", errors.New("unknown identity provider")
+	}
+	if callback.Error != "" {
+		// e.g. access_denied when the user cancels
+		return "", errors.New("sign-in was cancelled or refused by the identity provider")
+	}
+	if callback.State == "" || subtle.ConstantTimeCompare([]byte(callback.State), []byte(callback.BrowserState)) != 1 {
+		return "", errors.New("sign-in session is invalid or has expired")
+	}
+
+	// Each state works once
+	state, err := s.identities.TakeState(ctx, provider, hashSecretToken(callback.State))
+	if err != nil {
+		return "", err
+	}
+	if state == nil {
+		return "", errors.New("sign-in session is invalid or has expired")
+	}
+
+	claims, err := p.Exchange(ctx, callback.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		log.Printf("Warning: OIDC sign-in with %s failed: %v", provider, err)
+		return "", errors.New("sign-in with the identity provider failed")
+	}
+
+	user, err := s.linkOIDCIdentity(ctx, provider, claims)
+	if err != nil {
+		return "", err
+	}
+	return s.identities.IssueLoginCode(ctx, user.UserID)
+}
+
+func (s *authService) ExchangeOIDCLogin(ctx context.Context, code string) (*AuthResponse, *MFAChallenge, error) {
+	userID, err := s.identities.ConsumeLoginCode(ctx, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, errors.New("invalid or expired token")
+	}
+
+	// The provider replaces the password, not the second factor
+	if user.MFAEnabledAt != nil {
+		challenge, err := s.issueMFAChallenge(user)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, challenge, nil
+	}
+
+	response, err := s.generateAuthResponse(ctx, user)
+	return response, nil, err
+}
+
+// linkOIDCIdentity returns the user a provider account belongs to. Accounts
+// seen before are found by their subject; new ones are linked to the user
+// with the same verified email, or get a new user.
+func (s *authService) linkOIDCIdentity(ctx context.Context, provider string, claims *oidc.Claims) (*models.User, error) {
+	user, err := s.identities.LinkedUser(ctx, provider, claims)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	if claims.Email == "" || !bool(claims.EmailVerified) {
+		return nil, errors.New("the identity provider has not verified your email address")
+	}
+
+	identity := &models.UserIdentity{
+		Provider:    provider,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: time.Now(),
+	}
+	existing, err := s.identities.UserByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		// Anyone could have registered an unverified account, so the
+		// password they chose must not keep working
+		identity.UserID = existing.UserID
+		return s.identities.Link(ctx, identity, existing.EmailVerifiedAt == nil)
+	}
+
+	now := time.Now()
+	user = &models.User{
+		Name:            oidcDisplayName(claims),
+		Email:           claims.Email,
+		PasswordHash:    "", // Sign in through the provider, or set one with a reset link
+		IsPublic:        true,
+		EmailVerifiedAt: &now,
+	}
+	if claims.Picture != "" {
+		user.PhotoURL = &claims.Picture
+	}
+	if err := s.identities.CreateLinked(ctx, user, identity); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(event.UserChanged, user.UserID)
+	return user, nil
+}
+
+// oidcDisplayName picks a name for a new user, falling back to the local
+// part of the email address
+func oidcDisplayName(claims *oidc.Claims) string {
+	if name := strings.TrimSpace(claims.Name); len(name) >= 2 {
+		return name
+	}
+	return strings.SplitN(claims.Email, "@", 2)[0]
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/oidc"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// oidcStore keeps provider sign-ins in progress and the provider accounts
+// linked to users
+type oidcStore interface {
+	// SaveState stores a started sign-in and drops expired ones
+	SaveState(ctx context.Context, state *models.OIDCLoginState) error
+	// TakeState removes and returns the unexpired sign-in for stateHash, so
+	// each state works once. It returns nil when there is none.
+	TakeState(ctx context.Context, provider, stateHash string) (*models.OIDCLoginState, error)
+
+	// LinkedUser returns the user a provider account is linked to and
+	// records the sign-in. It returns nil for an account not seen before.
+	LinkedUser(ctx context.Context, provider string, claims *oidc.Claims) (*models.User, error)
+	// UserByEmail returns the user with email in any case, or nil
+	UserByEmail(ctx context.Context, email string) (*models.User, error)
+	// Link links a provider account to an existing user. With claim the
+	// account is taken over: its password is cleared, its email marked
+	// verified and its sessions ended.
+	Link(ctx context.Context, identity *models.UserIdentity, claim bool) (*models.User, error)
+	// CreateLinked creates a user together with its provider account
+	CreateLinked(ctx context.Context, user *models.User, identity *models.UserIdentity) error
+
+	// IssueLoginCode returns a one-time code the app exchanges for tokens
+	IssueLoginCode(ctx context.Context, userID uuid.UUID) (string, error)
+	// ConsumeLoginCode redeems a login code and returns its user
+	ConsumeLoginCode(ctx context.Context, code string) (uuid.UUID, error)
+}
+
+type oidcDBStore struct {
+	db *gorm.DB
+}
+
+func (s *oidcDBStore) SaveState(ctx context.Context, state *models.OIDCLoginState) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLoginState{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(state).Error
+	})
+}
+
+func (s *oidcDBStore) TakeState(ctx context.Context, provider, stateHash string) (*models.OIDCLoginState, error) {
+	var states []models.OIDCLoginState
+	err := s.db.WithContext(ctx).Raw(`
+		DELETE FROM oidc_login_states
+		WHERE state_hash = ? AND provider = ? AND expires_at > ?
+		RETURNING *`,
+		stateHash, provider, time.Now(),
+	).Scan(&states).Error
+	if err != nil || len(states) == 0 {
+		return nil, err
+	}
+	return &states[0], nil
+}
+
+func (s *oidcDBStore) LinkedUser(ctx context.Context, provider string, claims *oidc.Claims) (*models.User, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		if err := tx.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", identity.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		return tx.Model(&identity).Updates(map[string]interface{}{
+			"email":         claims.Email,
+			"last_login_at": time.Now(),
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *oidcDBStore) UserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *oidcDBStore) Link(ctx context.Context, identity *models.UserIdentity, claim bool) (*models.User, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if claim {
+			err := tx.Model(&models.User{}).Where("user_id = ?", identity.UserID).Updates(map[string]interface{}{
+				"password_hash":     "",
+				"email_verified_at": time.Now(),
+				"token_version":     gorm.Expr("token_version + 1"),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(identity).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", identity.UserID).First(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *oidcDBStore) CreateLinked(ctx context.Context, user *models.User, identity *models.UserIdentity) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.UserID
+		return tx.Create(identity).Error
+	})
+}
+
+func (s *oidcDBStore) IssueLoginCode(ctx context.Context, userID uuid.UUID) (string, error) {
+	return issueUserToken(s.db.WithContext(ctx), userID, models.TokenOIDCLogin, nil, oidcExchangeTTL)
+}
+
+func (s *oidcDBStore) ConsumeLoginCode(ctx context.Context, code string) (uuid.UUID, error) {
+	used, err := consumeUserToken(s.db.WithContext(ctx), code, models.TokenOIDCLogin)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return used.UserID, nil
+}
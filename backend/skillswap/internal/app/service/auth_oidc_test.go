@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/authtoken"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/oidc"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/oidc/oidctest"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/repository"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// fakeOIDCStore keeps sign-ins, users and identities in memory
+type fakeOIDCStore struct {
+	mu         sync.Mutex
+	states     map[string]models.OIDCLoginState // By state hash
+	users      map[uuid.UUID]*models.User
+	identities map[string]models.UserIdentity // By provider and subject
+	codes      map[string]uuid.UUID
+}
+
+func newFakeOIDCStore() *fakeOIDCStore {
+	return &fakeOIDCStore{
+		states:     make(map[string]models.OIDCLoginState),
+		users:      make(map[uuid.UUID]*models.User),
+		identities: make(map[string]models.UserIdentity),
+		codes:      make(map[string]uuid.UUID),
+	}
+}
+
+func (f *fakeOIDCStore) SaveState(_ context.Context, state *models.OIDCLoginState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.states[state.StateHash] = *state
+	return nil
+}
+
+func (f *fakeOIDCStore) TakeState(_ context.Context, provider, stateHash string) (*models.OIDCLoginState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	state, ok := f.states[stateHash]
+	if !ok || state.Provider != provider || !state.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	delete(f.states, stateHash)
+	return &state, nil
+}
+
+func (f *fakeOIDCStore) LinkedUser(_ context.Context, provider string, claims *oidc.Claims) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	identity, ok := f.identities[provider+"/"+claims.Subject]
+	if !ok {
+		return nil, nil
+	}
+	identity.Email = claims.Email
+	f.identities[provider+"/"+claims.Subject] = identity
+	return f.copyUser(identity.UserID), nil
+}
+
+func (f *fakeOIDCStore) UserByEmail(_ context.Context, email string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, user := range f.users {
+		if strings.EqualFold(user.Email, email) {
+			return f.copyUser(id), nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeOIDCStore) Link(_ context.Context, identity *models.UserIdentity, claim bool) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user := f.users[identity.UserID]
+	if claim {
+		now := time.Now()
+		user.PasswordHash = ""
+		user.EmailVerifiedAt = &now
+		user.TokenVersion++
+	}
+	f.identities[identity.Provider+"/"+identity.Subject] = *identity
+	return f.copyUser(user.UserID), nil
+}
+
+func (f *fakeOIDCStore) CreateLinked(_ context.Context, user *models.User, identity *models.UserIdentity) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user.UserID = uuid.New()
+	stored := *user
+	f.users[user.UserID] = &stored
+	identity.UserID = user.UserID
+	f.identities[identity.Provider+"/"+identity.Subject] = *identity
+	return nil
+}
+
+func (f *fakeOIDCStore) IssueLoginCode(_ context.Context, userID uuid.UUID) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	code := uuid.NewString()
+	f.codes[code] = userID
+	return code, nil
+}
+
+func (f *fakeOIDCStore) ConsumeLoginCode(_ context.Context, code string) (uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	userID, ok := f.codes[code]
+	if !ok {
+		return uuid.Nil, errors.New("invalid or expired token")
+	}
+	delete(f.codes, code)
+	return userID, nil
+}
+
+func (f *fakeOIDCStore) addUser(user models.User) *models.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user.UserID = uuid.New()
+	f.users[user.UserID] = &user
+	return f.copyUser(user.UserID)
+}
+
+func (f *fakeOIDCStore) user(id uuid.UUID) *models.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.copyUser(id)
+}
+
+func (f *fakeOIDCStore) copyUser(id uuid.UUID) *models.User {
+	user, ok := f.users[id]
+	if !ok {
+		return nil
+	}
+	copied := *user
+	return &copied
+}
+
+// fakeUserRepository serves users from a fakeOIDCStore. Methods the tests
+// do not use are left to the nil embedded interface.
+type fakeUserRepository struct {
+	repository.UserRepository
+	store *fakeOIDCStore
+}
+
+func (r fakeUserRepository) GetByID(_ context.Context, id uuid.UUID) (*models.User, error) {
+	if user := r.store.user(id); user != nil {
+		return user, nil
+	}
+	return nil, errors.New("user not found")
+}
+
+type oidcTest struct {
+	s        *authService
+	provider *oidctest.Provider
+	store    *fakeOIDCStore
+	created  []uuid.UUID // Users announced with event.UserChanged
+}
+
+func newOIDCTest(t *testing.T) *oidcTest {
+	provider := oidctest.NewProvider(t, "skillswap")
+	store := newFakeOIDCStore()
+	events := event.NewBus()
+	tt := &oidcTest{provider: provider, store: store}
+	events.Subscribe(func(e event.Event) { tt.created = append(tt.created, e.EntityID) }, event.UserChanged)
+
+	tt.s = &authService{
+		userRepo:   fakeUserRepository{store: store},
+		cfg:        config.Config{Auth: config.AuthConfig{JWTSecret: "test secret"}},
+		events:     events,
+		identities: store,
+		oidc: map[string]*oidc.Provider{"test": oidc.NewProvider(oidc.Config{
+			Name:        "test",
+			Issuer:      provider.Issuer(),
+			ClientID:    "skillswap",
+			RedirectURL: "https://app.example/callback",
+			Scopes:      []string{"openid", "email"},
+		}, provider.Client())},
+	}
+	return tt
+}
+
+// signIn starts a sign-in and has the provider answer it with claims,
+// returning the callback the browser would arrive with
+func (tt *oidcTest) signIn(t *testing.T, claims jwt.MapClaims) *OIDCCallback {
+	t.Helper()
+	login, err := tt.s.BeginOIDCLogin(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := uuid.NewString()
+	tt.provider.Authorize(t, login.AuthURL, code, claims)
+	return &OIDCCallback{State: login.State, Code: code, BrowserState: login.State}
+}
+
+// complete finishes a sign-in and returns the user the login code is for
+func (tt *oidcTest) complete(t *testing.T, callback *OIDCCallback) (*models.User, error) {
+	t.Helper()
+	code, err := tt.s.completeOIDCLogin(context.Background(), "test", callback)
+	if err != nil {
+		return nil, err
+	}
+	tt.store.mu.Lock()
+	userID := tt.store.codes[code]
+	tt.store.mu.Unlock()
+	return tt.store.user(userID), nil
+}
+
+func TestOIDCStateWorksOnce(t *testing.T) {
+	tt := newOIDCTest(t)
+	callback := tt.signIn(t, tt.provider.Claims("subject-1", "ada@example.com"))
+
+	if _, err := tt.complete(t, callback); err != nil {
+		t.Fatalf("completeOIDCLogin: %v", err)
+	}
+	if _, err := tt.complete(t, callback); err == nil || !strings.Contains(err.Error(), "invalid or has expired") {
+		t.Fatalf("replayed callback: error = %v", err)
+	}
+
+	// States expire
+	callback = tt.signIn(t, tt.provider.Claims("subject-1", "ada@example.com"))
+	tt.store.mu.Lock()
+	for hash, state := range tt.store.states {
+		state.ExpiresAt = time.Now().Add(-time.Second)
+		tt.store.states[hash] = state
+	}
+	tt.store.mu.Unlock()
+	if _, err := tt.complete(t, callback); err == nil || !strings.Contains(err.Error(), "invalid or has expired") {
+		t.Fatalf("expired state: error = %v", err)
+	}
+
+	// States are stored hashed
+	login, err := tt.s.BeginOIDCLogin(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tt.store.states[login.State]; ok {
+		t.Error("state stored in plain text")
+	}
+}
+
+func TestOIDCStateMustMatchBrowser(t *testing.T) {
+	tt := newOIDCTest(t)
+	callback := tt.signIn(t, tt.provider.Claims("subject-1", "ada@example.com"))
+
+	for name, browserState := range map[string]string{"other browser": "other", "no browser state": ""} {
+		mismatched := *callback
+		mismatched.BrowserState = browserState
+		if _, err := tt.complete(t, &mismatched); err == nil {
+			t.Errorf("%s: sign-in completed", name)
+		}
+	}
+	if _, err := tt.complete(t, &OIDCCallback{Code: callback.Code}); err == nil {
+		t.Error("sign-in completed without a state")
+	}
+
+	// A mismatch does not use up the state of the browser that started it
+	if _, err := tt.complete(t, callback); err != nil {
+		t.Fatalf("completeOIDCLogin from the right browser: %v", err)
+	}
+}
+
+func TestOIDCLinking(t *testing.T) {
+	t.Run("new account", func(t *testing.T) {
+		tt := newOIDCTest(t)
+		claims := tt.provider.Claims("subject-1", "ada@example.com")
+		claims["name"] = "Ada Lovelace"
+
+		user, err := tt.complete(t, tt.signIn(t, claims))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Name != "Ada Lovelace" || user.PasswordHash != "" || user.EmailVerifiedAt == nil {
+			t.Errorf("created user %+v", user)
+		}
+		if len(tt.created) != 1 || tt.created[0] != user.UserID {
+			t.Errorf("UserChanged published for %v, want the new user", tt.created)
+		}
+
+		// Later sign-ins find the account by its subject, whatever its email
+		claims = tt.provider.Claims("subject-1", "ada@new.example.com")
+		claims["email_verified"] = false
+		again, err := tt.complete(t, tt.signIn(t, claims))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.UserID != user.UserID || len(tt.created) != 1 {
+			t.Errorf("second sign-in gave user %s, want %s", again.UserID, user.UserID)
+		}
+		if got := tt.store.identities["test/subject-1"].Email; got != "ada@new.example.com" {
+			t.Errorf("identity email = %q, want the latest", got)
+		}
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		tt := newOIDCTest(t)
+		existing := tt.store.addUser(models.User{Name: "Ada", Email: "ada@example.com", PasswordHash: "hash"})
+		claims := tt.provider.Claims("subject-1", "ada@example.com")
+		claims["email_verified"] = false
+
+		if _, err := tt.complete(t, tt.signIn(t, claims)); err == nil || !strings.Contains(err.Error(), "not verified") {
+			t.Fatalf("error = %v, want an unverified email error", err)
+		}
+		if len(tt.store.users) != 1 || len(tt.store.identities) != 0 {
+			t.Error("unverified provider email created or linked an account")
+		}
+		if tt.store.user(existing.UserID).PasswordHash != "hash" {
+			t.Error("existing account changed")
+		}
+	})
+
+	t.Run("existing verified account", func(t *testing.T) {
+		tt := newOIDCTest(t)
+		verifiedAt := time.Now().Add(-time.Hour)
+		existing := tt.store.addUser(models.User{Name: "Ada", Email: "Ada@Example.com", PasswordHash: "hash", EmailVerifiedAt: &verifiedAt, TokenVersion: 2})
+
+		user, err := tt.complete(t, tt.signIn(t, tt.provider.Claims("subject-1", "ada@example.com")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.UserID != existing.UserID || user.PasswordHash != "hash" || user.TokenVersion != 2 {
+			t.Errorf("linked user %+v, want the existing account unchanged", user)
+		}
+		if len(tt.created) != 0 {
+			t.Error("UserChanged published for an existing user")
+		}
+	})
+
+	t.Run("existing unverified account", func(t *testing.T) {
+		tt := newOIDCTest(t)
+		existing := tt.store.addUser(models.User{Name: "Squatter", Email: "ada@example.com", PasswordHash: "squatter's hash", TokenVersion: 2})
+
+		user, err := tt.complete(t, tt.signIn(t, tt.provider.Claims("subject-1", "ada@example.com")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.UserID != existing.UserID {
+			t.Fatalf("linked user %s, want %s", user.UserID, existing.UserID)
+		}
+		if user.PasswordHash != "" || user.EmailVerifiedAt == nil || user.TokenVersion != 3 {
+			t.Errorf("password %q, verified at %v, token version %d: want the password cleared, the email verified and sessions ended",
+				user.PasswordHash, user.EmailVerifiedAt, user.TokenVersion)
+		}
+	})
+}
+
+func TestExchangeOIDCLoginRequiresMFA(t *testing.T) {
+	tt := newOIDCTest(t)
+	verifiedAt := time.Now().Add(-time.Hour)
+	existing := tt.store.addUser(models.User{Name: "Ada", Email: "ada@example.com", EmailVerifiedAt: &verifiedAt, MFAEnabledAt: &verifiedAt, TokenVersion: 4})
+
+	code, err := tt.s.completeOIDCLogin(context.Background(), "test", tt.signIn(t, tt.provider.Claims("subject-1", "ada@example.com")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, challenge, err := tt.s.ExchangeOIDCLogin(context.Background(), code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != nil {
+		t.Fatal("tokens issued without the second factor")
+	}
+	if challenge == nil || !challenge.MFARequired {
+		t.Fatalf("challenge = %+v, want MFA required", challenge)
+	}
+
+	var claims authtoken.Claims
+	_, err = jwt.ParseWithClaims(challenge.MFAToken, &claims, func(*jwt.Token) (interface{}, error) {
+		return mfaChallengeKey(tt.s.cfg.Auth.JWTSecret), nil
+	})
+	if err != nil || claims.TokenType != "mfa_challenge" || claims.UserID != existing.UserID || claims.TokenVersion != 4 {
+		t.Errorf("challenge token %+v (%v), want one for the user", claims, err)
+	}
+
+	if _, _, err := tt.s.ExchangeOIDCLogin(context.Background(), code); err == nil {
+		t.Error("login code exchanged twice")
+	}
+}
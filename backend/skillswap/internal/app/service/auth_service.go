@@ -1,33 +1,107 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"log"
+	"net/url"
 	"time"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/authtoken"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/geo"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jobs"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/mail"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/oidc"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/repository"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type AuthService interface {
-	Register(req *RegisterRequest) (*AuthResponse, error)
-	Login(req *LoginRequest) (*AuthResponse, error)
-	RefreshToken(refreshToken string) (*AuthResponse, error)
-	ValidateToken(tokenString string) (*TokenClaims, error)
+	Register(ctx context.Context, req *RegisterRequest) (*AuthResponse, error)
+	// Login returns a challenge instead of tokens when the account uses
+	// two-factor authentication; VerifyMFA completes the login
+	Login(ctx context.Context, req *LoginRequest) (*AuthResponse, *MFAChallenge, error)
+	VerifyMFA(ctx context.Context, req *VerifyMFARequest) (*AuthResponse, error)
+
+	// OIDCProviders lists the identity providers users can sign in with
+	OIDCProviders() []string
+	// BeginOIDCLogin starts a sign-in at an identity provider
+	BeginOIDCLogin(ctx context.Context, provider string) (*OIDCLogin, error)
+	CompleteOIDCLogin(ctx context.Context, provider string, callback *OIDCCallback) string
+	// ExchangeOIDCLogin trades the one-time code from the app's callback
+	// page for tokens, or for a challenge like Login
+	ExchangeOIDCLogin(ctx context.Context, code string) (*AuthResponse, *MFAChallenge, error)
+
+	RefreshToken(ctx context.Context, refreshToken string) (*AuthResponse, error)
+
+	// VerifyEmail confirms the address a verification token was sent to
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, userID uuid.UUID) error
+	// ForgotPassword emails a reset link if an account uses email. It
+	// succeeds either way, so it cannot be used to find accounts.
+	ForgotPassword(ctx context.Context, email string) error
+	// ResetPassword sets a new password and revokes all sessions
+	ResetPassword(ctx context.Context, req *ResetPasswordRequest) error
+
+	// ChangePassword sets a new password and signs out other sessions. The
+	// returned tokens keep the current session signed in.
+	ChangePassword(ctx context.Context, userID uuid.UUID, req *ChangePasswordRequest) (*AuthResponse, error)
+	// RequestEmailChange emails a confirmation link to the new address; the
+	// login email only changes once it is confirmed
+	RequestEmailChange(ctx context.Context, userID uuid.UUID, req *ChangeEmailRequest) error
+	// ConfirmEmailChange switches to the confirmed address, signs out all
+	// sessions and tells the old address
+	ConfirmEmailChange(ctx context.Context, token string) error
+
+	GetMFAStatus(ctx context.Context, userID uuid.UUID) (*MFAStatus, error)
+	// SetupMFA starts enrollment with a new secret, which only takes effect
+	// once EnableMFA confirms a code from it
+	SetupMFA(ctx context.Context, userID uuid.UUID, req *SetupMFARequest) (*MFASetup, error)
+	// EnableMFA turns on two-factor authentication, signs out other
+	// sessions and returns the recovery codes
+	EnableMFA(ctx context.Context, userID uuid.UUID, req *MFACodeRequest) (*EnableMFAResponse, error)
+	// DisableMFA turns off two-factor authentication, which staff cannot do
+	DisableMFA(ctx context.Context, userID uuid.UUID, req *DisableMFARequest) (*AuthResponse, error)
+	// RegenerateRecoveryCodes replaces all recovery codes
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, req *MFACodeRequest) (*RecoveryCodesResponse, error)
+
+	// RegisterJobs installs the job that deletes forgotten login failures
+	// and schedules its first run
+	RegisterJobs(ctx context.Context, scheduler *jobs.Scheduler) error
 }
 
 type authService struct {
-	userRepo repository.UserRepository
-	cfg      config.Config
+	db            *gorm.DB
+	userRepo      repository.UserRepository
+	cfg           config.Config
+	geocoder      geo.Geocoder
+	events        *event.Bus
+	notifications *NotificationService // Sends verification and reset emails
+	oidc          map[string]*oidc.Provider
+	identities    oidcStore // Provider sign-ins and linked accounts
+	tokens        *authtoken.Verifier
 }
 
-func NewAuthService(userRepo repository.UserRepository, cfg config.Config) AuthService {
+func NewAuthService(db *gorm.DB, userRepo repository.UserRepository, cfg config.Config, geocoder geo.Geocoder, events *event.Bus, notifications *NotificationService) AuthService {
+	if !notifications.EmailEnabled() {
+		log.Println("Warning: email is off, so new accounts are verified without confirming their address")
+	}
 	return &authService{
-		userRepo: userRepo,
-		cfg:      cfg,
+		db:            db,
+		userRepo:      userRepo,
+		cfg:           cfg,
+		geocoder:      geocoder,
+		events:        events,
+		notifications: notifications,
+		oidc:          newOIDCProviders(cfg),
+		identities:    &oidcDBStore{db: db},
+		tokens:        authtoken.NewVerifierFromConfig(cfg),
 	}
 }
 
@@ -45,12 +119,43 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
 type AuthResponse struct {
 	AccessToken  string   `json:"access_token"`
 	RefreshToken string   `json:"refresh_token"`
 	TokenType    string   `json:"token_type"`
 	ExpiresIn    int64    `json:"expires_in"`
 	User         UserInfo `json:"user"`
+
+	// MFASetupRequired is set for staff without two-factor authentication,
+	// who cannot use admin endpoints until they enable it
+	MFASetupRequired bool `json:"mfa_setup_required,omitempty"`
 }
 
 type UserInfo struct {
@@ -60,26 +165,25 @@ type UserInfo struct {
 	Location *string   `json:"location"`
 	PhotoURL *string   `json:"photo_url"`
 	IsPublic bool      `json:"is_public"`
-}
 
-type TokenClaims struct {
-	UserID    uuid.UUID `json:"user_id"`
-	Email     string    `json:"email"`
-	IsAdmin   bool      `json:"is_admin"`
-	TokenType string    `json:"token_type"` // "access" or "refresh"
-	jwt.RegisteredClaims
+	EmailVerified bool     `json:"email_verified"`
+	MFAEnabled    bool     `json:"mfa_enabled"`
+	Roles         []string `json:"roles"` // Staff roles, e.g. "moderator"
 }
 
 // Register creates a new user account
-func (s *authService) Register(req *RegisterRequest) (*AuthResponse, error) {
+func (s *authService) Register(ctx context.Context, req *RegisterRequest) (*AuthResponse, error) {
 	// Check if user already exists
-	existingUser, _ := s.userRepo.GetByEmail(req.Email)
+	existingUser, _ := s.userRepo.GetByEmail(ctx, req.Email)
 	if existingUser != nil {
 		return nil, errors.New("user with this email already exists")
 	}
+	if err := s.checkPasswordPolicy(req.Password); err != nil {
+		return nil, err
+	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), s.cfg.Auth.BcryptCost)
 	if err != nil {
 		return nil, errors.New("failed to hash password")
 	}
@@ -92,118 +196,147 @@ func (s *authService) Register(req *RegisterRequest) (*AuthResponse, error) {
 		IsPublic:     true, // Default to public profile
 	}
 
-	if req.Location != "" {
-		user.Location = &req.Location
-	}
+	applyLocation(user, req.Location, s.geocoder)
 	if req.PhotoURL != "" {
 		user.PhotoURL = &req.PhotoURL
 	}
+	if !s.notifications.EmailEnabled() {
+		// Without email there is no way to confirm the address
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, errors.New("failed to create user")
 	}
 
+	s.events.Publish(event.UserChanged, user.UserID)
+
+	if user.EmailVerifiedAt == nil {
+		if err := s.sendVerificationEmail(ctx, user); err != nil {
+			log.Printf("Warning: failed to send verification email to user %s: %v", user.UserID, err)
+		}
+	}
+
 	// Generate tokens
-	return s.generateAuthResponse(user)
+	return s.generateAuthResponse(ctx, user)
 }
 
 // Login authenticates a user
-func (s *authService) Login(req *LoginRequest) (*AuthResponse, error) {
+func (s *authService) Login(ctx context.Context, req *LoginRequest) (*AuthResponse, *MFAChallenge, error) {
+	if err := s.checkLoginThrottle(ctx, req.Email); err != nil {
+		return nil, nil, err
+	}
+
 	// Get user by email
-	user, err := s.userRepo.GetByEmail(req.Email)
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
-		return nil, errors.New("invalid email or password")
+		s.recordLoginFailure(ctx, req.Email, nil)
+		return nil, nil, errors.New("invalid email or password")
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return nil, errors.New("invalid email or password")
+		s.recordLoginFailure(ctx, req.Email, user)
+		return nil, nil, errors.New("invalid email or password")
+	}
+
+	s.upgradePasswordHash(ctx, user, req.Password)
+
+	// Failures are cleared once the second factor is checked too
+	if user.MFAEnabledAt != nil {
+		challenge, err := s.issueMFAChallenge(user)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, challenge, nil
+	}
+
+	if err := clearLoginFailures(s.db.WithContext(ctx), user.Email); err != nil {
+		return nil, nil, err
 	}
 
 	// Generate tokens
-	return s.generateAuthResponse(user)
+	response, err := s.generateAuthResponse(ctx, user)
+	return response, nil, err
 }
 
 // RefreshToken generates new access token from refresh token
-func (s *authService) RefreshToken(refreshToken string) (*AuthResponse, error) {
-	// Parse and validate refresh token
-	claims, err := s.ValidateToken(refreshToken)
+func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*AuthResponse, error) {
+	claims, err := s.tokens.Verify(ctx, refreshToken, authtoken.TypeRefresh)
 	if err != nil {
+		if errors.Is(err, authtoken.ErrWrongTokenType) {
+			return nil, err
+		}
 		return nil, errors.New("invalid refresh token")
 	}
 
-	if claims.TokenType != "refresh" {
-		return nil, errors.New("invalid token type")
-	}
-
 	// Get user to generate new tokens
-	user, err := s.userRepo.GetByID(claims.UserID)
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
 
-	return s.generateAuthResponse(user)
+	// Sessions from before a password reset are revoked
+	if claims.TokenVersion != user.TokenVersion {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	return s.generateAuthResponse(ctx, user)
 }
 
-// ValidateToken validates and parses JWT token
-func (s *authService) ValidateToken(tokenString string) (*TokenClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(s.cfg.JWTSecret), nil
-	})
+// Helper function to generate auth response with tokens. Sessions of users
+// with two-factor authentication are marked as having passed it: login asks
+// for a code, and enabling it revokes the sessions that did not.
+func (s *authService) generateAuthResponse(ctx context.Context, user *models.User) (*AuthResponse, error) {
+	accessTokenExp := time.Now().Add(s.cfg.Auth.AccessTokenTTL)
+	refreshTokenExp := time.Now().Add(s.cfg.Auth.RefreshTokenTTL)
 
+	roles, err := userRoles(s.db.WithContext(ctx), user.UserID)
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*TokenClaims); ok && token.Valid {
-		return claims, nil
-	}
-
-	return nil, errors.New("invalid token")
-}
-
-// Helper function to generate auth response with tokens
-func (s *authService) generateAuthResponse(user *models.User) (*AuthResponse, error) {
-	accessTokenExp := time.Now().Add(15 * time.Minute)
-	refreshTokenExp := time.Now().Add(7 * 24 * time.Hour)
-
 	// Generate access token
-	accessClaims := TokenClaims{
-		UserID:    user.UserID,
-		Email:     user.Email,
-		IsAdmin:   user.IsAdmin, // Use the user's actual admin status
-		TokenType: "access",
+	accessClaims := authtoken.Claims{
+		UserID:       user.UserID,
+		Email:        user.Email,
+		Roles:        roles,
+		TokenType:    authtoken.TypeAccess,
+		TokenVersion: user.TokenVersion,
+		MFA:          user.MFAEnabledAt != nil,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.cfg.Auth.Issuer,
+			Audience:  jwt.ClaimStrings{s.cfg.Auth.Audience},
 			ExpiresAt: jwt.NewNumericDate(accessTokenExp),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   user.UserID.String(),
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(s.cfg.JWTSecret))
+	accessTokenString, err := s.cfg.Auth.Keys.Sign(accessClaims)
 	if err != nil {
 		return nil, errors.New("failed to generate access token")
 	}
 
 	// Generate refresh token
-	refreshClaims := TokenClaims{
-		UserID:    user.UserID,
-		Email:     user.Email,
-		IsAdmin:   user.IsAdmin, // Use the user's actual admin status
-		TokenType: "refresh",
+	refreshClaims := authtoken.Claims{
+		UserID:       user.UserID,
+		Email:        user.Email,
+		Roles:        roles,
+		TokenType:    authtoken.TypeRefresh,
+		TokenVersion: user.TokenVersion,
+		MFA:          user.MFAEnabledAt != nil,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.cfg.Auth.Issuer,
+			Audience:  jwt.ClaimStrings{s.cfg.Auth.Audience},
 			ExpiresAt: jwt.NewNumericDate(refreshTokenExp),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   user.UserID.String(),
 		},
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(s.cfg.JWTSecret))
+	refreshTokenString, err := s.cfg.Auth.Keys.Sign(refreshClaims)
 	if err != nil {
 		return nil, errors.New("failed to generate refresh token")
 	}
@@ -220,6 +353,118 @@ func (s *authService) generateAuthResponse(user *models.User) (*AuthResponse, er
 			Location: user.Location,
 			PhotoURL: user.PhotoURL,
 			IsPublic: user.IsPublic,
+
+			EmailVerified: user.EmailVerifiedAt != nil,
+			MFAEnabled:    user.MFAEnabledAt != nil,
+			Roles:         roles,
 		},
+		MFASetupRequired: len(roles) > 0 && user.MFAEnabledAt == nil,
 	}, nil
 }
+
+func (s *authService) VerifyEmail(ctx context.Context, token string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		used, err := consumeUserToken(tx, token, models.TokenEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		// A link sent to an address the account no longer uses is void
+		result := tx.Model(&models.User{}).
+			Where("user_id = ? AND email = ?", used.UserID, used.Email).
+			Update("email_verified_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("invalid or expired token")
+		}
+		return nil
+	})
+}
+
+func (s *authService) ResendVerification(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return errors.New("email is already verified")
+	}
+	return s.sendVerificationEmail(ctx, user)
+}
+
+func (s *authService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		// Same response as for a known address
+		return nil
+	}
+
+	token, err := issueUserToken(s.db.WithContext(ctx), user.UserID, models.TokenPasswordReset, &user.Email, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	return s.notifications.QueueAccountEmail(ctx, user.UserID, mail.AccountEmailData{
+		To:            user.Email,
+		RecipientName: user.Name,
+		Kind:          "reset_password",
+		Title:         "Reset your password",
+		Link:          s.cfg.Server.AppURL + "/reset-password?token=" + url.QueryEscape(token),
+		Action:        "Choose a new password",
+		ExpiresIn:     formatLeadTime(passwordResetTTL),
+	})
+}
+
+func (s *authService) ResetPassword(ctx context.Context, req *ResetPasswordRequest) error {
+	if err := s.checkPasswordPolicy(req.Password); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), s.cfg.Auth.BcryptCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		used, err := consumeUserToken(tx, req.Token, models.TokenPasswordReset)
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"password_hash": string(hashedPassword),
+			"token_version": gorm.Expr("token_version + 1"),
+			// The reset link proved the user can read mail sent to the address
+			"email_verified_at": gorm.Expr("CASE WHEN email = ? THEN COALESCE(email_verified_at, ?) ELSE email_verified_at END", used.Email, time.Now()),
+		}
+		if err := tx.Model(&models.User{}).Where("user_id = ?", used.UserID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// The owner is back in control, so a lockout no longer helps
+		err = tx.Exec("DELETE FROM login_failures WHERE email = (SELECT LOWER(email) FROM users WHERE user_id = ?)", used.UserID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", used.UserID, models.TokenPasswordReset).
+			Delete(&models.UserToken{}).Error
+	})
+}
+
+func (s *authService) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := issueUserToken(s.db.WithContext(ctx), user.UserID, models.TokenEmailVerification, &user.Email, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.notifications.QueueAccountEmail(ctx, user.UserID, mail.AccountEmailData{
+		To:            user.Email,
+		RecipientName: user.Name,
+		Kind:          "verify_email",
+		Title:         "Confirm your email address",
+		Link:          s.cfg.Server.AppURL + "/verify-email?token=" + url.QueryEscape(token),
+		Action:        "Confirm email address",
+		ExpiresIn:     formatLeadTime(emailVerificationTTL),
+	})
+}
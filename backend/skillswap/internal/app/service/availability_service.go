@@ -1,9 +1,11 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"time"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/calendar"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -11,15 +13,21 @@ import (
 
 type AvailabilityService interface {
 	// Availability CRUD operations
-	CreateAvailabilitySlot(req *CreateAvailabilitySlotDTO) (*models.AvailabilitySlot, error)
-	GetUserAvailabilitySlots(userID uuid.UUID) ([]models.AvailabilitySlot, error)
-	GetAvailabilitySlot(slotID uuid.UUID, userID uuid.UUID) (*models.AvailabilitySlot, error)
-	UpdateAvailabilitySlot(slotID uuid.UUID, userID uuid.UUID, req *UpdateAvailabilitySlotDTO) (*models.AvailabilitySlot, error)
-	DeleteAvailabilitySlot(slotID uuid.UUID, userID uuid.UUID) error
+	CreateAvailabilitySlot(ctx context.Context, req *CreateAvailabilitySlotDTO) (*models.AvailabilitySlot, error)
+	GetUserAvailabilitySlots(ctx context.Context, userID uuid.UUID) ([]models.AvailabilitySlot, error)
+	GetAvailabilitySlot(ctx context.Context, slotID uuid.UUID, userID uuid.UUID) (*models.AvailabilitySlot, error)
+	UpdateAvailabilitySlot(ctx context.Context, slotID uuid.UUID, userID uuid.UUID, req *UpdateAvailabilitySlotDTO) (*models.AvailabilitySlot, error)
+	DeleteAvailabilitySlot(ctx context.Context, slotID uuid.UUID, userID uuid.UUID) error
+
+	// One-off availability and blackouts
+	CreateException(ctx context.Context, req *CreateAvailabilityExceptionDTO) (*models.AvailabilityException, error)
+	GetUserExceptions(ctx context.Context, userID uuid.UUID, from, to *time.Time) ([]models.AvailabilityException, error)
+	DeleteException(ctx context.Context, exceptionID uuid.UUID, userID uuid.UUID) error
 
 	// Availability queries
-	FindCommonAvailability(userID1, userID2 uuid.UUID) ([]CommonAvailabilitySlot, error)
-	GetAvailabilityByDayAndTime(userID uuid.UUID, dayOfWeek int, startTime, endTime time.Time) ([]models.AvailabilitySlot, error)
+	FreeTime(ctx context.Context, viewerID, userID uuid.UUID, from, to time.Time, timezone string) (*FreeTimeResponse, error)
+	FindCommonAvailability(ctx context.Context, viewerID, otherUserID uuid.UUID, opts CommonAvailabilityOptions) (*CommonAvailabilityResponse, error)
+	GetAvailabilityByDayAndTime(ctx context.Context, userID uuid.UUID, dayOfWeek int, startTime, endTime time.Time) ([]models.AvailabilitySlot, error)
 }
 
 // DTOs and Request structures
@@ -28,47 +36,77 @@ type CreateAvailabilitySlotDTO struct {
 	Label      string    `json:"label" binding:"required,min=1,max=100"`
 	DayBitmask int32     `json:"day_bitmask" binding:"required,min=1,max=127"` // 1-127 (binary representation of days)
 	StartTime  string    `json:"start_time" binding:"required"`                // Format: "15:04"
-	EndTime    string    `json:"end_time" binding:"required"`                  // Format: "15:04"
+	EndTime    string    `json:"end_time" binding:"required"`                  // Format: "15:04"; earlier than start_time runs overnight
+	Timezone   *string   `json:"timezone,omitempty"`                           // IANA zone; defaults to the user's timezone
 }
 
 type UpdateAvailabilitySlotDTO struct {
-	Label      string `json:"label" binding:"required,min=1,max=100"`
-	DayBitmask int32  `json:"day_bitmask" binding:"required,min=1,max=127"`
-	StartTime  string `json:"start_time" binding:"required"`
-	EndTime    string `json:"end_time" binding:"required"`
+	Label      string  `json:"label" binding:"required,min=1,max=100"`
+	DayBitmask int32   `json:"day_bitmask" binding:"required,min=1,max=127"`
+	StartTime  string  `json:"start_time" binding:"required"`
+	EndTime    string  `json:"end_time" binding:"required"`
+	Timezone   *string `json:"timezone,omitempty"`
+}
+
+type CreateAvailabilityExceptionDTO struct {
+	UserID   uuid.UUID `json:"user_id"`                                          // Will be set from JWT token
+	Kind     string    `json:"kind" binding:"required,oneof=available blackout"` // "available" or "blackout"
+	Label    string    `json:"label" binding:"max=100"`
+	StartsAt time.Time `json:"starts_at" binding:"required"` // RFC 3339
+	EndsAt   time.Time `json:"ends_at" binding:"required"`   // RFC 3339
+}
+
+// FreeTimeResponse lists merged, bookable intervals in the requested zone
+type FreeTimeResponse struct {
+	Timezone string              `json:"timezone"`
+	Free     []calendar.Interval `json:"free"`
+}
+
+// CommonAvailabilityOptions selects the week to compare and the zone the
+// result is shown in. Zero values mean the current week and the viewer's
+// own timezone.
+type CommonAvailabilityOptions struct {
+	WeekOf   time.Time // Only the date is used, read in the viewer's zone
+	Timezone string
 }
 
+// CommonAvailabilitySlot is one overlap in the viewer's timezone. Day and
+// the clock times are local; EndTime is earlier than StartTime when the
+// overlap runs past midnight. Start and End are the exact instants.
 type CommonAvailabilitySlot struct {
-	Day       string `json:"day"`
-	StartTime string `json:"start_time"`
-	EndTime   string `json:"end_time"`
-	Duration  int    `json:"duration_minutes"`
+	Day       string    `json:"day"`
+	StartTime string    `json:"start_time"`
+	EndTime   string    `json:"end_time"`
+	Duration  int       `json:"duration_minutes"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+}
+
+type CommonAvailabilityResponse struct {
+	Timezone  string                   `json:"timezone"`
+	WeekStart time.Time                `json:"week_start"`
+	Slots     []CommonAvailabilitySlot `json:"common_availability"`
 }
 
 type availabilityService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	schedule ScheduleService
 }
 
-func NewAvailabilityService(db *gorm.DB) AvailabilityService {
-	return &availabilityService{db: db}
+func NewAvailabilityService(db *gorm.DB, schedule ScheduleService) AvailabilityService {
+	return &availabilityService{db: db, schedule: schedule}
 }
 
 // CreateAvailabilitySlot creates a new availability slot for a user
-func (a *availabilityService) CreateAvailabilitySlot(req *CreateAvailabilitySlotDTO) (*models.AvailabilitySlot, error) {
-	// Parse time strings
-	startTime, err := time.Parse("15:04", req.StartTime)
+func (a *availabilityService) CreateAvailabilitySlot(ctx context.Context, req *CreateAvailabilitySlotDTO) (*models.AvailabilitySlot, error) {
+	startTime, endTime, err := parseSlotTimes(req.StartTime, req.EndTime)
 	if err != nil {
-		return nil, errors.New("invalid start_time format, use HH:MM")
+		return nil, err
 	}
 
-	endTime, err := time.Parse("15:04", req.EndTime)
+	timezone, err := slotTimezone(req.Timezone)
 	if err != nil {
-		return nil, errors.New("invalid end_time format, use HH:MM")
-	}
-
-	// Validate time range
-	if endTime.Before(startTime) || endTime.Equal(startTime) {
-		return nil, errors.New("end_time must be after start_time")
+		return nil, err
 	}
 
 	// Validate day bitmask (1-127, representing Monday=1, Tuesday=2, ..., Sunday=64)
@@ -82,22 +120,23 @@ func (a *availabilityService) CreateAvailabilitySlot(req *CreateAvailabilitySlot
 		DayBitmask: req.DayBitmask,
 		StartTime:  startTime,
 		EndTime:    endTime,
+		Timezone:   timezone,
 	}
 
-	err = a.db.Create(slot).Error
+	err = a.db.WithContext(ctx).Create(slot).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Load user relation
-	err = a.db.Preload("User").First(slot, slot.SlotID).Error
+	err = a.db.WithContext(ctx).Preload("User").First(slot, slot.SlotID).Error
 	return slot, err
 }
 
 // GetUserAvailabilitySlots retrieves all availability slots for a user
-func (a *availabilityService) GetUserAvailabilitySlots(userID uuid.UUID) ([]models.AvailabilitySlot, error) {
+func (a *availabilityService) GetUserAvailabilitySlots(ctx context.Context, userID uuid.UUID) ([]models.AvailabilitySlot, error) {
 	var slots []models.AvailabilitySlot
-	err := a.db.Where("user_id = ?", userID).
+	err := a.db.WithContext(ctx).Where("user_id = ?", userID).
 		Order("day_bitmask ASC, start_time ASC").
 		Find(&slots).Error
 
@@ -105,9 +144,9 @@ func (a *availabilityService) GetUserAvailabilitySlots(userID uuid.UUID) ([]mode
 }
 
 // GetAvailabilitySlot retrieves a specific availability slot
-func (a *availabilityService) GetAvailabilitySlot(slotID uuid.UUID, userID uuid.UUID) (*models.AvailabilitySlot, error) {
+func (a *availabilityService) GetAvailabilitySlot(ctx context.Context, slotID uuid.UUID, userID uuid.UUID) (*models.AvailabilitySlot, error) {
 	var slot models.AvailabilitySlot
-	err := a.db.Where("slot_id = ? AND user_id = ?", slotID, userID).
+	err := a.db.WithContext(ctx).Where("slot_id = ? AND user_id = ?", slotID, userID).
 		First(&slot).Error
 
 	if err != nil {
@@ -121,26 +160,20 @@ func (a *availabilityService) GetAvailabilitySlot(slotID uuid.UUID, userID uuid.
 }
 
 // UpdateAvailabilitySlot updates an existing availability slot
-func (a *availabilityService) UpdateAvailabilitySlot(slotID uuid.UUID, userID uuid.UUID, req *UpdateAvailabilitySlotDTO) (*models.AvailabilitySlot, error) {
-	slot, err := a.GetAvailabilitySlot(slotID, userID)
+func (a *availabilityService) UpdateAvailabilitySlot(ctx context.Context, slotID uuid.UUID, userID uuid.UUID, req *UpdateAvailabilitySlotDTO) (*models.AvailabilitySlot, error) {
+	slot, err := a.GetAvailabilitySlot(ctx, slotID, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse time strings
-	startTime, err := time.Parse("15:04", req.StartTime)
+	startTime, endTime, err := parseSlotTimes(req.StartTime, req.EndTime)
 	if err != nil {
-		return nil, errors.New("invalid start_time format, use HH:MM")
+		return nil, err
 	}
 
-	endTime, err := time.Parse("15:04", req.EndTime)
+	timezone, err := slotTimezone(req.Timezone)
 	if err != nil {
-		return nil, errors.New("invalid end_time format, use HH:MM")
-	}
-
-	// Validate time range
-	if endTime.Before(startTime) || endTime.Equal(startTime) {
-		return nil, errors.New("end_time must be after start_time")
+		return nil, err
 	}
 
 	// Validate day bitmask
@@ -153,8 +186,9 @@ func (a *availabilityService) UpdateAvailabilitySlot(slotID uuid.UUID, userID uu
 	slot.DayBitmask = req.DayBitmask
 	slot.StartTime = startTime
 	slot.EndTime = endTime
+	slot.Timezone = timezone
 
-	err = a.db.Save(slot).Error
+	err = a.db.WithContext(ctx).Save(slot).Error
 	if err != nil {
 		return nil, err
 	}
@@ -163,8 +197,8 @@ func (a *availabilityService) UpdateAvailabilitySlot(slotID uuid.UUID, userID uu
 }
 
 // DeleteAvailabilitySlot deletes an availability slot
-func (a *availabilityService) DeleteAvailabilitySlot(slotID uuid.UUID, userID uuid.UUID) error {
-	result := a.db.Where("slot_id = ? AND user_id = ?", slotID, userID).
+func (a *availabilityService) DeleteAvailabilitySlot(ctx context.Context, slotID uuid.UUID, userID uuid.UUID) error {
+	result := a.db.WithContext(ctx).Where("slot_id = ? AND user_id = ?", slotID, userID).
 		Delete(&models.AvailabilitySlot{})
 
 	if result.Error != nil {
@@ -178,74 +212,260 @@ func (a *availabilityService) DeleteAvailabilitySlot(slotID uuid.UUID, userID uu
 	return nil
 }
 
-// FindCommonAvailability finds overlapping availability between two users
-func (a *availabilityService) FindCommonAvailability(userID1, userID2 uuid.UUID) ([]CommonAvailabilitySlot, error) {
-	var user1Slots []models.AvailabilitySlot
-	var user2Slots []models.AvailabilitySlot
+// FindCommonAvailability finds overlapping free time between two users for
+// one concrete week. Each user's free time is worked out in their own
+// timezone, intersected as absolute times, and shown in the viewer's zone,
+// so overnight slots and DST changes line up correctly.
+func (a *availabilityService) FindCommonAvailability(ctx context.Context, viewerID, otherUserID uuid.UUID, opts CommonAvailabilityOptions) (*CommonAvailabilityResponse, error) {
+	var users []models.User
+	err := a.db.WithContext(ctx).Select("user_id", "timezone", "is_public").Where("user_id IN ?", []uuid.UUID{viewerID, otherUserID}).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	zones := make(map[uuid.UUID]*string, len(users))
+	otherPublic := false
+	for _, user := range users {
+		zones[user.UserID] = user.Timezone
+		if user.UserID == otherUserID {
+			otherPublic = user.IsPublic
+		}
+	}
+	if _, ok := zones[otherUserID]; !ok {
+		return nil, errors.New("user not found")
+	}
+	// The overlap reveals the other user's free and booked time
+	if viewerID != otherUserID && !otherPublic {
+		partners, err := a.haveAcceptedSwap(ctx, viewerID, otherUserID)
+		if err != nil {
+			return nil, err
+		}
+		if !partners {
+			return nil, errors.New("user not found")
+		}
+	}
 
-	// Get availability slots for both users
-	err := a.db.Where("user_id = ?", userID1).Find(&user1Slots).Error
+	viewerZone := zones[viewerID]
+	if opts.Timezone != "" {
+		viewerZone = &opts.Timezone
+	}
+	viewerLoc, err := loadLocation(viewerZone)
 	if err != nil {
 		return nil, err
 	}
 
-	err = a.db.Where("user_id = ?", userID2).Find(&user2Slots).Error
+	weekOf := time.Now()
+	if !opts.WeekOf.IsZero() {
+		weekOf = time.Date(opts.WeekOf.Year(), opts.WeekOf.Month(), opts.WeekOf.Day(), 12, 0, 0, 0, viewerLoc)
+	}
+	week := calendar.Week(weekOf, viewerLoc)
+
+	viewerFree, err := a.schedule.FreeTime(ctx, viewerID, week)
 	if err != nil {
 		return nil, err
 	}
+	otherFree, err := a.schedule.FreeTime(ctx, otherUserID, week)
+	if err != nil {
+		return nil, err
+	}
+
+	commonSlots := make([]CommonAvailabilitySlot, 0)
+	for _, overlap := range calendar.Intersect(viewerFree, otherFree) {
+		local := overlap.In(viewerLoc)
+		commonSlots = append(commonSlots, CommonAvailabilitySlot{
+			Day:       local.Start.Weekday().String(),
+			StartTime: local.Start.Format("15:04"),
+			EndTime:   local.End.Format("15:04"),
+			Duration:  int(local.Duration().Minutes()),
+			Start:     local.Start,
+			End:       local.End,
+		})
+	}
+
+	return &CommonAvailabilityResponse{
+		Timezone:  viewerLoc.String(),
+		WeekStart: week.Start,
+		Slots:     commonSlots,
+	}, nil
+}
+
+// CreateException adds one-off availability or a blackout for a user
+func (a *availabilityService) CreateException(ctx context.Context, req *CreateAvailabilityExceptionDTO) (*models.AvailabilityException, error) {
+	kind := models.ExceptionKind(req.Kind)
+	if kind != models.ExceptionAvailable && kind != models.ExceptionBlackout {
+		return nil, errors.New("kind must be available or blackout")
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return nil, errors.New("ends_at must be after starts_at")
+	}
+	if req.EndsAt.Sub(req.StartsAt) > maxScheduleRange {
+		return nil, errors.New("exception is too long")
+	}
+
+	exception := &models.AvailabilityException{
+		UserID:   req.UserID,
+		Kind:     kind,
+		Label:    req.Label,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+	}
+
+	if err := a.db.WithContext(ctx).Create(exception).Error; err != nil {
+		return nil, err
+	}
+
+	return exception, nil
+}
+
+// GetUserExceptions lists a user's exceptions, optionally only those
+// overlapping [from, to)
+func (a *availabilityService) GetUserExceptions(ctx context.Context, userID uuid.UUID, from, to *time.Time) ([]models.AvailabilityException, error) {
+	query := a.db.WithContext(ctx).Where("user_id = ?", userID)
+	if from != nil {
+		query = query.Where("ends_at > ?", *from)
+	}
+	if to != nil {
+		query = query.Where("starts_at < ?", *to)
+	}
+
+	var exceptions []models.AvailabilityException
+	err := query.Order("starts_at ASC").Find(&exceptions).Error
+	return exceptions, err
+}
+
+// DeleteException deletes one of a user's exceptions
+func (a *availabilityService) DeleteException(ctx context.Context, exceptionID uuid.UUID, userID uuid.UUID) error {
+	result := a.db.WithContext(ctx).Where("exception_id = ? AND user_id = ?", exceptionID, userID).
+		Delete(&models.AvailabilityException{})
+
+	if result.Error != nil {
+		return result.Error
+	}
 
-	var commonSlots []CommonAvailabilitySlot
-
-	// Find overlapping slots
-	for _, slot1 := range user1Slots {
-		for _, slot2 := range user2Slots {
-			// Check if days overlap using bitwise AND
-			if slot1.DayBitmask&slot2.DayBitmask != 0 {
-				// Find time overlap
-				start := slot1.StartTime
-				if slot2.StartTime.After(start) {
-					start = slot2.StartTime
-				}
-
-				end := slot1.EndTime
-				if slot2.EndTime.Before(end) {
-					end = slot2.EndTime
-				}
-
-				// If there's a valid overlap
-				if start.Before(end) {
-					// Convert overlapping days to string representation
-					days := getDaysFromBitmask(slot1.DayBitmask & slot2.DayBitmask)
-
-					for _, day := range days {
-						duration := int(end.Sub(start).Minutes())
-						commonSlots = append(commonSlots, CommonAvailabilitySlot{
-							Day:       day,
-							StartTime: start.Format("15:04"),
-							EndTime:   end.Format("15:04"),
-							Duration:  duration,
-						})
-					}
-				}
-			}
+	if result.RowsAffected == 0 {
+		return errors.New("availability exception not found")
+	}
+
+	return nil
+}
+
+// FreeTime returns a user's bookable time between from and to, shown in
+// timezone (the user's own when empty). The gaps reveal when sessions are
+// booked, so other viewers only see public users or their swap partners.
+func (a *availabilityService) FreeTime(ctx context.Context, viewerID, userID uuid.UUID, from, to time.Time, timezone string) (*FreeTimeResponse, error) {
+	var user models.User
+	if err := a.db.WithContext(ctx).Select("user_id", "timezone", "is_public").Where("user_id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
 		}
+		return nil, err
+	}
+	if viewerID != userID && !user.IsPublic {
+		partners, err := a.haveAcceptedSwap(ctx, viewerID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !partners {
+			return nil, errors.New("user not found")
+		}
+	}
+
+	if timezone == "" && user.Timezone != nil {
+		timezone = *user.Timezone
+	}
+	loc, err := loadLocation(&timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	free, err := a.schedule.FreeTime(ctx, userID, calendar.Interval{Start: from, End: to})
+	if err != nil {
+		return nil, err
 	}
 
-	return commonSlots, nil
+	for i := range free {
+		free[i] = free[i].In(loc)
+	}
+	if free == nil {
+		free = []calendar.Interval{}
+	}
+
+	return &FreeTimeResponse{Timezone: loc.String(), Free: free}, nil
 }
 
-// GetAvailabilityByDayAndTime finds availability slots for specific day and time range
-func (a *availabilityService) GetAvailabilityByDayAndTime(userID uuid.UUID, dayOfWeek int, startTime, endTime time.Time) ([]models.AvailabilitySlot, error) {
-	dayBitmask := 1 << (dayOfWeek - 1) // Convert day (1-7) to bitmask
+// haveAcceptedSwap reports whether the two users share an accepted swap
+func (a *availabilityService) haveAcceptedSwap(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+	var count int64
+	err := a.db.WithContext(ctx).Model(&models.SwapRequest{}).
+		Where("((requester_id = ? AND responder_id = ?) OR (requester_id = ? AND responder_id = ?)) AND status = ?",
+			userA, userB, userB, userA, models.StatusAccepted).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetAvailabilityByDayAndTime finds availability slots for specific day and time range,
+// in the slots' own wall-clock time. Overnight slots match on the evening of
+// their start day and on the morning after.
+func (a *availabilityService) GetAvailabilityByDayAndTime(ctx context.Context, userID uuid.UUID, dayOfWeek int, startTime, endTime time.Time) ([]models.AvailabilitySlot, error) {
+	dayBitmask := 1 << (dayOfWeek - 1)           // Convert day (1-7) to bitmask
+	prevDayBitmask := 1 << ((dayOfWeek + 5) % 7) // The day before, for slots running past midnight
+	start, end := startTime.Format("15:04"), endTime.Format("15:04")
 
 	var slots []models.AvailabilitySlot
-	err := a.db.Where("user_id = ? AND (day_bitmask & ?) > 0 AND start_time <= ? AND end_time >= ?",
-		userID, dayBitmask, endTime.Format("15:04"), startTime.Format("15:04")).
+	err := a.db.WithContext(ctx).Where("user_id = ?", userID).
+		Where(`(start_time < end_time AND (day_bitmask & ?) > 0 AND start_time <= ? AND end_time >= ?)
+			OR (start_time > end_time AND (day_bitmask & ?) > 0 AND start_time <= ?)
+			OR (start_time > end_time AND (day_bitmask & ?) > 0 AND end_time >= ?)`,
+			dayBitmask, end, start,
+			dayBitmask, end,
+			prevDayBitmask, start).
 		Find(&slots).Error
 
 	return slots, err
 }
 
+// parseSlotTimes parses "15:04" start and end times. An end before the start
+// means the slot runs past midnight into the next day.
+func parseSlotTimes(start, end string) (time.Time, time.Time, error) {
+	startTime, err := time.Parse("15:04", start)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid start_time format, use HH:MM")
+	}
+
+	endTime, err := time.Parse("15:04", end)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid end_time format, use HH:MM")
+	}
+
+	if endTime.Equal(startTime) {
+		return time.Time{}, time.Time{}, errors.New("end_time must differ from start_time")
+	}
+
+	return startTime, endTime, nil
+}
+
+// slotTimezone validates an optional slot timezone; empty means none
+func slotTimezone(timezone *string) (*string, error) {
+	if timezone == nil || *timezone == "" {
+		return nil, nil
+	}
+	if err := validateTimezone(*timezone); err != nil {
+		return nil, err
+	}
+	return timezone, nil
+}
+
+// loadLocation loads an IANA zone, defaulting to UTC when none is set
+func loadLocation(name *string) (*time.Location, error) {
+	if name == nil || *name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(*name)
+	if err != nil {
+		return nil, errors.New("invalid timezone")
+	}
+	return loc, nil
+}
+
 // Helper function to convert bitmask to day names
 func getDaysFromBitmask(bitmask int32) []string {
 	days := []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
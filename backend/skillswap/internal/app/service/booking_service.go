@@ -0,0 +1,458 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/calendar"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	minBookingDuration = 15 * time.Minute
+	maxBookingDuration = 8 * time.Hour
+)
+
+// sqlStateExclusionViolation is raised when booking_participants_no_overlap
+// rejects a row
+const sqlStateExclusionViolation = "23P01"
+
+// errBookingCancelled is returned when a booking is no longer confirmed,
+// including when a concurrent request cancelled it first
+var errBookingCancelled = errors.New("booking is cancelled")
+
+type BookingService interface {
+	CreateBooking(ctx context.Context, userID uuid.UUID, req *CreateBookingDTO) (*models.Booking, error)
+	GetBooking(ctx context.Context, userID, bookingID uuid.UUID) (*models.Booking, error)
+	GetUserBookings(ctx context.Context, userID uuid.UUID, filter BookingFilter) ([]models.Booking, error)
+	RescheduleBooking(ctx context.Context, userID, bookingID uuid.UUID, req *RescheduleBookingDTO) (*models.Booking, error)
+	CancelBooking(ctx context.Context, userID, bookingID uuid.UUID, reason string) (*models.Booking, error)
+
+	// Confirmed bookings count as busy time and appear in calendar feeds
+	BusySource
+	CalendarEventSource
+}
+
+type CreateBookingDTO struct {
+	SwapID   uuid.UUID
+	StartsAt time.Time
+	EndsAt   time.Time
+	Timezone string // Defaults to the creator's timezone
+}
+
+type RescheduleBookingDTO struct {
+	StartsAt time.Time
+	EndsAt   time.Time
+	Timezone string // Defaults to the booking's current timezone
+}
+
+type BookingFilter struct {
+	SwapID *uuid.UUID
+	Status *models.BookingStatus
+	From   *time.Time
+	To     *time.Time
+}
+
+type bookingService struct {
+	db            *gorm.DB
+	schedule      ScheduleService
+	notifications *NotificationService
+	events        *event.Bus
+	uidHost       string
+}
+
+func NewBookingService(db *gorm.DB, schedule ScheduleService, notifications *NotificationService, events *event.Bus, cfg config.Config) BookingService {
+	return &bookingService{
+		db:            db,
+		schedule:      schedule,
+		notifications: notifications,
+		events:        events,
+		uidHost:       calendarUIDHost(cfg.Server.BaseURL),
+	}
+}
+
+// CreateBooking reserves time for an accepted swap. The time must be free for
+// both participants; the exclusion constraint settles concurrent requests.
+func (s *bookingService) CreateBooking(ctx context.Context, userID uuid.UUID, req *CreateBookingDTO) (*models.Booking, error) {
+	var swap models.SwapRequest
+	if err := s.db.WithContext(ctx).Where("swap_id = ?", req.SwapID).First(&swap).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("swap request not found")
+		}
+		return nil, err
+	}
+	if swap.RequesterID != userID && swap.ResponderID != userID {
+		return nil, errors.New("access denied")
+	}
+	if swap.Status != models.StatusAccepted {
+		return nil, errors.New("only accepted swap requests can be booked")
+	}
+
+	slot := calendar.Interval{Start: req.StartsAt, End: req.EndsAt}
+	if err := validateBookingTime(slot); err != nil {
+		return nil, err
+	}
+
+	timezone, err := s.bookingTimezone(ctx, userID, req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	participants := []uuid.UUID{swap.RequesterID, swap.ResponderID}
+	if err := s.checkAvailability(ctx, userID, participants, slot, nil); err != nil {
+		return nil, err
+	}
+
+	booking := &models.Booking{
+		SwapID:    swap.SwapID,
+		CreatedBy: userID,
+		StartsAt:  slot.Start,
+		EndsAt:    slot.End,
+		Timezone:  timezone,
+		Status:    models.BookingConfirmed,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(booking).Error; err != nil {
+			return err
+		}
+		rows := make([]models.BookingParticipant, len(participants))
+		for i, participantID := range participants {
+			rows[i] = models.BookingParticipant{
+				BookingID: booking.BookingID,
+				UserID:    participantID,
+				StartsAt:  slot.Start,
+				EndsAt:    slot.End,
+				Active:    true,
+			}
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, translateBookingError(err)
+	}
+
+	s.events.Publish(event.BookingChanged, booking.BookingID)
+	s.notify(ctx, booking.BookingID, participants, models.NotificationTypeBookingCreated, slot.Start)
+
+	return s.GetBooking(ctx, userID, booking.BookingID)
+}
+
+func (s *bookingService) GetBooking(ctx context.Context, userID, bookingID uuid.UUID) (*models.Booking, error) {
+	var booking models.Booking
+	err := s.db.WithContext(ctx).Preload("Swap").Preload("Swap.Requester").Preload("Swap.Responder").
+		Preload("Swap.OfferedSkill").Preload("Swap.WantedSkill").
+		Where("booking_id = ?", bookingID).
+		First(&booking).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("booking not found")
+		}
+		return nil, err
+	}
+
+	if booking.Swap.RequesterID != userID && booking.Swap.ResponderID != userID {
+		return nil, errors.New("access denied")
+	}
+
+	return &booking, nil
+}
+
+func (s *bookingService) GetUserBookings(ctx context.Context, userID uuid.UUID, filter BookingFilter) ([]models.Booking, error) {
+	query := s.db.WithContext(ctx).Preload("Swap").Preload("Swap.Requester").Preload("Swap.Responder").
+		Preload("Swap.OfferedSkill").Preload("Swap.WantedSkill").
+		Where("booking_id IN (?)", s.db.WithContext(ctx).Model(&models.BookingParticipant{}).Select("booking_id").Where("user_id = ?", userID))
+
+	if filter.SwapID != nil {
+		query = query.Where("swap_id = ?", *filter.SwapID)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("ends_at > ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("starts_at < ?", *filter.To)
+	}
+
+	var bookings []models.Booking
+	if err := query.Order("starts_at ASC").Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// RescheduleBooking moves a confirmed booking. The booking being moved does
+// not count against its own new time.
+func (s *bookingService) RescheduleBooking(ctx context.Context, userID, bookingID uuid.UUID, req *RescheduleBookingDTO) (*models.Booking, error) {
+	booking, err := s.GetBooking(ctx, userID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != models.BookingConfirmed {
+		return nil, errBookingCancelled
+	}
+
+	slot := calendar.Interval{Start: req.StartsAt, End: req.EndsAt}
+	if err := validateBookingTime(slot); err != nil {
+		return nil, err
+	}
+
+	timezone := booking.Timezone
+	if req.Timezone != "" {
+		if _, err := loadLocation(&req.Timezone); err != nil {
+			return nil, err
+		}
+		timezone = req.Timezone
+	}
+
+	participants := []uuid.UUID{booking.Swap.RequesterID, booking.Swap.ResponderID}
+	if err := s.checkAvailability(ctx, userID, participants, slot, booking); err != nil {
+		return nil, err
+	}
+
+	// The status condition keeps a concurrent cancel from being undone
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Booking{}).
+			Where("booking_id = ? AND status = ?", bookingID, models.BookingConfirmed).
+			Updates(map[string]interface{}{
+				"starts_at": slot.Start,
+				"ends_at":   slot.End,
+				"timezone":  timezone,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errBookingCancelled
+		}
+
+		result = tx.Model(&models.BookingParticipant{}).
+			Where("booking_id = ? AND active", bookingID).
+			Updates(map[string]interface{}{
+				"starts_at": slot.Start,
+				"ends_at":   slot.End,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errBookingCancelled
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, translateBookingError(err)
+	}
+
+	s.events.Publish(event.BookingChanged, bookingID)
+	s.notify(ctx, bookingID, participants, models.NotificationTypeBookingRescheduled, slot.Start)
+
+	return s.GetBooking(ctx, userID, bookingID)
+}
+
+// CancelBooking releases the booked time for both participants
+func (s *bookingService) CancelBooking(ctx context.Context, userID, bookingID uuid.UUID, reason string) (*models.Booking, error) {
+	booking, err := s.GetBooking(ctx, userID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != models.BookingConfirmed {
+		return nil, errBookingCancelled
+	}
+
+	updates := map[string]interface{}{
+		"status":       models.BookingCancelled,
+		"cancelled_by": userID,
+	}
+	if reason != "" {
+		updates["cancel_reason"] = reason
+	}
+
+	// Only one of several concurrent cancels changes the row and notifies
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Booking{}).
+			Where("booking_id = ? AND status = ?", bookingID, models.BookingConfirmed).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errBookingCancelled
+		}
+		return tx.Model(&models.BookingParticipant{}).Where("booking_id = ?", bookingID).Update("active", false).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	participants := []uuid.UUID{booking.Swap.RequesterID, booking.Swap.ResponderID}
+	s.events.Publish(event.BookingChanged, bookingID)
+	s.notify(ctx, bookingID, participants, models.NotificationTypeBookingCancelled, booking.StartsAt)
+
+	return s.GetBooking(ctx, userID, bookingID)
+}
+
+// BusyTimes reports the user's confirmed bookings overlapping window
+func (s *bookingService) BusyTimes(ctx context.Context, userID uuid.UUID, window calendar.Interval) ([]calendar.Interval, error) {
+	var rows []models.BookingParticipant
+	err := s.db.WithContext(ctx).Where("user_id = ? AND active AND starts_at < ? AND ends_at > ?", userID, window.End, window.Start).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	busy := make([]calendar.Interval, len(rows))
+	for i, row := range rows {
+		busy[i] = calendar.Interval{Start: row.StartsAt, End: row.EndsAt}
+	}
+	return busy, nil
+}
+
+// CalendarEvents lists the user's bookings for their feed. Cancelled
+// bookings are kept as cancelled events so subscribed calendars remove them.
+func (s *bookingService) CalendarEvents(ctx context.Context, userID uuid.UUID, window calendar.Interval) ([]calendar.Event, error) {
+	bookings, err := s.GetUserBookings(ctx, userID, BookingFilter{From: &window.Start, To: &window.End})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]calendar.Event, 0, len(bookings))
+	for _, booking := range bookings {
+		partner := booking.Swap.Responder
+		if partner.UserID == userID {
+			partner = booking.Swap.Requester
+		}
+		// The requester teaches the offered skill and learns the wanted one
+		learn, teach := booking.Swap.WantedSkill.Name, booking.Swap.OfferedSkill.Name
+		if booking.Swap.ResponderID == userID {
+			learn, teach = teach, learn
+		}
+
+		loc, err := loadLocation(&booking.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+
+		events = append(events, calendar.Event{
+			UID:         fmt.Sprintf("booking-%s@%s", booking.BookingID, s.uidHost),
+			Summary:     fmt.Sprintf("Skill swap with %s", partner.Name),
+			Description: fmt.Sprintf("You teach %s and learn %s", teach, learn),
+			Start:       booking.StartsAt,
+			End:         booking.EndsAt,
+			Location:    loc,
+			Cancelled:   booking.Status == models.BookingCancelled,
+		})
+	}
+	return events, nil
+}
+
+// checkAvailability requires slot to lie within every participant's free
+// time. current, when set, is the booking being moved; its time is treated
+// as free.
+func (s *bookingService) checkAvailability(ctx context.Context, userID uuid.UUID, participants []uuid.UUID, slot calendar.Interval, current *models.Booking) error {
+	overlaps := s.db.WithContext(ctx).Model(&models.BookingParticipant{}).
+		Where("user_id IN ? AND active AND starts_at < ? AND ends_at > ?", participants, slot.End, slot.Start)
+	if current != nil {
+		overlaps = overlaps.Where("booking_id <> ?", current.BookingID)
+	}
+	var booked int64
+	if err := overlaps.Count(&booked).Error; err != nil {
+		return err
+	}
+	if booked > 0 {
+		return errors.New("time slot is already booked")
+	}
+
+	for _, participantID := range participants {
+		free, err := s.schedule.FreeTime(ctx, participantID, slot)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			free = calendar.Normalize(append(free, calendar.Interval{Start: current.StartsAt, End: current.EndsAt}))
+		}
+		if len(calendar.Subtract([]calendar.Interval{slot}, free)) == 0 {
+			continue
+		}
+		if participantID == userID {
+			return errors.New("time is outside your availability")
+		}
+		return errors.New("time is outside your partner's availability")
+	}
+	return nil
+}
+
+// bookingTimezone validates the requested zone, falling back to the user's
+// own and then UTC
+func (s *bookingService) bookingTimezone(ctx context.Context, userID uuid.UUID, requested string) (string, error) {
+	if requested != "" {
+		if _, err := loadLocation(&requested); err != nil {
+			return "", err
+		}
+		return requested, nil
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("user_id", "timezone").Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return "", err
+	}
+	if user.Timezone != nil {
+		if _, err := loadLocation(user.Timezone); err == nil {
+			return *user.Timezone, nil
+		}
+	}
+	return "UTC", nil
+}
+
+// notify tells both participants. A failed notification does not undo the
+// booking change.
+func (s *bookingService) notify(ctx context.Context, bookingID uuid.UUID, participants []uuid.UUID, notificationType models.NotificationType, startsAt time.Time) {
+	if s.notifications == nil {
+		return
+	}
+	// The booking is saved, so a client disconnecting must not cost either
+	// participant their notification
+	ctx = context.WithoutCancel(ctx)
+	for i, userID := range participants {
+		partnerID := participants[1-i]
+		if err := s.notifications.CreateBookingNotification(ctx, userID, partnerID, bookingID, notificationType, startsAt); err != nil {
+			log.Printf("Warning: failed to notify %s about booking %s: %v", userID, bookingID, err)
+		}
+	}
+}
+
+func validateBookingTime(slot calendar.Interval) error {
+	if slot.Empty() {
+		return errors.New("end must be after start")
+	}
+	if !slot.Start.After(time.Now()) {
+		return errors.New("booking must start in the future")
+	}
+	if slot.Duration() < minBookingDuration {
+		return errors.New("booking is too short")
+	}
+	if slot.Duration() > maxBookingDuration {
+		return errors.New("booking is too long")
+	}
+	return nil
+}
+
+// translateBookingError turns a rejected overlap, typically from a
+// concurrent request that passed the availability check, into a user error
+func translateBookingError(err error) error {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) && state.SQLState() == sqlStateExclusionViolation {
+		return errors.New("time slot is already booked")
+	}
+	return err
+}
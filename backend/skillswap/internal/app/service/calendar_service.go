@@ -0,0 +1,402 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/calendar"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Window of one-off entries included in the feed
+const (
+	calendarFeedPast   = 30 * 24 * time.Hour
+	calendarFeedFuture = 365 * 24 * time.Hour
+)
+
+// CalendarEventSource contributes events such as scheduled swap sessions to
+// a user's calendar feed
+type CalendarEventSource interface {
+	CalendarEvents(ctx context.Context, userID uuid.UUID, window calendar.Interval) ([]calendar.Event, error)
+}
+
+type CalendarService interface {
+	// RotateFeedToken issues a new feed token, invalidating the old one
+	RotateFeedToken(ctx context.Context, userID uuid.UUID) (*CalendarFeedResponse, error)
+	RevokeFeedToken(ctx context.Context, userID uuid.UUID) error
+
+	// Feed renders the .ics feed for the user owning token
+	Feed(ctx context.Context, token string) ([]byte, error)
+
+	// Import creates availability slots from recurring events and
+	// blackouts from one-off events
+	Import(ctx context.Context, userID uuid.UUID, r io.Reader) (*CalendarImportResult, error)
+
+	// AddEventSource registers another source of feed events
+	AddEventSource(source CalendarEventSource)
+}
+
+type CalendarFeedResponse struct {
+	Token   string `json:"token"`
+	FeedURL string `json:"feed_url"`
+}
+
+// CalendarImportResult summarises an import. Events that could not be
+// represented are skipped with a warning rather than failing the import.
+type CalendarImportResult struct {
+	SlotsImported     int      `json:"slots_imported"`
+	BlackoutsImported int      `json:"blackouts_imported"`
+	Skipped           int      `json:"skipped"`
+	Warnings          []string `json:"warnings,omitempty"`
+}
+
+type calendarService struct {
+	db      *gorm.DB
+	baseURL string
+	uidHost string
+
+	mu           sync.RWMutex
+	eventSources []CalendarEventSource
+}
+
+func NewCalendarService(db *gorm.DB, cfg config.Config) CalendarService {
+	return &calendarService{db: db, baseURL: cfg.Server.BaseURL, uidHost: calendarUIDHost(cfg.Server.BaseURL)}
+}
+
+// calendarUIDHost is the domain part of the event UIDs we publish, so they
+// stay unique across calendars
+func calendarUIDHost(baseURL string) string {
+	if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "skillswap"
+}
+
+func (s *calendarService) AddEventSource(source CalendarEventSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.eventSources = append(s.eventSources, source)
+}
+
+// RotateFeedToken stores only the hash of the new token; the token itself is
+// shown once
+func (s *calendarService) RotateFeedToken(ctx context.Context, userID uuid.UUID) (*CalendarFeedResponse, error) {
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.db.WithContext(ctx).Model(&models.User{}).Where("user_id = ?", userID).Update("calendar_token_hash", hash)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("user not found")
+	}
+
+	return &CalendarFeedResponse{
+		Token:   token,
+		FeedURL: fmt.Sprintf("%s/api/v1/calendar/%s.ics", s.baseURL, token),
+	}, nil
+}
+
+func (s *calendarService) RevokeFeedToken(ctx context.Context, userID uuid.UUID) error {
+	return s.db.WithContext(ctx).Model(&models.User{}).Where("user_id = ?", userID).Update("calendar_token_hash", nil).Error
+}
+
+// Feed exports weekly slots as recurring events, exceptions and events from
+// the registered sources as one-off events
+func (s *calendarService) Feed(ctx context.Context, token string) ([]byte, error) {
+	if token == "" {
+		return nil, errors.New("calendar feed not found")
+	}
+
+	var user models.User
+	err := s.db.WithContext(ctx).Select("user_id", "name", "timezone").
+		Where("calendar_token_hash = ?", hashSecretToken(token)).
+		First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("calendar feed not found")
+		}
+		return nil, err
+	}
+
+	userLoc, err := loadLocation(user.Timezone)
+	if err != nil {
+		userLoc = time.UTC
+	}
+
+	var slots []models.AvailabilitySlot
+	if err := s.db.WithContext(ctx).Where("user_id = ?", user.UserID).Order("created_at ASC").Find(&slots).Error; err != nil {
+		return nil, err
+	}
+
+	events := make([]calendar.Event, 0, len(slots))
+	for _, slot := range slots {
+		events = append(events, s.slotEvent(slot, userLoc))
+	}
+
+	now := time.Now()
+	window := calendar.Interval{Start: now.Add(-calendarFeedPast), End: now.Add(calendarFeedFuture)}
+
+	var exceptions []models.AvailabilityException
+	err = s.db.WithContext(ctx).Where("user_id = ? AND starts_at < ? AND ends_at > ?", user.UserID, window.End, window.Start).
+		Order("starts_at ASC").
+		Find(&exceptions).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, exception := range exceptions {
+		events = append(events, s.exceptionEvent(exception))
+	}
+
+	s.mu.RLock()
+	sources := s.eventSources
+	s.mu.RUnlock()
+	for _, source := range sources {
+		sourceEvents, err := source.CalendarEvents(ctx, user.UserID, window)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, sourceEvents...)
+	}
+
+	var buf bytes.Buffer
+	if err := calendar.EncodeICal(&buf, user.Name+" · Skill Swap", events); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// slotEvent turns a weekly slot into a recurring event starting on the first
+// matching day on or after the slot was created
+func (s *calendarService) slotEvent(slot models.AvailabilitySlot, userLoc *time.Location) calendar.Event {
+	loc := userLoc
+	if slot.Timezone != nil {
+		if l, err := loadLocation(slot.Timezone); err == nil {
+			loc = l
+		}
+	}
+
+	created := slot.CreatedAt.In(loc)
+	day := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i < 7 && slot.DayBitmask&calendar.DayBit(day.Weekday()) == 0; i++ {
+		day = day.AddDate(0, 0, 1)
+	}
+
+	start := calendar.ClockOf(slot.StartTime)
+	end := calendar.ClockOf(slot.EndTime)
+	endDay := day
+	if end <= start {
+		endDay = day.AddDate(0, 0, 1)
+	}
+
+	return calendar.Event{
+		UID:         fmt.Sprintf("slot-%s@%s", slot.SlotID, s.uidHost),
+		Summary:     "Available: " + slot.Label,
+		Start:       time.Date(day.Year(), day.Month(), day.Day(), int(start)/60, int(start)%60, 0, 0, loc),
+		End:         time.Date(endDay.Year(), endDay.Month(), endDay.Day(), int(end)/60, int(end)%60, 0, 0, loc),
+		Location:    loc,
+		RRule:       &calendar.RRule{Freq: "WEEKLY", Days: slot.DayBitmask},
+		Transparent: true, // Availability should not show as busy
+	}
+}
+
+func (s *calendarService) exceptionEvent(exception models.AvailabilityException) calendar.Event {
+	event := calendar.Event{
+		UID:   fmt.Sprintf("exception-%s@%s", exception.ExceptionID, s.uidHost),
+		Start: exception.StartsAt,
+		End:   exception.EndsAt,
+	}
+	if exception.Kind == models.ExceptionBlackout {
+		event.Summary = "Unavailable"
+	} else {
+		event.Summary = "Available"
+		event.Transparent = true
+	}
+	if exception.Label != "" {
+		event.Summary += ": " + exception.Label
+	}
+	return event
+}
+
+// Import maps recurring weekly or daily events to availability slots and
+// future one-off events to blackouts. Entries are keyed by the event UID, so
+// importing the same calendar again updates them instead of adding copies.
+func (s *calendarService) Import(ctx context.Context, userID uuid.UUID, r io.Reader) (*CalendarImportResult, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("user_id", "timezone").Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	userLoc, err := loadLocation(user.Timezone)
+	if err != nil {
+		userLoc = time.UTC
+	}
+
+	events, err := calendar.ParseICal(r, userLoc)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CalendarImportResult{}
+	skip := func(event calendar.Event, reason string) {
+		result.Skipped++
+		name := event.Summary
+		if name == "" {
+			name = event.UID
+		}
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%q: %s", name, reason))
+	}
+
+	now := time.Now()
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, event := range events {
+			switch {
+			case event.Cancelled:
+				continue
+			case strings.HasSuffix(event.UID, "@"+s.uidHost):
+				// Our own feed imported back
+				continue
+			case event.RRule != nil:
+				slot, reason := importSlot(userID, event, now)
+				if slot == nil {
+					skip(event, reason)
+					continue
+				}
+				err := tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "user_id"}, {Name: "ical_uid"}},
+					DoUpdates: clause.AssignmentColumns([]string{"label", "day_bitmask", "start_time", "end_time", "timezone"}),
+				}).Create(slot).Error
+				if err != nil {
+					return err
+				}
+				result.SlotsImported++
+				if event.RRule.Until != nil || event.RRule.Count > 0 {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("%q: imported without its end date", slot.Label))
+				}
+			default:
+				if event.Transparent || !event.End.After(now) {
+					continue
+				}
+				if event.End.Sub(event.Start) > maxScheduleRange {
+					skip(event, "longer than 93 days")
+					continue
+				}
+				uid := importUID(event) + "/" + event.Start.UTC().Format(time.RFC3339)
+				blackout := &models.AvailabilityException{
+					UserID:   userID,
+					Kind:     models.ExceptionBlackout,
+					Label:    truncateLabel(event.Summary),
+					StartsAt: event.Start,
+					EndsAt:   event.End,
+					ICalUID:  &uid,
+				}
+				err := tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "user_id"}, {Name: "ical_uid"}},
+					DoUpdates: clause.AssignmentColumns([]string{"label", "starts_at", "ends_at"}),
+				}).Create(blackout).Error
+				if err != nil {
+					return err
+				}
+				result.BlackoutsImported++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// importSlot converts a recurring event to a weekly slot, or explains why it
+// cannot be one
+func importSlot(userID uuid.UUID, event calendar.Event, now time.Time) (*models.AvailabilitySlot, string) {
+	rule := event.RRule
+	if rule.Interval > 1 {
+		return nil, "only rules repeating every day or every week can be imported"
+	}
+	if rule.Until != nil && rule.Until.Before(now) {
+		return nil, "recurrence has ended"
+	}
+	if event.AllDay {
+		return nil, "all-day recurring events cannot be availability slots"
+	}
+	if event.End.Sub(event.Start) >= 24*time.Hour || !event.End.After(event.Start) {
+		return nil, "occurrences must be shorter than a day"
+	}
+
+	var days int32
+	switch rule.Freq {
+	case "DAILY":
+		days = calendar.AllDays
+	case "WEEKLY":
+		days = rule.Days
+		if days == 0 {
+			days = calendar.DayBit(event.Start.Weekday())
+		}
+	default:
+		return nil, "only daily and weekly recurrences can be imported"
+	}
+
+	loc := event.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	timezone := loc.String()
+	start := event.Start.In(loc)
+	end := event.End.In(loc)
+	startTime := time.Date(0, 1, 1, start.Hour(), start.Minute(), 0, 0, time.UTC)
+	endTime := time.Date(0, 1, 1, end.Hour(), end.Minute(), 0, 0, time.UTC)
+	if startTime.Equal(endTime) {
+		// Slots are kept to the minute; this one would be empty
+		return nil, "occurrences must last at least a minute"
+	}
+	uid := importUID(event)
+
+	return &models.AvailabilitySlot{
+		UserID:     userID,
+		Label:      truncateLabel(event.Summary),
+		DayBitmask: days,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Timezone:   &timezone,
+		ICalUID:    &uid,
+	}, ""
+}
+
+// importUID identifies an imported event; events without a UID are keyed by
+// their content
+func importUID(event calendar.Event) string {
+	if event.UID != "" {
+		return event.UID
+	}
+	sum := sha256.Sum256([]byte(event.Summary + "|" + event.Start.UTC().Format(time.RFC3339) + "|" + event.End.UTC().Format(time.RFC3339)))
+	return hex.EncodeToString(sum[:16])
+}
+
+func truncateLabel(label string) string {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return "Imported"
+	}
+	if runes := []rune(label); len(runes) > 100 {
+		return string(runes[:100])
+	}
+	return label
+}
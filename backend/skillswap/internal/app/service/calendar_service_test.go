@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/calendar"
+	"github.com/google/uuid"
+)
+
+func TestImportSlot(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-24 * time.Hour)
+	monday := time.Date(2024, 6, 3, 18, 0, 0, 0, berlin)
+
+	event := func(start, end time.Time, rule calendar.RRule) calendar.Event {
+		return calendar.Event{UID: "event-1", Summary: "Piano practice", Start: start, End: end, Location: berlin, RRule: &rule}
+	}
+	weekly := calendar.RRule{Freq: "WEEKLY", Interval: 1}
+
+	tests := []struct {
+		name       string
+		event      calendar.Event
+		days       int32
+		start, end string // HH:MM; empty when the event cannot be imported
+		reason     string
+	}{
+		{name: "weekly on the start's weekday", event: event(monday, monday.Add(90*time.Minute), weekly), days: calendar.Monday, start: "18:00", end: "19:30"},
+		{
+			name:  "weekly on listed days",
+			event: event(monday, monday.Add(time.Hour), calendar.RRule{Freq: "WEEKLY", Days: calendar.Tuesday | calendar.Thursday}),
+			days:  calendar.Tuesday | calendar.Thursday, start: "18:00", end: "19:00",
+		},
+		{name: "daily", event: event(monday, monday.Add(time.Hour), calendar.RRule{Freq: "DAILY"}), days: calendar.AllDays, start: "18:00", end: "19:00"},
+		{name: "seconds are dropped", event: event(monday.Add(10*time.Second), monday.Add(time.Hour+50*time.Second), weekly), days: calendar.Monday, start: "18:00", end: "19:00"},
+		{
+			name:   "start and end in the same minute",
+			event:  event(monday.Add(10*time.Second), monday.Add(50*time.Second), weekly),
+			reason: "occurrences must last at least a minute",
+		},
+		{name: "ends where it starts", event: event(monday, monday, weekly), reason: "occurrences must be shorter than a day"},
+		{name: "a day long", event: event(monday, monday.Add(24*time.Hour), weekly), reason: "occurrences must be shorter than a day"},
+		{
+			name:   "every other week",
+			event:  event(monday, monday.Add(time.Hour), calendar.RRule{Freq: "WEEKLY", Interval: 2}),
+			reason: "only rules repeating every day or every week can be imported",
+		},
+		{
+			name:   "monthly",
+			event:  event(monday, monday.Add(time.Hour), calendar.RRule{Freq: "MONTHLY"}),
+			reason: "only daily and weekly recurrences can be imported",
+		},
+		{name: "ended", event: event(monday, monday.Add(time.Hour), calendar.RRule{Freq: "WEEKLY", Until: &past}), reason: "recurrence has ended"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slot, reason := importSlot(uuid.New(), tt.event, now)
+			if tt.reason != "" {
+				if slot != nil || reason != tt.reason {
+					t.Fatalf("importSlot = %+v, %q, want it skipped because %q", slot, reason, tt.reason)
+				}
+				return
+			}
+			if slot == nil {
+				t.Fatalf("importSlot skipped the event: %s", reason)
+			}
+			if slot.DayBitmask != tt.days || slot.StartTime.Format("15:04") != tt.start || slot.EndTime.Format("15:04") != tt.end {
+				t.Errorf("slot on days %b from %s to %s, want %b from %s to %s",
+					slot.DayBitmask, slot.StartTime.Format("15:04"), slot.EndTime.Format("15:04"), tt.days, tt.start, tt.end)
+			}
+			if *slot.Timezone != "Europe/Berlin" || *slot.ICalUID != "event-1" || slot.Label != "Piano practice" {
+				t.Errorf("slot %q in %s with UID %s", slot.Label, *slot.Timezone, *slot.ICalUID)
+			}
+		})
+	}
+}
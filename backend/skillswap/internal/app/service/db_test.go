@@ -0,0 +1,28 @@
+package service
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/database"
+	"gorm.io/gorm"
+)
+
+// testDB connects to TEST_DATABASE_URL and migrates it. Tests that need
+// Postgres are skipped without it.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := database.Initialize(config.DatabaseConfig{URL: url, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -32,14 +33,14 @@ func NewFileUploadService(db *gorm.DB, cfg config.Config) *FileUploadService {
 
 	return &FileUploadService{
 		db:           db,
-		maxFileSize:  5 * 1024 * 1024, // 5MB default
+		maxFileSize:  cfg.Uploads.MaxPhotoSize,
 		allowedTypes: allowedTypes,
-		baseURL:      cfg.BaseURL,
+		baseURL:      cfg.Server.BaseURL,
 	}
 }
 
 // UploadUserPhoto uploads a user's profile photo to database
-func (s *FileUploadService) UploadUserPhoto(userID uuid.UUID, file *multipart.FileHeader) (*models.FileUploadResponse, error) {
+func (s *FileUploadService) UploadUserPhoto(ctx context.Context, userID uuid.UUID, file *multipart.FileHeader) (*models.FileUploadResponse, error) {
 	// Validate file
 	if err := s.validateFile(file); err != nil {
 		return nil, err
@@ -64,7 +65,7 @@ func (s *FileUploadService) UploadUserPhoto(userID uuid.UUID, file *multipart.Fi
 	photoURL := s.generatePhotoURL(userID)
 
 	// Update user's photo in database
-	result := s.db.Model(&models.User{}).
+	result := s.db.WithContext(ctx).Model(&models.User{}).
 		Where("user_id = ?", userID).
 		Updates(map[string]interface{}{
 			"photo_data":      photoData,
@@ -89,10 +90,10 @@ func (s *FileUploadService) UploadUserPhoto(userID uuid.UUID, file *multipart.Fi
 }
 
 // DeleteUserPhoto deletes a user's profile photo from database
-func (s *FileUploadService) DeleteUserPhoto(userID uuid.UUID) error {
+func (s *FileUploadService) DeleteUserPhoto(ctx context.Context, userID uuid.UUID) error {
 	// Check if user exists and has photo
 	var user models.User
-	if err := s.db.Select("photo_data").First(&user, "user_id = ?", userID).Error; err != nil {
+	if err := s.db.WithContext(ctx).Select("photo_data").First(&user, "user_id = ?", userID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return fmt.Errorf("user not found")
 		}
@@ -105,7 +106,7 @@ func (s *FileUploadService) DeleteUserPhoto(userID uuid.UUID) error {
 	}
 
 	// Clear photo data in database
-	result := s.db.Model(&models.User{}).
+	result := s.db.WithContext(ctx).Model(&models.User{}).
 		Where("user_id = ?", userID).
 		Updates(map[string]interface{}{
 			"photo_data":      nil,
@@ -121,9 +122,9 @@ func (s *FileUploadService) DeleteUserPhoto(userID uuid.UUID) error {
 }
 
 // GetUserPhoto returns user's photo data and MIME type
-func (s *FileUploadService) GetUserPhoto(userID uuid.UUID) ([]byte, string, error) {
+func (s *FileUploadService) GetUserPhoto(ctx context.Context, userID uuid.UUID) ([]byte, string, error) {
 	var user models.User
-	if err := s.db.Select("photo_data, photo_mime_type").First(&user, "user_id = ?", userID).Error; err != nil {
+	if err := s.db.WithContext(ctx).Select("photo_data, photo_mime_type").First(&user, "user_id = ?", userID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, "", fmt.Errorf("user not found")
 		}
@@ -143,9 +144,9 @@ func (s *FileUploadService) GetUserPhoto(userID uuid.UUID) ([]byte, string, erro
 }
 
 // GetFileInfo returns information about a user's photo
-func (s *FileUploadService) GetFileInfo(userID uuid.UUID) (*models.FileInfo, error) {
+func (s *FileUploadService) GetFileInfo(ctx context.Context, userID uuid.UUID) (*models.FileInfo, error) {
 	var user models.User
-	if err := s.db.Select("photo_data, photo_mime_type, photo_url, updated_at").First(&user, "user_id = ?", userID).Error; err != nil {
+	if err := s.db.WithContext(ctx).Select("photo_data, photo_mime_type, photo_url, updated_at").First(&user, "user_id = ?", userID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("user not found")
 		}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/geo"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+)
+
+// applyLocation stores the free-text location on the user and resolves it to
+// a place, coordinates and timezone. Unknown places keep the text but clear
+// the structured fields, so a stale position never outlives its location.
+// The timezone follows the place; callers apply an explicit choice after.
+func applyLocation(user *models.User, location string, geocoder geo.Geocoder) {
+	location = strings.TrimSpace(location)
+
+	user.LocationPlace = nil
+	user.Latitude = nil
+	user.Longitude = nil
+
+	if location == "" {
+		user.Location = nil
+		return
+	}
+	user.Location = &location
+
+	if geocoder == nil {
+		return
+	}
+
+	place, err := geocoder.Geocode(location)
+	if err != nil {
+		if !errors.Is(err, geo.ErrNotFound) {
+			log.Printf("Warning: Could not geocode location %q: %v", location, err)
+		}
+		return
+	}
+
+	user.LocationPlace = &place.Name
+	user.Latitude = &place.Latitude
+	user.Longitude = &place.Longitude
+	if place.Timezone != "" {
+		user.Timezone = &place.Timezone
+	}
+}
+
+// validateTimezone checks that name is a known IANA zone
+func validateTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return errors.New("invalid timezone")
+	}
+	return nil
+}
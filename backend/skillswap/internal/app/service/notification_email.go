@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jobs"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/mail"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobEmailDelivery is the scheduled job kind that sends one outbox email
+const JobEmailDelivery = "email_delivery"
+
+// unsubscribeAll in a token stops every optional email rather than one type
+const unsubscribeAll = "all"
+
+// UnsubscribeResponse describes what an unsubscribe link turns off
+type UnsubscribeResponse struct {
+	Email string                  `json:"email"`
+	Type  models.NotificationType `json:"type,omitempty"` // Empty for all optional emails
+}
+
+type emailPayload struct {
+	EmailID uuid.UUID `json:"email_id"`
+}
+
+// queueEmail renders the email version of a notification into the outbox,
+// subject to the recipient's email preferences. Low-priority notifications
+// held in the inbox for a digest are not emailed; the digest is.
+func (s *NotificationService) queueEmail(ctx context.Context, req *models.NotificationRequest, notificationID *uuid.UUID, now time.Time) error {
+	if !s.EmailEnabled() {
+		return nil
+	}
+
+	plan, err := s.planDelivery(ctx, req.UserID, req.Type, models.ChannelEmail, now)
+	if err != nil {
+		return err
+	}
+	if plan.Suppressed || (plan.DigestAt != nil && notificationID != nil) {
+		return nil
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("user_id", "name", "email").Where("user_id = ?", req.UserID).First(&user).Error; err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return nil
+	}
+
+	data := mail.NotificationData{
+		To:                user.Email,
+		RecipientName:     user.Name,
+		Type:              string(req.Type),
+		Title:             req.Title,
+		Message:           req.Message,
+		UnsubscribeAllURL: s.unsubscribeURL(req.UserID, unsubscribeAll),
+	}
+	if req.Type.Priority() != models.PriorityHigh {
+		data.UnsubscribeURL = s.unsubscribeURL(req.UserID, string(req.Type))
+	}
+	msg, err := mail.RenderNotification(data)
+	if err != nil {
+		return err
+	}
+
+	email := &models.OutboundEmail{
+		UserID:         req.UserID,
+		NotificationID: notificationID,
+		Type:           string(req.Type),
+		ToAddress:      msg.To,
+		Subject:        msg.Subject,
+		TextBody:       msg.Text,
+		HTMLBody:       msg.HTML,
+		Status:         models.EmailPending,
+		SendAfter:      now,
+	}
+	if data.UnsubscribeURL != "" {
+		email.UnsubscribeURL = &data.UnsubscribeURL
+	}
+	if plan.DeliverAt != nil {
+		email.SendAfter = *plan.DeliverAt
+	}
+	if err := s.db.WithContext(ctx).Create(email).Error; err != nil {
+		return err
+	}
+
+	return s.enqueueEmail(ctx, email)
+}
+
+// EmailEnabled reports whether a mail transport is configured
+func (s *NotificationService) EmailEnabled() bool {
+	return s.mailer != nil && s.scheduler != nil
+}
+
+// QueueAccountEmail puts an account email, such as a verification link, in
+// the outbox. Account emails ignore notification preferences.
+func (s *NotificationService) QueueAccountEmail(ctx context.Context, userID uuid.UUID, data mail.AccountEmailData) error {
+	if !s.EmailEnabled() {
+		return errors.New("email is not configured")
+	}
+
+	msg, err := mail.RenderAccountEmail(data)
+	if err != nil {
+		return err
+	}
+
+	email := &models.OutboundEmail{
+		UserID:    userID,
+		Type:      data.Kind,
+		ToAddress: msg.To,
+		Subject:   msg.Subject,
+		TextBody:  msg.Text,
+		HTMLBody:  msg.HTML,
+		Status:    models.EmailPending,
+		SendAfter: time.Now(),
+	}
+	if err := s.db.WithContext(ctx).Create(email).Error; err != nil {
+		return err
+	}
+
+	return s.enqueueEmail(ctx, email)
+}
+
+func (s *NotificationService) enqueueEmail(ctx context.Context, email *models.OutboundEmail) error {
+	return s.scheduler.Enqueue(ctx, jobs.Job{
+		Kind:      JobEmailDelivery,
+		Key:       fmt.Sprintf("%s:%s", JobEmailDelivery, email.EmailID),
+		SubjectID: &email.EmailID,
+		RunAt:     email.SendAfter,
+		Payload:   emailPayload{EmailID: email.EmailID},
+	})
+}
+
+// queueEmailOrWarn queues an email without failing the notification that
+// triggered it
+func (s *NotificationService) queueEmailOrWarn(ctx context.Context, req *models.NotificationRequest, notificationID *uuid.UUID, now time.Time) {
+	if err := s.queueEmail(ctx, req, notificationID, now); err != nil {
+		log.Printf("Warning: failed to queue %s email for user %s: %v", req.Type, req.UserID, err)
+	}
+}
+
+// ResumeEmails makes sure every pending outbox email has a delivery job,
+// e.g. after a crash between saving an email and scheduling it
+func (s *NotificationService) ResumeEmails(ctx context.Context) (int, error) {
+	if !s.EmailEnabled() {
+		return 0, nil
+	}
+
+	var pending []models.OutboundEmail
+	err := s.db.WithContext(ctx).Select("email_id", "send_after").Where("status = ?", models.EmailPending).Find(&pending).Error
+	if err != nil {
+		return 0, err
+	}
+	for i := range pending {
+		if err := s.enqueueEmail(ctx, &pending[i]); err != nil {
+			return 0, err
+		}
+	}
+	return len(pending), nil
+}
+
+// deliverEmail sends one outbox email. Failures are retried by the
+// scheduler; after the last attempt the email is marked failed.
+func (s *NotificationService) deliverEmail(ctx context.Context, job models.ScheduledJob) error {
+	var payload emailPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+
+	var email models.OutboundEmail
+	if err := s.db.WithContext(ctx).Where("email_id = ?", payload.EmailID).First(&email).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if email.Status != models.EmailPending {
+		return nil
+	}
+
+	sendErr := s.mailer.Send(outboxMessage(email))
+
+	updates := deliveryUpdates(sendErr, job.Attempts, time.Now())
+	if err := s.db.WithContext(ctx).Model(&models.OutboundEmail{}).Where("email_id = ?", email.EmailID).Updates(updates).Error; err != nil {
+		log.Printf("Warning: failed to record delivery of email %s: %v", email.EmailID, err)
+	}
+
+	return sendErr
+}
+
+// outboxMessage turns a stored email back into a message
+func outboxMessage(email models.OutboundEmail) mail.Message {
+	msg := mail.Message{
+		To:      email.ToAddress,
+		Subject: email.Subject,
+		Text:    email.TextBody,
+		HTML:    email.HTMLBody,
+	}
+	if email.UnsubscribeURL != nil {
+		// One-click unsubscribe (RFC 8058) from the mail client
+		msg.Headers = map[string]string{
+			"List-Unsubscribe":      "<" + *email.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
+	return msg
+}
+
+// deliveryUpdates records the outcome of a delivery attempt. A failed email
+// stays pending for the scheduler to retry until its last attempt.
+func deliveryUpdates(sendErr error, attempts int, now time.Time) map[string]interface{} {
+	updates := map[string]interface{}{"attempts": gorm.Expr("attempts + 1")}
+	switch {
+	case sendErr == nil:
+		updates["status"] = models.EmailSent
+		updates["sent_at"] = &now
+		updates["last_error"] = nil
+	case attempts >= jobs.MaxAttempts:
+		updates["status"] = models.EmailFailed
+		updates["last_error"] = sendErr.Error()
+	default:
+		updates["last_error"] = sendErr.Error()
+	}
+	return updates
+}
+
+// Unsubscribe turns off the email channel for the type in token, or for
+// every optional type
+func (s *NotificationService) Unsubscribe(ctx context.Context, token string) (*UnsubscribeResponse, error) {
+	response, userID, err := s.checkUnsubscribe(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	types := []models.NotificationType{response.Type}
+	if response.Type == "" {
+		types = nil
+		for _, notificationType := range models.NotificationTypes {
+			if notificationType.Priority() != models.PriorityHigh {
+				types = append(types, notificationType)
+			}
+		}
+	}
+
+	prefs := make([]models.NotificationPreference, len(types))
+	for i, notificationType := range types {
+		prefs[i] = models.NotificationPreference{UserID: userID, Type: notificationType, Channel: models.ChannelEmail, Enabled: false}
+	}
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(&prefs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// DescribeUnsubscribe says what an unsubscribe link would turn off, for a
+// confirmation page
+func (s *NotificationService) DescribeUnsubscribe(ctx context.Context, token string) (*UnsubscribeResponse, error) {
+	response, _, err := s.checkUnsubscribe(ctx, token)
+	return response, err
+}
+
+func (s *NotificationService) checkUnsubscribe(ctx context.Context, token string) (*UnsubscribeResponse, uuid.UUID, error) {
+	userID, scope, ok := s.parseUnsubscribeToken(token)
+	if !ok {
+		return nil, uuid.Nil, errors.New("invalid unsubscribe link")
+	}
+
+	response := &UnsubscribeResponse{}
+	if scope != unsubscribeAll {
+		notificationType := models.NotificationType(scope)
+		if !knownNotificationType(notificationType) || notificationType.Priority() == models.PriorityHigh {
+			return nil, uuid.Nil, errors.New("invalid unsubscribe link")
+		}
+		response.Type = notificationType
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("user_id", "email").Where("user_id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, uuid.Nil, errors.New("invalid unsubscribe link")
+		}
+		return nil, uuid.Nil, err
+	}
+	response.Email = user.Email
+
+	return response, userID, nil
+}
+
+// unsubscribeURL links to the unsubscribe endpoint with a signed token.
+// Tokens do not expire, so links in old emails keep working.
+func (s *NotificationService) unsubscribeURL(userID uuid.UUID, scope string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(userID.String() + ":" + scope))
+	token := payload + "." + base64.RawURLEncoding.EncodeToString(s.signUnsubscribe(payload))
+	return fmt.Sprintf("%s/api/v1/notifications/unsubscribe?token=%s", s.baseURL, url.QueryEscape(token))
+}
+
+func (s *NotificationService) parseUnsubscribeToken(token string) (uuid.UUID, string, bool) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return uuid.Nil, "", false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.signUnsubscribe(payload)) {
+		return uuid.Nil, "", false
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return uuid.Nil, "", false
+	}
+	id, scope, found := strings.Cut(string(raw), ":")
+	if !found {
+		return uuid.Nil, "", false
+	}
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, "", false
+	}
+	return userID, scope, true
+}
+
+func (s *NotificationService) signUnsubscribe(payload string) []byte {
+	mac := hmac.New(sha256.New, s.unsubscribeKey)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// unsubscribeKey derives the key for unsubscribe tokens from the JWT secret,
+// so a token of one kind can never pass as the other
+func unsubscribeKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("skillswap email unsubscribe"))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jobs"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/mail"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+)
+
+// fakeMailer records messages and fails while err is set
+type fakeMailer struct {
+	sent []mail.Message
+	err  error
+}
+
+func (m *fakeMailer) Send(msg mail.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func unsubscribeToken(t *testing.T, link string) string {
+	t.Helper()
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("token")
+}
+
+func TestUnsubscribeToken(t *testing.T) {
+	s := &NotificationService{baseURL: "https://skillswap.test", unsubscribeKey: unsubscribeKey("secret")}
+	userID := uuid.New()
+
+	link := s.unsubscribeURL(userID, string(models.NotificationTypeSwapRequest))
+	if !strings.HasPrefix(link, "https://skillswap.test/api/v1/notifications/unsubscribe?token=") {
+		t.Fatalf("unsubscribe URL = %s", link)
+	}
+	token := unsubscribeToken(t, link)
+	if id, scope, ok := s.parseUnsubscribeToken(token); !ok || id != userID || scope != "swap_request" {
+		t.Fatalf("parseUnsubscribeToken = %s, %q, %v", id, scope, ok)
+	}
+
+	payload, signature, _ := strings.Cut(token, ".")
+	otherUser := base64.RawURLEncoding.EncodeToString([]byte(uuid.NewString() + ":swap_request"))
+	allScope := base64.RawURLEncoding.EncodeToString([]byte(userID.String() + ":" + unsubscribeAll))
+	other := &NotificationService{unsubscribeKey: unsubscribeKey("another secret")}
+
+	tests := map[string]string{
+		"another user":          otherUser + "." + signature,
+		"widened scope":         allScope + "." + signature,
+		"altered signature":     payload + "." + base64.RawURLEncoding.EncodeToString([]byte("forged")),
+		"signature not base64":  payload + ".!!!",
+		"no signature":          payload,
+		"empty":                 "",
+		"signed with other key": unsubscribeToken(t, other.unsubscribeURL(userID, "swap_request")),
+	}
+	for name, tampered := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, ok := s.parseUnsubscribeToken(tampered); ok {
+				t.Error("tampered token accepted")
+			}
+			// Rejected before the database is consulted
+			if _, err := s.DescribeUnsubscribe(context.Background(), tampered); err == nil || err.Error() != "invalid unsubscribe link" {
+				t.Errorf("DescribeUnsubscribe error = %v", err)
+			}
+		})
+	}
+
+	// Emails that cannot be turned off have no valid link, even a signed one
+	alert := unsubscribeToken(t, s.unsubscribeURL(userID, string(models.NotificationTypeSystemAlert)))
+	if _, err := s.DescribeUnsubscribe(context.Background(), alert); err == nil {
+		t.Error("unsubscribe from a high-priority type accepted")
+	}
+}
+
+func TestOutboxMessage(t *testing.T) {
+	link := "https://skillswap.test/api/v1/notifications/unsubscribe?token=abc"
+	email := models.OutboundEmail{ToAddress: "ada@example.com", Subject: "Hi", TextBody: "text", HTMLBody: "<p>html</p>", UnsubscribeURL: &link}
+
+	msg := outboxMessage(email)
+	if msg.To != "ada@example.com" || msg.Subject != "Hi" || msg.Text != "text" || msg.HTML != "<p>html</p>" {
+		t.Errorf("message = %+v", msg)
+	}
+	if got := msg.Headers["List-Unsubscribe"]; got != "<"+link+">" {
+		t.Errorf("List-Unsubscribe = %q", got)
+	}
+	if got := msg.Headers["List-Unsubscribe-Post"]; got != "List-Unsubscribe=One-Click" {
+		t.Errorf("List-Unsubscribe-Post = %q", got)
+	}
+
+	email.UnsubscribeURL = nil
+	if msg := outboxMessage(email); len(msg.Headers) != 0 {
+		t.Errorf("headers %v on an email that cannot be unsubscribed from", msg.Headers)
+	}
+}
+
+func TestDeliveryUpdates(t *testing.T) {
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	sendErr := errors.New("connection refused")
+
+	tests := []struct {
+		name      string
+		sendErr   error
+		attempts  int
+		status    interface{} // Nil when the email stays pending
+		lastError interface{}
+	}{
+		{name: "sent", attempts: 1, status: models.EmailSent},
+		{name: "sent on a retry", attempts: 3, status: models.EmailSent},
+		{name: "failed, retried later", sendErr: sendErr, attempts: 1, lastError: "connection refused"},
+		{name: "failed on the last attempt", sendErr: sendErr, attempts: jobs.MaxAttempts, status: models.EmailFailed, lastError: "connection refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates := deliveryUpdates(tt.sendErr, tt.attempts, now)
+			if updates["status"] != tt.status {
+				t.Errorf("status = %v, want %v", updates["status"], tt.status)
+			}
+			if tt.lastError != nil && updates["last_error"] != tt.lastError {
+				t.Errorf("last_error = %v, want %v", updates["last_error"], tt.lastError)
+			}
+			if _, ok := updates["attempts"]; !ok {
+				t.Error("attempt not counted")
+			}
+			sentAt, _ := updates["sent_at"].(*time.Time)
+			if (tt.sendErr == nil) != (sentAt != nil && sentAt.Equal(now)) {
+				t.Errorf("sent_at = %v", updates["sent_at"])
+			}
+		})
+	}
+}
+
+func TestDeliverEmailRetriesFromOutbox(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	mailer := &fakeMailer{err: errors.New("connection refused")}
+	s := &NotificationService{db: db, mailer: mailer}
+
+	user := &models.User{Name: "Outbox", Email: "outbox-" + uuid.NewString() + "@example.com", PasswordHash: "x"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	link := "https://skillswap.test/api/v1/notifications/unsubscribe?token=abc"
+	email := &models.OutboundEmail{
+		UserID: user.UserID, Type: "swap_request", ToAddress: user.Email, Subject: "Hi",
+		TextBody: "text", HTMLBody: "<p>html</p>", UnsubscribeURL: &link, Status: models.EmailPending, SendAfter: time.Now(),
+	}
+	if err := db.Create(email).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Delete(email)
+		db.Unscoped().Delete(user)
+	})
+
+	payload, _ := json.Marshal(emailPayload{EmailID: email.EmailID})
+	deliver := func(attempt int) error {
+		return s.deliverEmail(ctx, models.ScheduledJob{Kind: JobEmailDelivery, Payload: string(payload), Attempts: attempt})
+	}
+	reload := func() models.OutboundEmail {
+		var stored models.OutboundEmail
+		if err := db.Where("email_id = ?", email.EmailID).First(&stored).Error; err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+
+	if err := deliver(1); err == nil {
+		t.Fatal("failed delivery not reported, so it would not be retried")
+	}
+	if stored := reload(); stored.Status != models.EmailPending || stored.Attempts != 1 || stored.LastError == nil {
+		t.Fatalf("after a failure: status %s, %d attempts, last error %v", stored.Status, stored.Attempts, stored.LastError)
+	}
+
+	mailer.err = nil
+	if err := deliver(2); err != nil {
+		t.Fatal(err)
+	}
+	stored := reload()
+	if stored.Status != models.EmailSent || stored.Attempts != 2 || stored.SentAt == nil || stored.LastError != nil {
+		t.Fatalf("after a retry: status %s, %d attempts, sent at %v, last error %v", stored.Status, stored.Attempts, stored.SentAt, stored.LastError)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].Headers["List-Unsubscribe"] != "<"+link+">" {
+		t.Fatalf("sent %+v", mailer.sent)
+	}
+
+	// A sent email is not sent again when its job runs twice
+	if err := deliver(3); err != nil || len(mailer.sent) != 1 {
+		t.Errorf("second run: error %v, %d sent", err, len(mailer.sent))
+	}
+}
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/calendar"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jobs"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobNotificationDigest is the scheduled job kind that sends a user's digest
+const JobNotificationDigest = "notification_digest"
+
+// Digests go out at this local time, on Mondays for weekly digests
+const digestClock = calendar.Clock(8 * 60)
+
+type NotificationPreferencesResponse struct {
+	Muted      bool                         `json:"muted"`
+	QuietHours *QuietHours                  `json:"quiet_hours"`
+	Digest     models.DigestMode            `json:"digest"`
+	Timezone   string                       `json:"timezone"` // Zone quiet hours and digests follow
+	Types      []NotificationTypePreference `json:"types"`
+}
+
+type QuietHours struct {
+	Start string `json:"start" binding:"required"` // "22:00"
+	End   string `json:"end" binding:"required"`   // "07:00"; before Start for overnight quiet hours
+}
+
+type NotificationTypePreference struct {
+	Type     models.NotificationType             `json:"type"`
+	Priority models.NotificationPriority         `json:"priority"`
+	Channels map[models.NotificationChannel]bool `json:"channels"`
+}
+
+type UpdateNotificationPreferencesDTO struct {
+	Muted           *bool                                                           `json:"muted"`
+	QuietHours      *QuietHours                                                     `json:"quiet_hours"`
+	ClearQuietHours bool                                                            `json:"clear_quiet_hours"`
+	Digest          *models.DigestMode                                              `json:"digest"`
+	Types           map[models.NotificationType]map[models.NotificationChannel]bool `json:"types"`
+}
+
+// deliveryPlan is what preferences make of one notification
+type deliveryPlan struct {
+	Suppressed bool
+	DeliverAt  *time.Time // Hold until then
+	DigestAt   *time.Time // Hold for the digest sent then
+}
+
+// digestPayload is stored with each digest job
+type digestPayload struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// RegisterJobs installs the digest and email delivery job handlers
+func (s *NotificationService) RegisterJobs(scheduler *jobs.Scheduler) {
+	scheduler.Register(JobNotificationDigest, s.runDigest)
+	scheduler.Register(JobEmailDelivery, s.deliverEmail)
+}
+
+func (s *NotificationService) GetPreferences(ctx context.Context, userID uuid.UUID) (*NotificationPreferencesResponse, error) {
+	settings, loc, err := s.notificationSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefs []models.NotificationPreference
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+	disabled := make(map[models.NotificationType]map[models.NotificationChannel]bool)
+	for _, pref := range prefs {
+		if !pref.Enabled {
+			if disabled[pref.Type] == nil {
+				disabled[pref.Type] = make(map[models.NotificationChannel]bool)
+			}
+			disabled[pref.Type][pref.Channel] = true
+		}
+	}
+
+	response := &NotificationPreferencesResponse{
+		Muted:    settings.Muted,
+		Digest:   settings.Digest,
+		Timezone: loc.String(),
+		Types:    make([]NotificationTypePreference, 0, len(models.NotificationTypes)),
+	}
+	if settings.QuietHoursStart != nil && settings.QuietHoursEnd != nil {
+		response.QuietHours = &QuietHours{
+			Start: calendar.ClockOf(*settings.QuietHoursStart).String(),
+			End:   calendar.ClockOf(*settings.QuietHoursEnd).String(),
+		}
+	}
+
+	for _, notificationType := range models.NotificationTypes {
+		channels := make(map[models.NotificationChannel]bool, len(models.NotificationChannels))
+		for _, channel := range models.NotificationChannels {
+			channels[channel] = notificationType.Priority() == models.PriorityHigh || !disabled[notificationType][channel]
+		}
+		response.Types = append(response.Types, NotificationTypePreference{
+			Type:     notificationType,
+			Priority: notificationType.Priority(),
+			Channels: channels,
+		})
+	}
+
+	return response, nil
+}
+
+// UpdatePreferences applies the fields that are set. Turning the digest off
+// or changing its schedule releases or reschedules held notifications.
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID uuid.UUID, req *UpdateNotificationPreferencesDTO) (*NotificationPreferencesResponse, error) {
+	settings, _, err := s.notificationSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	previousDigest := settings.Digest
+
+	if req.Muted != nil {
+		settings.Muted = *req.Muted
+	}
+	if req.ClearQuietHours {
+		settings.QuietHoursStart = nil
+		settings.QuietHoursEnd = nil
+	} else if req.QuietHours != nil {
+		start, err := time.Parse("15:04", req.QuietHours.Start)
+		if err != nil {
+			return nil, errors.New("invalid quiet hours start, use HH:MM")
+		}
+		end, err := time.Parse("15:04", req.QuietHours.End)
+		if err != nil {
+			return nil, errors.New("invalid quiet hours end, use HH:MM")
+		}
+		if start.Equal(end) {
+			return nil, errors.New("quiet hours must not start and end at the same time")
+		}
+		settings.QuietHoursStart = &start
+		settings.QuietHoursEnd = &end
+	}
+	if req.Digest != nil {
+		switch *req.Digest {
+		case models.DigestOff, models.DigestDaily, models.DigestWeekly:
+			settings.Digest = *req.Digest
+		default:
+			return nil, errors.New("digest must be off, daily or weekly")
+		}
+	}
+
+	var prefs []models.NotificationPreference
+	for notificationType, channels := range req.Types {
+		if !knownNotificationType(notificationType) {
+			return nil, fmt.Errorf("unknown notification type: %s", notificationType)
+		}
+		for channel, enabled := range channels {
+			if channel != models.ChannelInApp && channel != models.ChannelEmail {
+				return nil, fmt.Errorf("unknown notification channel: %s", channel)
+			}
+			if !enabled && notificationType.Priority() == models.PriorityHigh {
+				return nil, fmt.Errorf("%s notifications cannot be turned off", notificationType)
+			}
+			prefs = append(prefs, models.NotificationPreference{UserID: userID, Type: notificationType, Channel: channel, Enabled: enabled})
+		}
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"muted", "quiet_hours_start", "quiet_hours_end", "digest", "updated_at"}),
+		}).Create(settings).Error
+		if err != nil {
+			return err
+		}
+		if len(prefs) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "channel"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+		}).Create(&prefs).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.Digest != previousDigest {
+		if err := s.rescheduleDigest(ctx, userID, settings.Digest); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetPreferences(ctx, userID)
+}
+
+// planDelivery applies the user's preferences to a notification about to be
+// delivered on channel
+func (s *NotificationService) planDelivery(ctx context.Context, userID uuid.UUID, notificationType models.NotificationType, channel models.NotificationChannel, now time.Time) (deliveryPlan, error) {
+	if notificationType.Priority() == models.PriorityHigh {
+		return deliveryPlan{}, nil
+	}
+
+	settings, loc, err := s.notificationSettings(ctx, userID)
+	if err != nil {
+		return deliveryPlan{}, err
+	}
+	if settings.Muted {
+		return deliveryPlan{Suppressed: true}, nil
+	}
+
+	var pref models.NotificationPreference
+	err = s.db.WithContext(ctx).Where("user_id = ? AND type = ? AND channel = ?", userID, notificationType, channel).First(&pref).Error
+	if err == nil && !pref.Enabled {
+		return deliveryPlan{Suppressed: true}, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return deliveryPlan{}, err
+	}
+
+	if notificationType.Priority() == models.PriorityLow && settings.Digest != models.DigestOff && s.scheduler != nil {
+		digestAt := nextDigest(now, loc, settings.Digest)
+		return deliveryPlan{DigestAt: &digestAt}, nil
+	}
+
+	if settings.QuietHoursStart != nil && settings.QuietHoursEnd != nil {
+		if end, quiet := quietHoursEnd(now, loc, *settings.QuietHoursStart, *settings.QuietHoursEnd); quiet {
+			return deliveryPlan{DeliverAt: &end}, nil
+		}
+	}
+
+	return deliveryPlan{}, nil
+}
+
+// notificationSettings loads the user's settings, or the defaults, and the
+// timezone they are interpreted in
+func (s *NotificationService) notificationSettings(ctx context.Context, userID uuid.UUID) (*models.NotificationSettings, *time.Location, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("user_id", "timezone").Where("user_id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("user not found")
+		}
+		return nil, nil, err
+	}
+	loc, err := loadLocation(user.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	settings := &models.NotificationSettings{UserID: userID, Digest: models.DigestOff}
+	err = s.db.WithContext(ctx).Where("user_id = ?", userID).First(settings).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+	return settings, loc, nil
+}
+
+// scheduleDigest makes sure a digest job exists for the digest at digestAt
+func (s *NotificationService) scheduleDigest(ctx context.Context, userID uuid.UUID, digestAt time.Time) error {
+	return s.scheduler.Enqueue(ctx, jobs.Job{
+		Kind:      JobNotificationDigest,
+		Key:       fmt.Sprintf("%s:%s:%d", JobNotificationDigest, userID, digestAt.Unix()),
+		SubjectID: &userID,
+		RunAt:     digestAt,
+		Payload:   digestPayload{UserID: userID},
+	})
+}
+
+// rescheduleDigest moves held notifications to the new digest schedule, or
+// releases them into the inbox when the digest is turned off
+func (s *NotificationService) rescheduleDigest(ctx context.Context, userID uuid.UUID, mode models.DigestMode) error {
+	if s.scheduler == nil {
+		return nil
+	}
+	if err := s.scheduler.Cancel(ctx, JobNotificationDigest, userID); err != nil {
+		return err
+	}
+
+	if mode == models.DigestOff {
+		return s.db.WithContext(ctx).Model(&models.Notification{}).
+			Where("user_id = ? AND digest_pending", userID).
+			Update("digest_pending", false).Error
+	}
+
+	var held int64
+	if err := s.db.WithContext(ctx).Model(&models.Notification{}).Where("user_id = ? AND digest_pending", userID).Count(&held).Error; err != nil {
+		return err
+	}
+	if held == 0 {
+		return nil
+	}
+
+	_, loc, err := s.notificationSettings(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.scheduleDigest(ctx, userID, nextDigest(time.Now(), loc, mode))
+}
+
+// runDigest sends one summary of the user's held notifications. The held
+// notifications move to the inbox already marked as read.
+func (s *NotificationService) runDigest(ctx context.Context, job models.ScheduledJob) error {
+	var payload digestPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+
+	var held []models.Notification
+	if err := s.db.WithContext(ctx).Where("user_id = ? AND digest_pending", payload.UserID).Order("created_at ASC").Find(&held).Error; err != nil {
+		return err
+	}
+	if len(held) == 0 {
+		return nil
+	}
+
+	counts := make(map[models.NotificationType]int)
+	for _, notification := range held {
+		counts[notification.Type]++
+	}
+	types := make([]string, 0, len(counts))
+	for notificationType, n := range counts {
+		types = append(types, digestLine(notificationType, n))
+	}
+	sort.Strings(types)
+
+	settings, _, err := s.notificationSettings(ctx, payload.UserID)
+	if err != nil {
+		return err
+	}
+	title := "Your Digest"
+	switch settings.Digest {
+	case models.DigestDaily:
+		title = "Your Daily Digest"
+	case models.DigestWeekly:
+		title = "Your Weekly Digest"
+	}
+
+	ids := make([]uuid.UUID, len(held))
+	for i, notification := range held {
+		ids[i] = notification.NotificationID
+	}
+
+	summary := &models.Notification{
+		UserID:  payload.UserID,
+		Type:    models.NotificationTypeDigest,
+		Title:   title,
+		Message: "Since your last digest: " + strings.Join(types, ", "),
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(summary).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Notification{}).Where("notification_id IN ?", ids).
+			Updates(map[string]interface{}{"digest_pending": false, "is_read": true}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	req := &models.NotificationRequest{UserID: summary.UserID, Type: summary.Type, Title: summary.Title, Message: summary.Message}
+	s.queueEmailOrWarn(ctx, req, &summary.NotificationID, time.Now())
+	return nil
+}
+
+// digestLine describes n held notifications of one type, e.g. "2 new ratings"
+func digestLine(notificationType models.NotificationType, n int) string {
+	var one, many string
+	switch notificationType {
+	case models.NotificationTypeNewRating:
+		one, many = "new rating", "new ratings"
+	case models.NotificationTypeSkillMatched:
+		one, many = "skill match", "skill matches"
+	case models.NotificationTypeSwapCompleted:
+		one, many = "completed swap", "completed swaps"
+	default:
+		one, many = string(notificationType)+" notification", string(notificationType)+" notifications"
+	}
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// nextDigest returns the next digest time after now: 08:00 local every day,
+// or on Mondays for weekly digests
+func nextDigest(now time.Time, loc *time.Location, mode models.DigestMode) time.Time {
+	days := calendar.AllDays
+	if mode == models.DigestWeekly {
+		days = calendar.DayBit(time.Monday)
+	}
+	rule := calendar.WeeklyRule{Days: days, Start: digestClock, End: digestClock + 1, Location: loc}
+	occurrences := rule.Expand(calendar.Interval{Start: now.Add(time.Second), End: now.AddDate(0, 0, 8)})
+	if len(occurrences) == 0 {
+		// Unreachable: an eight-day window always contains a Monday
+		log.Printf("Warning: no digest time found after %s", now)
+		return now.Add(24 * time.Hour)
+	}
+	return occurrences[0].Start
+}
+
+// quietHoursEnd reports whether now falls within the quiet hours and, if so,
+// when they end. start and end are wall-clock times in loc.
+func quietHoursEnd(now time.Time, loc *time.Location, start, end time.Time) (time.Time, bool) {
+	rule := calendar.WeeklyRule{
+		Days:     calendar.AllDays,
+		Start:    calendar.ClockOf(start),
+		End:      calendar.ClockOf(end),
+		Location: loc,
+	}
+	// Expand clips to the window, so an ongoing quiet period starts at now
+	occurrences := rule.Expand(calendar.Interval{Start: now, End: now.Add(25 * time.Hour)})
+	if len(occurrences) > 0 && !occurrences[0].Start.After(now) {
+		return occurrences[0].End, true
+	}
+	return time.Time{}, false
+}
+
+func knownNotificationType(notificationType models.NotificationType) bool {
+	for _, known := range models.NotificationTypes {
+		if known == notificationType {
+			return true
+		}
+	}
+	return false
+}
@@ -1,44 +1,88 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jobs"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/mail"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type NotificationService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	scheduler *jobs.Scheduler // Sends digests and emails; without one both are off
+	mailer    mail.Mailer     // Nil turns the email channel off
+
+	baseURL        string
+	unsubscribeKey []byte
 }
 
-func NewNotificationService(db *gorm.DB) *NotificationService {
-	return &NotificationService{db: db}
+func NewNotificationService(db *gorm.DB, scheduler *jobs.Scheduler, mailer mail.Mailer, cfg config.Config) *NotificationService {
+	return &NotificationService{
+		db:             db,
+		scheduler:      scheduler,
+		mailer:         mailer,
+		baseURL:        cfg.Server.BaseURL,
+		unsubscribeKey: unsubscribeKey(cfg.Auth.JWTSecret),
+	}
 }
 
-// CreateNotification creates a new notification
-func (s *NotificationService) CreateNotification(req *models.NotificationRequest) (*models.Notification, error) {
+// CreateNotification creates a new notification, subject to the recipient's
+// preferences, and queues its email. It returns nil without an error when
+// the recipient has turned the in-app notification off. Notifications
+// arriving in quiet hours or held for a digest are created but hidden until
+// they are due.
+func (s *NotificationService) CreateNotification(ctx context.Context, req *models.NotificationRequest) (*models.Notification, error) {
+	now := time.Now()
+	plan, err := s.planDelivery(ctx, req.UserID, req.Type, models.ChannelInApp, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply notification preferences: %w", err)
+	}
+	if plan.Suppressed {
+		s.queueEmailOrWarn(ctx, req, nil, now)
+		return nil, nil
+	}
+
 	notification := &models.Notification{
-		UserID:    req.UserID,
-		Type:      req.Type,
-		Title:     req.Title,
-		Message:   req.Message,
-		RelatedID: req.RelatedID,
-		IsRead:    false,
+		UserID:        req.UserID,
+		Type:          req.Type,
+		Title:         req.Title,
+		Message:       req.Message,
+		RelatedID:     req.RelatedID,
+		IsRead:        false,
+		DeliverAt:     plan.DeliverAt,
+		DigestPending: plan.DigestAt != nil,
 	}
 
-	if err := s.db.Create(notification).Error; err != nil {
+	if err := s.db.WithContext(ctx).Create(notification).Error; err != nil {
 		return nil, fmt.Errorf("failed to create notification: %w", err)
 	}
 
+	if plan.DigestAt != nil {
+		if err := s.scheduleDigest(ctx, req.UserID, *plan.DigestAt); err != nil {
+			return nil, fmt.Errorf("failed to schedule digest: %w", err)
+		}
+	}
+
+	s.queueEmailOrWarn(ctx, req, &notification.NotificationID, now)
+
 	return notification, nil
 }
 
+// visibleNotifications hides notifications held for quiet hours or a digest
+func visibleNotifications(db *gorm.DB) *gorm.DB {
+	return db.Where("NOT digest_pending AND (deliver_at IS NULL OR deliver_at <= ?)", time.Now())
+}
+
 // CreateSwapRequestNotification creates notification for swap request
-func (s *NotificationService) CreateSwapRequestNotification(receiverID, requesterID uuid.UUID, swapRequestID uuid.UUID, skillName string) error {
+func (s *NotificationService) CreateSwapRequestNotification(ctx context.Context, receiverID, requesterID uuid.UUID, swapRequestID uuid.UUID, skillName string) error {
 	var requester models.User
-	if err := s.db.Select("name").First(&requester, "user_id = ?", requesterID).Error; err != nil {
+	if err := s.db.WithContext(ctx).Select("name").First(&requester, "user_id = ?", requesterID).Error; err != nil {
 		return fmt.Errorf("failed to get requester info: %w", err)
 	}
 
@@ -50,12 +94,12 @@ func (s *NotificationService) CreateSwapRequestNotification(receiverID, requeste
 		RelatedID: &swapRequestID,
 	}
 
-	_, err := s.CreateNotification(req)
+	_, err := s.CreateNotification(ctx, req)
 	return err
 }
 
 // CreateSwapStatusNotification creates notification for swap status changes
-func (s *NotificationService) CreateSwapStatusNotification(userID uuid.UUID, swapRequestID uuid.UUID, status string, skillName string) error {
+func (s *NotificationService) CreateSwapStatusNotification(ctx context.Context, userID uuid.UUID, swapRequestID uuid.UUID, status string, skillName string) error {
 	var title, message string
 	var notificationType models.NotificationType
 
@@ -84,14 +128,14 @@ func (s *NotificationService) CreateSwapStatusNotification(userID uuid.UUID, swa
 		RelatedID: &swapRequestID,
 	}
 
-	_, err := s.CreateNotification(req)
+	_, err := s.CreateNotification(ctx, req)
 	return err
 }
 
 // CreateRatingNotification creates notification for new rating
-func (s *NotificationService) CreateRatingNotification(userID, raterID uuid.UUID, rating int, comment string) error {
+func (s *NotificationService) CreateRatingNotification(ctx context.Context, userID, raterID uuid.UUID, rating int, comment string) error {
 	var rater models.User
-	if err := s.db.Select("name").First(&rater, "user_id = ?", raterID).Error; err != nil {
+	if err := s.db.WithContext(ctx).Select("name").First(&rater, "user_id = ?", raterID).Error; err != nil {
 		return fmt.Errorf("failed to get rater info: %w", err)
 	}
 
@@ -107,12 +151,12 @@ func (s *NotificationService) CreateRatingNotification(userID, raterID uuid.UUID
 		Message: message,
 	}
 
-	_, err := s.CreateNotification(req)
+	_, err := s.CreateNotification(ctx, req)
 	return err
 }
 
 // CreateSkillMatchNotification creates notification for skill matches
-func (s *NotificationService) CreateSkillMatchNotification(userID uuid.UUID, matchedUsers []string, skillName string) error {
+func (s *NotificationService) CreateSkillMatchNotification(ctx context.Context, userID uuid.UUID, matchedUsers []string, skillName string) error {
 	message := fmt.Sprintf("Found %d potential matches for your wanted skill: %s", len(matchedUsers), skillName)
 	if len(matchedUsers) > 0 {
 		message += fmt.Sprintf(". Users: %v", matchedUsers)
@@ -125,12 +169,114 @@ func (s *NotificationService) CreateSkillMatchNotification(userID uuid.UUID, mat
 		Message: message,
 	}
 
-	_, err := s.CreateNotification(req)
+	_, err := s.CreateNotification(ctx, req)
+	return err
+}
+
+// CreateBookingNotification tells a participant about a booked, moved or
+// cancelled session, with the time shown in their own timezone
+func (s *NotificationService) CreateBookingNotification(ctx context.Context, userID, partnerID, bookingID uuid.UUID, notificationType models.NotificationType, startsAt time.Time) error {
+	partnerName, loc, err := s.sessionParticipants(ctx, userID, partnerID)
+	if err != nil {
+		return err
+	}
+	when := startsAt.In(loc).Format("Mon 2 Jan 2006, 15:04 MST")
+
+	var title, message string
+	switch notificationType {
+	case models.NotificationTypeBookingCreated:
+		title = "Session Booked"
+		message = fmt.Sprintf("Your swap session with %s is booked for %s", partnerName, when)
+	case models.NotificationTypeBookingRescheduled:
+		title = "Session Rescheduled"
+		message = fmt.Sprintf("Your swap session with %s has moved to %s", partnerName, when)
+	case models.NotificationTypeBookingCancelled:
+		title = "Session Cancelled"
+		message = fmt.Sprintf("Your swap session with %s on %s has been cancelled", partnerName, when)
+	default:
+		return fmt.Errorf("unknown booking notification type: %s", notificationType)
+	}
+
+	req := &models.NotificationRequest{
+		UserID:    userID,
+		Type:      notificationType,
+		Title:     title,
+		Message:   message,
+		RelatedID: &bookingID,
+	}
+
+	_, err = s.CreateNotification(ctx, req)
 	return err
 }
 
+// CreateSessionReminderNotification reminds a participant of an upcoming
+// session
+func (s *NotificationService) CreateSessionReminderNotification(ctx context.Context, userID, partnerID, bookingID uuid.UUID, startsAt time.Time) error {
+	partnerName, loc, err := s.sessionParticipants(ctx, userID, partnerID)
+	if err != nil {
+		return err
+	}
+
+	req := &models.NotificationRequest{
+		UserID:    userID,
+		Type:      models.NotificationTypeSessionReminder,
+		Title:     "Upcoming Session",
+		Message:   fmt.Sprintf("Your swap session with %s starts in %s (%s)", partnerName, formatLeadTime(time.Until(startsAt)), startsAt.In(loc).Format("Mon 2 Jan 2006, 15:04 MST")),
+		RelatedID: &bookingID,
+	}
+
+	_, err = s.CreateNotification(ctx, req)
+	return err
+}
+
+// sessionParticipants returns the partner's name and the recipient's
+// timezone for session notifications
+func (s *NotificationService) sessionParticipants(ctx context.Context, userID, partnerID uuid.UUID) (string, *time.Location, error) {
+	var users []models.User
+	if err := s.db.WithContext(ctx).Select("user_id", "name", "timezone").Where("user_id IN ?", []uuid.UUID{userID, partnerID}).Find(&users).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to get booking participants: %w", err)
+	}
+
+	partnerName := "your swap partner"
+	loc := time.UTC
+	for _, user := range users {
+		if user.UserID == partnerID {
+			partnerName = user.Name
+		}
+		if user.UserID == userID && user.Timezone != nil {
+			if l, err := time.LoadLocation(*user.Timezone); err == nil {
+				loc = l
+			}
+		}
+	}
+
+	return partnerName, loc, nil
+}
+
+// formatLeadTime rounds to the largest sensible unit, e.g. "1 day", "3 hours"
+func formatLeadTime(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	d = d.Round(time.Minute)
+	switch {
+	case d >= 24*time.Hour:
+		return plural(int((d+12*time.Hour)/(24*time.Hour)), "day")
+	case d >= time.Hour:
+		return plural(int((d+30*time.Minute)/time.Hour), "hour")
+	case d >= time.Minute:
+		return plural(int(d/time.Minute), "minute")
+	default:
+		return "less than a minute"
+	}
+}
+
 // CreateSystemNotification creates system-wide notification
-func (s *NotificationService) CreateSystemNotification(userIDs []uuid.UUID, title, message string) error {
+func (s *NotificationService) CreateSystemNotification(ctx context.Context, userIDs []uuid.UUID, title, message string) error {
 	notifications := make([]models.Notification, len(userIDs))
 
 	for i, userID := range userIDs {
@@ -143,19 +289,25 @@ func (s *NotificationService) CreateSystemNotification(userIDs []uuid.UUID, titl
 		}
 	}
 
-	if err := s.db.Create(&notifications).Error; err != nil {
+	if err := s.db.WithContext(ctx).Create(&notifications).Error; err != nil {
 		return fmt.Errorf("failed to create system notifications: %w", err)
 	}
 
+	now := time.Now()
+	for _, notification := range notifications {
+		req := &models.NotificationRequest{UserID: notification.UserID, Type: notification.Type, Title: title, Message: message}
+		s.queueEmailOrWarn(ctx, req, &notification.NotificationID, now)
+	}
+
 	return nil
 }
 
 // GetUserNotifications retrieves notifications for a user with pagination
-func (s *NotificationService) GetUserNotifications(userID uuid.UUID, page, limit int, unreadOnly bool) ([]models.Notification, int64, error) {
+func (s *NotificationService) GetUserNotifications(ctx context.Context, userID uuid.UUID, page, limit int, unreadOnly bool) ([]models.Notification, int64, error) {
 	var notifications []models.Notification
 	var total int64
 
-	query := s.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	query := s.db.WithContext(ctx).Model(&models.Notification{}).Scopes(visibleNotifications).Where("user_id = ?", userID)
 
 	if unreadOnly {
 		query = query.Where("is_read = ?", false)
@@ -168,7 +320,7 @@ func (s *NotificationService) GetUserNotifications(userID uuid.UUID, page, limit
 
 	// Get paginated results
 	offset := (page - 1) * limit
-	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&notifications).Error; err != nil {
+	if err := query.Order("COALESCE(deliver_at, created_at) DESC").Offset(offset).Limit(limit).Find(&notifications).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to get notifications: %w", err)
 	}
 
@@ -176,8 +328,8 @@ func (s *NotificationService) GetUserNotifications(userID uuid.UUID, page, limit
 }
 
 // MarkNotificationsAsRead marks notifications as read
-func (s *NotificationService) MarkNotificationsAsRead(userID uuid.UUID, notificationIDs []uuid.UUID) error {
-	result := s.db.Model(&models.Notification{}).
+func (s *NotificationService) MarkNotificationsAsRead(ctx context.Context, userID uuid.UUID, notificationIDs []uuid.UUID) error {
+	result := s.db.WithContext(ctx).Model(&models.Notification{}).
 		Where("user_id = ? AND notification_id IN ?", userID, notificationIDs).
 		Update("is_read", true)
 
@@ -193,8 +345,8 @@ func (s *NotificationService) MarkNotificationsAsRead(userID uuid.UUID, notifica
 }
 
 // MarkAllAsRead marks all notifications as read for a user
-func (s *NotificationService) MarkAllAsRead(userID uuid.UUID) error {
-	if err := s.db.Model(&models.Notification{}).
+func (s *NotificationService) MarkAllAsRead(ctx context.Context, userID uuid.UUID) error {
+	if err := s.db.WithContext(ctx).Model(&models.Notification{}).Scopes(visibleNotifications).
 		Where("user_id = ? AND is_read = ?", userID, false).
 		Update("is_read", true).Error; err != nil {
 		return fmt.Errorf("failed to mark all notifications as read: %w", err)
@@ -204,8 +356,8 @@ func (s *NotificationService) MarkAllAsRead(userID uuid.UUID) error {
 }
 
 // DeleteNotification soft deletes a notification
-func (s *NotificationService) DeleteNotification(userID, notificationID uuid.UUID) error {
-	result := s.db.Where("user_id = ? AND notification_id = ?", userID, notificationID).
+func (s *NotificationService) DeleteNotification(ctx context.Context, userID, notificationID uuid.UUID) error {
+	result := s.db.WithContext(ctx).Where("user_id = ? AND notification_id = ?", userID, notificationID).
 		Delete(&models.Notification{})
 
 	if result.Error != nil {
@@ -220,18 +372,18 @@ func (s *NotificationService) DeleteNotification(userID, notificationID uuid.UUI
 }
 
 // GetNotificationStats returns notification statistics for a user
-func (s *NotificationService) GetNotificationStats(userID uuid.UUID) (*models.NotificationStatsResponse, error) {
+func (s *NotificationService) GetNotificationStats(ctx context.Context, userID uuid.UUID) (*models.NotificationStatsResponse, error) {
 	var stats models.NotificationStatsResponse
 
 	// Total notifications
-	if err := s.db.Model(&models.Notification{}).
+	if err := s.db.WithContext(ctx).Model(&models.Notification{}).Scopes(visibleNotifications).
 		Where("user_id = ?", userID).
 		Count(&stats.TotalNotifications).Error; err != nil {
 		return nil, fmt.Errorf("failed to count total notifications: %w", err)
 	}
 
 	// Unread notifications
-	if err := s.db.Model(&models.Notification{}).
+	if err := s.db.WithContext(ctx).Model(&models.Notification{}).Scopes(visibleNotifications).
 		Where("user_id = ? AND is_read = ?", userID, false).
 		Count(&stats.UnreadCount).Error; err != nil {
 		return nil, fmt.Errorf("failed to count unread notifications: %w", err)
@@ -243,10 +395,10 @@ func (s *NotificationService) GetNotificationStats(userID uuid.UUID) (*models.No
 }
 
 // CleanupOldNotifications removes notifications older than specified days
-func (s *NotificationService) CleanupOldNotifications(daysOld int) error {
+func (s *NotificationService) CleanupOldNotifications(ctx context.Context, daysOld int) error {
 	cutoffDate := time.Now().AddDate(0, 0, -daysOld)
 
-	result := s.db.Where("created_at < ?", cutoffDate).Delete(&models.Notification{})
+	result := s.db.WithContext(ctx).Where("created_at < ?", cutoffDate).Delete(&models.Notification{})
 	if result.Error != nil {
 		return fmt.Errorf("failed to cleanup old notifications: %w", result.Error)
 	}
@@ -255,10 +407,10 @@ func (s *NotificationService) CleanupOldNotifications(daysOld int) error {
 }
 
 // GetNotificationByID retrieves a specific notification
-func (s *NotificationService) GetNotificationByID(userID, notificationID uuid.UUID) (*models.Notification, error) {
+func (s *NotificationService) GetNotificationByID(ctx context.Context, userID, notificationID uuid.UUID) (*models.Notification, error) {
 	var notification models.Notification
 
-	if err := s.db.Where("user_id = ? AND notification_id = ?", userID, notificationID).
+	if err := s.db.WithContext(ctx).Scopes(visibleNotifications).Where("user_id = ? AND notification_id = ?", userID, notificationID).
 		First(&notification).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("notification not found")
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
@@ -10,19 +11,19 @@ import (
 
 type RatingService interface {
 	// Rating CRUD operations
-	CreateRating(req *CreateRatingDTO) (*models.SwapRating, error)
-	GetRatingByID(ratingID uuid.UUID) (*models.SwapRating, error)
-	UpdateRating(ratingID uuid.UUID, userID uuid.UUID, req *UpdateRatingDTO) (*models.SwapRating, error)
-	DeleteRating(ratingID uuid.UUID, userID uuid.UUID) error
+	CreateRating(ctx context.Context, req *CreateRatingDTO) (*models.SwapRating, error)
+	GetRatingByID(ctx context.Context, ratingID uuid.UUID) (*models.SwapRating, error)
+	UpdateRating(ctx context.Context, ratingID uuid.UUID, userID uuid.UUID, req *UpdateRatingDTO) (*models.SwapRating, error)
+	DeleteRating(ctx context.Context, ratingID uuid.UUID, userID uuid.UUID) error
 
 	// Rating queries
-	GetSwapRatings(swapID uuid.UUID) ([]models.SwapRating, error)
-	GetUserRatings(userID uuid.UUID, filter RatingFilter) ([]models.SwapRating, error)
-	GetUserRatingStats(userID uuid.UUID) (*UserRatingStats, error)
+	GetSwapRatings(ctx context.Context, swapID uuid.UUID) ([]models.SwapRating, error)
+	GetUserRatings(ctx context.Context, userID uuid.UUID, filter RatingFilter) ([]models.SwapRating, error)
+	GetUserRatingStats(ctx context.Context, userID uuid.UUID) (*UserRatingStats, error)
 
 	// Rating checks
-	CanUserRateSwap(swapID uuid.UUID, raterID uuid.UUID) (bool, error)
-	HasUserRatedSwap(swapID uuid.UUID, raterID uuid.UUID) (bool, error)
+	CanUserRateSwap(ctx context.Context, swapID uuid.UUID, raterID uuid.UUID) (bool, error)
+	HasUserRatedSwap(ctx context.Context, swapID uuid.UUID, raterID uuid.UUID) (bool, error)
 }
 
 // DTOs and Request structures
@@ -68,10 +69,10 @@ func NewRatingService(db *gorm.DB) RatingService {
 }
 
 // CreateRating creates a new rating for a completed swap
-func (r *ratingService) CreateRating(req *CreateRatingDTO) (*models.SwapRating, error) {
+func (r *ratingService) CreateRating(ctx context.Context, req *CreateRatingDTO) (*models.SwapRating, error) {
 	// Check if the swap exists and is completed (accepted)
 	var swap models.SwapRequest
-	err := r.db.First(&swap, "swap_id = ?", req.SwapID).Error
+	err := r.db.WithContext(ctx).First(&swap, "swap_id = ?", req.SwapID).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("swap request not found")
@@ -98,7 +99,7 @@ func (r *ratingService) CreateRating(req *CreateRatingDTO) (*models.SwapRating,
 
 	// Check if user has already rated this swap
 	var existingCount int64
-	r.db.Model(&models.SwapRating{}).
+	r.db.WithContext(ctx).Model(&models.SwapRating{}).
 		Where("swap_id = ? AND rater_id = ?", req.SwapID, req.RaterID).
 		Count(&existingCount)
 	if existingCount > 0 {
@@ -113,22 +114,22 @@ func (r *ratingService) CreateRating(req *CreateRatingDTO) (*models.SwapRating,
 		Comment: req.Comment,
 	}
 
-	err = r.db.Create(rating).Error
+	err = r.db.WithContext(ctx).Create(rating).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Load relationships
-	err = r.db.Preload("Swap").Preload("Rater").Preload("Ratee").
+	err = r.db.WithContext(ctx).Preload("Swap").Preload("Rater").Preload("Ratee").
 		First(rating, rating.RatingID).Error
 
 	return rating, err
 }
 
 // GetRatingByID retrieves a rating by its ID
-func (r *ratingService) GetRatingByID(ratingID uuid.UUID) (*models.SwapRating, error) {
+func (r *ratingService) GetRatingByID(ctx context.Context, ratingID uuid.UUID) (*models.SwapRating, error) {
 	var rating models.SwapRating
-	err := r.db.Preload("Swap").Preload("Rater").Preload("Ratee").
+	err := r.db.WithContext(ctx).Preload("Swap").Preload("Rater").Preload("Ratee").
 		First(&rating, "rating_id = ?", ratingID).Error
 
 	if err != nil {
@@ -142,8 +143,8 @@ func (r *ratingService) GetRatingByID(ratingID uuid.UUID) (*models.SwapRating, e
 }
 
 // UpdateRating updates an existing rating
-func (r *ratingService) UpdateRating(ratingID uuid.UUID, userID uuid.UUID, req *UpdateRatingDTO) (*models.SwapRating, error) {
-	rating, err := r.GetRatingByID(ratingID)
+func (r *ratingService) UpdateRating(ctx context.Context, ratingID uuid.UUID, userID uuid.UUID, req *UpdateRatingDTO) (*models.SwapRating, error) {
+	rating, err := r.GetRatingByID(ctx, ratingID)
 	if err != nil {
 		return nil, err
 	}
@@ -157,7 +158,7 @@ func (r *ratingService) UpdateRating(ratingID uuid.UUID, userID uuid.UUID, req *
 	rating.Score = req.Score
 	rating.Comment = req.Comment
 
-	err = r.db.Save(rating).Error
+	err = r.db.WithContext(ctx).Save(rating).Error
 	if err != nil {
 		return nil, err
 	}
@@ -166,8 +167,8 @@ func (r *ratingService) UpdateRating(ratingID uuid.UUID, userID uuid.UUID, req *
 }
 
 // DeleteRating deletes a rating
-func (r *ratingService) DeleteRating(ratingID uuid.UUID, userID uuid.UUID) error {
-	rating, err := r.GetRatingByID(ratingID)
+func (r *ratingService) DeleteRating(ctx context.Context, ratingID uuid.UUID, userID uuid.UUID) error {
+	rating, err := r.GetRatingByID(ctx, ratingID)
 	if err != nil {
 		return err
 	}
@@ -177,13 +178,13 @@ func (r *ratingService) DeleteRating(ratingID uuid.UUID, userID uuid.UUID) error
 		return errors.New("only the rater can delete this rating")
 	}
 
-	return r.db.Delete(&models.SwapRating{}, "rating_id = ?", ratingID).Error
+	return r.db.WithContext(ctx).Delete(&models.SwapRating{}, "rating_id = ?", ratingID).Error
 }
 
 // GetSwapRatings retrieves all ratings for a specific swap
-func (r *ratingService) GetSwapRatings(swapID uuid.UUID) ([]models.SwapRating, error) {
+func (r *ratingService) GetSwapRatings(ctx context.Context, swapID uuid.UUID) ([]models.SwapRating, error) {
 	var ratings []models.SwapRating
-	err := r.db.Preload("Rater").Preload("Ratee").
+	err := r.db.WithContext(ctx).Preload("Rater").Preload("Ratee").
 		Where("swap_id = ?", swapID).
 		Order("created_at DESC").
 		Find(&ratings).Error
@@ -192,8 +193,8 @@ func (r *ratingService) GetSwapRatings(swapID uuid.UUID) ([]models.SwapRating, e
 }
 
 // GetUserRatings retrieves ratings for a user with filtering
-func (r *ratingService) GetUserRatings(userID uuid.UUID, filter RatingFilter) ([]models.SwapRating, error) {
-	query := r.db.Model(&models.SwapRating{}).
+func (r *ratingService) GetUserRatings(ctx context.Context, userID uuid.UUID, filter RatingFilter) ([]models.SwapRating, error) {
+	query := r.db.WithContext(ctx).Model(&models.SwapRating{}).
 		Preload("Swap").Preload("Rater").Preload("Ratee")
 
 	// Apply filters
@@ -234,7 +235,7 @@ func (r *ratingService) GetUserRatings(userID uuid.UUID, filter RatingFilter) ([
 }
 
 // GetUserRatingStats calculates rating statistics for a user
-func (r *ratingService) GetUserRatingStats(userID uuid.UUID) (*UserRatingStats, error) {
+func (r *ratingService) GetUserRatingStats(ctx context.Context, userID uuid.UUID) (*UserRatingStats, error) {
 	stats := &UserRatingStats{
 		UserID: userID,
 	}
@@ -243,7 +244,7 @@ func (r *ratingService) GetUserRatingStats(userID uuid.UUID) (*UserRatingStats,
 	var totalRatings int64
 	var totalScore int64
 
-	err := r.db.Model(&models.SwapRating{}).
+	err := r.db.WithContext(ctx).Model(&models.SwapRating{}).
 		Where("ratee_id = ?", userID).
 		Count(&totalRatings).Error
 	if err != nil {
@@ -254,7 +255,7 @@ func (r *ratingService) GetUserRatingStats(userID uuid.UUID) (*UserRatingStats,
 		return stats, nil
 	}
 
-	err = r.db.Model(&models.SwapRating{}).
+	err = r.db.WithContext(ctx).Model(&models.SwapRating{}).
 		Select("SUM(score)").
 		Where("ratee_id = ?", userID).
 		Scan(&totalScore).Error
@@ -271,7 +272,7 @@ func (r *ratingService) GetUserRatingStats(userID uuid.UUID) (*UserRatingStats,
 		Count int64
 	}{}
 
-	err = r.db.Model(&models.SwapRating{}).
+	err = r.db.WithContext(ctx).Model(&models.SwapRating{}).
 		Select("score, COUNT(*) as count").
 		Where("ratee_id = ?", userID).
 		Group("score").
@@ -300,10 +301,10 @@ func (r *ratingService) GetUserRatingStats(userID uuid.UUID) (*UserRatingStats,
 }
 
 // CanUserRateSwap checks if a user can rate a specific swap
-func (r *ratingService) CanUserRateSwap(swapID uuid.UUID, raterID uuid.UUID) (bool, error) {
+func (r *ratingService) CanUserRateSwap(ctx context.Context, swapID uuid.UUID, raterID uuid.UUID) (bool, error) {
 	// Check if swap exists and is accepted
 	var swap models.SwapRequest
-	err := r.db.First(&swap, "swap_id = ? AND status = ?", swapID, models.StatusAccepted).Error
+	err := r.db.WithContext(ctx).First(&swap, "swap_id = ? AND status = ?", swapID, models.StatusAccepted).Error
 	if err != nil {
 		return false, nil // Swap not found or not accepted
 	}
@@ -314,7 +315,7 @@ func (r *ratingService) CanUserRateSwap(swapID uuid.UUID, raterID uuid.UUID) (bo
 	}
 
 	// Check if user has already rated
-	hasRated, err := r.HasUserRatedSwap(swapID, raterID)
+	hasRated, err := r.HasUserRatedSwap(ctx, swapID, raterID)
 	if err != nil {
 		return false, err
 	}
@@ -323,9 +324,9 @@ func (r *ratingService) CanUserRateSwap(swapID uuid.UUID, raterID uuid.UUID) (bo
 }
 
 // HasUserRatedSwap checks if a user has already rated a specific swap
-func (r *ratingService) HasUserRatedSwap(swapID uuid.UUID, raterID uuid.UUID) (bool, error) {
+func (r *ratingService) HasUserRatedSwap(ctx context.Context, swapID uuid.UUID, raterID uuid.UUID) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.SwapRating{}).
+	err := r.db.WithContext(ctx).Model(&models.SwapRating{}).
 		Where("swap_id = ? AND rater_id = ?", swapID, raterID).
 		Count(&count).Error
 
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jobs"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobSessionReminder is the scheduled job kind that sends one reminder
+const JobSessionReminder = "session_reminder"
+
+// Limits on user-chosen reminder offsets
+const (
+	maxReminderOffsets = 5
+	minReminderOffset  = 5 * time.Minute
+	maxReminderOffset  = 7 * 24 * time.Hour
+)
+
+type ReminderService interface {
+	GetPreferences(ctx context.Context, userID uuid.UUID) (*ReminderPreferencesResponse, error)
+	UpdatePreferences(ctx context.Context, userID uuid.UUID, req *UpdateReminderPreferencesDTO) (*ReminderPreferencesResponse, error)
+
+	// ScheduleBooking replaces the pending reminders of a booking
+	ScheduleBooking(ctx context.Context, bookingID uuid.UUID) error
+	// ScheduleUpcoming makes sure every future booking has its reminders,
+	// e.g. after a crash between saving a booking and scheduling them
+	ScheduleUpcoming(ctx context.Context) (int, error)
+
+	// Subscribe schedules reminders whenever a booking changes
+	Subscribe(bus *event.Bus)
+	// Register installs the reminder job handler
+	Register(scheduler *jobs.Scheduler)
+}
+
+type UpdateReminderPreferencesDTO struct {
+	Enabled        *bool `json:"enabled"`
+	OffsetsMinutes []int `json:"offsets_minutes"`
+	UseDefaults    bool  `json:"use_defaults"` // Drop custom offsets
+}
+
+type ReminderPreferencesResponse struct {
+	Enabled        bool  `json:"enabled"`
+	OffsetsMinutes []int `json:"offsets_minutes"`
+	IsDefault      bool  `json:"is_default"`
+}
+
+// reminderPayload is stored with each reminder job. StartsAt lets the job
+// notice that the booking moved after it was scheduled.
+type reminderPayload struct {
+	BookingID uuid.UUID `json:"booking_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	StartsAt  time.Time `json:"starts_at"`
+}
+
+type reminderService struct {
+	db             *gorm.DB
+	scheduler      *jobs.Scheduler
+	notifications  *NotificationService
+	defaultOffsets []int
+}
+
+func NewReminderService(db *gorm.DB, scheduler *jobs.Scheduler, notifications *NotificationService, defaultOffsets []time.Duration) ReminderService {
+	minutes := make([]int, len(defaultOffsets))
+	for i, offset := range defaultOffsets {
+		minutes[i] = int(offset / time.Minute)
+	}
+	return &reminderService{
+		db:             db,
+		scheduler:      scheduler,
+		notifications:  notifications,
+		defaultOffsets: normalizeOffsets(minutes),
+	}
+}
+
+func (s *reminderService) Subscribe(bus *event.Bus) {
+	bus.Subscribe(func(evt event.Event) {
+		if err := s.ScheduleBooking(context.Background(), evt.EntityID); err != nil {
+			log.Printf("Warning: failed to schedule reminders for booking %s: %v", evt.EntityID, err)
+		}
+	}, event.BookingChanged)
+}
+
+func (s *reminderService) Register(scheduler *jobs.Scheduler) {
+	scheduler.Register(JobSessionReminder, s.run)
+}
+
+func (s *reminderService) GetPreferences(ctx context.Context, userID uuid.UUID) (*ReminderPreferencesResponse, error) {
+	var pref models.ReminderPreference
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &ReminderPreferencesResponse{Enabled: true, OffsetsMinutes: s.defaultOffsets, IsDefault: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ReminderPreferencesResponse{Enabled: pref.Enabled, OffsetsMinutes: pref.OffsetsMinutes}
+	if len(pref.OffsetsMinutes) == 0 {
+		response.OffsetsMinutes = s.defaultOffsets
+		response.IsDefault = true
+	}
+	return response, nil
+}
+
+// UpdatePreferences saves the user's choice and reschedules the reminders of
+// their upcoming bookings
+func (s *reminderService) UpdatePreferences(ctx context.Context, userID uuid.UUID, req *UpdateReminderPreferencesDTO) (*ReminderPreferencesResponse, error) {
+	current, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pref := models.ReminderPreference{UserID: userID, Enabled: current.Enabled}
+	if !current.IsDefault {
+		pref.OffsetsMinutes = current.OffsetsMinutes
+	}
+	if req.Enabled != nil {
+		pref.Enabled = *req.Enabled
+	}
+	if req.UseDefaults {
+		pref.OffsetsMinutes = models.ReminderOffsets{}
+	} else if req.OffsetsMinutes != nil {
+		if len(req.OffsetsMinutes) == 0 {
+			return nil, errors.New("at least one reminder offset is required")
+		}
+		if len(req.OffsetsMinutes) > maxReminderOffsets {
+			return nil, errors.New("too many reminder offsets")
+		}
+		for _, minutes := range req.OffsetsMinutes {
+			offset := time.Duration(minutes) * time.Minute
+			if offset < minReminderOffset || offset > maxReminderOffset {
+				return nil, errors.New("reminder offsets must be between 5 minutes and 7 days")
+			}
+		}
+		pref.OffsetsMinutes = normalizeOffsets(req.OffsetsMinutes)
+	}
+
+	err = s.db.WithContext(ctx).Omit("User").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "offsets_minutes", "updated_at"}),
+	}).Create(&pref).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var bookingIDs []uuid.UUID
+	err = s.db.WithContext(ctx).Model(&models.BookingParticipant{}).
+		Where("user_id = ? AND active AND starts_at > ?", userID, time.Now()).
+		Pluck("booking_id", &bookingIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, bookingID := range bookingIDs {
+		if err := s.ScheduleBooking(ctx, bookingID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetPreferences(ctx, userID)
+}
+
+func (s *reminderService) ScheduleBooking(ctx context.Context, bookingID uuid.UUID) error {
+	var booking models.Booking
+	if err := s.db.WithContext(ctx).Preload("Participants").Where("booking_id = ?", bookingID).First(&booking).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.scheduler.Cancel(ctx, JobSessionReminder, bookingID); err != nil {
+		return err
+	}
+	if booking.Status != models.BookingConfirmed {
+		return nil
+	}
+
+	now := time.Now()
+	for _, participant := range booking.Participants {
+		pref, err := s.GetPreferences(ctx, participant.UserID)
+		if err != nil {
+			return err
+		}
+		if !pref.Enabled {
+			continue
+		}
+
+		for _, minutes := range pref.OffsetsMinutes {
+			runAt := booking.StartsAt.Add(-time.Duration(minutes) * time.Minute)
+			if !runAt.After(now) {
+				continue
+			}
+			err := s.scheduler.Enqueue(ctx, jobs.Job{
+				Kind:      JobSessionReminder,
+				Key:       fmt.Sprintf("%s:%s:%s:%d:%d", JobSessionReminder, bookingID, participant.UserID, minutes, booking.StartsAt.Unix()),
+				SubjectID: &booking.BookingID,
+				RunAt:     runAt,
+				Payload: reminderPayload{
+					BookingID: booking.BookingID,
+					UserID:    participant.UserID,
+					StartsAt:  booking.StartsAt,
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *reminderService) ScheduleUpcoming(ctx context.Context) (int, error) {
+	var bookingIDs []uuid.UUID
+	err := s.db.WithContext(ctx).Model(&models.Booking{}).
+		Where("status = ? AND starts_at > ?", models.BookingConfirmed, time.Now()).
+		Pluck("booking_id", &bookingIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for _, bookingID := range bookingIDs {
+		if err := s.ScheduleBooking(ctx, bookingID); err != nil {
+			return 0, err
+		}
+	}
+	return len(bookingIDs), nil
+}
+
+// run sends one reminder. Reminders for bookings that were cancelled, moved
+// or have already started are dropped.
+func (s *reminderService) run(ctx context.Context, job models.ScheduledJob) error {
+	var payload reminderPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+
+	var booking models.Booking
+	if err := s.db.WithContext(ctx).Preload("Participants").Where("booking_id = ?", payload.BookingID).First(&booking).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if booking.Status != models.BookingConfirmed || !booking.StartsAt.Equal(payload.StartsAt) || !booking.StartsAt.After(time.Now()) {
+		return nil
+	}
+
+	partnerID := uuid.Nil
+	for _, participant := range booking.Participants {
+		if participant.UserID != payload.UserID {
+			partnerID = participant.UserID
+		}
+	}
+
+	return s.notifications.CreateSessionReminderNotification(ctx, payload.UserID, partnerID, booking.BookingID, booking.StartsAt)
+}
+
+// normalizeOffsets sorts offsets from earliest reminder to latest and drops
+// duplicates
+func normalizeOffsets(minutes []int) models.ReminderOffsets {
+	sorted := append([]int(nil), minutes...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+
+	result := models.ReminderOffsets{}
+	for i, m := range sorted {
+		if i == 0 || m != sorted[i-1] {
+			result = append(result, m)
+		}
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// userRoles returns the names of the roles granted to a user
+func userRoles(db *gorm.DB, userID uuid.UUID) ([]string, error) {
+	roles := []string{}
+	err := db.Model(&models.UserRole{}).
+		Where("user_id = ?", userID).
+		Order("role").
+		Pluck("role", &roles).Error
+	return roles, err
+}
+
+// authorize checks that a user may do what p allows. Roles are read from the
+// database rather than the access token, so a revoked role stops working at
+// once.
+func authorize(db *gorm.DB, userID uuid.UUID, p rbac.Permission) error {
+	var user models.User
+	if err := db.First(&user, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("permission denied")
+		}
+		return err
+	}
+
+	roles, err := userRoles(db, userID)
+	if err != nil {
+		return err
+	}
+	return checkPermission(user.IsBanned, roles, p)
+}
+
+// checkPermission is the rule authorize applies: banned users may do
+// nothing, others what their roles grant
+func checkPermission(banned bool, roles []string, p rbac.Permission) error {
+	if banned || !rbac.Allows(roles, p) {
+		return errors.New("permission denied")
+	}
+	return nil
+}
+
+// checkNotStaff refuses action against a user holding roles; staff have to
+// lose their roles first
+func checkNotStaff(roles []string, action string) error {
+	if len(roles) > 0 {
+		return fmt.Errorf("cannot %s a user with a staff role", action)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
+)
+
+func TestCheckPermission(t *testing.T) {
+	tests := []struct {
+		name   string
+		banned bool
+		roles  []string
+		perm   rbac.Permission
+		ok     bool
+	}{
+		{name: "role grants it", roles: []string{rbac.RoleModerator}, perm: rbac.PermUsersBan, ok: true},
+		{name: "role does not grant it", roles: []string{rbac.RoleModerator}, perm: rbac.PermUsersDelete},
+		{name: "no roles", perm: rbac.PermUsersRead},
+		{name: "banned staff", banned: true, roles: []string{rbac.RoleAdmin}, perm: rbac.PermUsersRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPermission(tt.banned, tt.roles, tt.perm)
+			if (err == nil) != tt.ok {
+				t.Errorf("checkPermission = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCheckNotStaff(t *testing.T) {
+	for _, action := range []string{"ban", "delete"} {
+		if err := checkNotStaff(nil, action); err != nil {
+			t.Errorf("%s of a regular user refused: %v", action, err)
+		}
+		for _, role := range []string{rbac.RoleAdmin, rbac.RoleModerator, rbac.RoleSkillCurator} {
+			err := checkNotStaff([]string{role}, action)
+			if err == nil || err.Error() != "cannot "+action+" a user with a staff role" {
+				t.Errorf("%s of a %s: error = %v", action, role, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/calendar"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxScheduleRange bounds how far a single free-time query may expand
+const maxScheduleRange = 93 * 24 * time.Hour
+
+// BusySource reports time a user is already committed to, such as booked
+// swap sessions. Busy time is subtracted from free time.
+type BusySource interface {
+	BusyTimes(ctx context.Context, userID uuid.UUID, window calendar.Interval) ([]calendar.Interval, error)
+}
+
+type ScheduleService interface {
+	// FreeTime returns the user's bookable time within window: weekly slots
+	// and one-off availability, minus blackouts and busy time, merged
+	FreeTime(ctx context.Context, userID uuid.UUID, window calendar.Interval) ([]calendar.Interval, error)
+
+	// AddBusySource registers another source of busy time
+	AddBusySource(source BusySource)
+}
+
+type scheduleService struct {
+	db *gorm.DB
+
+	mu          sync.RWMutex
+	busySources []BusySource
+}
+
+func NewScheduleService(db *gorm.DB, busySources ...BusySource) ScheduleService {
+	return &scheduleService{db: db, busySources: busySources}
+}
+
+func (s *scheduleService) AddBusySource(source BusySource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.busySources = append(s.busySources, source)
+}
+
+// FreeTime expands the user's weekly slots in their timezone, adds one-off
+// availability, and subtracts blackouts and busy time
+func (s *scheduleService) FreeTime(ctx context.Context, userID uuid.UUID, window calendar.Interval) ([]calendar.Interval, error) {
+	if window.Empty() {
+		return nil, errors.New("end must be after start")
+	}
+	if window.Duration() > maxScheduleRange {
+		return nil, errors.New("date range is too long")
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("user_id", "timezone").Where("user_id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	free, err := s.weeklyAvailability(ctx, userID, user.Timezone, window)
+	if err != nil {
+		return nil, err
+	}
+
+	var exceptions []models.AvailabilityException
+	err = s.db.WithContext(ctx).Where("user_id = ? AND starts_at < ? AND ends_at > ?", userID, window.End, window.Start).
+		Find(&exceptions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var blocked []calendar.Interval
+	for _, exception := range exceptions {
+		interval := calendar.Interval{Start: exception.StartsAt, End: exception.EndsAt}
+		if exception.Kind == models.ExceptionBlackout {
+			blocked = append(blocked, interval)
+		} else {
+			free = append(free, interval)
+		}
+	}
+
+	s.mu.RLock()
+	sources := s.busySources
+	s.mu.RUnlock()
+	for _, source := range sources {
+		busy, err := source.BusyTimes(ctx, userID, window)
+		if err != nil {
+			return nil, err
+		}
+		blocked = append(blocked, busy...)
+	}
+
+	return calendar.Subtract(calendar.Clip(free, window), blocked), nil
+}
+
+// weeklyAvailability expands a user's slots into absolute intervals within
+// window. Slots without their own timezone use the user's.
+func (s *scheduleService) weeklyAvailability(ctx context.Context, userID uuid.UUID, userZone *string, window calendar.Interval) ([]calendar.Interval, error) {
+	var slots []models.AvailabilitySlot
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&slots).Error; err != nil {
+		return nil, err
+	}
+
+	var intervals []calendar.Interval
+	for _, slot := range slots {
+		zone := slot.Timezone
+		if zone == nil {
+			zone = userZone
+		}
+		loc, err := loadLocation(zone)
+		if err != nil {
+			// A zone that no longer loads should not hide the other slots
+			loc = time.UTC
+		}
+
+		rule := calendar.WeeklyRule{
+			Days:     slot.DayBitmask,
+			Start:    calendar.ClockOf(slot.StartTime),
+			End:      calendar.ClockOf(slot.EndTime),
+			Location: loc,
+		}
+		intervals = append(intervals, rule.Expand(window)...)
+	}
+
+	return intervals, nil
+}
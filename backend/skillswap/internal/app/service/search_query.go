@@ -0,0 +1,81 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// searchQuery is a parsed search string. Free-text words end up in Terms,
+// while "field:value" pairs (e.g. `skill:go location:"new york"`) are
+// collected per field so each entity search can apply them to the columns
+// they map to.
+type searchQuery struct {
+	Terms  []string
+	Fields map[string][]string
+}
+
+// parseSearchQuery splits a raw query into free-text terms and field filters.
+// Only fields listed in allowedFields are recognised; anything else (including
+// a colon inside a normal word such as "c++:basics") is kept as free text.
+func parseSearchQuery(raw string, allowedFields ...string) searchQuery {
+	allowed := make(map[string]bool, len(allowedFields))
+	for _, f := range allowedFields {
+		allowed[f] = true
+	}
+
+	parsed := searchQuery{Fields: make(map[string][]string)}
+	for _, token := range tokenizeSearchQuery(raw) {
+		if idx := strings.Index(token, ":"); idx > 0 {
+			field := strings.ToLower(token[:idx])
+			value := strings.Trim(token[idx+1:], "\"")
+			if allowed[field] && value != "" {
+				parsed.Fields[field] = append(parsed.Fields[field], value)
+				continue
+			}
+		}
+
+		if term := strings.Trim(token, "\""); term != "" {
+			parsed.Terms = append(parsed.Terms, term)
+		}
+	}
+
+	return parsed
+}
+
+// Text returns the free-text part of the query as a single string
+func (q searchQuery) Text() string {
+	return strings.Join(q.Terms, " ")
+}
+
+// IsEmpty reports whether the query has neither terms nor field filters
+func (q searchQuery) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Fields) == 0
+}
+
+// tokenizeSearchQuery splits on whitespace while keeping double-quoted
+// sections (including `field:"quoted value"`) together.
+func tokenizeSearchQuery(raw string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuotes:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
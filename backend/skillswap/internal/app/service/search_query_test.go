@@ -0,0 +1,74 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		raw    string
+		terms  []string
+		fields map[string][]string
+	}{
+		{raw: "", fields: map[string][]string{}},
+		{raw: "golang", terms: []string{"golang"}, fields: map[string][]string{}},
+		{
+			raw:    `react skill:go location:"new york"`,
+			terms:  []string{"react"},
+			fields: map[string][]string{"skill": {"go"}, "location": {"new york"}},
+		},
+		{
+			raw:    "skill:go skill:rust",
+			fields: map[string][]string{"skill": {"go", "rust"}},
+		},
+		{raw: "Skill:Go", fields: map[string][]string{"skill": {"Go"}}},
+		{raw: `"machine learning" basics`, terms: []string{"machine learning", "basics"}, fields: map[string][]string{}},
+		// Unknown fields, empty values and leading colons are plain text
+		{raw: "c++:basics", terms: []string{"c++:basics"}, fields: map[string][]string{}},
+		{raw: "status:open", terms: []string{"status:open"}, fields: map[string][]string{}},
+		{raw: "skill:", terms: []string{"skill:"}, fields: map[string][]string{}},
+		{raw: ":go", terms: []string{":go"}, fields: map[string][]string{}},
+		{raw: "  spaced   out  ", terms: []string{"spaced", "out"}, fields: map[string][]string{}},
+		{raw: `location:"unclosed quote`, fields: map[string][]string{"location": {"unclosed quote"}}},
+	}
+
+	for _, tt := range tests {
+		got := parseSearchQuery(tt.raw, "skill", "location")
+		if !reflect.DeepEqual(got.Terms, tt.terms) || !reflect.DeepEqual(got.Fields, tt.fields) {
+			t.Errorf("parseSearchQuery(%q) = %q %q, want %q %q", tt.raw, got.Terms, got.Fields, tt.terms, tt.fields)
+		}
+	}
+}
+
+func TestSearchQueryText(t *testing.T) {
+	parsed := parseSearchQuery(`mach learn skill:python`, "skill")
+	if got := parsed.Text(); got != "mach learn" {
+		t.Errorf("Text() = %q, want %q", got, "mach learn")
+	}
+	if parsed.IsEmpty() {
+		t.Error("IsEmpty() = true for a query with terms")
+	}
+	if !parseSearchQuery("   ").IsEmpty() {
+		t.Error("IsEmpty() = false for a blank query")
+	}
+	if parseSearchQuery("skill:go", "skill").IsEmpty() {
+		t.Error("IsEmpty() = true for a query with a field filter")
+	}
+}
+
+func TestRelevanceOrder(t *testing.T) {
+	var none *searchHits
+	if got := none.relevanceOrder("users.created_at DESC"); got != "users.created_at DESC" {
+		t.Errorf("without hits = %q", got)
+	}
+	if got := (&searchHits{ranked: map[uuid.UUID]rankedHit{}}).relevanceOrder("users.created_at DESC"); got != "" {
+		t.Errorf("with ranked hits = %q, want the index's order", got)
+	}
+	if got := (&searchHits{query: &gorm.DB{}}).relevanceOrder("users.created_at DESC"); got != "hits.score DESC, users.created_at DESC" {
+		t.Errorf("with joined hits = %q", got)
+	}
+}
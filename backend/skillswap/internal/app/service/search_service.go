@@ -1,9 +1,15 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/geo"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/searchindex"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -11,34 +17,48 @@ import (
 
 type SearchService interface {
 	// Advanced user search
-	SearchUsers(filter UserSearchFilter) ([]models.User, int64, error)
+	SearchUsers(ctx context.Context, filter UserSearchFilter) ([]UserSearchResult, int64, error)
 
 	// Advanced swap search
-	SearchSwaps(filter SwapSearchFilter) ([]models.SwapRequest, int64, error)
+	SearchSwaps(ctx context.Context, filter SwapSearchFilter) ([]SwapSearchResult, int64, error)
 
 	// Advanced skill search
-	SearchSkills(filter SkillSearchFilter) ([]models.Skill, int64, error)
+	SearchSkills(ctx context.Context, filter SkillSearchFilter) ([]SkillSearchResult, int64, error)
 
 	// Global search across all entities
-	GlobalSearch(query string, entityTypes []string, limit int) (*GlobalSearchResults, error)
+	GlobalSearch(ctx context.Context, query string, entityTypes []string, limit int) (*GlobalSearchResults, error)
 }
 
+// Field names understood in the query string, e.g. `skill:go location:berlin`
+var (
+	userSearchFields  = []string{"name", "location", "skill", "offers", "wants"}
+	swapSearchFields  = []string{"skill", "offered", "wanted", "user", "status"}
+	skillSearchFields = []string{"name"}
+)
+
 // Search filters and DTOs
 type UserSearchFilter struct {
-	Query         string      `json:"query,omitempty"`          // Search in name, location
-	Location      string      `json:"location,omitempty"`       // Filter by location
+	Query         string      `json:"query,omitempty"`          // Search in name, location; supports name:, location:, skill:, offers:, wants:
+	Location      string      `json:"location,omitempty"`       // Filter by location; "NYC" also matches "New York"
+	Near          string      `json:"near,omitempty"`           // Center of a radius search, as a place name
+	Latitude      *float64    `json:"latitude,omitempty"`       // Center of a radius search, as coordinates
+	Longitude     *float64    `json:"longitude,omitempty"`      // Used together with Latitude
+	Origin        *uuid.UUID  `json:"origin,omitempty"`         // Use this user's location as the center
+	RadiusKm      *float64    `json:"radius_km,omitempty"`      // Only users within this distance of the center
+	IsRemote      *bool       `json:"is_remote,omitempty"`      // Only users who do (or don't) swap remotely
+	IncludeRemote bool        `json:"include_remote,omitempty"` // Let remote users through the radius filter
 	SkillsOffered []uuid.UUID `json:"skills_offered,omitempty"` // Users offering these skills
 	SkillsWanted  []uuid.UUID `json:"skills_wanted,omitempty"`  // Users wanting these skills
 	MinRating     *float64    `json:"min_rating,omitempty"`     // Minimum average rating
 	IsPublic      *bool       `json:"is_public,omitempty"`      // Public profiles only
-	SortBy        string      `json:"sort_by,omitempty"`        // "created_at", "name", "rating"
+	SortBy        string      `json:"sort_by,omitempty"`        // "relevance", "created_at", "name", "rating", "distance"
 	SortOrder     string      `json:"sort_order,omitempty"`     // "asc", "desc"
 	Limit         int         `json:"limit,omitempty"`
 	Offset        int         `json:"offset,omitempty"`
 }
 
 type SwapSearchFilter struct {
-	Query          string     `json:"query,omitempty"`            // Search in description
+	Query          string     `json:"query,omitempty"`            // Search in skill and participant names; supports skill:, offered:, wanted:, user:, status:
 	Status         *string    `json:"status,omitempty"`           // Filter by status
 	OfferedSkillID *uuid.UUID `json:"offered_skill_id,omitempty"` // Filter by offered skill
 	WantedSkillID  *uuid.UUID `json:"wanted_skill_id,omitempty"`  // Filter by wanted skill
@@ -47,283 +67,318 @@ type SwapSearchFilter struct {
 	LocationRadius *float64   `json:"location_radius,omitempty"`  // Search within radius (future)
 	CreatedAfter   *string    `json:"created_after,omitempty"`    // Created after date
 	CreatedBefore  *string    `json:"created_before,omitempty"`   // Created before date
-	SortBy         string     `json:"sort_by,omitempty"`          // "created_at", "updated_at"
+	SortBy         string     `json:"sort_by,omitempty"`          // "relevance", "created_at", "updated_at"
 	SortOrder      string     `json:"sort_order,omitempty"`       // "asc", "desc"
 	Limit          int        `json:"limit,omitempty"`
 	Offset         int        `json:"offset,omitempty"`
 }
 
 type SkillSearchFilter struct {
-	Query     string `json:"query,omitempty"`      // Search in name, description
-	Category  string `json:"category,omitempty"`   // Filter by category
-	SortBy    string `json:"sort_by,omitempty"`    // "name", "created_at", "popularity"
+	Query     string `json:"query,omitempty"`      // Search in name; supports name:
+	SortBy    string `json:"sort_by,omitempty"`    // "relevance", "name", "created_at", "popularity"
 	SortOrder string `json:"sort_order,omitempty"` // "asc", "desc"
 	Limit     int    `json:"limit,omitempty"`
 	Offset    int    `json:"offset,omitempty"`
 }
 
-type GlobalSearchResults struct {
-	Users        []models.User        `json:"users,omitempty"`
-	Skills       []models.Skill       `json:"skills,omitempty"`
-	SwapRequests []models.SwapRequest `json:"swap_requests,omitempty"`
-	Total        int                  `json:"total"`
+// Ranked search results. Rank is only meaningful when free text was given;
+// Highlight holds a snippet with matched words wrapped in <mark> tags.
+// DistanceKm is set when the search had a center and the user a location.
+type UserSearchResult struct {
+	User       models.User `json:"user"`
+	Rank       float64     `json:"rank"`
+	Highlight  string      `json:"highlight,omitempty"`
+	DistanceKm *float64    `json:"distance_km,omitempty"`
 }
 
-type searchService struct {
-	db *gorm.DB
+type SwapSearchResult struct {
+	Swap      models.SwapRequest `json:"swap"`
+	Rank      float64            `json:"rank"`
+	Highlight string             `json:"highlight,omitempty"`
+}
+
+type SkillSearchResult struct {
+	Skill     models.Skill `json:"skill"`
+	Rank      float64      `json:"rank"`
+	Highlight string       `json:"highlight,omitempty"`
 }
 
-func NewSearchService(db *gorm.DB) SearchService {
-	return &searchService{db: db}
+type GlobalSearchResults struct {
+	Users        []UserSearchResult  `json:"users,omitempty"`
+	Skills       []SkillSearchResult `json:"skills,omitempty"`
+	SwapRequests []SwapSearchResult  `json:"swap_requests,omitempty"`
+	Total        int                 `json:"total"`
 }
 
-// SearchUsers performs advanced user search with filtering
-func (s *searchService) SearchUsers(filter UserSearchFilter) ([]models.User, int64, error) {
-	query := s.db.Model(&models.User{}).Where("deleted_at IS NULL")
+// maxSearchRadiusKm bounds radius searches to roughly half the planet
+const maxSearchRadiusKm = 20000
 
-	// Apply filters
-	if filter.Query != "" {
-		searchTerm := fmt.Sprintf("%%%s%%", strings.ToLower(filter.Query))
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(location) LIKE ?", searchTerm, searchTerm)
-	}
+// searchService only reads, so its db may be a read replica
+type searchService struct {
+	db       *gorm.DB
+	indexer  searchindex.Indexer
+	geocoder geo.Geocoder
+}
 
+func NewSearchService(db *gorm.DB, indexer searchindex.Indexer, geocoder geo.Geocoder) SearchService {
+	return &searchService{db: db, indexer: indexer, geocoder: geocoder}
+}
+
+// SearchUsers performs relevance-ranked user search with filtering
+func (s *searchService) SearchUsers(ctx context.Context, filter UserSearchFilter) ([]UserSearchResult, int64, error) {
+	parsed := parseSearchQuery(filter.Query, userSearchFields...)
 	if filter.Location != "" {
-		locationTerm := fmt.Sprintf("%%%s%%", strings.ToLower(filter.Location))
-		query = query.Where("LOWER(location) LIKE ?", locationTerm)
+		parsed.Fields["location"] = append(parsed.Fields["location"], filter.Location)
 	}
 
-	if filter.IsPublic != nil {
-		query = query.Where("is_public = ?", *filter.IsPublic)
+	// Locations the geocoder knows are matched on the resolved place, so
+	// "NYC" finds "New York"; anything else stays a text match in the index
+	places, unresolved := s.resolveLocations(parsed.Fields["location"])
+	if len(unresolved) > 0 {
+		parsed.Fields["location"] = unresolved
+	} else {
+		delete(parsed.Fields, "location")
 	}
 
-	// Filter by skills offered
-	if len(filter.SkillsOffered) > 0 {
-		query = query.Joins("JOIN user_skills_offered uso ON users.user_id = uso.user_id").
-			Where("uso.skill_id IN ?", filter.SkillsOffered).
-			Distinct("users.user_id")
+	center, err := s.searchCenter(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if filter.RadiusKm != nil {
+		if *filter.RadiusKm <= 0 || *filter.RadiusKm > maxSearchRadiusKm {
+			return nil, 0, errors.New("invalid radius")
+		}
+		if center == nil {
+			return nil, 0, errors.New("radius search requires a location")
+		}
+	}
+	if filter.SortBy == "distance" && center == nil {
+		return nil, 0, errors.New("distance sort requires a location")
 	}
 
-	// Filter by skills wanted
-	if len(filter.SkillsWanted) > 0 {
-		query = query.Joins("JOIN user_skills_wanted usw ON users.user_id = usw.user_id").
-			Where("usw.skill_id IN ?", filter.SkillsWanted).
-			Distinct("users.user_id")
+	hits, err := s.candidates(ctx, searchindex.KindUser, parsed)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Filter by minimum rating (requires calculating average rating)
-	if filter.MinRating != nil {
-		query = query.Joins("LEFT JOIN swap_ratings sr ON users.user_id = sr.ratee_id").
-			Group("users.user_id").
-			Having("COALESCE(AVG(sr.score), 0) >= ?", *filter.MinRating)
+	query := s.db.WithContext(ctx).Table("users").Where("users.deleted_at IS NULL")
+
+	for _, place := range places {
+		query = query.Where("users.location_place = ?", place.Name)
 	}
 
-	// Get total count
-	var total int64
-	countQuery := s.db.Model(&models.User{}).Where("deleted_at IS NULL")
-	if err := applyUserFiltersForCount(countQuery, filter).Count(&total).Error; err != nil {
-		return nil, 0, err
+	if filter.IsRemote != nil {
+		query = query.Where("users.is_remote = ?", *filter.IsRemote)
 	}
 
-	// Apply sorting
-	sortBy := "created_at"
-	if filter.SortBy != "" {
-		switch filter.SortBy {
-		case "name", "created_at":
-			sortBy = filter.SortBy
-		case "rating":
-			sortBy = "AVG(sr.score)"
+	if filter.RadiusKm != nil {
+		minLat, maxLat, minLng, maxLng := geo.BoundingBox(center.Latitude, center.Longitude, *filter.RadiusKm)
+		nearby := "(users.latitude BETWEEN ? AND ? AND users.longitude BETWEEN ? AND ? AND " +
+			distanceSQL(center.Latitude, center.Longitude) + " <= ?)"
+		if filter.IncludeRemote {
+			nearby = "(" + nearby + " OR users.is_remote = TRUE)"
 		}
+		query = query.Where(nearby, minLat, maxLat, minLng, maxLng, *filter.RadiusKm)
 	}
-	sortOrder := "DESC"
-	if filter.SortOrder == "asc" {
-		sortOrder = "ASC"
+
+	if filter.IsPublic != nil {
+		query = query.Where("users.is_public = ?", *filter.IsPublic)
 	}
-	query = query.Order(fmt.Sprintf("%s %s", sortBy, sortOrder))
 
-	// Apply pagination
-	if filter.Limit > 0 {
-		query = query.Limit(filter.Limit)
-	} else {
-		query = query.Limit(20) // Default limit
+	if len(filter.SkillsOffered) > 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM user_skills_offered uso WHERE uso.user_id = users.user_id AND uso.skill_id IN ?)", filter.SkillsOffered)
 	}
-	if filter.Offset > 0 {
-		query = query.Offset(filter.Offset)
+	if len(filter.SkillsWanted) > 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM user_skills_wanted usw WHERE usw.user_id = users.user_id AND usw.skill_id IN ?)", filter.SkillsWanted)
 	}
 
-	var users []models.User
-	err := query.Preload("SkillsOffered.Skill").Preload("SkillsWanted.Skill").Find(&users).Error
-	return users, total, err
-}
-
-// SearchSwaps performs advanced swap search with filtering
-func (s *searchService) SearchSwaps(filter SwapSearchFilter) ([]models.SwapRequest, int64, error) {
-	query := s.db.Model(&models.SwapRequest{}).
-		Preload("Requester").
-		Preload("Responder").
-		Preload("OfferedSkill").
-		Preload("WantedSkill")
-
-	// Apply filters
-	if filter.Query != "" {
-		searchTerm := fmt.Sprintf("%%%s%%", strings.ToLower(filter.Query))
-		query = query.Where("LOWER(description) LIKE ?", searchTerm)
+	if filter.MinRating != nil {
+		query = query.Where(userAverageRating+" >= ?", *filter.MinRating)
 	}
 
-	if filter.Status != nil {
-		query = query.Where("status = ?", *filter.Status)
+	// Apply sorting; an empty order means relevance
+	sortOrder := normalizeSortOrder(filter.SortOrder, "DESC")
+	var orderBy string
+	switch filter.SortBy {
+	case "name":
+		orderBy = "users.name " + normalizeSortOrder(filter.SortOrder, "ASC")
+	case "rating":
+		orderBy = userAverageRating + " " + sortOrder
+	case "created_at":
+		orderBy = "users.created_at " + sortOrder
+	case "distance":
+		orderBy = distanceSQL(center.Latitude, center.Longitude) + " " + normalizeSortOrder(filter.SortOrder, "ASC") + " NULLS LAST"
+	default:
+		orderBy = hits.relevanceOrder("users.created_at " + sortOrder)
 	}
 
-	if filter.OfferedSkillID != nil {
-		query = query.Where("offered_skill_id = ?", *filter.OfferedSkillID)
+	ids, found, total, err := rankedPage(query, "users.user_id", hits, orderBy, searchLimit(filter.Limit, 20), filter.Offset)
+	if err != nil || len(ids) == 0 {
+		return []UserSearchResult{}, total, err
 	}
 
-	if filter.WantedSkillID != nil {
-		query = query.Where("wanted_skill_id = ?", *filter.WantedSkillID)
+	var users []models.User
+	err = s.db.WithContext(ctx).Preload("SkillsOffered.Skill").Preload("SkillsWanted.Skill").
+		Where("user_id IN ?", ids).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
 	}
 
-	if filter.RequesterID != nil {
-		query = query.Where("requester_id = ?", *filter.RequesterID)
+	byID := make(map[uuid.UUID]models.User, len(users))
+	for _, user := range users {
+		byID[user.UserID] = user
 	}
 
-	if filter.ResponderID != nil {
-		query = query.Where("responder_id = ?", *filter.ResponderID)
+	results := make([]UserSearchResult, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := byID[id]; ok {
+			hit := found[id]
+			result := UserSearchResult{User: user, Rank: hit.Score, Highlight: hit.Highlight}
+			if center != nil && user.Latitude != nil && user.Longitude != nil {
+				distance := geo.DistanceKm(center.Latitude, center.Longitude, *user.Latitude, *user.Longitude)
+				result.DistanceKm = &distance
+			}
+			results = append(results, result)
+		}
 	}
 
-	if filter.CreatedAfter != nil {
-		query = query.Where("created_at >= ?", *filter.CreatedAfter)
-	}
+	return results, total, nil
+}
 
-	if filter.CreatedBefore != nil {
-		query = query.Where("created_at <= ?", *filter.CreatedBefore)
+// SearchSwaps performs relevance-ranked swap search with filtering.
+// Swap requests carry no free text of their own, so the query is matched
+// against the offered/wanted skill names and the participants' names.
+func (s *searchService) SearchSwaps(ctx context.Context, filter SwapSearchFilter) ([]SwapSearchResult, int64, error) {
+	parsed := parseSearchQuery(filter.Query, swapSearchFields...)
+
+	// status: is an exact filter rather than text, so it is not sent to the index
+	statuses := parsed.Fields["status"]
+	delete(parsed.Fields, "status")
+
+	hits, err := s.candidates(ctx, searchindex.KindSwap, parsed)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Get total count - rebuild query for count
-	var total int64
-	countQuery := s.db.Model(&models.SwapRequest{})
-	if filter.Query != "" {
-		searchTerm := fmt.Sprintf("%%%s%%", strings.ToLower(filter.Query))
-		countQuery = countQuery.Where("LOWER(description) LIKE ?", searchTerm)
+	query := s.db.WithContext(ctx).Table("swap_requests").Where("swap_requests.deleted_at IS NULL")
+
+	for _, status := range statuses {
+		query = query.Where("swap_requests.status = ?", strings.ToLower(status))
 	}
 	if filter.Status != nil {
-		countQuery = countQuery.Where("status = ?", *filter.Status)
+		query = query.Where("swap_requests.status = ?", *filter.Status)
 	}
 	if filter.OfferedSkillID != nil {
-		countQuery = countQuery.Where("offered_skill_id = ?", *filter.OfferedSkillID)
+		query = query.Where("swap_requests.offered_skill_id = ?", *filter.OfferedSkillID)
 	}
 	if filter.WantedSkillID != nil {
-		countQuery = countQuery.Where("wanted_skill_id = ?", *filter.WantedSkillID)
+		query = query.Where("swap_requests.wanted_skill_id = ?", *filter.WantedSkillID)
 	}
 	if filter.RequesterID != nil {
-		countQuery = countQuery.Where("requester_id = ?", *filter.RequesterID)
+		query = query.Where("swap_requests.requester_id = ?", *filter.RequesterID)
 	}
 	if filter.ResponderID != nil {
-		countQuery = countQuery.Where("responder_id = ?", *filter.ResponderID)
+		query = query.Where("swap_requests.responder_id = ?", *filter.ResponderID)
 	}
 	if filter.CreatedAfter != nil {
-		countQuery = countQuery.Where("created_at >= ?", *filter.CreatedAfter)
+		query = query.Where("swap_requests.created_at >= ?", *filter.CreatedAfter)
 	}
 	if filter.CreatedBefore != nil {
-		countQuery = countQuery.Where("created_at <= ?", *filter.CreatedBefore)
+		query = query.Where("swap_requests.created_at <= ?", *filter.CreatedBefore)
 	}
-	if err := countQuery.Count(&total).Error; err != nil {
-		return nil, 0, err
+
+	// Apply sorting; an empty order means relevance
+	sortOrder := normalizeSortOrder(filter.SortOrder, "DESC")
+	var orderBy string
+	switch filter.SortBy {
+	case "created_at", "updated_at":
+		orderBy = "swap_requests." + filter.SortBy + " " + sortOrder
+	default:
+		orderBy = hits.relevanceOrder("swap_requests.created_at " + sortOrder)
 	}
 
-	// Apply sorting
-	sortBy := "created_at"
-	if filter.SortBy != "" {
-		switch filter.SortBy {
-		case "created_at", "updated_at":
-			sortBy = filter.SortBy
-		}
+	ids, found, total, err := rankedPage(query, "swap_requests.swap_id", hits, orderBy, searchLimit(filter.Limit, 20), filter.Offset)
+	if err != nil || len(ids) == 0 {
+		return []SwapSearchResult{}, total, err
 	}
-	sortOrder := "DESC"
-	if filter.SortOrder == "asc" {
-		sortOrder = "ASC"
+
+	var swaps []models.SwapRequest
+	err = s.db.WithContext(ctx).Preload("Requester").Preload("Responder").
+		Preload("OfferedSkill").Preload("WantedSkill").
+		Where("swap_id IN ?", ids).
+		Find(&swaps).Error
+	if err != nil {
+		return nil, 0, err
 	}
-	query = query.Order(fmt.Sprintf("%s %s", sortBy, sortOrder))
 
-	// Apply pagination
-	if filter.Limit > 0 {
-		query = query.Limit(filter.Limit)
-	} else {
-		query = query.Limit(20) // Default limit
+	byID := make(map[uuid.UUID]models.SwapRequest, len(swaps))
+	for _, swap := range swaps {
+		byID[swap.SwapID] = swap
 	}
-	if filter.Offset > 0 {
-		query = query.Offset(filter.Offset)
+
+	results := make([]SwapSearchResult, 0, len(ids))
+	for _, id := range ids {
+		if swap, ok := byID[id]; ok {
+			hit := found[id]
+			results = append(results, SwapSearchResult{Swap: swap, Rank: hit.Score, Highlight: hit.Highlight})
+		}
 	}
 
-	var swaps []models.SwapRequest
-	err := query.Find(&swaps).Error
-	return swaps, total, err
+	return results, total, nil
 }
 
-// SearchSkills performs advanced skill search with filtering
-func (s *searchService) SearchSkills(filter SkillSearchFilter) ([]models.Skill, int64, error) {
-	query := s.db.Model(&models.Skill{})
+// SearchSkills performs relevance-ranked skill search with filtering
+func (s *searchService) SearchSkills(ctx context.Context, filter SkillSearchFilter) ([]SkillSearchResult, int64, error) {
+	parsed := parseSearchQuery(filter.Query, skillSearchFields...)
 
-	// Apply filters
-	if filter.Query != "" {
-		searchTerm := fmt.Sprintf("%%%s%%", strings.ToLower(filter.Query))
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ?", searchTerm, searchTerm)
+	hits, err := s.candidates(ctx, searchindex.KindSkill, parsed)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	if filter.Category != "" {
-		query = query.Where("LOWER(category) = LOWER(?)", filter.Category)
-	}
+	query := s.db.WithContext(ctx).Table("skills")
 
-	// Get total count
-	var total int64
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+	// Apply sorting; an empty order means relevance
+	var orderBy string
+	switch filter.SortBy {
+	case "name", "created_at":
+		orderBy = "skills." + filter.SortBy + " " + normalizeSortOrder(filter.SortOrder, "ASC")
+	case "popularity":
+		orderBy = skillPopularity + " " + normalizeSortOrder(filter.SortOrder, "DESC")
+	default:
+		orderBy = hits.relevanceOrder("skills.name " + normalizeSortOrder(filter.SortOrder, "ASC"))
 	}
 
-	// Apply sorting
-	sortBy := "name"
-	if filter.SortBy != "" {
-		switch filter.SortBy {
-		case "name", "created_at":
-			sortBy = filter.SortBy
-		case "popularity":
-			// Sort by number of times this skill is offered/wanted
-			query = query.Select("skills.*, (SELECT COUNT(*) FROM user_skills_offered WHERE skill_id = skills.skill_id) + (SELECT COUNT(*) FROM user_skills_wanted WHERE skill_id = skills.skill_id) as popularity").
-				Order("popularity")
-		}
+	ids, found, total, err := rankedPage(query, "skills.skill_id", hits, orderBy, searchLimit(filter.Limit, 20), filter.Offset)
+	if err != nil || len(ids) == 0 {
+		return []SkillSearchResult{}, total, err
 	}
 
-	if filter.SortBy != "popularity" {
-		sortOrder := "ASC"
-		if filter.SortOrder == "desc" {
-			sortOrder = "DESC"
-		}
-		query = query.Order(fmt.Sprintf("%s %s", sortBy, sortOrder))
-	} else {
-		sortOrder := "DESC"
-		if filter.SortOrder == "asc" {
-			sortOrder = "ASC"
-		}
-		query = query.Order(fmt.Sprintf("popularity %s", sortOrder))
+	var skills []models.Skill
+	if err := s.db.WithContext(ctx).Where("skill_id IN ?", ids).Find(&skills).Error; err != nil {
+		return nil, 0, err
 	}
 
-	// Apply pagination
-	if filter.Limit > 0 {
-		query = query.Limit(filter.Limit)
-	} else {
-		query = query.Limit(20) // Default limit
+	byID := make(map[uuid.UUID]models.Skill, len(skills))
+	for _, skill := range skills {
+		byID[skill.SkillID] = skill
 	}
-	if filter.Offset > 0 {
-		query = query.Offset(filter.Offset)
+
+	results := make([]SkillSearchResult, 0, len(ids))
+	for _, id := range ids {
+		if skill, ok := byID[id]; ok {
+			hit := found[id]
+			results = append(results, SkillSearchResult{Skill: skill, Rank: hit.Score, Highlight: hit.Highlight})
+		}
 	}
 
-	var skills []models.Skill
-	err := query.Find(&skills).Error
-	return skills, total, err
+	return results, total, nil
 }
 
 // GlobalSearch performs search across all entities
-func (s *searchService) GlobalSearch(query string, entityTypes []string, limit int) (*GlobalSearchResults, error) {
+func (s *searchService) GlobalSearch(ctx context.Context, query string, entityTypes []string, limit int) (*GlobalSearchResults, error) {
 	results := &GlobalSearchResults{}
-	searchTerm := fmt.Sprintf("%%%s%%", strings.ToLower(query))
 
 	if limit <= 0 {
 		limit = 10 // Default limit per entity type
@@ -332,13 +387,8 @@ func (s *searchService) GlobalSearch(query string, entityTypes []string, limit i
 	for _, entityType := range entityTypes {
 		switch entityType {
 		case "users":
-			var users []models.User
-			err := s.db.Model(&models.User{}).
-				Where("deleted_at IS NULL AND is_public = true AND (LOWER(name) LIKE ? OR LOWER(location) LIKE ?)", searchTerm, searchTerm).
-				Limit(limit).
-				Preload("SkillsOffered.Skill").
-				Preload("SkillsWanted.Skill").
-				Find(&users).Error
+			isPublic := true
+			users, _, err := s.SearchUsers(ctx, UserSearchFilter{Query: query, IsPublic: &isPublic, Limit: limit})
 			if err != nil {
 				return nil, err
 			}
@@ -346,11 +396,7 @@ func (s *searchService) GlobalSearch(query string, entityTypes []string, limit i
 			results.Total += len(users)
 
 		case "skills":
-			var skills []models.Skill
-			err := s.db.Model(&models.Skill{}).
-				Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ?", searchTerm, searchTerm).
-				Limit(limit).
-				Find(&skills).Error
+			skills, _, err := s.SearchSkills(ctx, SkillSearchFilter{Query: query, Limit: limit})
 			if err != nil {
 				return nil, err
 			}
@@ -358,15 +404,7 @@ func (s *searchService) GlobalSearch(query string, entityTypes []string, limit i
 			results.Total += len(skills)
 
 		case "swaps":
-			var swaps []models.SwapRequest
-			err := s.db.Model(&models.SwapRequest{}).
-				Where("LOWER(description) LIKE ?", searchTerm).
-				Limit(limit).
-				Preload("Requester").
-				Preload("Responder").
-				Preload("OfferedSkill").
-				Preload("WantedSkill").
-				Find(&swaps).Error
+			swaps, _, err := s.SearchSwaps(ctx, SwapSearchFilter{Query: query, Limit: limit})
 			if err != nil {
 				return nil, err
 			}
@@ -378,21 +416,240 @@ func (s *searchService) GlobalSearch(query string, entityTypes []string, limit i
 	return results, nil
 }
 
-// Helper function to apply user filters for count query (simplified)
-func applyUserFiltersForCount(query *gorm.DB, filter UserSearchFilter) *gorm.DB {
-	if filter.Query != "" {
-		searchTerm := fmt.Sprintf("%%%s%%", strings.ToLower(filter.Query))
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(location) LIKE ?", searchTerm, searchTerm)
+// SQL fragments shared by the search queries
+const (
+	userAverageRating = "(SELECT COALESCE(AVG(sr.score), 0) FROM swap_ratings sr WHERE sr.ratee_id = users.user_id)"
+	skillPopularity   = "((SELECT COUNT(*) FROM user_skills_offered WHERE skill_id = skills.skill_id) + (SELECT COUNT(*) FROM user_skills_wanted WHERE skill_id = skills.skill_id))"
+)
+
+func normalizeSortOrder(order, fallback string) string {
+	switch strings.ToLower(order) {
+	case "asc":
+		return "ASC"
+	case "desc":
+		return "DESC"
 	}
+	return fallback
+}
 
-	if filter.Location != "" {
-		locationTerm := fmt.Sprintf("%%%s%%", strings.ToLower(filter.Location))
-		query = query.Where("LOWER(location) LIKE ?", locationTerm)
+func searchLimit(limit, fallback int) int {
+	if limit <= 0 {
+		return fallback
+	}
+	if limit > 100 {
+		return 100
 	}
+	return limit
+}
 
-	if filter.IsPublic != nil {
-		query = query.Where("is_public = ?", *filter.IsPublic)
+// resolveLocations geocodes location filters, returning the places found and
+// the values the geocoder did not recognise
+func (s *searchService) resolveLocations(locations []string) ([]*geo.Place, []string) {
+	var places []*geo.Place
+	var unresolved []string
+	for _, location := range locations {
+		if s.geocoder != nil {
+			if place, err := s.geocoder.Geocode(location); err == nil {
+				places = append(places, place)
+				continue
+			}
+		}
+		unresolved = append(unresolved, location)
+	}
+	return places, unresolved
+}
+
+// searchCenter works out the point distances are measured from: explicit
+// coordinates, then a place name, then the searching user's own location.
+// It returns nil when there is no center, including an origin user whose
+// location could not be resolved.
+func (s *searchService) searchCenter(ctx context.Context, filter UserSearchFilter) (*geo.Place, error) {
+	switch {
+	case filter.Latitude != nil || filter.Longitude != nil:
+		if filter.Latitude == nil || filter.Longitude == nil ||
+			*filter.Latitude < -90 || *filter.Latitude > 90 ||
+			*filter.Longitude < -180 || *filter.Longitude > 180 {
+			return nil, errors.New("invalid coordinates")
+		}
+		return &geo.Place{Latitude: *filter.Latitude, Longitude: *filter.Longitude}, nil
+
+	case filter.Near != "":
+		if s.geocoder == nil {
+			return nil, errors.New("unknown location")
+		}
+		place, err := s.geocoder.Geocode(filter.Near)
+		if err != nil {
+			return nil, errors.New("unknown location")
+		}
+		return place, nil
+
+	case filter.Origin != nil:
+		var user models.User
+		if err := s.db.WithContext(ctx).Select("latitude", "longitude").Where("user_id = ?", *filter.Origin).First(&user).Error; err != nil {
+			return nil, err
+		}
+		if user.Latitude == nil || user.Longitude == nil {
+			return nil, nil
+		}
+		return &geo.Place{Latitude: *user.Latitude, Longitude: *user.Longitude}, nil
+	}
+	return nil, nil
+}
+
+// distanceSQL is the haversine distance in km from a fixed point to each
+// user. The coordinates are formatted into the SQL so the expression can be
+// used in ORDER BY as well as WHERE.
+func distanceSQL(lat, lng float64) string {
+	latStr := strconv.FormatFloat(lat, 'f', -1, 64)
+	lngStr := strconv.FormatFloat(lng, 'f', -1, 64)
+	return fmt.Sprintf("(2 * %g * ASIN(LEAST(1, SQRT("+
+		"POWER(SIN(RADIANS(users.latitude - %s) / 2), 2) + "+
+		"COS(RADIANS(%s)) * COS(RADIANS(users.latitude)) * POWER(SIN(RADIANS(users.longitude - %s) / 2), 2)))))",
+		geo.EarthRadiusKm, latStr, latStr, lngStr)
+}
+
+// searchHits are the results of the text part of a search. A LiveIndexer
+// provides them as a subquery, so the structured filters, count and
+// pagination run over every hit in one query; other indexers return them
+// all, keyed by ID with their position in relevance order.
+type searchHits struct {
+	query  *gorm.DB
+	ranked map[uuid.UUID]rankedHit
+}
+
+// rankedHit is an index hit together with its place in the relevance order
+type rankedHit struct {
+	searchindex.Hit
+	Position int
+}
+
+// candidates runs the text part of a search through the indexer. nil means
+// the query had no text or field filters, so every row is a candidate.
+func (s *searchService) candidates(ctx context.Context, kind searchindex.Kind, parsed searchQuery) (*searchHits, error) {
+	if parsed.IsEmpty() {
+		return nil, nil
+	}
+
+	q := searchindex.Query{Kind: kind, Text: parsed.Text(), Fields: parsed.Fields}
+	if live, ok := s.indexer.(searchindex.LiveIndexer); ok {
+		query, err := live.HitsQuery(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		return &searchHits{query: query}, nil
+	}
+
+	hits, err := s.indexer.Search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make(map[uuid.UUID]rankedHit, len(hits))
+	for i, hit := range hits {
+		ranked[hit.ID] = rankedHit{Hit: hit, Position: i}
+	}
+	return &searchHits{ranked: ranked}, nil
+}
+
+// relevanceOrder is the ORDER BY for sorting by relevance, with ties broken
+// by fallback, which is also the order when there are no hits to rank. An
+// empty string means the index's own order.
+func (h *searchHits) relevanceOrder(fallback string) string {
+	switch {
+	case h == nil:
+		return fallback
+	case h.query != nil:
+		return "hits.score DESC, " + fallback
+	}
+	return ""
+}
+
+// rankedPage restricts query to the hits (if any) and returns one page of
+// IDs, the hits on that page and the total. With an empty orderBy the page
+// follows the index's relevance order; otherwise the database sorts.
+func rankedPage(query *gorm.DB, idColumn string, hits *searchHits, orderBy string, limit, offset int) ([]uuid.UUID, map[uuid.UUID]searchindex.Hit, int64, error) {
+	switch {
+	case hits == nil:
+		return sortedPage(query, idColumn, orderBy, limit, offset)
+	case hits.query != nil:
+		return joinedPage(query, idColumn, hits.query, orderBy, limit, offset)
+	}
+
+	if len(hits.ranked) == 0 {
+		return nil, nil, 0, nil
+	}
+	candidateIDs := make([]uuid.UUID, 0, len(hits.ranked))
+	for id := range hits.ranked {
+		candidateIDs = append(candidateIDs, id)
+	}
+	query = query.Where(idColumn+" IN ?", candidateIDs)
+
+	var ids []uuid.UUID
+	var total int64
+	if orderBy == "" {
+		if err := query.Pluck(idColumn, &ids).Error; err != nil {
+			return nil, nil, 0, err
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return hits.ranked[ids[i]].Position < hits.ranked[ids[j]].Position
+		})
+
+		total = int64(len(ids))
+		if offset >= len(ids) {
+			return nil, nil, total, nil
+		}
+		ids = ids[offset:]
+		if len(ids) > limit {
+			ids = ids[:limit]
+		}
+	} else {
+		var err error
+		if ids, _, total, err = sortedPage(query, idColumn, orderBy, limit, offset); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+
+	found := make(map[uuid.UUID]searchindex.Hit, len(ids))
+	for _, id := range ids {
+		found[id] = hits.ranked[id].Hit
+	}
+	return ids, found, total, nil
+}
+
+// sortedPage counts query and returns one page of IDs in orderBy
+func sortedPage(query *gorm.DB, idColumn, orderBy string, limit, offset int) ([]uuid.UUID, map[uuid.UUID]searchindex.Hit, int64, error) {
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, nil, 0, err
 	}
+	var ids []uuid.UUID
+	err := query.Order(orderBy).Limit(limit).Offset(offset).Pluck(idColumn, &ids).Error
+	return ids, nil, total, err
+}
+
+// joinedPage joins the hits subquery into query, so a page and the total
+// take one query each however many rows match the text
+func joinedPage(query *gorm.DB, idColumn string, hitsQuery *gorm.DB, orderBy string, limit, offset int) ([]uuid.UUID, map[uuid.UUID]searchindex.Hit, int64, error) {
+	query = query.Joins("JOIN (?) AS hits ON hits.id = "+idColumn, hitsQuery)
 
-	return query
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, nil, 0, err
+	}
+
+	var page []searchindex.Hit
+	err := query.Select(idColumn + " AS id, hits.score, hits.highlight").
+		Order(orderBy).Limit(limit).Offset(offset).
+		Scan(&page).Error
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	ids := make([]uuid.UUID, len(page))
+	found := make(map[uuid.UUID]searchindex.Hit, len(page))
+	for i, hit := range page {
+		ids[i] = hit.ID
+		found[hit.ID] = hit
+	}
+	return ids, found, total, nil
 }
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"context"
 	"errors"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -10,44 +13,46 @@ import (
 
 type SkillService interface {
 	// Skill CRUD operations
-	GetAllSkills() ([]models.Skill, error)
-	GetSkillByID(skillID uuid.UUID) (*models.Skill, error)
-	CreateSkill(name string) (*models.Skill, error)
-	UpdateSkill(skillID uuid.UUID, name string) (*models.Skill, error)
-	DeleteSkill(skillID uuid.UUID) error
+	GetAllSkills(ctx context.Context) ([]models.Skill, error)
+	GetSkillByID(ctx context.Context, skillID uuid.UUID) (*models.Skill, error)
+	// Catalog changes need the skills:manage permission
+	CreateSkill(ctx context.Context, actorID uuid.UUID, name string) (*models.Skill, error)
+	UpdateSkill(ctx context.Context, actorID, skillID uuid.UUID, name string) (*models.Skill, error)
+	DeleteSkill(ctx context.Context, actorID, skillID uuid.UUID) error
 
 	// User skill management
-	AddOfferedSkill(userID, skillID uuid.UUID) error
-	RemoveOfferedSkill(userID, skillID uuid.UUID) error
-	AddWantedSkill(userID, skillID uuid.UUID) error
-	RemoveWantedSkill(userID, skillID uuid.UUID) error
+	AddOfferedSkill(ctx context.Context, userID, skillID uuid.UUID) error
+	RemoveOfferedSkill(ctx context.Context, userID, skillID uuid.UUID) error
+	AddWantedSkill(ctx context.Context, userID, skillID uuid.UUID) error
+	RemoveWantedSkill(ctx context.Context, userID, skillID uuid.UUID) error
 
 	// User skill queries
-	GetUserOfferedSkills(userID uuid.UUID) ([]models.Skill, error)
-	GetUserWantedSkills(userID uuid.UUID) ([]models.Skill, error)
-	GetUsersWithOfferedSkill(skillID uuid.UUID) ([]models.User, error)
-	GetUsersWithWantedSkill(skillID uuid.UUID) ([]models.User, error)
+	GetUserOfferedSkills(ctx context.Context, userID uuid.UUID) ([]models.Skill, error)
+	GetUserWantedSkills(ctx context.Context, userID uuid.UUID) ([]models.Skill, error)
+	GetUsersWithOfferedSkill(ctx context.Context, skillID uuid.UUID) ([]models.User, error)
+	GetUsersWithWantedSkill(ctx context.Context, skillID uuid.UUID) ([]models.User, error)
 }
 
 type skillService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	events *event.Bus
 }
 
-func NewSkillService(db *gorm.DB) SkillService {
-	return &skillService{db: db}
+func NewSkillService(db *gorm.DB, events *event.Bus) SkillService {
+	return &skillService{db: db, events: events}
 }
 
 // GetAllSkills retrieves all available skills
-func (s *skillService) GetAllSkills() ([]models.Skill, error) {
+func (s *skillService) GetAllSkills(ctx context.Context) ([]models.Skill, error) {
 	var skills []models.Skill
-	err := s.db.Find(&skills).Error
+	err := s.db.WithContext(ctx).Find(&skills).Error
 	return skills, err
 }
 
 // GetSkillByID retrieves a skill by its ID
-func (s *skillService) GetSkillByID(skillID uuid.UUID) (*models.Skill, error) {
+func (s *skillService) GetSkillByID(ctx context.Context, skillID uuid.UUID) (*models.Skill, error) {
 	var skill models.Skill
-	err := s.db.First(&skill, "skill_id = ?", skillID).Error
+	err := s.db.WithContext(ctx).First(&skill, "skill_id = ?", skillID).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("skill not found")
@@ -58,66 +63,85 @@ func (s *skillService) GetSkillByID(skillID uuid.UUID) (*models.Skill, error) {
 }
 
 // CreateSkill creates a new skill
-func (s *skillService) CreateSkill(name string) (*models.Skill, error) {
+func (s *skillService) CreateSkill(ctx context.Context, actorID uuid.UUID, name string) (*models.Skill, error) {
+	if err := authorize(s.db.WithContext(ctx), actorID, rbac.PermSkillsManage); err != nil {
+		return nil, err
+	}
+
 	skill := &models.Skill{
 		Name: name,
 	}
 
-	err := s.db.Create(skill).Error
+	err := s.db.WithContext(ctx).Create(skill).Error
 	if err != nil {
 		return nil, err
 	}
 
+	s.events.Publish(event.SkillChanged, skill.SkillID)
 	return skill, nil
 }
 
 // UpdateSkill updates an existing skill
-func (s *skillService) UpdateSkill(skillID uuid.UUID, name string) (*models.Skill, error) {
-	skill, err := s.GetSkillByID(skillID)
+func (s *skillService) UpdateSkill(ctx context.Context, actorID, skillID uuid.UUID, name string) (*models.Skill, error) {
+	if err := authorize(s.db.WithContext(ctx), actorID, rbac.PermSkillsManage); err != nil {
+		return nil, err
+	}
+
+	skill, err := s.GetSkillByID(ctx, skillID)
 	if err != nil {
 		return nil, err
 	}
 
 	skill.Name = name
-	err = s.db.Save(skill).Error
+	err = s.db.WithContext(ctx).Save(skill).Error
 	if err != nil {
 		return nil, err
 	}
 
+	s.events.Publish(event.SkillChanged, skill.SkillID)
 	return skill, nil
 }
 
-// DeleteSkill deletes a skill (admin only)
-func (s *skillService) DeleteSkill(skillID uuid.UUID) error {
+// DeleteSkill deletes a skill that no user offers or wants
+func (s *skillService) DeleteSkill(ctx context.Context, actorID, skillID uuid.UUID) error {
+	if err := authorize(s.db.WithContext(ctx), actorID, rbac.PermSkillsManage); err != nil {
+		return err
+	}
+
 	// Check if skill exists
-	_, err := s.GetSkillByID(skillID)
+	_, err := s.GetSkillByID(ctx, skillID)
 	if err != nil {
 		return err
 	}
 
 	// Check if skill is referenced in user skills or swap requests
 	var offeredCount, wantedCount int64
-	s.db.Model(&models.UserSkillOffered{}).Where("skill_id = ?", skillID).Count(&offeredCount)
-	s.db.Model(&models.UserSkillWanted{}).Where("skill_id = ?", skillID).Count(&wantedCount)
+	s.db.WithContext(ctx).Model(&models.UserSkillOffered{}).Where("skill_id = ?", skillID).Count(&offeredCount)
+	s.db.WithContext(ctx).Model(&models.UserSkillWanted{}).Where("skill_id = ?", skillID).Count(&wantedCount)
 
 	if offeredCount > 0 || wantedCount > 0 {
 		return errors.New("skill is in use and cannot be deleted")
 	}
 
-	return s.db.Delete(&models.Skill{}, "skill_id = ?", skillID).Error
+	if err := s.db.WithContext(ctx).Delete(&models.Skill{}, "skill_id = ?", skillID).Error; err != nil {
+		return err
+	}
+
+	s.events.Publish(event.SkillDeleted, skillID)
+	return nil
 }
 
 // AddOfferedSkill adds a skill to user's offered skills
-func (s *skillService) AddOfferedSkill(userID, skillID uuid.UUID) error {
+func (s *skillService) AddOfferedSkill(ctx context.Context, userID, skillID uuid.UUID) error {
 	// Check if skill exists
-	_, err := s.GetSkillByID(skillID)
+	_, err := s.GetSkillByID(ctx, skillID)
 	if err != nil {
 		return err
 	}
 
 	// Check if already exists
 	var count int64
-	s.db.Model(&models.UserSkillOffered{}).Where("user_id = ? AND skill_id = ?", userID, skillID).Count(&count)
+	s.db.WithContext(ctx).Model(&models.UserSkillOffered{}).Where("user_id = ? AND skill_id = ?", userID, skillID).Count(&count)
 	if count > 0 {
 		return errors.New("skill already in offered skills")
 	}
@@ -127,29 +151,39 @@ func (s *skillService) AddOfferedSkill(userID, skillID uuid.UUID) error {
 		SkillID: skillID,
 	}
 
-	return s.db.Create(userSkill).Error
+	if err := s.db.WithContext(ctx).Create(userSkill).Error; err != nil {
+		return err
+	}
+
+	s.events.Publish(event.UserChanged, userID)
+	return nil
 }
 
 // RemoveOfferedSkill removes a skill from user's offered skills
-func (s *skillService) RemoveOfferedSkill(userID, skillID uuid.UUID) error {
-	result := s.db.Delete(&models.UserSkillOffered{}, "user_id = ? AND skill_id = ?", userID, skillID)
+func (s *skillService) RemoveOfferedSkill(ctx context.Context, userID, skillID uuid.UUID) error {
+	result := s.db.WithContext(ctx).Delete(&models.UserSkillOffered{}, "user_id = ? AND skill_id = ?", userID, skillID)
 	if result.RowsAffected == 0 {
 		return errors.New("offered skill not found")
 	}
-	return result.Error
+	if result.Error != nil {
+		return result.Error
+	}
+
+	s.events.Publish(event.UserChanged, userID)
+	return nil
 }
 
 // AddWantedSkill adds a skill to user's wanted skills
-func (s *skillService) AddWantedSkill(userID, skillID uuid.UUID) error {
+func (s *skillService) AddWantedSkill(ctx context.Context, userID, skillID uuid.UUID) error {
 	// Check if skill exists
-	_, err := s.GetSkillByID(skillID)
+	_, err := s.GetSkillByID(ctx, skillID)
 	if err != nil {
 		return err
 	}
 
 	// Check if already exists
 	var count int64
-	s.db.Model(&models.UserSkillWanted{}).Where("user_id = ? AND skill_id = ?", userID, skillID).Count(&count)
+	s.db.WithContext(ctx).Model(&models.UserSkillWanted{}).Where("user_id = ? AND skill_id = ?", userID, skillID).Count(&count)
 	if count > 0 {
 		return errors.New("skill already in wanted skills")
 	}
@@ -159,22 +193,32 @@ func (s *skillService) AddWantedSkill(userID, skillID uuid.UUID) error {
 		SkillID: skillID,
 	}
 
-	return s.db.Create(userSkill).Error
+	if err := s.db.WithContext(ctx).Create(userSkill).Error; err != nil {
+		return err
+	}
+
+	s.events.Publish(event.UserChanged, userID)
+	return nil
 }
 
 // RemoveWantedSkill removes a skill from user's wanted skills
-func (s *skillService) RemoveWantedSkill(userID, skillID uuid.UUID) error {
-	result := s.db.Delete(&models.UserSkillWanted{}, "user_id = ? AND skill_id = ?", userID, skillID)
+func (s *skillService) RemoveWantedSkill(ctx context.Context, userID, skillID uuid.UUID) error {
+	result := s.db.WithContext(ctx).Delete(&models.UserSkillWanted{}, "user_id = ? AND skill_id = ?", userID, skillID)
 	if result.RowsAffected == 0 {
 		return errors.New("wanted skill not found")
 	}
-	return result.Error
+	if result.Error != nil {
+		return result.Error
+	}
+
+	s.events.Publish(event.UserChanged, userID)
+	return nil
 }
 
 // GetUserOfferedSkills retrieves all skills offered by a user
-func (s *skillService) GetUserOfferedSkills(userID uuid.UUID) ([]models.Skill, error) {
+func (s *skillService) GetUserOfferedSkills(ctx context.Context, userID uuid.UUID) ([]models.Skill, error) {
 	var skills []models.Skill
-	err := s.db.Table("skills").
+	err := s.db.WithContext(ctx).Table("skills").
 		Joins("JOIN user_skills_offered ON skills.skill_id = user_skills_offered.skill_id").
 		Where("user_skills_offered.user_id = ?", userID).
 		Find(&skills).Error
@@ -183,9 +227,9 @@ func (s *skillService) GetUserOfferedSkills(userID uuid.UUID) ([]models.Skill, e
 }
 
 // GetUserWantedSkills retrieves all skills wanted by a user
-func (s *skillService) GetUserWantedSkills(userID uuid.UUID) ([]models.Skill, error) {
+func (s *skillService) GetUserWantedSkills(ctx context.Context, userID uuid.UUID) ([]models.Skill, error) {
 	var skills []models.Skill
-	err := s.db.Table("skills").
+	err := s.db.WithContext(ctx).Table("skills").
 		Joins("JOIN user_skills_wanted ON skills.skill_id = user_skills_wanted.skill_id").
 		Where("user_skills_wanted.user_id = ?", userID).
 		Find(&skills).Error
@@ -194,9 +238,9 @@ func (s *skillService) GetUserWantedSkills(userID uuid.UUID) ([]models.Skill, er
 }
 
 // GetUsersWithOfferedSkill retrieves all users who offer a specific skill
-func (s *skillService) GetUsersWithOfferedSkill(skillID uuid.UUID) ([]models.User, error) {
+func (s *skillService) GetUsersWithOfferedSkill(ctx context.Context, skillID uuid.UUID) ([]models.User, error) {
 	var users []models.User
-	err := s.db.Table("users").
+	err := s.db.WithContext(ctx).Table("users").
 		Joins("JOIN user_skills_offered ON users.user_id = user_skills_offered.user_id").
 		Where("user_skills_offered.skill_id = ? AND users.is_public = true AND users.deleted_at IS NULL", skillID).
 		Find(&users).Error
@@ -205,9 +249,9 @@ func (s *skillService) GetUsersWithOfferedSkill(skillID uuid.UUID) ([]models.Use
 }
 
 // GetUsersWithWantedSkill retrieves all users who want a specific skill
-func (s *skillService) GetUsersWithWantedSkill(skillID uuid.UUID) ([]models.User, error) {
+func (s *skillService) GetUsersWithWantedSkill(ctx context.Context, skillID uuid.UUID) ([]models.User, error) {
 	var users []models.User
-	err := s.db.Table("users").
+	err := s.db.WithContext(ctx).Table("users").
 		Joins("JOIN user_skills_wanted ON users.user_id = user_skills_wanted.user_id").
 		Where("user_skills_wanted.skill_id = ? AND users.is_public = true AND users.deleted_at IS NULL", skillID).
 		Find(&users).Error
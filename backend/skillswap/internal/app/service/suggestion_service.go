@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// suggestionRebuildDelay batches bursts of change events into one rebuild
+const suggestionRebuildDelay = 2 * time.Second
+
+// Suggestion types
+const (
+	SuggestionTypeSkill = "skill"
+	SuggestionTypeUser  = "user"
+)
+
+type SuggestionService interface {
+	// Suggest returns up to limit completions for prefix. kind is "skills",
+	// "users" or "all".
+	Suggest(prefix, kind string, limit int) []Suggestion
+
+	// Rebuild reloads skills, aliases and public users from the database
+	Rebuild(ctx context.Context) error
+}
+
+// Suggestion is one autocomplete entry. Popularity is the number of users
+// offering or wanting a skill, or the number of accepted swaps for a user.
+// Alias is set when the prefix matched an alias rather than the name.
+type Suggestion struct {
+	ID         uuid.UUID `json:"id"`
+	Type       string    `json:"type"`
+	Text       string    `json:"text"`
+	Alias      string    `json:"alias,omitempty"`
+	Popularity int64     `json:"popularity"`
+}
+
+// suggestionIndex is swapped in whole on every rebuild so lookups never lock
+type suggestionIndex struct {
+	skills *suggestionTrie
+	users  *suggestionTrie
+}
+
+type suggestionService struct {
+	db    *gorm.DB
+	index atomic.Pointer[suggestionIndex]
+
+	mu             sync.Mutex
+	rebuildPending bool
+}
+
+// NewSuggestionService builds the initial index and keeps it up to date from
+// skill, user and swap events
+func NewSuggestionService(db *gorm.DB, events *event.Bus) SuggestionService {
+	s := &suggestionService{db: db}
+	s.index.Store(&suggestionIndex{
+		skills: newSuggestionTrie(nil),
+		users:  newSuggestionTrie(nil),
+	})
+
+	if err := s.Rebuild(context.Background()); err != nil {
+		log.Printf("Warning: Could not build suggestion index: %v", err)
+	}
+
+	if events != nil {
+		events.Subscribe(s.scheduleRebuild,
+			event.SkillChanged, event.SkillDeleted,
+			event.UserChanged, event.UserDeleted,
+			event.SwapChanged)
+	}
+
+	return s
+}
+
+// Suggest looks up completions in the current in-memory index
+func (s *suggestionService) Suggest(prefix, kind string, limit int) []Suggestion {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > maxSuggestions {
+		limit = maxSuggestions
+	}
+
+	index := s.index.Load()
+	switch kind {
+	case "users":
+		return index.users.lookup(prefix, limit)
+	case "all":
+		return mergeSuggestions(index.skills.lookup(prefix, limit), index.users.lookup(prefix, limit), limit)
+	default:
+		return index.skills.lookup(prefix, limit)
+	}
+}
+
+// Rebuild loads everything needed for suggestions and swaps in a new index
+func (s *suggestionService) Rebuild(ctx context.Context) error {
+	skills, err := s.loadSkillEntries(ctx)
+	if err != nil {
+		return err
+	}
+	users, err := s.loadUserEntries(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.index.Store(&suggestionIndex{
+		skills: newSuggestionTrie(skills),
+		users:  newSuggestionTrie(users),
+	})
+	return nil
+}
+
+// scheduleRebuild rebuilds shortly after the first of a burst of events
+func (s *suggestionService) scheduleRebuild(event.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rebuildPending {
+		return
+	}
+	s.rebuildPending = true
+
+	time.AfterFunc(suggestionRebuildDelay, func() {
+		s.mu.Lock()
+		s.rebuildPending = false
+		s.mu.Unlock()
+
+		if err := s.Rebuild(context.Background()); err != nil {
+			log.Printf("Warning: Could not rebuild suggestion index: %v", err)
+		}
+	})
+}
+
+func (s *suggestionService) loadSkillEntries(ctx context.Context) ([]suggestionEntry, error) {
+	var rows []struct {
+		SkillID    uuid.UUID
+		Name       string
+		Popularity int64
+	}
+	err := s.db.WithContext(ctx).Table("skills").
+		Select("skills.skill_id, skills.name, " + skillPopularity + " AS popularity").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var aliases []models.SkillAlias
+	if err := s.db.WithContext(ctx).Find(&aliases).Error; err != nil {
+		return nil, err
+	}
+	aliasesBySkill := make(map[uuid.UUID][]string)
+	for _, alias := range aliases {
+		aliasesBySkill[alias.SkillID] = append(aliasesBySkill[alias.SkillID], alias.Alias)
+	}
+
+	entries := make([]suggestionEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = suggestionEntry{
+			Suggestion: Suggestion{
+				ID:         row.SkillID,
+				Type:       SuggestionTypeSkill,
+				Text:       row.Name,
+				Popularity: row.Popularity,
+			},
+			aliases: aliasesBySkill[row.SkillID],
+		}
+	}
+	return entries, nil
+}
+
+// loadUserEntries loads public, non-banned users ranked by accepted swaps
+func (s *suggestionService) loadUserEntries(ctx context.Context) ([]suggestionEntry, error) {
+	var rows []struct {
+		UserID     uuid.UUID
+		Name       string
+		Popularity int64
+	}
+	err := s.db.WithContext(ctx).Table("users").
+		Select(`users.user_id, users.name,
+			(SELECT COUNT(*) FROM swap_requests sr
+			 WHERE (sr.requester_id = users.user_id OR sr.responder_id = users.user_id)
+			   AND sr.status = ? AND sr.deleted_at IS NULL) AS popularity`, models.StatusAccepted).
+		Where("users.deleted_at IS NULL AND users.is_public = ? AND users.is_banned = ?", true, false).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]suggestionEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = suggestionEntry{
+			Suggestion: Suggestion{
+				ID:         row.UserID,
+				Type:       SuggestionTypeUser,
+				Text:       row.Name,
+				Popularity: row.Popularity,
+			},
+		}
+	}
+	return entries, nil
+}
+
+// mergeSuggestions interleaves skill and user suggestions by popularity,
+// preferring skills on ties
+func mergeSuggestions(skills, users []Suggestion, limit int) []Suggestion {
+	merged := append(append(make([]Suggestion, 0, len(skills)+len(users)), skills...), users...)
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Popularity != merged[j].Popularity {
+			return merged[i].Popularity > merged[j].Popularity
+		}
+		return merged[i].Type == SuggestionTypeSkill && merged[j].Type == SuggestionTypeUser
+	})
+
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
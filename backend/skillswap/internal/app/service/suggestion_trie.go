@@ -0,0 +1,166 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSuggestions is how many suggestions each trie node keeps precomputed,
+// and so the largest limit a caller can ask for
+const maxSuggestions = 20
+
+// suggestionTrie is an immutable prefix tree over suggestion keys. Every node
+// stores the best-ranked entries below it, so a lookup is a walk down the
+// prefix followed by a slice copy, independent of how many entries match.
+type suggestionTrie struct {
+	root    *trieNode
+	entries []suggestionEntry
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	top      []int // indexes into entries, best first
+}
+
+// suggestionEntry is a suggestion plus the names it can be found by
+type suggestionEntry struct {
+	Suggestion
+	aliases []string
+}
+
+// newSuggestionTrie ranks the entries by popularity and indexes each one
+// under its name, its aliases and every word within them, so "learn" finds
+// "Machine Learning" and "k8s" finds "Kubernetes"
+func newSuggestionTrie(entries []suggestionEntry) *suggestionTrie {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Popularity != entries[j].Popularity {
+			return entries[i].Popularity > entries[j].Popularity
+		}
+		return strings.ToLower(entries[i].Text) < strings.ToLower(entries[j].Text)
+	})
+
+	t := &suggestionTrie{root: &trieNode{}, entries: entries}
+
+	// Entries are inserted best first, so appending keeps every node's top
+	// list in rank order
+	for i, entry := range entries {
+		for _, name := range append([]string{entry.Text}, entry.aliases...) {
+			for _, key := range suggestionKeys(name) {
+				t.insert(key, i)
+			}
+		}
+	}
+
+	return t
+}
+
+func (t *suggestionTrie) insert(key string, entry int) {
+	node := t.root
+	for _, r := range key {
+		child, ok := node.children[r]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[rune]*trieNode)
+			}
+			child = &trieNode{}
+			node.children[r] = child
+		}
+		node = child
+		node.add(entry)
+	}
+}
+
+func (n *trieNode) add(entry int) {
+	if len(n.top) >= maxSuggestions {
+		return
+	}
+	// The same entry reaches a node through several keys ("go" and "golang")
+	if len(n.top) > 0 && n.top[len(n.top)-1] == entry {
+		return
+	}
+	n.top = append(n.top, entry)
+}
+
+// lookup returns up to limit entries whose name, alias or one of their words
+// starts with prefix
+func (t *suggestionTrie) lookup(prefix string, limit int) []Suggestion {
+	normalized := normalizeSuggestionText(prefix)
+	if normalized == "" {
+		return []Suggestion{}
+	}
+
+	node := t.root
+	for _, r := range normalized {
+		node = node.children[r]
+		if node == nil {
+			return []Suggestion{}
+		}
+	}
+	if limit > len(node.top) {
+		limit = len(node.top)
+	}
+
+	suggestions := make([]Suggestion, 0, limit)
+	for _, idx := range node.top[:limit] {
+		entry := t.entries[idx]
+		suggestion := entry.Suggestion
+		if !hasKeyWithPrefix(entry.Text, normalized) {
+			suggestion.Alias = matchingAlias(entry.aliases, normalized)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
+
+// suggestionKeys returns the normalized name and every suffix that starts at
+// a word boundary ("ui/ux design" -> "ui/ux design", "ux design", "design")
+func suggestionKeys(name string) []string {
+	normalized := normalizeSuggestionText(name)
+	if normalized == "" {
+		return nil
+	}
+
+	keys := []string{normalized}
+	prevWordChar := true
+	for i, r := range normalized {
+		wordChar := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if wordChar && !prevWordChar && i > 0 {
+			keys = append(keys, normalized[i:])
+		}
+		prevWordChar = wordChar
+	}
+	return keys
+}
+
+// normalizeSuggestionText lower-cases, strips diacritics and collapses
+// whitespace, so "cafe" finds "Café"
+func normalizeSuggestionText(s string) string {
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, norm.NFD.String(s))
+	return strings.Join(strings.Fields(strings.ToLower(norm.NFC.String(stripped))), " ")
+}
+
+func hasKeyWithPrefix(name, prefix string) bool {
+	for _, key := range suggestionKeys(name) {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchingAlias(aliases []string, prefix string) string {
+	for _, alias := range aliases {
+		if hasKeyWithPrefix(alias, prefix) {
+			return alias
+		}
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+)
+
+func skillEntry(name string, popularity int64, aliases ...string) suggestionEntry {
+	return suggestionEntry{
+		Suggestion: Suggestion{Type: SuggestionTypeSkill, Text: name, Popularity: popularity},
+		aliases:    aliases,
+	}
+}
+
+func TestSuggestionTrieLookup(t *testing.T) {
+	// Popularity stands for how many users offer or want each skill
+	trie := newSuggestionTrie([]suggestionEntry{
+		skillEntry("Golang", 12, "go"),
+		skillEntry("Google Sheets", 30),
+		skillEntry("Machine Learning", 25, "ML"),
+		skillEntry("Kubernetes", 8, "k8s"),
+		skillEntry("UI/UX Design", 5),
+		skillEntry("Café Latte Art", 3),
+		skillEntry("gouache", 12),
+		skillEntry("Go-kart Racing", 1, "karting"),
+	})
+
+	type result struct{ text, alias string }
+	tests := []struct {
+		name   string
+		prefix string
+		limit  int
+		want   []result
+	}{
+		{
+			name:   "ranked by popularity, ties by name",
+			prefix: "go",
+			limit:  10,
+			want:   []result{{"Google Sheets", ""}, {"Golang", ""}, {"gouache", ""}, {"Go-kart Racing", ""}},
+		},
+		{name: "limit", prefix: "go", limit: 2, want: []result{{"Google Sheets", ""}, {"Golang", ""}}},
+		{name: "word inside the name", prefix: "learn", limit: 10, want: []result{{"Machine Learning", ""}}},
+		{name: "word after punctuation", prefix: "ux", limit: 10, want: []result{{"UI/UX Design", ""}}},
+		{name: "alias", prefix: "k8", limit: 10, want: []result{{"Kubernetes", "k8s"}}},
+		{name: "alias is case-insensitive", prefix: "ml", limit: 10, want: []result{{"Machine Learning", "ML"}}},
+		{
+			name:   "alias and name both match",
+			prefix: "kart",
+			limit:  10,
+			want:   []result{{"Go-kart Racing", ""}},
+		},
+		{name: "case and whitespace", prefix: "  MACHINE   le", limit: 10, want: []result{{"Machine Learning", ""}}},
+		{name: "diacritics in the name", prefix: "cafe", limit: 10, want: []result{{"Café Latte Art", ""}}},
+		{name: "diacritics in the prefix", prefix: "Gõû", limit: 10, want: []result{{"gouache", ""}}},
+		{name: "no match", prefix: "rust", limit: 10, want: nil},
+		{name: "blank prefix", prefix: "   ", limit: 10, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trie.lookup(tt.prefix, tt.limit)
+			if got == nil {
+				t.Fatal("lookup returned nil, want an empty slice")
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("lookup(%q) = %+v, want %v", tt.prefix, got, tt.want)
+			}
+			for i, s := range got {
+				if s.Text != tt.want[i].text || s.Alias != tt.want[i].alias {
+					t.Fatalf("lookup(%q) = %+v, want %v", tt.prefix, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggestionTrieKeepsTopEntriesPerNode(t *testing.T) {
+	var entries []suggestionEntry
+	for i := 0; i < maxSuggestions+5; i++ {
+		entries = append(entries, skillEntry(fmt.Sprintf("Skill %02d", i), int64(i)))
+	}
+	trie := newSuggestionTrie(entries)
+
+	got := trie.lookup("skill", 100)
+	if len(got) != maxSuggestions {
+		t.Fatalf("got %d suggestions, want %d", len(got), maxSuggestions)
+	}
+	for i, s := range got {
+		if want := fmt.Sprintf("Skill %02d", maxSuggestions+4-i); s.Text != want {
+			t.Fatalf("suggestion %d = %q, want %q", i, s.Text, want)
+		}
+	}
+
+	// Less popular entries are still found by a more specific prefix
+	if got := trie.lookup("skill 00", 10); len(got) != 1 || got[0].Text != "Skill 00" {
+		t.Errorf("lookup(skill 00) = %+v", got)
+	}
+}
+
+func BenchmarkSuggestionLookup(b *testing.B) {
+	words := []string{
+		"advanced", "applied", "basic", "creative", "digital", "french", "guitar", "japanese",
+		"machine", "music", "piano", "python", "rust", "spanish", "urban", "web",
+	}
+	topics := []string{
+		"design", "development", "drawing", "learning", "painting", "photography", "production", "writing",
+	}
+
+	// Around 10,000 skills, each with an alias
+	var entries []suggestionEntry
+	for i := 0; i < 10000; i++ {
+		name := fmt.Sprintf("%s %s %s %d", words[i%len(words)], words[(i/len(words))%len(words)], topics[i%len(topics)], i)
+		entries = append(entries, skillEntry(name, int64(i%97), fmt.Sprintf("s%d", i)))
+	}
+	trie := newSuggestionTrie(entries)
+	prefixes := []string{"g", "py", "mach", "web dev", "photo", "s12", "unknown"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie.lookup(prefixes[i%len(prefixes)], 10)
+	}
+}
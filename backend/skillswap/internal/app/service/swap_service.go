@@ -1,8 +1,12 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"sort"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/geo"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -10,19 +14,19 @@ import (
 
 type SwapService interface {
 	// Swap request CRUD operations
-	CreateSwapRequest(req *CreateSwapRequestDTO) (*models.SwapRequest, error)
-	GetSwapRequestByID(swapID uuid.UUID) (*models.SwapRequest, error)
-	GetUserSwapRequests(userID uuid.UUID, filter SwapRequestFilter) ([]models.SwapRequest, error)
-	UpdateSwapStatus(swapID uuid.UUID, userID uuid.UUID, status models.SwapStatus) (*models.SwapRequest, error)
-	DeleteSwapRequest(swapID uuid.UUID, userID uuid.UUID) error
+	CreateSwapRequest(ctx context.Context, req *CreateSwapRequestDTO) (*models.SwapRequest, error)
+	GetSwapRequestByID(ctx context.Context, swapID uuid.UUID) (*models.SwapRequest, error)
+	GetUserSwapRequests(ctx context.Context, userID uuid.UUID, filter SwapRequestFilter) ([]models.SwapRequest, error)
+	UpdateSwapStatus(ctx context.Context, swapID uuid.UUID, userID uuid.UUID, status models.SwapStatus) (*models.SwapRequest, error)
+	DeleteSwapRequest(ctx context.Context, swapID uuid.UUID, userID uuid.UUID) error
 
 	// Swap request queries
-	GetSwapRequestsForUser(userID uuid.UUID) (*SwapRequestsResponse, error)
-	GetPendingSwapRequests(userID uuid.UUID) ([]models.SwapRequest, error)
-	GetSwapHistory(userID uuid.UUID) ([]models.SwapRequest, error)
+	GetSwapRequestsForUser(ctx context.Context, userID uuid.UUID) (*SwapRequestsResponse, error)
+	GetPendingSwapRequests(ctx context.Context, userID uuid.UUID) ([]models.SwapRequest, error)
+	GetSwapHistory(ctx context.Context, userID uuid.UUID) ([]models.SwapRequest, error)
 
 	// Matching and recommendations
-	FindPotentialMatches(userID uuid.UUID) ([]SwapMatch, error)
+	FindPotentialMatches(ctx context.Context, userID uuid.UUID, opts MatchOptions) ([]SwapMatch, error)
 }
 
 // DTOs and Request structures
@@ -50,27 +54,45 @@ type SwapMatch struct {
 	User         models.User  `json:"user"`
 	OfferedSkill models.Skill `json:"offered_skill"`
 	WantedSkill  models.Skill `json:"wanted_skill"`
-	MatchScore   int          `json:"match_score"` // 1-100 compatibility score
+	MatchScore   int          `json:"match_score"`           // 1-100 compatibility score
+	DistanceKm   *float64     `json:"distance_km,omitempty"` // Set when both users have a location
+}
+
+// MatchOptions narrows potential matches. RadiusKm limits matches to users
+// near the requesting user; IncludeRemote also keeps remote users beyond it.
+type MatchOptions struct {
+	RadiusKm      *float64
+	IncludeRemote bool
 }
 
 type swapService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	events *event.Bus
 }
 
-func NewSwapService(db *gorm.DB) SwapService {
-	return &swapService{db: db}
+func NewSwapService(db *gorm.DB, events *event.Bus) SwapService {
+	return &swapService{db: db, events: events}
 }
 
 // CreateSwapRequest creates a new swap request
-func (s *swapService) CreateSwapRequest(req *CreateSwapRequestDTO) (*models.SwapRequest, error) {
+func (s *swapService) CreateSwapRequest(ctx context.Context, req *CreateSwapRequestDTO) (*models.SwapRequest, error) {
 	// Validate that requester and responder are different
 	if req.RequesterID == req.ResponderID {
 		return nil, errors.New("cannot create swap request with yourself")
 	}
 
+	// Only users who confirmed their email can ask others for a swap
+	var requester models.User
+	if err := s.db.WithContext(ctx).Select("user_id", "email_verified_at").Where("user_id = ?", req.RequesterID).First(&requester).Error; err != nil {
+		return nil, errors.New("requester not found")
+	}
+	if requester.EmailVerifiedAt == nil {
+		return nil, errors.New("verify your email address before requesting swaps")
+	}
+
 	// Validate that requester offers the offered skill
 	var offeredCount int64
-	s.db.Model(&models.UserSkillOffered{}).
+	s.db.WithContext(ctx).Model(&models.UserSkillOffered{}).
 		Where("user_id = ? AND skill_id = ?", req.RequesterID, req.OfferedSkillID).
 		Count(&offeredCount)
 	if offeredCount == 0 {
@@ -79,7 +101,7 @@ func (s *swapService) CreateSwapRequest(req *CreateSwapRequestDTO) (*models.Swap
 
 	// Validate that responder wants the offered skill
 	var wantedCount int64
-	s.db.Model(&models.UserSkillWanted{}).
+	s.db.WithContext(ctx).Model(&models.UserSkillWanted{}).
 		Where("user_id = ? AND skill_id = ?", req.ResponderID, req.OfferedSkillID).
 		Count(&wantedCount)
 	if wantedCount == 0 {
@@ -88,7 +110,7 @@ func (s *swapService) CreateSwapRequest(req *CreateSwapRequestDTO) (*models.Swap
 
 	// Validate that responder offers the wanted skill
 	var responderOffersCount int64
-	s.db.Model(&models.UserSkillOffered{}).
+	s.db.WithContext(ctx).Model(&models.UserSkillOffered{}).
 		Where("user_id = ? AND skill_id = ?", req.ResponderID, req.WantedSkillID).
 		Count(&responderOffersCount)
 	if responderOffersCount == 0 {
@@ -97,7 +119,7 @@ func (s *swapService) CreateSwapRequest(req *CreateSwapRequestDTO) (*models.Swap
 
 	// Check for existing pending request between same users and skills
 	var existingCount int64
-	s.db.Model(&models.SwapRequest{}).
+	s.db.WithContext(ctx).Model(&models.SwapRequest{}).
 		Where("requester_id = ? AND responder_id = ? AND offered_skill_id = ? AND wanted_skill_id = ? AND status = ?",
 			req.RequesterID, req.ResponderID, req.OfferedSkillID, req.WantedSkillID, models.StatusPending).
 		Count(&existingCount)
@@ -113,13 +135,15 @@ func (s *swapService) CreateSwapRequest(req *CreateSwapRequestDTO) (*models.Swap
 		Status:         models.StatusPending,
 	}
 
-	err := s.db.Create(swapRequest).Error
+	err := s.db.WithContext(ctx).Create(swapRequest).Error
 	if err != nil {
 		return nil, err
 	}
 
+	s.events.Publish(event.SwapChanged, swapRequest.SwapID)
+
 	// Load relationships
-	err = s.db.Preload("Requester").Preload("Responder").
+	err = s.db.WithContext(ctx).Preload("Requester").Preload("Responder").
 		Preload("OfferedSkill").Preload("WantedSkill").
 		First(swapRequest, swapRequest.SwapID).Error
 
@@ -127,9 +151,9 @@ func (s *swapService) CreateSwapRequest(req *CreateSwapRequestDTO) (*models.Swap
 }
 
 // GetSwapRequestByID retrieves a swap request by ID
-func (s *swapService) GetSwapRequestByID(swapID uuid.UUID) (*models.SwapRequest, error) {
+func (s *swapService) GetSwapRequestByID(ctx context.Context, swapID uuid.UUID) (*models.SwapRequest, error) {
 	var swapRequest models.SwapRequest
-	err := s.db.Preload("Requester").Preload("Responder").
+	err := s.db.WithContext(ctx).Preload("Requester").Preload("Responder").
 		Preload("OfferedSkill").Preload("WantedSkill").
 		First(&swapRequest, "swap_id = ?", swapID).Error
 
@@ -144,8 +168,8 @@ func (s *swapService) GetSwapRequestByID(swapID uuid.UUID) (*models.SwapRequest,
 }
 
 // GetUserSwapRequests retrieves swap requests for a user with filtering
-func (s *swapService) GetUserSwapRequests(userID uuid.UUID, filter SwapRequestFilter) ([]models.SwapRequest, error) {
-	query := s.db.Model(&models.SwapRequest{}).
+func (s *swapService) GetUserSwapRequests(ctx context.Context, userID uuid.UUID, filter SwapRequestFilter) ([]models.SwapRequest, error) {
+	query := s.db.WithContext(ctx).Model(&models.SwapRequest{}).
 		Preload("Requester").Preload("Responder").
 		Preload("OfferedSkill").Preload("WantedSkill")
 
@@ -183,9 +207,9 @@ func (s *swapService) GetUserSwapRequests(userID uuid.UUID, filter SwapRequestFi
 }
 
 // UpdateSwapStatus updates the status of a swap request
-func (s *swapService) UpdateSwapStatus(swapID uuid.UUID, userID uuid.UUID, status models.SwapStatus) (*models.SwapRequest, error) {
+func (s *swapService) UpdateSwapStatus(ctx context.Context, swapID uuid.UUID, userID uuid.UUID, status models.SwapStatus) (*models.SwapRequest, error) {
 	// Get the swap request
-	swapRequest, err := s.GetSwapRequestByID(swapID)
+	swapRequest, err := s.GetSwapRequestByID(ctx, swapID)
 	if err != nil {
 		return nil, err
 	}
@@ -211,17 +235,19 @@ func (s *swapService) UpdateSwapStatus(swapID uuid.UUID, userID uuid.UUID, statu
 
 	// Update status
 	swapRequest.Status = status
-	err = s.db.Save(swapRequest).Error
+	err = s.db.WithContext(ctx).Save(swapRequest).Error
 	if err != nil {
 		return nil, err
 	}
 
+	s.events.Publish(event.SwapChanged, swapRequest.SwapID)
+
 	return swapRequest, nil
 }
 
 // DeleteSwapRequest deletes a swap request (only requester can delete)
-func (s *swapService) DeleteSwapRequest(swapID uuid.UUID, userID uuid.UUID) error {
-	swapRequest, err := s.GetSwapRequestByID(swapID)
+func (s *swapService) DeleteSwapRequest(ctx context.Context, swapID uuid.UUID, userID uuid.UUID) error {
+	swapRequest, err := s.GetSwapRequestByID(ctx, swapID)
 	if err != nil {
 		return err
 	}
@@ -236,21 +262,26 @@ func (s *swapService) DeleteSwapRequest(swapID uuid.UUID, userID uuid.UUID) erro
 		return errors.New("can only delete pending requests")
 	}
 
-	return s.db.Delete(&models.SwapRequest{}, "swap_id = ?", swapID).Error
+	if err := s.db.WithContext(ctx).Delete(&models.SwapRequest{}, "swap_id = ?", swapID).Error; err != nil {
+		return err
+	}
+
+	s.events.Publish(event.SwapDeleted, swapID)
+	return nil
 }
 
 // GetSwapRequestsForUser retrieves organized swap requests for a user
-func (s *swapService) GetSwapRequestsForUser(userID uuid.UUID) (*SwapRequestsResponse, error) {
+func (s *swapService) GetSwapRequestsForUser(ctx context.Context, userID uuid.UUID) (*SwapRequestsResponse, error) {
 	// Get sent requests
 	sentFilter := SwapRequestFilter{Sent: true, Limit: 50}
-	sent, err := s.GetUserSwapRequests(userID, sentFilter)
+	sent, err := s.GetUserSwapRequests(ctx, userID, sentFilter)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get received requests
 	receivedFilter := SwapRequestFilter{Received: true, Limit: 50}
-	received, err := s.GetUserSwapRequests(userID, receivedFilter)
+	received, err := s.GetUserSwapRequests(ctx, userID, receivedFilter)
 	if err != nil {
 		return nil, err
 	}
@@ -262,16 +293,16 @@ func (s *swapService) GetSwapRequestsForUser(userID uuid.UUID) (*SwapRequestsRes
 }
 
 // GetPendingSwapRequests retrieves pending swap requests for a user
-func (s *swapService) GetPendingSwapRequests(userID uuid.UUID) ([]models.SwapRequest, error) {
+func (s *swapService) GetPendingSwapRequests(ctx context.Context, userID uuid.UUID) ([]models.SwapRequest, error) {
 	status := models.StatusPending
 	filter := SwapRequestFilter{Status: &status, Limit: 100}
-	return s.GetUserSwapRequests(userID, filter)
+	return s.GetUserSwapRequests(ctx, userID, filter)
 }
 
 // GetSwapHistory retrieves completed swap requests for a user
-func (s *swapService) GetSwapHistory(userID uuid.UUID) ([]models.SwapRequest, error) {
+func (s *swapService) GetSwapHistory(ctx context.Context, userID uuid.UUID) ([]models.SwapRequest, error) {
 	var swapRequests []models.SwapRequest
-	err := s.db.Model(&models.SwapRequest{}).
+	err := s.db.WithContext(ctx).Model(&models.SwapRequest{}).
 		Preload("Requester").Preload("Responder").
 		Preload("OfferedSkill").Preload("WantedSkill").
 		Where("(requester_id = ? OR responder_id = ?) AND status IN ?",
@@ -284,12 +315,27 @@ func (s *swapService) GetSwapHistory(userID uuid.UUID) ([]models.SwapRequest, er
 }
 
 // FindPotentialMatches finds potential swap matches for a user
-func (s *swapService) FindPotentialMatches(userID uuid.UUID) ([]SwapMatch, error) {
+func (s *swapService) FindPotentialMatches(ctx context.Context, userID uuid.UUID, opts MatchOptions) ([]SwapMatch, error) {
 	var matches []SwapMatch
 
+	var self models.User
+	if err := s.db.WithContext(ctx).Select("user_id", "latitude", "longitude").Where("user_id = ?", userID).First(&self).Error; err != nil {
+		return nil, err
+	}
+	hasLocation := self.Latitude != nil && self.Longitude != nil
+
+	if opts.RadiusKm != nil {
+		if *opts.RadiusKm <= 0 || *opts.RadiusKm > maxSearchRadiusKm {
+			return nil, errors.New("invalid radius")
+		}
+		if !hasLocation {
+			return nil, errors.New("your profile has no resolved location")
+		}
+	}
+
 	// Get user's offered skills
 	var userOfferedSkills []models.Skill
-	err := s.db.Table("skills").
+	err := s.db.WithContext(ctx).Table("skills").
 		Joins("JOIN user_skills_offered ON skills.skill_id = user_skills_offered.skill_id").
 		Where("user_skills_offered.user_id = ?", userID).
 		Find(&userOfferedSkills).Error
@@ -299,7 +345,7 @@ func (s *swapService) FindPotentialMatches(userID uuid.UUID) ([]SwapMatch, error
 
 	// Get user's wanted skills
 	var userWantedSkills []models.Skill
-	err = s.db.Table("skills").
+	err = s.db.WithContext(ctx).Table("skills").
 		Joins("JOIN user_skills_wanted ON skills.skill_id = user_skills_wanted.skill_id").
 		Where("user_skills_wanted.user_id = ?", userID).
 		Find(&userWantedSkills).Error
@@ -312,12 +358,23 @@ func (s *swapService) FindPotentialMatches(userID uuid.UUID) ([]SwapMatch, error
 		for _, wantedSkill := range userWantedSkills {
 			// Find users who want our offered skill AND offer our wanted skill
 			var potentialUsers []models.User
-			err := s.db.Table("users").
+			query := s.db.WithContext(ctx).Table("users").
 				Joins("JOIN user_skills_wanted ON users.user_id = user_skills_wanted.user_id").
 				Joins("JOIN user_skills_offered ON users.user_id = user_skills_offered.user_id").
 				Where("user_skills_wanted.skill_id = ? AND user_skills_offered.skill_id = ? AND users.user_id != ? AND users.is_public = true AND users.deleted_at IS NULL",
-					offeredSkill.SkillID, wantedSkill.SkillID, userID).
-				Find(&potentialUsers).Error
+					offeredSkill.SkillID, wantedSkill.SkillID, userID)
+
+			if opts.RadiusKm != nil {
+				minLat, maxLat, minLng, maxLng := geo.BoundingBox(*self.Latitude, *self.Longitude, *opts.RadiusKm)
+				nearby := "(users.latitude BETWEEN ? AND ? AND users.longitude BETWEEN ? AND ? AND " +
+					distanceSQL(*self.Latitude, *self.Longitude) + " <= ?)"
+				if opts.IncludeRemote {
+					nearby = "(" + nearby + " OR users.is_remote = TRUE)"
+				}
+				query = query.Where(nearby, minLat, maxLat, minLng, maxLng, *opts.RadiusKm)
+			}
+
+			err := query.Find(&potentialUsers).Error
 
 			if err != nil {
 				continue
@@ -327,16 +384,31 @@ func (s *swapService) FindPotentialMatches(userID uuid.UUID) ([]SwapMatch, error
 				// Calculate match score (simple algorithm for now)
 				matchScore := 80 // Base score for mutual skill match
 
-				matches = append(matches, SwapMatch{
+				match := SwapMatch{
 					User:         user,
 					OfferedSkill: offeredSkill,
 					WantedSkill:  wantedSkill,
 					MatchScore:   matchScore,
-				})
+				}
+				if hasLocation && user.Latitude != nil && user.Longitude != nil {
+					distance := geo.DistanceKm(*self.Latitude, *self.Longitude, *user.Latitude, *user.Longitude)
+					match.DistanceKm = &distance
+				}
+				matches = append(matches, match)
 			}
 		}
 	}
 
+	// Within a radius the closest matches come first; remote users follow
+	if opts.RadiusKm != nil {
+		sort.SliceStable(matches, func(i, j int) bool {
+			if matches[i].DistanceKm == nil || matches[j].DistanceKm == nil {
+				return matches[j].DistanceKm == nil && matches[i].DistanceKm != nil
+			}
+			return *matches[i].DistanceKm < *matches[j].DistanceKm
+		})
+	}
+
 	// Limit results
 	if len(matches) > 20 {
 		matches = matches[:20]
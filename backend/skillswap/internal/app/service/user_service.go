@@ -1,26 +1,33 @@
 package service
 
 import (
+	"context"
 	"time"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/geo"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/repository"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
 	"github.com/google/uuid"
 )
 
 type UserService interface {
-	GetProfile(userID uuid.UUID) (*UserProfileResponse, error)
-	UpdateProfile(userID uuid.UUID, req *UpdateProfileRequest) error
-	SearchUsers(req *SearchUsersRequest) (*SearchUsersResponse, error)
+	GetProfile(ctx context.Context, userID uuid.UUID) (*UserProfileResponse, error)
+	UpdateProfile(ctx context.Context, userID uuid.UUID, req *UpdateProfileRequest) error
+	SearchUsers(ctx context.Context, req *SearchUsersRequest) (*SearchUsersResponse, error)
 }
 
 type userService struct {
 	userRepo repository.UserRepository
+	geocoder geo.Geocoder
+	events   *event.Bus
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, geocoder geo.Geocoder, events *event.Bus) UserService {
 	return &userService{
 		userRepo: userRepo,
+		geocoder: geocoder,
+		events:   events,
 	}
 }
 
@@ -30,6 +37,9 @@ type UserProfileResponse struct {
 	Name          string          `json:"name"`
 	Email         string          `json:"email"`
 	Location      *string         `json:"location"`
+	LocationPlace *string         `json:"location_place"`
+	Timezone      *string         `json:"timezone"`
+	IsRemote      bool            `json:"is_remote"`
 	PhotoURL      *string         `json:"photo_url"`
 	IsPublic      bool            `json:"is_public"`
 	SkillsOffered []SkillResponse `json:"skills_offered"`
@@ -47,6 +57,8 @@ type UpdateProfileRequest struct {
 	Location *string `json:"location,omitempty"`
 	PhotoURL *string `json:"photo_url,omitempty"`
 	IsPublic *bool   `json:"is_public,omitempty"`
+	Timezone *string `json:"timezone,omitempty"` // IANA zone; defaults to the location's
+	IsRemote *bool   `json:"is_remote,omitempty"`
 }
 
 type SearchUsersRequest struct {
@@ -64,8 +76,8 @@ type SearchUsersResponse struct {
 	TotalPages int                   `json:"total_pages"`
 }
 
-func (s *userService) GetProfile(userID uuid.UUID) (*UserProfileResponse, error) {
-	user, err := s.userRepo.GetByID(userID)
+func (s *userService) GetProfile(ctx context.Context, userID uuid.UUID) (*UserProfileResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -73,8 +85,14 @@ func (s *userService) GetProfile(userID uuid.UUID) (*UserProfileResponse, error)
 	return s.toUserProfileResponse(user), nil
 }
 
-func (s *userService) UpdateProfile(userID uuid.UUID, req *UpdateProfileRequest) error {
-	user, err := s.userRepo.GetByID(userID)
+func (s *userService) UpdateProfile(ctx context.Context, userID uuid.UUID, req *UpdateProfileRequest) error {
+	if req.Timezone != nil {
+		if err := validateTimezone(*req.Timezone); err != nil {
+			return err
+		}
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return err
 	}
@@ -83,7 +101,17 @@ func (s *userService) UpdateProfile(userID uuid.UUID, req *UpdateProfileRequest)
 		user.Name = *req.Name
 	}
 	if req.Location != nil {
-		user.Location = req.Location
+		applyLocation(user, *req.Location, s.geocoder)
+	}
+	if req.Timezone != nil {
+		if *req.Timezone == "" {
+			user.Timezone = nil
+		} else {
+			user.Timezone = req.Timezone
+		}
+	}
+	if req.IsRemote != nil {
+		user.IsRemote = *req.IsRemote
 	}
 	if req.PhotoURL != nil {
 		user.PhotoURL = req.PhotoURL
@@ -92,10 +120,15 @@ func (s *userService) UpdateProfile(userID uuid.UUID, req *UpdateProfileRequest)
 		user.IsPublic = *req.IsPublic
 	}
 
-	return s.userRepo.Update(user)
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	s.events.Publish(event.UserChanged, userID)
+	return nil
 }
 
-func (s *userService) SearchUsers(req *SearchUsersRequest) (*SearchUsersResponse, error) {
+func (s *userService) SearchUsers(ctx context.Context, req *SearchUsersRequest) (*SearchUsersResponse, error) {
 	if req.Limit <= 0 {
 		req.Limit = 10
 	}
@@ -114,7 +147,7 @@ func (s *userService) SearchUsers(req *SearchUsersRequest) (*SearchUsersResponse
 		SearchTerm: req.SearchTerm,
 	}
 
-	users, total, err := s.userRepo.List(req.Limit, offset, filters)
+	users, total, err := s.userRepo.List(ctx, req.Limit, offset, filters)
 	if err != nil {
 		return nil, err
 	}
@@ -158,6 +191,9 @@ func (s *userService) toUserProfileResponse(user *models.User) *UserProfileRespo
 		Name:          user.Name,
 		Email:         user.Email,
 		Location:      user.Location,
+		LocationPlace: user.LocationPlace,
+		Timezone:      user.Timezone,
+		IsRemote:      user.IsRemote,
 		PhotoURL:      user.PhotoURL,
 		IsPublic:      user.IsPublic,
 		SkillsOffered: skillsOffered,
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// How long emailed tokens stay valid
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+)
+
+// newSecretToken returns a random URL-safe token and the hash to store
+func newSecretToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashSecretToken(token), nil
+}
+
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueUserToken creates a single-use token for purpose. Unused tokens of
+// the same purpose are deleted, so only the latest link works, along with
+// the user's expired tokens.
+func issueUserToken(db *gorm.DB, userID uuid.UUID, purpose models.TokenPurpose, email *string, ttl time.Duration) (string, error) {
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND ((purpose = ? AND used_at IS NULL) OR expires_at < ?)", userID, purpose, time.Now()).
+			Delete(&models.UserToken{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.UserToken{
+			TokenHash: hash,
+			UserID:    userID,
+			Purpose:   purpose,
+			Email:     email,
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeUserToken marks a valid token as used and returns it. The update
+// is atomic, so a token can only be consumed once.
+func consumeUserToken(db *gorm.DB, token string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	var used []models.UserToken
+	now := time.Now()
+	err := db.Raw(`
+		UPDATE user_tokens SET used_at = ?
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?
+		RETURNING *`,
+		now, hashSecretToken(token), purpose, now,
+	).Scan(&used).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(used) == 0 {
+		return nil, errors.New("invalid or expired token")
+	}
+	return &used[0], nil
+}
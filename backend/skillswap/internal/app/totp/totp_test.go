@@ -0,0 +1,110 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key from RFC 6238, appendix B, base32 encoded
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeRFC6238(t *testing.T) {
+	// The RFC lists 8 digit codes; these are their last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	code := func(step int64) string {
+		c, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		ok     bool
+		step   int64
+	}{
+		{name: "current step", secret: rfcSecret, code: code(step), ok: true, step: step},
+		{name: "one step early", secret: rfcSecret, code: code(step + 1), ok: true, step: step + 1},
+		{name: "one step late", secret: rfcSecret, code: code(step - 1), ok: true, step: step - 1},
+		{name: "outside the skew window", secret: rfcSecret, code: code(step - 2)},
+		{name: "spaces are ignored", secret: rfcSecret, code: code(step)[:3] + " " + code(step)[3:], ok: true, step: step},
+		{name: "lower case secret", secret: strings.ToLower(rfcSecret), code: code(step), ok: true, step: step},
+		{name: "too short", secret: rfcSecret, code: code(step)[:5]},
+		{name: "too long", secret: rfcSecret, code: code(step) + "0"},
+		{name: "empty", secret: rfcSecret, code: ""},
+		{name: "invalid secret", secret: "not base32!", code: code(step)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Validate(tt.secret, tt.code, now)
+			if ok != tt.ok || got != tt.step {
+				t.Errorf("Validate = %d, %v, want %d, %v", got, ok, tt.step, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidateReturnsStepForReuseCheck(t *testing.T) {
+	// A code typed at the end of its period and checked just after is still
+	// reported as its own step, so the caller can refuse to accept it twice
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued := time.Unix(1700000000, 0)
+	c, err := Code(secret, Step(issued))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, ok := Validate(secret, c, issued)
+	if !ok {
+		t.Fatal("Validate rejected a fresh code")
+	}
+	second, ok := Validate(secret, c, issued.Add(Period))
+	if !ok || second != first {
+		t.Errorf("Validate in the next period = %d, %v, want %d, true", second, ok, first)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Skill Swap", "ada@example.com", "ABC")
+	want := "otpauth://totp/Skill%20Swap:ada@example.com?"
+	if !strings.HasPrefix(uri, want) {
+		t.Errorf("ProvisioningURI = %q, want prefix %q", uri, want)
+	}
+	for _, param := range []string{"secret=ABC", "issuer=Skill%20Swap", "digits=6", "period=30", "algorithm=SHA1"} {
+		if !strings.Contains(uri, param) {
+			t.Errorf("ProvisioningURI = %q, missing %s", uri, param)
+		}
+	}
+}
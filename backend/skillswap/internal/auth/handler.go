@@ -1,9 +1,13 @@
 package auth
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	appservice "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,7 +29,7 @@ func NewHandler(authService appservice.AuthService) *Handler {
 // @Produce json
 // @Param request body appservice.RegisterRequest true "Registration data"
 // @Success 201 {object} appservice.AuthResponse
-// @Failure 400 {object} ErrorResponse
+// @Failure 400 {object} ErrorResponse "Invalid data or breached password"
 // @Failure 409 {object} ErrorResponse "User already exists"
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/register [post]
@@ -36,11 +40,14 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.Register(&req)
+	response, err := h.authService.Register(c.Request.Context(), &req)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
-		if err.Error() == "user with this email already exists" {
+		switch err.Error() {
+		case "user with this email already exists":
 			statusCode = http.StatusConflict
+		case "this password has appeared in a data breach, please choose another":
+			statusCode = http.StatusBadRequest
 		}
 		c.JSON(statusCode, gin.H{"error": err.Error()})
 		return
@@ -51,14 +58,16 @@ func (h *Handler) Register(c *gin.Context) {
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate user and return tokens
+// @Description Authenticate user and return tokens. Accounts with two-factor authentication get an MFA challenge instead, completed with /auth/mfa/verify.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body appservice.LoginRequest true "Login credentials"
 // @Success 200 {object} appservice.AuthResponse
+// @Success 202 {object} appservice.MFAChallenge "Two-factor code required"
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse "Invalid credentials"
+// @Failure 429 {object} ErrorResponse "Too many failed attempts; see Retry-After"
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/login [post]
 func (h *Handler) Login(c *gin.Context) {
@@ -68,8 +77,11 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.Login(&req)
+	response, challenge, err := h.authService.Login(c.Request.Context(), &req)
 	if err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "invalid email or password" {
 			statusCode = http.StatusUnauthorized
@@ -77,6 +89,42 @@ func (h *Handler) Login(c *gin.Context) {
 		c.JSON(statusCode, gin.H{"error": err.Error()})
 		return
 	}
+	if challenge != nil {
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VerifyMFA godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the MFA token from login and a TOTP or recovery code for tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body appservice.VerifyMFARequest true "MFA token and code"
+// @Success 200 {object} appservice.AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "Invalid code or MFA token"
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/mfa/verify [post]
+func (h *Handler) VerifyMFA(c *gin.Context) {
+	var req appservice.VerifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authService.VerifyMFA(c.Request.Context(), &req)
+	if err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, response)
 }
@@ -100,7 +148,7 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.RefreshToken(req.RefreshToken)
+	response, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "invalid refresh token" || err.Error() == "invalid token type" {
@@ -140,24 +188,583 @@ func (h *Handler) Logout(c *gin.Context) {
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/me [get]
 func (h *Handler) GetMe(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
-		return
-	}
-
-	email, exists := c.Get("email")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email not found in token"})
+	principal, ok := middleware.CurrentPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
 	// Return basic user info from token
 	// For complete profile, user should use /users/profile endpoint
 	c.JSON(http.StatusOK, gin.H{
-		"user_id": userIDStr,
-		"email":   email,
+		"user_id": principal.UserID,
+		"email":   principal.Email,
+	})
+}
+
+// VerifyEmail godoc
+// @Summary Verify email address
+// @Description Confirm the email address with the token from the verification email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body appservice.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse "Invalid or expired token"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var req appservice.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "invalid or expired token" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification godoc
+// @Summary Resend verification email
+// @Description Send a new verification link to the authenticated user's email; earlier links stop working
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Email already verified"
+// @Failure 503 {object} ErrorResponse "Email is not configured"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/verify-email/resend [post]
+func (h *Handler) ResendVerification(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.ResendVerification(c.Request.Context(), userID); err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "email is already verified":
+			statusCode = http.StatusConflict
+		case "email is not configured":
+			statusCode = http.StatusServiceUnavailable
+		case "user not found":
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Email a password reset link. The response is the same whether or not the address has an account.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body appservice.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Email is not configured"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/forgot-password [post]
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req appservice.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		if err.Error() == "email is not configured" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Password reset is not available"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send password reset email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account uses this email, a password reset link has been sent"})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Set a new password with the token from the reset email. All existing sessions are signed out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body appservice.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse "Invalid or expired token, or breached password"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/reset-password [post]
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req appservice.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), &req); err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "invalid or expired token", "this password has appeared in a data breach, please choose another":
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully. Please log in again."})
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Change the password of the authenticated user. Other sessions are signed out; the returned tokens replace the current ones.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body appservice.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} appservice.AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "Current password is incorrect"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/password [put]
+func (h *Handler) ChangePassword(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req appservice.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authService.ChangePassword(c.Request.Context(), userID, &req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "current password is incorrect":
+			statusCode = http.StatusForbidden
+		case "new password must be different from the current password", "this password has appeared in a data breach, please choose another":
+			statusCode = http.StatusBadRequest
+		case "user not found":
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ChangeEmail godoc
+// @Summary Change email address
+// @Description Send a confirmation link to a new email address. The login email changes once the link is used.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body appservice.ChangeEmailRequest true "New email and current password"
+// @Success 202 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "Password is incorrect"
+// @Failure 409 {object} ErrorResponse "Email already in use"
+// @Failure 503 {object} ErrorResponse "Email is not configured"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/email [post]
+func (h *Handler) ChangeEmail(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req appservice.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.RequestEmailChange(c.Request.Context(), userID, &req); err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "password is incorrect":
+			statusCode = http.StatusForbidden
+		case "new email is the same as the current one":
+			statusCode = http.StatusBadRequest
+		case "email is already in use":
+			statusCode = http.StatusConflict
+		case "email is not configured":
+			statusCode = http.StatusServiceUnavailable
+		case "user not found":
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation link sent to the new email address"})
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm email change
+// @Description Switch to the new email address with the token from the confirmation email. All sessions are signed out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body appservice.ConfirmEmailChangeRequest true "Confirmation token"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse "Invalid or expired token"
+// @Failure 409 {object} ErrorResponse "Email already in use"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/email/confirm [post]
+func (h *Handler) ConfirmEmailChange(c *gin.Context) {
+	var req appservice.ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ConfirmEmailChange(c.Request.Context(), req.Token); err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "invalid or expired token":
+			statusCode = http.StatusBadRequest
+		case "email is already in use":
+			statusCode = http.StatusConflict
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email changed successfully. Please log in with your new email."})
+}
+
+// GetOIDCProviders godoc
+// @Summary List identity providers
+// @Description Identity providers users can sign in with
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string][]string
+// @Router /auth/oidc/providers [get]
+func (h *Handler) GetOIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.authService.OIDCProviders()})
+}
+
+// BeginOIDCLogin godoc
+// @Summary Sign in with an identity provider
+// @Description Redirect the browser to the identity provider's sign-in page
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Success 302
+// @Failure 404 {object} ErrorResponse "Unknown provider"
+// @Failure 503 {object} ErrorResponse "Provider unavailable"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/oidc/{provider}/login [get]
+func (h *Handler) BeginOIDCLogin(c *gin.Context) {
+	login, err := h.authService.BeginOIDCLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "unknown identity provider":
+			statusCode = http.StatusNotFound
+		case "identity provider is unavailable":
+			statusCode = http.StatusServiceUnavailable
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Binds the callback to this browser, against login CSRF
+	setOIDCStateCookie(c, login.State, 600)
+	c.Redirect(http.StatusFound, login.AuthURL)
+}
+
+// OIDCCallback godoc
+// @Summary Identity provider callback
+// @Description Where the identity provider sends the browser back. Redirects to APP_URL/oauth/callback with a one-time code, or an error.
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Param code query string false "Authorization code"
+// @Param state query string false "State"
+// @Success 302
+// @Router /auth/oidc/{provider}/callback [get]
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	browserState, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+
+	redirectURL := h.authService.CompleteOIDCLogin(c.Request.Context(), c.Param("provider"), &appservice.OIDCCallback{
+		State:        c.Query("state"),
+		Code:         c.Query("code"),
+		Error:        c.Query("error"),
+		BrowserState: browserState,
 	})
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// ExchangeOIDCLogin godoc
+// @Summary Finish signing in with an identity provider
+// @Description Exchange the one-time code from APP_URL/oauth/callback for tokens. Accounts with two-factor authentication get an MFA challenge instead, like login.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body appservice.OIDCExchangeRequest true "One-time code"
+// @Success 200 {object} appservice.AuthResponse
+// @Success 202 {object} appservice.MFAChallenge "Two-factor code required"
+// @Failure 400 {object} ErrorResponse "Invalid or expired code"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/oidc/exchange [post]
+func (h *Handler) ExchangeOIDCLogin(c *gin.Context) {
+	var req appservice.OIDCExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, challenge, err := h.authService.ExchangeOIDCLogin(c.Request.Context(), req.Code)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "invalid or expired token" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+const oidcStateCookie = "oidc_state"
+
+// setOIDCStateCookie stores the sign-in state for the callback. SameSite
+// Lax lets it through on the provider's redirect back.
+func setOIDCStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, "/api/v1/auth/oidc", "", secure, true)
+}
+
+// GetMFAStatus godoc
+// @Summary Get two-factor status
+// @Description Whether two-factor authentication is enabled or required, and how many recovery codes are left
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} appservice.MFAStatus
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/mfa [get]
+func (h *Handler) GetMFAStatus(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.authService.GetMFAStatus(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SetupMFA godoc
+// @Summary Start two-factor enrollment
+// @Description Create a TOTP secret and provisioning URI to show as a QR code. It takes effect once confirmed with /auth/mfa/enable.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body appservice.SetupMFARequest true "Current password"
+// @Success 200 {object} appservice.MFASetup
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "Password is incorrect"
+// @Failure 409 {object} ErrorResponse "Already enabled"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/mfa/setup [post]
+func (h *Handler) SetupMFA(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req appservice.SetupMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setup, err := h.authService.SetupMFA(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// EnableMFA godoc
+// @Summary Enable two-factor authentication
+// @Description Confirm enrollment with a code from the authenticator app. Other sessions are signed out; the returned tokens replace the current ones. The recovery codes are only shown once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body appservice.MFACodeRequest true "TOTP code"
+// @Success 200 {object} appservice.EnableMFAResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "Invalid code"
+// @Failure 409 {object} ErrorResponse "Already enabled"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/mfa/enable [post]
+func (h *Handler) EnableMFA(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req appservice.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authService.EnableMFA(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DisableMFA godoc
+// @Summary Disable two-factor authentication
+// @Description Turn off two-factor authentication. Admins cannot. Other sessions are signed out; the returned tokens replace the current ones.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body appservice.DisableMFARequest true "Password and TOTP or recovery code"
+// @Success 200 {object} appservice.AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "Invalid code"
+// @Failure 403 {object} ErrorResponse "Password is incorrect or user is an admin"
+// @Failure 429 {object} ErrorResponse "Too many failed attempts; see Retry-After"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/mfa [delete]
+func (h *Handler) DisableMFA(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req appservice.DisableMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authService.DisableMFA(c.Request.Context(), userID, &req)
+	if err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes. The new codes are only shown once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body appservice.MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} appservice.RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "Invalid code"
+// @Failure 429 {object} ErrorResponse "Too many failed attempts; see Retry-After"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/mfa/recovery-codes [post]
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req appservice.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authService.RegenerateRecoveryCodes(c.Request.Context(), userID, &req)
+	if err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// mfaErrorStatus maps two-factor errors to status codes
+func mfaErrorStatus(err error) int {
+	switch err.Error() {
+	case "invalid authentication code", "invalid or expired mfa token":
+		return http.StatusUnauthorized
+	case "password is incorrect", "two-factor authentication is required for staff":
+		return http.StatusForbidden
+	case "two-factor authentication is already enabled":
+		return http.StatusConflict
+	case "two-factor setup has not been started", "two-factor authentication is not enabled":
+		return http.StatusBadRequest
+	case "user not found":
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// respondThrottled answers logins refused after failed attempts with 429
+// and when to try again
+func respondThrottled(c *gin.Context, err error) bool {
+	var throttled *appservice.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "retry_after": seconds})
+	return true
 }
 
 // DTOs
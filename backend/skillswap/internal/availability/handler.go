@@ -3,20 +3,24 @@ package availability
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type Handler struct {
 	availabilityService service.AvailabilityService
+	calendarService     service.CalendarService
 }
 
-func NewHandler(availabilityService service.AvailabilityService) *Handler {
+func NewHandler(availabilityService service.AvailabilityService, calendarService service.CalendarService) *Handler {
 	return &Handler{
 		availabilityService: availabilityService,
+		calendarService:     calendarService,
 	}
 }
 
@@ -40,14 +44,13 @@ func (h *Handler) CreateAvailabilitySlot(c *gin.Context) {
 	}
 
 	// Get user ID from JWT token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
-	req.UserID = userID.(uuid.UUID)
+	req.UserID = userID
 
-	slot, err := h.availabilityService.CreateAvailabilitySlot(&req)
+	slot, err := h.availabilityService.CreateAvailabilitySlot(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -68,13 +71,12 @@ func (h *Handler) CreateAvailabilitySlot(c *gin.Context) {
 // @Router /api/v1/availability [get]
 func (h *Handler) GetUserAvailabilitySlots(c *gin.Context) {
 	// Get user ID from JWT token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	slots, err := h.availabilityService.GetUserAvailabilitySlots(userID.(uuid.UUID))
+	slots, err := h.availabilityService.GetUserAvailabilitySlots(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -105,13 +107,12 @@ func (h *Handler) GetAvailabilitySlot(c *gin.Context) {
 	}
 
 	// Get user ID from JWT token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	slot, err := h.availabilityService.GetAvailabilitySlot(slotID, userID.(uuid.UUID))
+	slot, err := h.availabilityService.GetAvailabilitySlot(c.Request.Context(), slotID, userID)
 	if err != nil {
 		if err.Error() == "availability slot not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Availability slot not found"})
@@ -153,13 +154,12 @@ func (h *Handler) UpdateAvailabilitySlot(c *gin.Context) {
 	}
 
 	// Get user ID from JWT token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	slot, err := h.availabilityService.UpdateAvailabilitySlot(slotID, userID.(uuid.UUID), &req)
+	slot, err := h.availabilityService.UpdateAvailabilitySlot(c.Request.Context(), slotID, userID, &req)
 	if err != nil {
 		if err.Error() == "availability slot not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Availability slot not found"})
@@ -194,13 +194,12 @@ func (h *Handler) DeleteAvailabilitySlot(c *gin.Context) {
 	}
 
 	// Get user ID from JWT token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	err = h.availabilityService.DeleteAvailabilitySlot(slotID, userID.(uuid.UUID))
+	err = h.availabilityService.DeleteAvailabilitySlot(c.Request.Context(), slotID, userID)
 	if err != nil {
 		if err.Error() == "availability slot not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Availability slot not found"})
@@ -215,14 +214,17 @@ func (h *Handler) DeleteAvailabilitySlot(c *gin.Context) {
 
 // FindCommonAvailability finds overlapping availability between two users
 // @Summary Find common availability
-// @Description Find overlapping availability between authenticated user and another user
+// @Description Find overlapping availability between authenticated user and another user for one week, shown in the caller's timezone
 // @Tags availability
 // @Accept json
 // @Produce json
 // @Param user_id path string true "Other user ID"
-// @Success 200 {array} service.CommonAvailabilitySlot
+// @Param week query string false "Any date in the week to compare (YYYY-MM-DD), defaults to this week"
+// @Param timezone query string false "IANA timezone for the results, defaults to the caller's profile timezone"
+// @Success 200 {object} service.CommonAvailabilityResponse
 // @Failure 400 {object} gin.H
 // @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
 // @Failure 500 {object} gin.H
 // @Router /api/v1/availability/common/{user_id} [get]
 func (h *Handler) FindCommonAvailability(c *gin.Context) {
@@ -234,19 +236,35 @@ func (h *Handler) FindCommonAvailability(c *gin.Context) {
 	}
 
 	// Get user ID from JWT token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	commonSlots, err := h.availabilityService.FindCommonAvailability(userID.(uuid.UUID), otherUserID)
+	opts := service.CommonAvailabilityOptions{Timezone: c.Query("timezone")}
+	if weekStr := c.Query("week"); weekStr != "" {
+		weekOf, err := time.Parse("2006-01-02", weekStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid week format, use YYYY-MM-DD"})
+			return
+		}
+		opts.WeekOf = weekOf
+	}
+
+	common, err := h.availabilityService.FindCommonAvailability(c.Request.Context(), userID, otherUserID, opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch err.Error() {
+		case "invalid timezone":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"common_availability": commonSlots})
+	c.JSON(http.StatusOK, common)
 }
 
 // GetAvailabilityByDayAndTime finds availability slots for specific day and time
@@ -293,13 +311,12 @@ func (h *Handler) GetAvailabilityByDayAndTime(c *gin.Context) {
 	}
 
 	// Get user ID from JWT token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	slots, err := h.availabilityService.GetAvailabilityByDayAndTime(userID.(uuid.UUID), day, startTime, endTime)
+	slots, err := h.availabilityService.GetAvailabilityByDayAndTime(c.Request.Context(), userID, day, startTime, endTime)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -307,3 +324,307 @@ func (h *Handler) GetAvailabilityByDayAndTime(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"availability_slots": slots})
 }
+
+// CreateException adds one-off availability or a blackout
+// @Summary Create an availability exception
+// @Description Add extra free time ("available") or time away ("blackout") for a concrete date range
+// @Tags availability
+// @Accept json
+// @Produce json
+// @Param exception body service.CreateAvailabilityExceptionDTO true "Availability exception data"
+// @Success 201 {object} models.AvailabilityException
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/availability/exceptions [post]
+func (h *Handler) CreateException(c *gin.Context) {
+	var req service.CreateAvailabilityExceptionDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get user ID from JWT token
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+	req.UserID = userID
+
+	exception, err := h.availabilityService.CreateException(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, exception)
+}
+
+// GetUserExceptions lists the authenticated user's availability exceptions
+// @Summary Get user's availability exceptions
+// @Description List one-off availability and blackouts, optionally only those overlapping a date range
+// @Tags availability
+// @Accept json
+// @Produce json
+// @Param from query string false "Start of range (RFC 3339)"
+// @Param to query string false "End of range (RFC 3339)"
+// @Success 200 {array} models.AvailabilityException
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/availability/exceptions [get]
+func (h *Handler) GetUserExceptions(c *gin.Context) {
+	var from, to *time.Time
+	if fromStr := c.Query("from"); fromStr != "" {
+		t, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from format, use RFC 3339"})
+			return
+		}
+		from = &t
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		t, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to format, use RFC 3339"})
+			return
+		}
+		to = &t
+	}
+
+	// Get user ID from JWT token
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	exceptions, err := h.availabilityService.GetUserExceptions(c.Request.Context(), userID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"availability_exceptions": exceptions})
+}
+
+// DeleteException deletes an availability exception
+// @Summary Delete an availability exception
+// @Description Delete one of the authenticated user's availability exceptions
+// @Tags availability
+// @Accept json
+// @Produce json
+// @Param id path string true "Availability exception ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/availability/exceptions/{id} [delete]
+func (h *Handler) DeleteException(c *gin.Context) {
+	exceptionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exception ID"})
+		return
+	}
+
+	// Get user ID from JWT token
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	err = h.availabilityService.DeleteException(c.Request.Context(), exceptionID, userID)
+	if err != nil {
+		if err.Error() == "availability exception not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Availability exception not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetFreeTime returns a user's bookable free time for a date range
+// @Summary Get free time
+// @Description Expand weekly slots for a date range, add one-off availability, subtract blackouts and booked sessions, and merge the result
+// @Tags availability
+// @Accept json
+// @Produce json
+// @Param from query string true "Start of range (RFC 3339)"
+// @Param to query string true "End of range (RFC 3339), at most 93 days after from"
+// @Param user_id query string false "User to look up, defaults to the authenticated user. Private users are only visible to their swap partners."
+// @Param timezone query string false "IANA timezone for the results, defaults to the user's timezone"
+// @Success 200 {object} service.FreeTimeResponse
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/availability/free [get]
+func (h *Handler) GetFreeTime(c *gin.Context) {
+	from, err := time.Parse(time.RFC3339, c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from is required, use RFC 3339"})
+		return
+	}
+	to, err := time.Parse(time.RFC3339, c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to is required, use RFC 3339"})
+		return
+	}
+
+	// Get user ID from JWT token
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+	targetID := userID
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		targetID, err = uuid.Parse(userIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+	}
+
+	free, err := h.availabilityService.FreeTime(c.Request.Context(), userID, targetID, from, to, c.Query("timezone"))
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case "invalid timezone", "end must be after start", "date range is too long":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, free)
+}
+
+// maxCalendarImportSize bounds uploaded .ics files
+const maxCalendarImportSize = 2 << 20
+
+// RotateCalendarToken issues a new calendar feed token
+// @Summary Create or rotate the calendar feed token
+// @Description Issue a secret URL for subscribing to your availability and swap sessions from a calendar app. Any previous URL stops working.
+// @Tags availability
+// @Produce json
+// @Success 200 {object} service.CalendarFeedResponse
+// @Failure 401 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/availability/calendar/token [post]
+func (h *Handler) RotateCalendarToken(c *gin.Context) {
+	// Get user ID from JWT token
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	feed, err := h.calendarService.RotateFeedToken(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, feed)
+}
+
+// RevokeCalendarToken disables the calendar feed
+// @Summary Revoke the calendar feed token
+// @Description Disable the calendar feed URL
+// @Tags availability
+// @Success 204
+// @Failure 401 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/availability/calendar/token [delete]
+func (h *Handler) RevokeCalendarToken(c *gin.Context) {
+	// Get user ID from JWT token
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.calendarService.RevokeFeedToken(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CalendarFeed serves a user's calendar feed
+// @Summary Calendar feed
+// @Description iCalendar feed of weekly availability (as recurring events), exceptions and scheduled swap sessions. The token in the URL is the only credential.
+// @Tags availability
+// @Produce text/calendar
+// @Param token path string true "Feed token followed by .ics"
+// @Success 200 {string} string
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/calendar/{token}.ics [get]
+func (h *Handler) CalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	feed, err := h.calendarService.Feed(c.Request.Context(), token)
+	if err != nil {
+		if err.Error() == "calendar feed not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", feed)
+}
+
+// ImportCalendar imports availability from an .ics file
+// @Summary Import an iCalendar file
+// @Description Weekly or daily recurring events become availability slots; future one-off events become blackouts. Re-importing the same file updates the entries.
+// @Tags availability
+// @Accept multipart/form-data
+// @Accept text/calendar
+// @Produce json
+// @Param file formData file false ".ics file (or send the calendar as a text/calendar body)"
+// @Success 200 {object} service.CalendarImportResult
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/availability/import [post]
+func (h *Handler) ImportCalendar(c *gin.Context) {
+	// Get user ID from JWT token
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCalendarImportSize)
+
+	body := c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read uploaded file"})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	result, err := h.calendarService.Import(c.Request.Context(), userID, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
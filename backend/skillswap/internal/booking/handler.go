@@ -0,0 +1,468 @@
+package booking
+
+import (
+	"net/http"
+	"time"
+
+	appservice "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
+	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	bookingService  appservice.BookingService
+	reminderService appservice.ReminderService
+}
+
+func NewHandler(bookingService appservice.BookingService, reminderService appservice.ReminderService) *Handler {
+	return &Handler{
+		bookingService:  bookingService,
+		reminderService: reminderService,
+	}
+}
+
+// Request and response structures
+type CreateBookingRequest struct {
+	SwapID   string    `json:"swap_id" binding:"required,uuid"`
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+	Timezone string    `json:"timezone"`
+}
+
+type RescheduleBookingRequest struct {
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+	Timezone string    `json:"timezone"`
+}
+
+type CancelBookingRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+type BookingResponse struct {
+	BookingID    string         `json:"booking_id"`
+	SwapID       string         `json:"swap_id"`
+	Status       string         `json:"status"`
+	StartsAt     string         `json:"starts_at"`
+	EndsAt       string         `json:"ends_at"`
+	Timezone     string         `json:"timezone"`
+	CreatedBy    string         `json:"created_by"`
+	CancelledBy  string         `json:"cancelled_by,omitempty"`
+	CancelReason string         `json:"cancel_reason,omitempty"`
+	Requester    *UserResponse  `json:"requester,omitempty"`
+	Responder    *UserResponse  `json:"responder,omitempty"`
+	OfferedSkill *SkillResponse `json:"offered_skill,omitempty"`
+	WantedSkill  *SkillResponse `json:"wanted_skill,omitempty"`
+	CreatedAt    string         `json:"created_at"`
+	UpdatedAt    string         `json:"updated_at"`
+}
+
+type UserResponse struct {
+	UserID   string `json:"user_id"`
+	Name     string `json:"name"`
+	PhotoURL string `json:"photo_url,omitempty"`
+}
+
+type SkillResponse struct {
+	SkillID string `json:"skill_id"`
+	Name    string `json:"name"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// convertToBookingResponse renders start and end in the booking's timezone
+func (h *Handler) convertToBookingResponse(booking *models.Booking) BookingResponse {
+	loc, err := time.LoadLocation(booking.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	response := BookingResponse{
+		BookingID: booking.BookingID.String(),
+		SwapID:    booking.SwapID.String(),
+		Status:    string(booking.Status),
+		StartsAt:  booking.StartsAt.In(loc).Format(time.RFC3339),
+		EndsAt:    booking.EndsAt.In(loc).Format(time.RFC3339),
+		Timezone:  booking.Timezone,
+		CreatedBy: booking.CreatedBy.String(),
+		CreatedAt: booking.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt: booking.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if booking.CancelledBy != nil {
+		response.CancelledBy = booking.CancelledBy.String()
+	}
+	if booking.CancelReason != nil {
+		response.CancelReason = *booking.CancelReason
+	}
+
+	swap := booking.Swap
+	if swap.Requester.UserID != uuid.Nil {
+		response.Requester = &UserResponse{
+			UserID:   swap.Requester.UserID.String(),
+			Name:     swap.Requester.Name,
+			PhotoURL: h.getStringValue(swap.Requester.PhotoURL),
+		}
+	}
+	if swap.Responder.UserID != uuid.Nil {
+		response.Responder = &UserResponse{
+			UserID:   swap.Responder.UserID.String(),
+			Name:     swap.Responder.Name,
+			PhotoURL: h.getStringValue(swap.Responder.PhotoURL),
+		}
+	}
+	if swap.OfferedSkill.SkillID != uuid.Nil {
+		response.OfferedSkill = &SkillResponse{
+			SkillID: swap.OfferedSkill.SkillID.String(),
+			Name:    swap.OfferedSkill.Name,
+		}
+	}
+	if swap.WantedSkill.SkillID != uuid.Nil {
+		response.WantedSkill = &SkillResponse{
+			SkillID: swap.WantedSkill.SkillID.String(),
+			Name:    swap.WantedSkill.Name,
+		}
+	}
+
+	return response
+}
+
+func (h *Handler) getStringValue(ptr *string) string {
+	if ptr == nil {
+		return ""
+	}
+	return *ptr
+}
+
+// writeError maps booking service errors to status codes
+func (h *Handler) writeError(c *gin.Context, err error, fallback string) {
+	switch err.Error() {
+	case "swap request not found", "booking not found", "user not found":
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case "access denied":
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "Access denied"})
+	case "time slot is already booked", "time is outside your availability",
+		"time is outside your partner's availability", "booking is cancelled":
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	case "only accepted swap requests can be booked", "end must be after start",
+		"booking must start in the future", "booking is too short", "booking is too long",
+		"invalid timezone":
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: fallback})
+	}
+}
+
+// CreateBooking godoc
+// @Summary Book a session
+// @Description Book a session for an accepted swap request. The time must be free for both participants.
+// @Tags bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param booking body CreateBookingRequest true "Booking data"
+// @Success 201 {object} BookingResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/v1/bookings [post]
+func (h *Handler) CreateBooking(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	swapID, err := uuid.Parse(req.SwapID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid swap ID"})
+		return
+	}
+
+	booking, err := h.bookingService.CreateBooking(c.Request.Context(), userID, &appservice.CreateBookingDTO{
+		SwapID:   swapID,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Timezone: req.Timezone,
+	})
+	if err != nil {
+		h.writeError(c, err, "Failed to create booking")
+		return
+	}
+
+	c.JSON(http.StatusCreated, h.convertToBookingResponse(booking))
+}
+
+// GetUserBookings godoc
+// @Summary Get user's bookings
+// @Description Get bookings the authenticated user takes part in, ordered by start time
+// @Tags bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param swap_id query string false "Only bookings for this swap request"
+// @Param status query string false "Filter by status" Enums(confirmed, cancelled)
+// @Param from query string false "Only bookings ending after this time (RFC3339)"
+// @Param to query string false "Only bookings starting before this time (RFC3339)"
+// @Success 200 {array} BookingResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/v1/bookings [get]
+func (h *Handler) GetUserBookings(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	filter := appservice.BookingFilter{}
+
+	if swapIDStr := c.Query("swap_id"); swapIDStr != "" {
+		swapID, err := uuid.Parse(swapIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid swap ID"})
+			return
+		}
+		filter.SwapID = &swapID
+	}
+
+	if statusStr := c.Query("status"); statusStr != "" {
+		status := models.BookingStatus(statusStr)
+		if status != models.BookingConfirmed && status != models.BookingCancelled {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid status"})
+			return
+		}
+		filter.Status = &status
+	}
+
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid from time, expected RFC3339"})
+			return
+		}
+		filter.From = &from
+	}
+
+	if toStr := c.Query("to"); toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid to time, expected RFC3339"})
+			return
+		}
+		filter.To = &to
+	}
+
+	bookings, err := h.bookingService.GetUserBookings(c.Request.Context(), userID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch bookings"})
+		return
+	}
+
+	response := make([]BookingResponse, len(bookings))
+	for i := range bookings {
+		response[i] = h.convertToBookingResponse(&bookings[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetBooking godoc
+// @Summary Get booking
+// @Description Get a booking the authenticated user takes part in
+// @Tags bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Booking ID"
+// @Success 200 {object} BookingResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/bookings/{id} [get]
+func (h *Handler) GetBooking(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid booking ID"})
+		return
+	}
+
+	booking, err := h.bookingService.GetBooking(c.Request.Context(), userID, bookingID)
+	if err != nil {
+		h.writeError(c, err, "Failed to fetch booking")
+		return
+	}
+
+	c.JSON(http.StatusOK, h.convertToBookingResponse(booking))
+}
+
+// RescheduleBooking godoc
+// @Summary Reschedule booking
+// @Description Move a confirmed booking to a new time that is free for both participants
+// @Tags bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Booking ID"
+// @Param booking body RescheduleBookingRequest true "New time"
+// @Success 200 {object} BookingResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/v1/bookings/{id} [put]
+func (h *Handler) RescheduleBooking(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid booking ID"})
+		return
+	}
+
+	var req RescheduleBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	booking, err := h.bookingService.RescheduleBooking(c.Request.Context(), userID, bookingID, &appservice.RescheduleBookingDTO{
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Timezone: req.Timezone,
+	})
+	if err != nil {
+		h.writeError(c, err, "Failed to reschedule booking")
+		return
+	}
+
+	c.JSON(http.StatusOK, h.convertToBookingResponse(booking))
+}
+
+// CancelBooking godoc
+// @Summary Cancel booking
+// @Description Cancel a confirmed booking and release the time for both participants
+// @Tags bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Booking ID"
+// @Param booking body CancelBookingRequest false "Cancellation reason"
+// @Success 200 {object} BookingResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/v1/bookings/{id}/cancel [post]
+func (h *Handler) CancelBooking(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid booking ID"})
+		return
+	}
+
+	// The reason is optional, so an empty body is fine
+	var req CancelBookingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	booking, err := h.bookingService.CancelBooking(c.Request.Context(), userID, bookingID, req.Reason)
+	if err != nil {
+		h.writeError(c, err, "Failed to cancel booking")
+		return
+	}
+
+	c.JSON(http.StatusOK, h.convertToBookingResponse(booking))
+}
+
+// GetReminderPreferences godoc
+// @Summary Get reminder preferences
+// @Description Get when the authenticated user is reminded before booked sessions
+// @Tags bookings
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} appservice.ReminderPreferencesResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/v1/bookings/reminders [get]
+func (h *Handler) GetReminderPreferences(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.reminderService.GetPreferences(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch reminder preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateReminderPreferences godoc
+// @Summary Update reminder preferences
+// @Description Turn session reminders on or off, or choose how many minutes before a session they are sent
+// @Tags bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param preferences body appservice.UpdateReminderPreferencesDTO true "Reminder preferences"
+// @Success 200 {object} appservice.ReminderPreferencesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/v1/bookings/reminders [put]
+func (h *Handler) UpdateReminderPreferences(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req appservice.UpdateReminderPreferencesDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	prefs, err := h.reminderService.UpdatePreferences(c.Request.Context(), userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "at least one reminder offset is required", "too many reminder offsets",
+			"reminder offsets must be between 5 minutes and 7 days":
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update reminder preferences"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
@@ -1,60 +1,242 @@
+// Package config loads the server's settings. Each setting has a default,
+// which a YAML or TOML file, then environment variables, then command-line
+// flags may override:
+//
+//	server -config skillswap.yaml -server.port 9090
+//
+// File keys and flags are the yaml names below joined with dots; the env
+// tags name the environment variables.
 package config
 
 import (
-	"log"
-	"os"
+	"net/netip"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jwtkeys"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/passwords"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/ratelimit"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type Config struct {
-	DBUrl     string
-	Port      string
-	JWTSecret string
-	UploadDir string
-	BaseURL   string
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Auth      AuthConfig      `yaml:"auth"`
+	OIDC      OIDCConfig      `yaml:"oidc"`
+	Uploads   UploadsConfig   `yaml:"uploads"`
+	Search    SearchConfig    `yaml:"search"`
+	Jobs      JobsConfig      `yaml:"jobs"`
+	Mail      MailConfig      `yaml:"mail"`
+	CORS      CORSConfig      `yaml:"cors"`
+	Security  SecurityConfig  `yaml:"security"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+
+	sources map[string]string // Where settings not left at their default were set
 }
 
-func Load() Config {
-	_ = godotenv.Load()
+type ServerConfig struct {
+	Port int `yaml:"port" env:"PORT"`
+	// Mode is Gin's mode: "debug", "release" or "test". In release mode
+	// development fallbacks, such as generated keys, are refused.
+	Mode    string `yaml:"mode" env:"GIN_MODE"`
+	BaseURL string `yaml:"base_url" env:"BASE_URL"`
+	// AppURL is the web app that links in emails open, e.g. to verify an
+	// address or reset a password. Defaults to BaseURL.
+	AppURL string `yaml:"app_url" env:"APP_URL"`
 
-	// Try DATABASE_URL first (Heroku format), then fall back to DB_URL
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		dbURL = os.Getenv("DB_URL")
-	}
+	// TrustedProxies may append to ClientIPHeader, "X-Forwarded-For" or
+	// "Forwarded": CIDRs, addresses, "private" or "loopback". Without any
+	// the peer address is the client.
+	TrustedProxies  []string       `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	TrustedPrefixes []netip.Prefix `yaml:"-"` // Parsed TrustedProxies
+	ClientIPHeader  string         `yaml:"client_ip_header" env:"CLIENT_IP_HEADER"`
 
-	port := os.Getenv("PORT")
-	jwtSecret := os.Getenv("JWT_SECRET")
-	uploadDir := os.Getenv("UPLOAD_DIR")
-	baseURL := os.Getenv("BASE_URL")
+	// RequestTimeout cancels the database work of requests that take longer;
+	// 0 turns it off
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"REQUEST_TIMEOUT"`
+}
 
-	if dbURL == "" {
-		log.Fatal("DATABASE_URL or DB_URL environment variable is required")
-	}
+type DatabaseConfig struct {
+	URL string `yaml:"url" env:"DATABASE_URL,DB_URL" config:"secret"`
+	// ReplicaURL is an optional read replica that search and statistics
+	// queries go to; they may then lag behind writes by the replication delay
+	ReplicaURL string `yaml:"replica_url" env:"DATABASE_REPLICA_URL" config:"secret"`
+	// LogLevel is GORM's: "silent", "error", "warn" or "info", which logs
+	// every statement. Defaults to "warn" in release mode.
+	LogLevel      string        `yaml:"log_level" env:"DB_LOG_LEVEL"`
+	SlowThreshold time.Duration `yaml:"slow_threshold" env:"DB_SLOW_THRESHOLD"` // Slower statements are logged as warnings
 
-	if jwtSecret == "" {
-		jwtSecret = "default-secret-change-in-production"
-		log.Println("Warning: Using default JWT secret. Set JWT_SECRET environment variable in production.")
-	}
+	// Pool limits apply to the primary and the replica each
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	// StatementTimeout makes Postgres cancel statements that run longer,
+	// except during migrations; 0 turns it off
+	StatementTimeout time.Duration `yaml:"statement_timeout" env:"DB_STATEMENT_TIMEOUT"`
+}
 
-	if uploadDir == "" {
-		uploadDir = "./uploads"
-	}
+type AuthConfig struct {
+	// JWTSecret keys HMACs that are not JWTs for other services, such as
+	// unsubscribe links and two-factor challenges
+	JWTSecret string `yaml:"jwt_secret" env:"JWT_SECRET" config:"secret"`
+	// SigningKey signs access and refresh tokens, and VerifyKeys are older
+	// or upcoming keys still accepted; both are PEM text or file paths.
+	// VerifyKeys is secret too, since a private key is accepted there.
+	SigningKey string           `yaml:"signing_key" env:"JWT_SIGNING_KEY" config:"secret"`
+	VerifyKeys string           `yaml:"verify_keys" env:"JWT_VERIFY_KEYS" config:"secret"`
+	Keys       *jwtkeys.Keyring `yaml:"-"`
+	// Issuer and Audience are the iss and aud claims of our tokens, which
+	// other services verifying them should check too. Issuer defaults to
+	// the base URL.
+	Issuer          string        `yaml:"issuer" env:"JWT_ISSUER"`
+	Audience        string        `yaml:"audience" env:"JWT_AUDIENCE"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL"`
 
-	if baseURL == "" {
-		baseURL = "http://localhost:8080"
-	}
+	// BcryptCost is the work factor for password hashes. Hashes with a
+	// lower cost are upgraded when their owner logs in.
+	BcryptCost int `yaml:"bcrypt_cost" env:"BCRYPT_COST"`
+	// BreachedPasswords are refused as new passwords: the bundled list plus
+	// BreachedPasswordsFile
+	BreachedPasswordsFile string          `yaml:"breached_passwords_file" env:"BREACHED_PASSWORDS_FILE"`
+	BreachedPasswords     *passwords.List `yaml:"-"`
+}
 
-	if port == "" {
-		port = "8080"
-	}
+// OIDCConfig lists the identity providers users can sign in with. In the
+// environment, each name in OIDC_PROVIDERS is configured with
+// OIDC_<NAME>_* variables.
+type OIDCConfig struct {
+	Providers []OIDCProvider `yaml:"providers"`
+}
+
+// OIDCProvider is an OpenID Connect identity provider
+type OIDCProvider struct {
+	Name         string   `yaml:"name"` // Used in URLs, e.g. "google"
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret" config:"secret"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+type UploadsConfig struct {
+	Dir          string `yaml:"dir" env:"UPLOAD_DIR"`
+	MaxPhotoSize int64  `yaml:"max_photo_size" env:"MAX_PHOTO_SIZE"` // Bytes
+}
+
+type SearchConfig struct {
+	// Backend selects the search index: "postgres" or "memory"
+	Backend string `yaml:"backend" env:"SEARCH_BACKEND"`
+}
+
+type JobsConfig struct {
+	// PollInterval is how often the job scheduler looks for due jobs
+	PollInterval time.Duration `yaml:"poll_interval" env:"JOB_POLL_INTERVAL"`
+	// ReminderOffsets are the default times before a session that
+	// reminders are sent, for users who have not chosen their own
+	ReminderOffsets []time.Duration `yaml:"reminder_offsets" env:"REMINDER_OFFSETS" config:"allowempty"`
+}
+
+type MailConfig struct {
+	// Transport selects how email is sent: "smtp", "file", "log" or "none"
+	// to turn email off
+	Transport    string `yaml:"transport" env:"MAIL_TRANSPORT"`
+	From         string `yaml:"from" env:"MAIL_FROM"`
+	Dir          string `yaml:"dir" env:"MAIL_DIR"` // Where the file transport writes .eml files
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     string `yaml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD" config:"secret"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins may call the API from browsers: origins such as
+	// "https://app.example.com", subdomain patterns such as
+	// "https://*.example.com", or "*" for any. Defaults to the app's origin.
+	AllowedOrigins   []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" env:"CORS_MAX_AGE"` // How long browsers may cache preflight results
+}
+
+type SecurityConfig struct {
+	// ContentSecurityPolicy is sent with every response unless empty
+	ContentSecurityPolicy string `yaml:"content_security_policy" env:"CONTENT_SECURITY_POLICY" config:"allowempty"`
+	// HSTSMaxAge turns on Strict-Transport-Security when positive. It
+	// defaults to a year in release mode.
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age" env:"HSTS_MAX_AGE"`
+	HSTSIncludeSubdomains bool          `yaml:"hsts_include_subdomains" env:"HSTS_INCLUDE_SUBDOMAINS"`
+	HSTSPreload           bool          `yaml:"hsts_preload" env:"HSTS_PRELOAD"`
+}
+
+type RateLimitConfig struct {
+	// Backend selects where buckets are kept: "memory" or "postgres", which
+	// shares them between servers
+	Backend string `yaml:"backend" env:"RATE_LIMIT_BACKEND"`
+	// Policies override the built-in policies, e.g. {"api": "300/1m"}
+	Policies map[string]string           `yaml:"policies" env:"RATE_LIMIT_POLICIES"`
+	Limits   map[string]ratelimit.Policy `yaml:"-"` // Every policy routes may apply
+}
+
+// Production reports whether Gin runs in release mode
+func (c Config) Production() bool {
+	return c.Server.Mode == "release"
+}
 
+// defaults returns the settings used when nothing overrides them
+func defaults() Config {
 	return Config{
-		DBUrl:     dbURL,
-		Port:      port,
-		JWTSecret: jwtSecret,
-		UploadDir: uploadDir,
-		BaseURL:   baseURL,
+		Server: ServerConfig{
+			Port:           8080,
+			Mode:           "debug",
+			BaseURL:        "http://localhost:8080",
+			ClientIPHeader: "X-Forwarded-For",
+			RequestTimeout: 30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			LogLevel:         "info",
+			SlowThreshold:    200 * time.Millisecond,
+			MaxOpenConns:     25,
+			MaxIdleConns:     10,
+			ConnMaxLifetime:  30 * time.Minute,
+			ConnMaxIdleTime:  5 * time.Minute,
+			StatementTimeout: 30 * time.Second,
+		},
+		Auth: AuthConfig{
+			Audience:        "skillswap-api",
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+			BcryptCost:      bcrypt.DefaultCost,
+		},
+		Uploads: UploadsConfig{
+			Dir:          "./uploads",
+			MaxPhotoSize: 5 << 20,
+		},
+		Search: SearchConfig{
+			Backend: "postgres",
+		},
+		Jobs: JobsConfig{
+			PollInterval:    15 * time.Second,
+			ReminderOffsets: []time.Duration{24 * time.Hour, time.Hour},
+		},
+		Mail: MailConfig{
+			Transport: "log",
+			From:      "Skill Swap <no-reply@localhost>",
+			Dir:       "./mail",
+			SMTPPort:  "587",
+		},
+		CORS: CORSConfig{
+			AllowedHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-CSRF-Token", "Cache-Control"},
+			MaxAge:         2 * time.Hour, // Chromium caps it at two hours
+		},
+		Security: SecurityConfig{
+			// The API serves JSON and a few plain pages, such as unsubscribe
+			// confirmations, which only need inline styles and to post to
+			// themselves
+			ContentSecurityPolicy: "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'; base-uri 'none'",
+		},
+		RateLimit: RateLimitConfig{
+			Backend: ratelimit.BackendMemory,
+		},
 	}
 }
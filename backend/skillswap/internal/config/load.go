@@ -0,0 +1,423 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Error lists every problem found while loading the configuration
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Load returns the configuration for a run with the command-line arguments
+// args: the defaults, overridden by the file named by -config or
+// CONFIG_FILE, then the environment, then flags. A .env file in the working
+// directory is read into the environment first. It returns an *Error
+// listing every invalid setting, or flag.ErrHelp after printing usage for
+// -h.
+func Load(args []string) (Config, error) {
+	_ = godotenv.Load()
+
+	cfg := defaults()
+	l := &loader{sources: make(map[string]string)}
+
+	file, flagValues, err := parseFlags(&cfg, args)
+	if err != nil {
+		return Config{}, err
+	}
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+	if file != "" {
+		l.applyFile(&cfg, file)
+	}
+	l.applyEnv(&cfg)
+	l.applyFlags(&cfg, flagValues)
+	l.resolve(&cfg)
+
+	if len(l.problems) > 0 {
+		return Config{}, &Error{Problems: l.problems}
+	}
+	cfg.sources = l.sources
+	return cfg, nil
+}
+
+// loader tracks where each setting came from, and what was wrong
+type loader struct {
+	sources  map[string]string // Key to e.g. "env PORT"
+	problems []string
+}
+
+// problem records what is wrong with the setting key, and where it was set
+func (l *loader) problem(key, format string, args ...any) {
+	msg := key + ": " + fmt.Sprintf(format, args...)
+	if source, ok := l.sources[key]; ok {
+		msg += " (" + source + ")"
+	}
+	l.problems = append(l.problems, msg)
+}
+
+// isSet reports whether the file, environment or flags set key
+func (l *loader) isSet(key string) bool {
+	_, ok := l.sources[key]
+	return ok
+}
+
+// setting is a leaf of Config that files, the environment and flags can set
+type setting struct {
+	key        string // e.g. "server.port"
+	env        []string
+	secret     bool
+	allowEmpty bool // An empty environment variable sets the value
+	value      reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// settings lists the leaves of the struct v, in declaration order. Lists
+// of structs, such as OIDC providers, can only be set in files.
+func settings(v reflect.Value, prefix string) []setting {
+	var list []setting
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := yamlName(field)
+		if name == "" {
+			continue
+		}
+		key := joinKey(prefix, name)
+		value := v.Field(i)
+		switch {
+		case value.Kind() == reflect.Struct:
+			list = append(list, settings(value, key)...)
+		case isStructList(value):
+		default:
+			s := setting{key: key, value: value}
+			if env := field.Tag.Get("env"); env != "" {
+				s.env = strings.Split(env, ",")
+			}
+			for _, option := range strings.Split(field.Tag.Get("config"), ",") {
+				s.secret = s.secret || option == "secret"
+				s.allowEmpty = s.allowEmpty || option == "allowempty"
+			}
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func (l *loader) applyFile(cfg *Config, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("config file: %v", err))
+		return
+	}
+
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		err = errors.New("name must end in .yaml, .yml or .toml")
+	}
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("config file %s: %v", path, err))
+		return
+	}
+	l.applyMap(reflect.ValueOf(cfg).Elem(), values, "", "file "+path)
+}
+
+// applyMap sets the fields of the struct v from a decoded file section
+func (l *loader) applyMap(v reflect.Value, values map[string]any, prefix, source string) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		key := joinKey(prefix, name)
+		field, ok := fieldByName(v, name)
+		if !ok {
+			l.problems = append(l.problems, fmt.Sprintf("%s: unknown setting (%s)", key, source))
+			continue
+		}
+		raw := values[name]
+
+		switch {
+		case field.Kind() == reflect.Struct:
+			section, ok := raw.(map[string]any)
+			if !ok {
+				l.problems = append(l.problems, fmt.Sprintf("%s: must be a section (%s)", key, source))
+				continue
+			}
+			l.applyMap(field, section, key, source)
+		case isStructList(field):
+			items, ok := raw.([]any)
+			if !ok {
+				l.problems = append(l.problems, fmt.Sprintf("%s: must be a list (%s)", key, source))
+				continue
+			}
+			list := reflect.MakeSlice(field.Type(), 0, len(items))
+			for i, item := range items {
+				section, ok := item.(map[string]any)
+				itemKey := fmt.Sprintf("%s[%d]", key, i)
+				if !ok {
+					l.problems = append(l.problems, fmt.Sprintf("%s: must be a section (%s)", itemKey, source))
+					continue
+				}
+				elem := reflect.New(field.Type().Elem()).Elem()
+				l.applyMap(elem, section, itemKey, source)
+				list = reflect.Append(list, elem)
+			}
+			field.Set(list)
+			for itemKey := range l.sources {
+				if strings.HasPrefix(itemKey, key+"[") {
+					delete(l.sources, itemKey) // The list as a whole has a source
+				}
+			}
+			l.sources[key] = source
+		default:
+			l.sources[key] = source
+			if err := setValue(field, raw); err != nil {
+				l.problem(key, "%v", err)
+			}
+		}
+	}
+}
+
+func (l *loader) applyEnv(cfg *Config) {
+	for _, s := range settings(reflect.ValueOf(cfg).Elem(), "") {
+		for _, name := range s.env {
+			value, ok := os.LookupEnv(name)
+			if !ok || (value == "" && !s.allowEmpty) {
+				continue
+			}
+			l.sources[s.key] = "env " + name
+			if err := setString(s.value, value); err != nil {
+				l.problem(s.key, "%v", err)
+			}
+			break
+		}
+	}
+
+	if os.Getenv("OIDC_PROVIDERS") != "" {
+		l.sources["oidc.providers"] = "env OIDC_PROVIDERS"
+		cfg.OIDC.Providers = oidcProvidersFromEnv()
+	}
+}
+
+// oidcProvidersFromEnv reads the providers named in OIDC_PROVIDERS, e.g.
+// "google,okta" with OIDC_GOOGLE_ISSUER, OIDC_GOOGLE_CLIENT_ID and so on
+func oidcProvidersFromEnv() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range splitList(os.Getenv("OIDC_PROVIDERS")) {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProvider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		}
+		if v := os.Getenv(prefix + "SCOPES"); v != "" {
+			provider.Scopes = strings.Fields(strings.ReplaceAll(v, ",", " "))
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// flagValue collects a flag's value, which is applied after the file and
+// the environment
+type flagValue struct {
+	key    string
+	isBool bool
+	values map[string]string
+}
+
+func (f *flagValue) String() string   { return "" }
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
+func (f *flagValue) Set(value string) error {
+	f.values[f.key] = value
+	return nil
+}
+
+// parseFlags parses -config and a flag per setting, e.g. -server.port
+func parseFlags(cfg *Config, args []string) (string, map[string]string, error) {
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	file := fs.String("config", "", "YAML or TOML config `file` (env CONFIG_FILE)")
+
+	values := make(map[string]string)
+	for _, s := range settings(reflect.ValueOf(cfg).Elem(), "") {
+		usage := "default " + formatValue(s.value)
+		if s.secret {
+			usage = "secret"
+		}
+		if len(s.env) > 0 {
+			usage += ", env " + strings.Join(s.env, " or ")
+		}
+		fs.Var(&flagValue{key: s.key, isBool: s.value.Kind() == reflect.Bool, values: values}, s.key, usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return "", nil, err
+	}
+	if fs.NArg() > 0 {
+		return "", nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return *file, values, nil
+}
+
+func (l *loader) applyFlags(cfg *Config, values map[string]string) {
+	for _, s := range settings(reflect.ValueOf(cfg).Elem(), "") {
+		value, ok := values[s.key]
+		if !ok {
+			continue
+		}
+		l.sources[s.key] = "flag -" + s.key
+		if err := setString(s.value, value); err != nil {
+			l.problem(s.key, "%v", err)
+		}
+	}
+}
+
+// setValue sets a leaf from a value decoded from a file
+func setValue(v reflect.Value, raw any) error {
+	switch raw := raw.(type) {
+	case nil:
+		return setString(v, "")
+	case []any:
+		if v.Kind() != reflect.Slice {
+			return errors.New("must not be a list")
+		}
+		items := make([]string, len(raw))
+		for i, item := range raw {
+			items[i] = fmt.Sprint(item)
+		}
+		return setList(v, items)
+	case map[string]any:
+		if v.Kind() != reflect.Map {
+			return errors.New("must not be a section")
+		}
+		m := make(map[string]string, len(raw))
+		for name, value := range raw {
+			m[name] = fmt.Sprint(value)
+		}
+		v.Set(reflect.ValueOf(m))
+		return nil
+	}
+	return setString(v, fmt.Sprint(raw))
+}
+
+// setString sets a leaf from an environment variable or flag. Lists are
+// comma-separated, and maps are lists of name=value pairs.
+func setString(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as \"15m\"", s)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Int || v.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", s)
+		}
+		v.SetInt(n)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", s)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice:
+		return setList(v, splitList(s))
+	case v.Kind() == reflect.Map:
+		m := make(map[string]string)
+		for _, entry := range splitList(s) {
+			name, value, ok := strings.Cut(entry, "=")
+			if !ok {
+				return fmt.Errorf("%q is not a list of name=value pairs", s)
+			}
+			m[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+func setList(v reflect.Value, items []string) error {
+	list := reflect.MakeSlice(v.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := setString(list.Index(i), item); err != nil {
+			return err
+		}
+	}
+	v.Set(list)
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// yamlName returns the key of a struct field, or "" if it is not a setting
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" || !field.IsExported() {
+		return ""
+	}
+	return name
+}
+
+func fieldByName(v reflect.Value, name string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		if yamlName(v.Type().Field(i)) == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func isStructList(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[redacted]"
+
+// Print writes the configuration as YAML that -config can load back.
+// Secrets are redacted, and settings not left at their default are
+// commented with where they were set.
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.node(reflect.ValueOf(c), "", false)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func (c Config) node(v reflect.Value, key string, secret bool) *yaml.Node {
+	switch {
+	case v.Type() == durationType:
+		return scalar("!!str", formatValue(v))
+	case v.Kind() == reflect.Struct:
+		section := &yaml.Node{Kind: yaml.MappingNode}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := yamlName(field)
+			if name == "" {
+				continue
+			}
+			fieldKey := joinKey(key, name)
+			keyNode, value := scalar("!!str", name), c.node(v.Field(i), fieldKey, strings.Contains(field.Tag.Get("config"), "secret"))
+			if source, ok := c.sources[fieldKey]; ok {
+				// Comments after block lists and sections end up below them
+				if value.Kind == yaml.ScalarNode || value.Style == yaml.FlowStyle {
+					value.LineComment = source
+				} else {
+					keyNode.LineComment = source
+				}
+			}
+			section.Content = append(section.Content, keyNode, value)
+		}
+		return section
+	case v.Kind() == reflect.Slice:
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		if !isStructList(v) {
+			list.Style = yaml.FlowStyle
+		}
+		for i := 0; i < v.Len(); i++ {
+			list.Content = append(list.Content, c.node(v.Index(i), fmt.Sprintf("%s[%d]", key, i), secret))
+		}
+		return list
+	case v.Kind() == reflect.Map:
+		section := &yaml.Node{Kind: yaml.MappingNode}
+		names := make([]string, 0, v.Len())
+		for _, name := range v.MapKeys() {
+			names = append(names, name.String())
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			section.Content = append(section.Content, scalar("!!str", name), scalar("!!str", v.MapIndex(reflect.ValueOf(name)).String()))
+		}
+		return section
+	case v.Kind() == reflect.String:
+		if secret && v.String() != "" {
+			return scalar("!!str", redact(v.String()))
+		}
+		return scalar("!!str", v.String())
+	case v.Kind() == reflect.Bool:
+		return scalar("!!bool", formatValue(v))
+	}
+	return scalar("!!int", formatValue(v))
+}
+
+func scalar(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// formatValue formats a leaf the way setString parses it
+func formatValue(v reflect.Value) string {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(items, ",")
+	case v.Kind() == reflect.Map:
+		entries := make([]string, 0, v.Len())
+		for _, name := range v.MapKeys() {
+			entries = append(entries, name.String()+"="+formatValue(v.MapIndex(name)))
+		}
+		sort.Strings(entries)
+		return strings.Join(entries, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// redact hides a secret. URLs keep everything but the password, so the
+// database host can still be checked.
+func redact(s string) string {
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted()
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,307 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/clientip"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jwtkeys"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/passwords"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/ratelimit"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resolve checks the loaded settings, fills in the defaults that depend on
+// other settings, and derives keys, lists and policies from them
+func (l *loader) resolve(cfg *Config) {
+	l.resolveServer(cfg)
+	l.resolveDatabase(cfg)
+	l.resolveAuth(cfg)
+	l.resolveOIDC(cfg)
+	l.resolveServices(cfg)
+	l.resolveBrowserSecurity(cfg)
+	l.resolveRateLimit(cfg)
+}
+
+func (l *loader) resolveServer(cfg *Config) {
+	s := &cfg.Server
+	if s.Port < 1 || s.Port > 65535 {
+		l.problem("server.port", "must be from 1 to 65535, got %d", s.Port)
+	}
+	switch s.Mode {
+	case "debug", "release", "test":
+	default:
+		l.problem("server.mode", "must be \"debug\", \"release\" or \"test\", got %q", s.Mode)
+	}
+
+	s.BaseURL = strings.TrimSuffix(s.BaseURL, "/")
+	if !isHTTPURL(s.BaseURL) {
+		l.problem("server.base_url", "must be an http or https URL, got %q", s.BaseURL)
+	}
+	s.AppURL = strings.TrimSuffix(s.AppURL, "/")
+	if s.AppURL == "" {
+		s.AppURL = s.BaseURL
+	} else if !isHTTPURL(s.AppURL) {
+		l.problem("server.app_url", "must be an http or https URL, got %q", s.AppURL)
+	}
+
+	prefixes, err := clientip.ParsePrefixes(strings.Join(s.TrustedProxies, ","))
+	if err != nil {
+		l.problem("server.trusted_proxies", "%v", err)
+	}
+	s.TrustedPrefixes = prefixes
+	switch strings.ToLower(s.ClientIPHeader) {
+	case "x-forwarded-for":
+		s.ClientIPHeader = clientip.HeaderXForwardedFor
+	case "forwarded":
+		s.ClientIPHeader = clientip.HeaderForwarded
+	default:
+		l.problem("server.client_ip_header", "must be \"X-Forwarded-For\" or \"Forwarded\", got %q", s.ClientIPHeader)
+	}
+	if s.RequestTimeout < 0 {
+		l.problem("server.request_timeout", "must not be negative, got %s", s.RequestTimeout)
+	}
+}
+
+func (l *loader) resolveDatabase(cfg *Config) {
+	d := &cfg.Database
+	if d.URL == "" {
+		l.problem("database.url", "is required; set DATABASE_URL or DB_URL")
+	}
+	if d.ReplicaURL != "" && d.ReplicaURL == d.URL {
+		l.problem("database.replica_url", "is the same as database.url; leave it unset to read from the primary")
+	}
+
+	if !l.isSet("database.log_level") && cfg.Production() {
+		d.LogLevel = "warn"
+	}
+	switch d.LogLevel {
+	case "silent", "error", "warn", "info":
+	default:
+		l.problem("database.log_level", "must be \"silent\", \"error\", \"warn\" or \"info\", got %q", d.LogLevel)
+	}
+	if d.SlowThreshold < 0 {
+		l.problem("database.slow_threshold", "must not be negative, got %s", d.SlowThreshold)
+	}
+
+	if d.MaxOpenConns < 1 {
+		l.problem("database.max_open_conns", "must be at least 1, got %d", d.MaxOpenConns)
+	}
+	if d.MaxIdleConns < 0 || d.MaxIdleConns > d.MaxOpenConns {
+		l.problem("database.max_idle_conns", "must be from 0 to database.max_open_conns (%d), got %d", d.MaxOpenConns, d.MaxIdleConns)
+	}
+	if d.ConnMaxLifetime < 0 {
+		l.problem("database.conn_max_lifetime", "must not be negative, got %s", d.ConnMaxLifetime)
+	}
+	if d.ConnMaxIdleTime < 0 {
+		l.problem("database.conn_max_idle_time", "must not be negative, got %s", d.ConnMaxIdleTime)
+	}
+	if d.StatementTimeout < 0 || (d.StatementTimeout > 0 && d.StatementTimeout < time.Millisecond) {
+		l.problem("database.statement_timeout", "must be 0 or at least 1ms, got %s", d.StatementTimeout)
+	}
+}
+
+func (l *loader) resolveAuth(cfg *Config) {
+	a := &cfg.Auth
+	if a.JWTSecret == "" {
+		if cfg.Production() {
+			l.problem("auth.jwt_secret", "is required in release mode; set JWT_SECRET")
+		} else {
+			a.JWTSecret = "default-secret-change-in-production"
+			log.Println("Warning: Using default JWT secret. Set JWT_SECRET environment variable in production.")
+		}
+	}
+
+	switch {
+	case a.SigningKey == "" && cfg.Production():
+		l.problem("auth.signing_key", "is required in release mode; set JWT_SIGNING_KEY")
+	case a.SigningKey == "" && a.VerifyKeys != "":
+		l.problem("auth.verify_keys", "is set without auth.signing_key")
+	case a.SigningKey == "":
+		keys, err := jwtkeys.Generate()
+		if err != nil {
+			l.problem("auth.signing_key", "failed to generate a key: %v", err)
+			break
+		}
+		log.Println("Warning: Using a generated JWT signing key; tokens stop working on restart. Set JWT_SIGNING_KEY in production.")
+		a.Keys = keys
+	default:
+		keys, err := jwtkeys.Load(a.SigningKey, a.VerifyKeys)
+		if err != nil {
+			l.problem("auth.signing_key", "%v", err)
+			break
+		}
+		log.Printf("✓ Signing tokens with key %s (%d keys trusted)", keys.SigningKeyID(), len(keys.JWKS().Keys))
+		a.Keys = keys
+	}
+
+	if a.Issuer == "" {
+		a.Issuer = cfg.Server.BaseURL
+	}
+	if a.Audience == "" {
+		l.problem("auth.audience", "must not be empty")
+	}
+	if a.AccessTokenTTL <= 0 {
+		l.problem("auth.access_token_ttl", "must be positive, got %s", a.AccessTokenTTL)
+	}
+	if a.RefreshTokenTTL <= a.AccessTokenTTL {
+		l.problem("auth.refresh_token_ttl", "must be longer than auth.access_token_ttl (%s), got %s", a.AccessTokenTTL, a.RefreshTokenTTL)
+	}
+
+	if a.BcryptCost < bcrypt.MinCost || a.BcryptCost > bcrypt.MaxCost {
+		l.problem("auth.bcrypt_cost", "must be from %d to %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, a.BcryptCost)
+	} else if a.BcryptCost < bcrypt.DefaultCost {
+		log.Printf("Warning: BCRYPT_COST %d is below the recommended minimum of %d", a.BcryptCost, bcrypt.DefaultCost)
+	}
+
+	list, err := passwords.Load(a.BreachedPasswordsFile)
+	if err != nil {
+		l.problem("auth.breached_passwords_file", "%v", err)
+	}
+	a.BreachedPasswords = list
+}
+
+func (l *loader) resolveOIDC(cfg *Config) {
+	seen := make(map[string]bool)
+	for i := range cfg.OIDC.Providers {
+		p := &cfg.OIDC.Providers[i]
+		key := fmt.Sprintf("oidc.providers[%d]", i)
+		if source, ok := l.sources["oidc.providers"]; ok {
+			l.sources[key] = source
+		}
+
+		p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+		if p.Name == "" || strings.Trim(p.Name, "abcdefghijklmnopqrstuvwxyz0123456789-") != "" {
+			l.problem(key, "name may only contain letters, digits and \"-\", got %q", p.Name)
+		}
+		if seen[p.Name] {
+			l.problem(key, "provider %q is listed twice", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Issuer == "" || p.ClientID == "" {
+			l.problem(key, "issuer and client ID are required for provider %q", p.Name)
+		}
+
+		if len(p.Scopes) == 0 {
+			p.Scopes = []string{"openid", "email", "profile"}
+		}
+		hasOpenID := false
+		for _, scope := range p.Scopes {
+			hasOpenID = hasOpenID || scope == "openid"
+		}
+		if !hasOpenID {
+			p.Scopes = append([]string{"openid"}, p.Scopes...)
+		}
+	}
+}
+
+// resolveServices checks uploads, search, jobs and mail
+func (l *loader) resolveServices(cfg *Config) {
+	if cfg.Uploads.Dir == "" {
+		l.problem("uploads.dir", "must not be empty")
+	}
+	if cfg.Uploads.MaxPhotoSize <= 0 {
+		l.problem("uploads.max_photo_size", "must be a positive number of bytes, got %d", cfg.Uploads.MaxPhotoSize)
+	}
+
+	switch cfg.Search.Backend {
+	case "postgres", "memory":
+	default:
+		l.problem("search.backend", "must be \"postgres\" or \"memory\", got %q", cfg.Search.Backend)
+	}
+
+	if cfg.Jobs.PollInterval <= 0 {
+		l.problem("jobs.poll_interval", "must be positive, got %s", cfg.Jobs.PollInterval)
+	}
+	for _, offset := range cfg.Jobs.ReminderOffsets {
+		if offset <= 0 {
+			l.problem("jobs.reminder_offsets", "must be positive durations such as \"24h,1h\", got %s", offset)
+		}
+	}
+
+	switch cfg.Mail.Transport {
+	case "smtp":
+		if cfg.Mail.SMTPHost == "" {
+			l.problem("mail.smtp_host", "is required when mail.transport is \"smtp\"")
+		}
+	case "file", "log", "none":
+	default:
+		l.problem("mail.transport", "must be \"smtp\", \"file\", \"log\" or \"none\", got %q", cfg.Mail.Transport)
+	}
+}
+
+// resolveBrowserSecurity checks CORS, CSP and HSTS
+func (l *loader) resolveBrowserSecurity(cfg *Config) {
+	c := &cfg.CORS
+	if len(c.AllowedOrigins) == 0 && !l.isSet("cors.allowed_origins") {
+		if u, err := url.Parse(cfg.Server.AppURL); err == nil && u.Host != "" {
+			c.AllowedOrigins = []string{u.Scheme + "://" + u.Host}
+		}
+	}
+	for i, origin := range c.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		c.AllowedOrigins[i] = origin
+		if !validOrigin(origin) {
+			l.problem("cors.allowed_origins", "%q is not an origin such as \"https://app.example.com\" or \"https://*.example.com\"", origin)
+		}
+		if origin == "*" && c.AllowCredentials {
+			l.problem("cors.allow_credentials", "cannot be used with the allowed origin \"*\"; list the allowed origins instead")
+		}
+	}
+	if c.MaxAge < 0 {
+		l.problem("cors.max_age", "must not be negative, got %s", c.MaxAge)
+	}
+
+	s := &cfg.Security
+	s.ContentSecurityPolicy = strings.TrimSpace(s.ContentSecurityPolicy)
+	if !l.isSet("security.hsts_max_age") && cfg.Production() {
+		s.HSTSMaxAge = 365 * 24 * time.Hour
+	}
+	if s.HSTSMaxAge < 0 {
+		l.problem("security.hsts_max_age", "must not be negative, got %s", s.HSTSMaxAge)
+	}
+	if s.HSTSPreload && (!s.HSTSIncludeSubdomains || s.HSTSMaxAge < 365*24*time.Hour) {
+		l.problem("security.hsts_preload", "needs security.hsts_include_subdomains and an hsts_max_age of at least a year (8760h)")
+	}
+}
+
+func (l *loader) resolveRateLimit(cfg *Config) {
+	r := &cfg.RateLimit
+	switch r.Backend {
+	case ratelimit.BackendMemory, ratelimit.BackendPostgres:
+	default:
+		l.problem("rate_limit.backend", "must be \"memory\" or \"postgres\", got %q", r.Backend)
+	}
+
+	r.Limits = ratelimit.DefaultPolicies()
+	for name, value := range r.Policies {
+		if _, ok := r.Limits[name]; !ok {
+			l.problem("rate_limit.policies", "unknown policy %q", name)
+			continue
+		}
+		policy, err := ratelimit.ParsePolicy(value)
+		if err != nil {
+			l.problem("rate_limit.policies", "%v", err)
+			continue
+		}
+		r.Limits[name] = policy
+	}
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validOrigin accepts "*", origins, and origins whose host starts with "*."
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.User == nil && !strings.Contains(u.Host, "*")
+}
@@ -1,40 +1,102 @@
 package database
 
 import (
+	"fmt"
 	"log"
+	"os"
+	"strconv"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/geo"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
+// logLevels maps the database.log_level setting to GORM's levels
+var logLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
+
 // Initialize establishes database connection and returns GORM DB instance
-func Initialize(dbURL string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dbURL), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+func Initialize(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	db, err := open(cfg.URL, cfg, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// Test the connection
-	sqlDB, err := db.DB()
+	log.Println("Database connected successfully")
+
+	// Create PostgreSQL extensions and types if they don't exist
+	if err := createPostgreSQLExtensions(db); err != nil {
+		log.Printf("Warning: Could not create PostgreSQL extensions: %v", err)
+	}
+
+	return db, nil
+}
+
+// OpenReplica connects to the read replica, or returns primary when none is
+// configured. Its sessions are read-only, so a write sent to it by mistake
+// fails instead of diverging from the primary.
+func OpenReplica(cfg config.DatabaseConfig, primary *gorm.DB) (*gorm.DB, error) {
+	if cfg.ReplicaURL == "" {
+		return primary, nil
+	}
+
+	replica, err := open(cfg.ReplicaURL, cfg, map[string]string{"default_transaction_read_only": "on"})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("read replica: %w", err)
 	}
 
-	if err := sqlDB.Ping(); err != nil {
+	log.Println("✓ Read replica connected; search and statistics queries use it")
+	return replica, nil
+}
+
+// open connects to url with the configured pool limits, statement timeout
+// and logging, plus any extra session settings
+func open(url string, cfg config.DatabaseConfig, params map[string]string) (*gorm.DB, error) {
+	connConfig, err := pgx.ParseConfig(url)
+	if err != nil {
 		return nil, err
 	}
+	if cfg.StatementTimeout > 0 {
+		connConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+	for name, value := range params {
+		connConfig.RuntimeParams[name] = value
+	}
 
-	log.Println("Database connected successfully")
-
-	// Create PostgreSQL extensions and types if they don't exist
-	if err := createPostgreSQLExtensions(db); err != nil {
-		log.Printf("Warning: Could not create PostgreSQL extensions: %v", err)
+	sqlDB := stdlib.OpenDB(*connConfig)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+			SlowThreshold:             cfg.SlowThreshold,
+			LogLevel:                  logLevels[cfg.LogLevel],
+			IgnoreRecordNotFoundError: true, // Lookups that find nothing are not errors
+			Colorful:                  true,
+		}),
+	})
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
 	}
 
+	// Test the connection
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
 	return db, nil
 }
 
@@ -55,8 +117,19 @@ func createPostgreSQLExtensions(db *gorm.DB) error {
 	return nil
 }
 
-// Migrate runs database migrations
+// Migrate runs database migrations. Building indexes on large tables can
+// outlast the statement timeout, so they run on one connection without it.
 func Migrate(db *gorm.DB) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SET statement_timeout = 0").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("RESET statement_timeout")
+		return migrate(conn)
+	})
+}
+
+func migrate(db *gorm.DB) error {
 	log.Println("Starting database migrations...")
 
 	// Check if core tables exist first
@@ -72,6 +145,9 @@ func Migrate(db *gorm.DB) error {
 		if err := seedDefaultSkills(db); err != nil {
 			log.Printf("Warning: Could not seed default skills: %v", err)
 		}
+		if err := seedSkillAliases(db); err != nil {
+			log.Printf("Warning: Could not seed skill aliases: %v", err)
+		}
 
 		log.Println("✅ Database schema is ready")
 		return nil
@@ -93,6 +169,11 @@ func Migrate(db *gorm.DB) error {
 		log.Printf("Warning: Could not run additional migrations: %v", err)
 	}
 
+	// Aliases reference the default skills and the table added above
+	if err := seedSkillAliases(db); err != nil {
+		log.Printf("Warning: Could not seed skill aliases: %v", err)
+	}
+
 	log.Println("✅ Database migrations completed successfully")
 	return nil
 }
@@ -384,6 +465,643 @@ func runAdditionalMigrations(db *gorm.DB) error {
 		log.Println("✓ Photo storage fields already exist")
 	}
 
+	// Check if search vectors exist
+	var hasSearchVector bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name='users' AND column_name='search_vector')").Scan(&hasSearchVector).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasSearchVector {
+		log.Println("Adding full-text search columns and trigram indexes...")
+
+		// Add tsvector columns and search indexes
+		sql := `
+			CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+			ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS search_vector tsvector
+				GENERATED ALWAYS AS (
+					setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+					setweight(to_tsvector('simple', COALESCE(location, '')), 'B')
+				) STORED;
+
+			ALTER TABLE skills
+			ADD COLUMN IF NOT EXISTS search_vector tsvector
+				GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(name, ''))) STORED;
+
+			-- Full-text indexes
+			CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+			CREATE INDEX IF NOT EXISTS idx_skills_search_vector ON skills USING GIN (search_vector);
+
+			-- Trigram indexes for typo tolerance and substring matches
+			CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING GIN (name gin_trgm_ops);
+			CREATE INDEX IF NOT EXISTS idx_users_location_trgm ON users USING GIN (location gin_trgm_ops);
+			CREATE INDEX IF NOT EXISTS idx_skills_name_trgm ON skills USING GIN (name gin_trgm_ops);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Added search columns and indexes")
+	} else {
+		log.Println("✓ Search columns already exist")
+	}
+
+	// Check if skill aliases table exists
+	var hasSkillAliasesTable bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name='skill_aliases')").Scan(&hasSkillAliasesTable).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasSkillAliasesTable {
+		log.Println("Creating skill aliases table...")
+
+		sql := `
+			CREATE TABLE IF NOT EXISTS skill_aliases (
+				alias_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				skill_id UUID NOT NULL REFERENCES skills(skill_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				alias TEXT NOT NULL UNIQUE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_skill_aliases_skill_id ON skill_aliases(skill_id);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Created skill aliases table")
+	} else {
+		log.Println("✓ Skill aliases table already exists")
+	}
+
+	// Check if geolocation fields exist
+	var hasLatitude bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name='users' AND column_name='latitude')").Scan(&hasLatitude).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasLatitude {
+		log.Println("Adding geolocation fields to users table...")
+
+		sql := `
+			ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS location_place TEXT,
+			ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION,
+			ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION,
+			ADD COLUMN IF NOT EXISTS timezone TEXT,
+			ADD COLUMN IF NOT EXISTS is_remote BOOLEAN DEFAULT FALSE;
+
+			CREATE INDEX IF NOT EXISTS idx_users_lat_lng ON users(latitude, longitude) WHERE latitude IS NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_users_location_place ON users(location_place);
+
+			-- Rebuild the search vector so the resolved place is searchable too
+			ALTER TABLE users DROP COLUMN IF EXISTS search_vector;
+			ALTER TABLE users
+			ADD COLUMN search_vector tsvector
+				GENERATED ALWAYS AS (
+					setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+					setweight(to_tsvector('simple', COALESCE(location, '') || ' ' || COALESCE(location_place, '')), 'B')
+				) STORED;
+			CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		if err := backfillUserLocations(db, geo.DefaultGazetteer()); err != nil {
+			return err
+		}
+
+		log.Println("✓ Added geolocation fields to users table")
+	} else {
+		log.Println("✓ Geolocation fields already exist")
+	}
+
+	// Check if availability timezone field exists
+	var hasSlotTimezone bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name='availability_slots' AND column_name='timezone')").Scan(&hasSlotTimezone).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasSlotTimezone {
+		log.Println("Adding timezone to availability slots...")
+
+		sql := `
+			ALTER TABLE availability_slots
+			ADD COLUMN IF NOT EXISTS timezone TEXT;
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Added timezone to availability slots")
+	} else {
+		log.Println("✓ Availability slot timezone already exists")
+	}
+
+	// Check if availability exceptions table exists
+	var hasExceptionsTable bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name='availability_exceptions')").Scan(&hasExceptionsTable).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasExceptionsTable {
+		log.Println("Creating availability exceptions table...")
+
+		sql := `
+			CREATE TABLE IF NOT EXISTS availability_exceptions (
+				exception_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				kind TEXT NOT NULL CHECK (kind IN ('available', 'blackout')),
+				label TEXT NOT NULL DEFAULT '',
+				starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				CHECK (ends_at > starts_at)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_availability_exceptions_user_time ON availability_exceptions(user_id, starts_at, ends_at);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Created availability exceptions table")
+	} else {
+		log.Println("✓ Availability exceptions table already exists")
+	}
+
+	// Check if calendar sync fields exist
+	var hasCalendarToken bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name='users' AND column_name='calendar_token_hash')").Scan(&hasCalendarToken).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasCalendarToken {
+		log.Println("Adding calendar sync fields...")
+
+		sql := `
+			ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS calendar_token_hash TEXT;
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_users_calendar_token_hash ON users(calendar_token_hash);
+
+			ALTER TABLE availability_slots
+			ADD COLUMN IF NOT EXISTS ical_uid TEXT;
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_availability_slots_ical_uid ON availability_slots(user_id, ical_uid);
+
+			ALTER TABLE availability_exceptions
+			ADD COLUMN IF NOT EXISTS ical_uid TEXT;
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_availability_exceptions_ical_uid ON availability_exceptions(user_id, ical_uid);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Added calendar sync fields")
+	} else {
+		log.Println("✓ Calendar sync fields already exist")
+	}
+
+	// Check if bookings table exists
+	var hasBookingsTable bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name='bookings')").Scan(&hasBookingsTable).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasBookingsTable {
+		log.Println("Creating bookings tables...")
+
+		sql := `
+			CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+			CREATE TABLE IF NOT EXISTS bookings (
+				booking_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				swap_id UUID NOT NULL REFERENCES swap_requests(swap_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				created_by UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				timezone TEXT NOT NULL,
+				status TEXT NOT NULL DEFAULT 'confirmed' CHECK (status IN ('confirmed', 'cancelled')),
+				cancelled_by UUID REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE SET NULL,
+				cancel_reason TEXT,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				CHECK (ends_at > starts_at)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_bookings_swap_id ON bookings(swap_id);
+
+			CREATE TABLE IF NOT EXISTS booking_participants (
+				booking_id UUID NOT NULL REFERENCES bookings(booking_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				active BOOLEAN NOT NULL DEFAULT TRUE,
+				PRIMARY KEY (booking_id, user_id),
+				CHECK (ends_at > starts_at),
+				CONSTRAINT booking_participants_no_overlap EXCLUDE USING gist (
+					user_id WITH =,
+					tstzrange(starts_at, ends_at, '[)') WITH &&
+				) WHERE (active)
+			);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Created bookings tables")
+	} else {
+		log.Println("✓ Bookings tables already exist")
+	}
+
+	// Check if scheduled jobs table exists
+	var hasJobsTable bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name='scheduled_jobs')").Scan(&hasJobsTable).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasJobsTable {
+		log.Println("Creating scheduled jobs and reminder preferences tables...")
+
+		sql := `
+			CREATE TABLE IF NOT EXISTS scheduled_jobs (
+				job_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				kind TEXT NOT NULL,
+				key TEXT UNIQUE,
+				subject_id UUID,
+				payload JSONB NOT NULL DEFAULT '{}',
+				status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+				run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				locked_until TIMESTAMP WITH TIME ZONE,
+				last_error TEXT,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_due ON scheduled_jobs(run_at) WHERE status IN ('pending', 'running');
+			CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_subject ON scheduled_jobs(kind, subject_id) WHERE status = 'pending';
+
+			CREATE TABLE IF NOT EXISTS reminder_preferences (
+				user_id UUID PRIMARY KEY REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				enabled BOOLEAN NOT NULL DEFAULT TRUE,
+				offsets_minutes JSONB NOT NULL DEFAULT '[]',
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Created scheduled jobs and reminder preferences tables")
+	} else {
+		log.Println("✓ Scheduled jobs table already exists")
+	}
+
+	// Check if notification preferences exist
+	var hasNotificationSettings bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name='notification_settings')").Scan(&hasNotificationSettings).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasNotificationSettings {
+		log.Println("Adding notification preferences...")
+
+		sql := `
+			ALTER TABLE notifications
+			ADD COLUMN IF NOT EXISTS deliver_at TIMESTAMP WITH TIME ZONE,
+			ADD COLUMN IF NOT EXISTS digest_pending BOOLEAN NOT NULL DEFAULT FALSE;
+			CREATE INDEX IF NOT EXISTS idx_notifications_digest_pending ON notifications(user_id) WHERE digest_pending;
+
+			CREATE TABLE IF NOT EXISTS notification_settings (
+				user_id UUID PRIMARY KEY REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				muted BOOLEAN NOT NULL DEFAULT FALSE,
+				quiet_hours_start TIME,
+				quiet_hours_end TIME,
+				digest TEXT NOT NULL DEFAULT 'off' CHECK (digest IN ('off', 'daily', 'weekly')),
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				CHECK ((quiet_hours_start IS NULL) = (quiet_hours_end IS NULL))
+			);
+
+			CREATE TABLE IF NOT EXISTS notification_preferences (
+				user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				type VARCHAR(50) NOT NULL,
+				channel TEXT NOT NULL CHECK (channel IN ('in_app', 'email')),
+				enabled BOOLEAN NOT NULL,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (user_id, type, channel)
+			);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Added notification preferences")
+	} else {
+		log.Println("✓ Notification preferences already exist")
+	}
+
+	// Check if email outbox table exists
+	var hasEmailOutbox bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name='email_outbox')").Scan(&hasEmailOutbox).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasEmailOutbox {
+		log.Println("Creating email outbox table...")
+
+		sql := `
+			CREATE TABLE IF NOT EXISTS email_outbox (
+				email_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				notification_id UUID REFERENCES notifications(notification_id) ON DELETE SET NULL,
+				type VARCHAR(50) NOT NULL,
+				to_address TEXT NOT NULL,
+				subject TEXT NOT NULL,
+				text_body TEXT NOT NULL,
+				html_body TEXT NOT NULL,
+				unsubscribe_url TEXT,
+				status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+				attempts INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT,
+				send_after TIMESTAMP WITH TIME ZONE NOT NULL,
+				sent_at TIMESTAMP WITH TIME ZONE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_email_outbox_user_id ON email_outbox(user_id);
+			CREATE INDEX IF NOT EXISTS idx_email_outbox_pending ON email_outbox(send_after) WHERE status = 'pending';
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Created email outbox table")
+	} else {
+		log.Println("✓ Email outbox table already exists")
+	}
+
+	// Check if user tokens table exists
+	var hasUserTokens bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name='user_tokens')").Scan(&hasUserTokens).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasUserTokens {
+		log.Println("Adding email verification and password reset...")
+
+		sql := `
+			ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE,
+			ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
+
+			UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+			CREATE TABLE IF NOT EXISTS user_tokens (
+				token_hash TEXT PRIMARY KEY,
+				user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				purpose TEXT NOT NULL CHECK (purpose IN ('email_verification', 'password_reset')),
+				email TEXT,
+				expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				used_at TIMESTAMP WITH TIME ZONE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_user_tokens_user_purpose ON user_tokens(user_id, purpose);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Added email verification and password reset")
+	} else {
+		log.Println("✓ User tokens table already exists")
+	}
+
+	// Check if user tokens allow email changes
+	var hasEmailChangeTokens bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM pg_constraint WHERE conname='user_tokens_purpose_check' AND pg_get_constraintdef(oid) LIKE '%email_change%')").Scan(&hasEmailChangeTokens).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasEmailChangeTokens {
+		log.Println("Adding email change tokens...")
+
+		sql := `
+			ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+			ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+				CHECK (purpose IN ('email_verification', 'password_reset', 'email_change'));
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Added email change tokens")
+	} else {
+		log.Println("✓ Email change tokens already allowed")
+	}
+
+	// Check if two-factor authentication columns exist
+	var hasMFA bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name='users' AND column_name='mfa_secret')").Scan(&hasMFA).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasMFA {
+		log.Println("Adding two-factor authentication...")
+
+		sql := `
+			ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS mfa_secret TEXT,
+			ADD COLUMN IF NOT EXISTS mfa_enabled_at TIMESTAMP WITH TIME ZONE,
+			ADD COLUMN IF NOT EXISTS mfa_last_step BIGINT NOT NULL DEFAULT 0;
+
+			CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+				code_hash TEXT PRIMARY KEY,
+				user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				used_at TIMESTAMP WITH TIME ZONE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Added two-factor authentication")
+	} else {
+		log.Println("✓ Two-factor authentication columns already exist")
+	}
+
+	// Check if user identities table exists
+	var hasUserIdentities bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name='user_identities')").Scan(&hasUserIdentities).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasUserIdentities {
+		log.Println("Adding OpenID Connect sign-in...")
+
+		sql := `
+			CREATE TABLE IF NOT EXISTS user_identities (
+				provider TEXT NOT NULL,
+				subject TEXT NOT NULL,
+				user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				email TEXT,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				last_login_at TIMESTAMP WITH TIME ZONE,
+				PRIMARY KEY (provider, subject)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+			CREATE TABLE IF NOT EXISTS oidc_login_states (
+				state_hash TEXT PRIMARY KEY,
+				provider TEXT NOT NULL,
+				nonce TEXT NOT NULL,
+				code_verifier TEXT NOT NULL,
+				expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			);
+
+			ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+			ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+				CHECK (purpose IN ('email_verification', 'password_reset', 'email_change', 'oidc_login'));
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Added OpenID Connect sign-in")
+	} else {
+		log.Println("✓ User identities table already exists")
+	}
+
+	// Check if user roles table exists
+	var hasUserRoles bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name='user_roles')").Scan(&hasUserRoles).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasUserRoles {
+		log.Println("Adding staff roles...")
+
+		sql := `
+			CREATE TABLE IF NOT EXISTS user_roles (
+				user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+				role TEXT NOT NULL,
+				granted_by UUID REFERENCES users(user_id) ON DELETE SET NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (user_id, role)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_user_roles_role ON user_roles(role);
+
+			INSERT INTO user_roles (user_id, role)
+			SELECT user_id, 'admin' FROM users WHERE is_admin = TRUE
+			ON CONFLICT DO NOTHING;
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Added staff roles")
+	} else {
+		log.Println("✓ User roles table already exists")
+	}
+
+	// Check if login failures table exists
+	var hasLoginFailures bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name='login_failures')").Scan(&hasLoginFailures).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasLoginFailures {
+		log.Println("Adding login failure tracking...")
+
+		sql := `
+			CREATE TABLE IF NOT EXISTS login_failures (
+				email TEXT PRIMARY KEY,
+				failed_attempts INTEGER NOT NULL DEFAULT 0,
+				last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				locked_until TIMESTAMP WITH TIME ZONE
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_login_failures_last_failed_at ON login_failures(last_failed_at);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Added login failure tracking")
+	} else {
+		log.Println("✓ Login failures table already exists")
+	}
+
+	// Check if rate limit buckets table exists
+	var hasRateLimitBuckets bool
+	err = db.Raw("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name='rate_limit_buckets')").Scan(&hasRateLimitBuckets).Error
+	if err != nil {
+		return err
+	}
+
+	if !hasRateLimitBuckets {
+		log.Println("Adding shared rate limit buckets...")
+
+		sql := `
+			CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+				key TEXT PRIMARY KEY,
+				tokens DOUBLE PRECISION NOT NULL,
+				updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_expires_at ON rate_limit_buckets(expires_at);
+		`
+
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+
+		log.Println("✓ Added shared rate limit buckets")
+	} else {
+		log.Println("✓ Rate limit buckets table already exists")
+	}
 	return nil
 }
 
@@ -471,3 +1189,88 @@ func seedDefaultSkills(db *gorm.DB) error {
 
 	return nil
 }
+
+// backfillUserLocations resolves the free-text location of existing users
+func backfillUserLocations(db *gorm.DB, geocoder geo.Geocoder) error {
+	var locations []string
+	err := db.Model(&models.User{}).
+		Where("location IS NOT NULL AND location <> '' AND latitude IS NULL").
+		Distinct().Pluck("location", &locations).Error
+	if err != nil {
+		return err
+	}
+
+	resolved := 0
+	for _, location := range locations {
+		place, err := geocoder.Geocode(location)
+		if err != nil {
+			continue
+		}
+
+		result := db.Model(&models.User{}).
+			Where("location = ? AND latitude IS NULL", location).
+			Updates(map[string]interface{}{
+				"location_place": place.Name,
+				"latitude":       place.Latitude,
+				"longitude":      place.Longitude,
+				"timezone":       place.Timezone,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		resolved += int(result.RowsAffected)
+	}
+
+	log.Printf("✓ Resolved locations for %d existing users", resolved)
+	return nil
+}
+
+// seedSkillAliases adds common alternative names for the default skills.
+// Aliases whose skill does not exist (e.g. it was renamed) are skipped.
+func seedSkillAliases(db *gorm.DB) error {
+	defaultAliases := map[string][]string{
+		"JavaScript":       {"js", "ecmascript"},
+		"TypeScript":       {"ts"},
+		"Python":           {"py"},
+		"Go":               {"golang"},
+		"Kubernetes":       {"k8s"},
+		"PostgreSQL":       {"postgres", "psql"},
+		"Node.js":          {"node", "nodejs"},
+		"Vue.js":           {"vue", "vuejs"},
+		"Machine Learning": {"ml"},
+		"UI/UX Design":     {"user experience", "user interface"},
+		"HTML/CSS":         {"html", "css"},
+		"C++":              {"cpp"},
+		"AWS":              {"amazon web services"},
+		"MongoDB":          {"mongo"},
+		"Cybersecurity":    {"infosec", "security"},
+	}
+
+	var skills []models.Skill
+	if err := db.Find(&skills).Error; err != nil {
+		return err
+	}
+
+	var aliases []models.SkillAlias
+	for _, skill := range skills {
+		for _, alias := range defaultAliases[skill.Name] {
+			aliases = append(aliases, models.SkillAlias{SkillID: skill.SkillID, Alias: alias})
+		}
+	}
+
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&aliases)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("✓ Inserted %d skill aliases", result.RowsAffected)
+	} else {
+		log.Println("✓ Skill aliases already exist")
+	}
+
+	return nil
+}
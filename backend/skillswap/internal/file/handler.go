@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -25,7 +26,7 @@ func NewHandler(fileUploadService *service.FileUploadService) *Handler {
 // @Tags files
 // @Accept multipart/form-data
 // @Produce json
-// @Param file formData file true "Profile photo file (max 5MB, jpg/png/gif/webp)"
+// @Param file formData file true "Profile photo file (max 5MB by default, jpg/png/gif/webp)"
 // @Security BearerAuth
 // @Success 200 {object} models.FileUploadResponse
 // @Failure 400 {object} map[string]string
@@ -35,15 +36,8 @@ func NewHandler(fileUploadService *service.FileUploadService) *Handler {
 // @Failure 500 {object} map[string]string
 // @Router /api/files/users/photo [post]
 func (h *Handler) UploadUserPhoto(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	uid, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -55,7 +49,7 @@ func (h *Handler) UploadUserPhoto(c *gin.Context) {
 	}
 
 	// Upload file
-	response, err := h.fileUploadService.UploadUserPhoto(uid, file)
+	response, err := h.fileUploadService.UploadUserPhoto(c.Request.Context(), uid, file)
 	if err != nil {
 		if err.Error() == "file too large" {
 			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
@@ -84,19 +78,12 @@ func (h *Handler) UploadUserPhoto(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/files/users/photo [delete]
 func (h *Handler) DeleteUserPhoto(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	uid, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	if err := h.fileUploadService.DeleteUserPhoto(uid); err != nil {
+	if err := h.fileUploadService.DeleteUserPhoto(c.Request.Context(), uid); err != nil {
 		if err.Error() == "user not found" || err.Error() == "user has no photo to delete" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
@@ -131,7 +118,7 @@ func (h *Handler) GetUserPhoto(c *gin.Context) {
 	}
 
 	// Get photo data from database
-	photoData, mimeType, err := h.fileUploadService.GetUserPhoto(userID)
+	photoData, mimeType, err := h.fileUploadService.GetUserPhoto(c.Request.Context(), userID)
 	if err != nil {
 		if err.Error() == "user not found" || err.Error() == "user has no photo" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
@@ -171,7 +158,7 @@ func (h *Handler) GetUserPhotoInfo(c *gin.Context) {
 		return
 	}
 
-	fileInfo, err := h.fileUploadService.GetFileInfo(userID)
+	fileInfo, err := h.fileUploadService.GetFileInfo(c.Request.Context(), userID)
 	if err != nil {
 		if err.Error() == "user not found" || err.Error() == "user has no photo" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
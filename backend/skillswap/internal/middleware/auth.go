@@ -5,36 +5,34 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/authtoken"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 // AuthConfig holds authentication middleware configuration
 type AuthConfig struct {
-	JWTSecret     string
+	Verifier      *authtoken.Verifier
 	TokenLookup   string // "header:Authorization" or "query:token" or "cookie:jwt"
 	TokenHeadName string // "Bearer"
 	SkipPaths     []string
 }
 
-// DefaultAuthConfig returns default auth configuration
-func DefaultAuthConfig(cfg config.Config) AuthConfig {
+// DefaultAuthConfig returns default auth configuration. Tokens whose
+// version is behind the user's current one in versions are rejected.
+func DefaultAuthConfig(cfg config.Config, versions authtoken.VersionSource) AuthConfig {
 	return AuthConfig{
-		JWTSecret:     cfg.JWTSecret,
+		Verifier:      authtoken.NewVerifierFromConfig(cfg).WithVersions(versions),
 		TokenLookup:   "header:Authorization",
 		TokenHeadName: "Bearer",
 		SkipPaths:     []string{},
 	}
 }
 
-// JWTAuth returns a configurable JWT authentication middleware
-func JWTAuth(cfg config.Config, authConfig ...AuthConfig) gin.HandlerFunc {
-	config := DefaultAuthConfig(cfg)
-	if len(authConfig) > 0 {
-		config = authConfig[0]
-	}
-
+// JWTAuth returns a configurable JWT authentication middleware. Only access
+// tokens are accepted; the principal is available from CurrentPrincipal.
+func JWTAuth(config AuthConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip authentication for specified paths
 		for _, path := range config.SkipPaths {
@@ -51,47 +49,37 @@ func JWTAuth(cfg config.Config, authConfig ...AuthConfig) gin.HandlerFunc {
 			return
 		}
 
-		// Parse and validate token
-		jwtToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(config.JWTSecret), nil
-		})
-
-		if err != nil || !jwtToken.Valid {
+		claims, err := config.Verifier.Verify(c.Request.Context(), token, authtoken.TypeAccess)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		// Extract claims and set in context
-		if claims, ok := jwtToken.Claims.(jwt.MapClaims); ok && jwtToken.Valid {
-			c.Set("user_id", claims["user_id"])
-			c.Set("email", claims["email"])
-
-			// Set admin flag if present
-			if isAdmin, exists := claims["is_admin"]; exists {
-				c.Set("is_admin", isAdmin)
-			} else {
-				c.Set("is_admin", false)
-			}
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
-		}
-
+		setPrincipal(c, claims.Principal())
 		c.Next()
 	}
 }
 
-// AdminAuth middleware for admin-only routes
-func AdminAuth() gin.HandlerFunc {
+// RequirePermission only lets staff whose roles grant all of perms through.
+// Staff must have signed in with two-factor authentication.
+func RequirePermission(perms ...rbac.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		isAdmin, exists := c.Get("is_admin")
-		if !exists || !isAdmin.(bool) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		principal, ok := CurrentPrincipal(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+		for _, perm := range perms {
+			if !principal.Can(perm) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied", "permission": perm})
+				c.Abort()
+				return
+			}
+		}
+		if !principal.MFA {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for staff access"})
 			c.Abort()
 			return
 		}
@@ -99,41 +87,19 @@ func AdminAuth() gin.HandlerFunc {
 	}
 }
 
-// OptionalAuth middleware that doesn't fail if no token is provided
-func OptionalAuth(cfg config.Config) gin.HandlerFunc {
+// OptionalAuth middleware that doesn't fail if no token is provided. An
+// invalid token is treated like none.
+func OptionalAuth(config AuthConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		token, err := extractToken(c, config)
+		if err != nil {
 			// No token provided, continue without setting user context
 			c.Next()
 			return
 		}
 
-		// Token provided, try to validate it
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			// Invalid format, continue without setting user context
-			c.Next()
-			return
-		}
-
-		// Parse token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(cfg.JWTSecret), nil
-		})
-
-		// If valid, set user context
-		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				c.Set("user_id", claims["user_id"])
-				c.Set("email", claims["email"])
-				if isAdmin, exists := claims["is_admin"]; exists {
-					c.Set("is_admin", isAdmin)
-				}
-			}
+		if claims, err := config.Verifier.Verify(c.Request.Context(), token, authtoken.TypeAccess); err == nil {
+			setPrincipal(c, claims.Principal())
 		}
 
 		c.Next()
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/authtoken"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *authtoken.Principal
+		perms     []rbac.Permission
+		want      int
+	}{
+		{name: "not signed in", perms: []rbac.Permission{rbac.PermUsersRead}, want: http.StatusUnauthorized},
+		{
+			name:      "regular user",
+			principal: &authtoken.Principal{UserID: uuid.New(), MFA: true},
+			perms:     []rbac.Permission{rbac.PermUsersRead},
+			want:      http.StatusForbidden,
+		},
+		{
+			name:      "role grants it",
+			principal: &authtoken.Principal{UserID: uuid.New(), Roles: []string{rbac.RoleModerator}, MFA: true},
+			perms:     []rbac.Permission{rbac.PermUsersRead, rbac.PermUsersBan},
+			want:      http.StatusOK,
+		},
+		{
+			name:      "role grants only some",
+			principal: &authtoken.Principal{UserID: uuid.New(), Roles: []string{rbac.RoleModerator}, MFA: true},
+			perms:     []rbac.Permission{rbac.PermUsersBan, rbac.PermUsersDelete},
+			want:      http.StatusForbidden,
+		},
+		{
+			name:      "staff without two-factor authentication",
+			principal: &authtoken.Principal{UserID: uuid.New(), Roles: []string{rbac.RoleAdmin}},
+			perms:     []rbac.Permission{rbac.PermUsersRead},
+			want:      http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", func(c *gin.Context) {
+				if tt.principal != nil {
+					setPrincipal(c, tt.principal)
+				}
+			}, RequirePermission(tt.perms...), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
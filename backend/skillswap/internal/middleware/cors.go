@@ -3,70 +3,87 @@ package middleware
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/gin-gonic/gin"
 )
 
 // CORSConfig holds CORS configuration
 type CORSConfig struct {
-	AllowOrigins     []string
+	AllowOrigins     []string // Origins, "https://*.example.com" patterns or "*"
 	AllowMethods     []string
 	AllowHeaders     []string
+	ExposeHeaders    []string // Response headers scripts may read
 	AllowCredentials bool
-	MaxAge           int
+	MaxAge           int // Seconds browsers may cache preflight results
 }
 
-// DefaultCORSConfig returns default CORS configuration
-func DefaultCORSConfig() CORSConfig {
+// DefaultCORSConfig returns the CORS configuration from cfg
+func DefaultCORSConfig(cfg config.Config) CORSConfig {
 	return CORSConfig{
-		AllowOrigins: []string{"*"},
+		AllowOrigins: cfg.CORS.AllowedOrigins,
 		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
-		AllowHeaders: []string{
-			"Origin",
-			"Content-Type",
-			"Accept",
-			"Authorization",
-			"X-Requested-With",
-			"X-CSRF-Token",
-			"Cache-Control",
+		AllowHeaders: cfg.CORS.AllowedHeaders,
+		ExposeHeaders: []string{
+			"RateLimit-Policy",
+			"RateLimit-Limit",
+			"RateLimit-Remaining",
+			"RateLimit-Reset",
+			"Retry-After",
 		},
-		AllowCredentials: true,
-		MaxAge:           86400, // 24 hours
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           int(cfg.CORS.MaxAge.Seconds()),
 	}
 }
 
-// ConfigurableCORS returns a configurable CORS middleware
-func ConfigurableCORS(config ...CORSConfig) gin.HandlerFunc {
-	cfg := DefaultCORSConfig()
+// ConfigurableCORS returns a configurable CORS middleware. Allowed origins
+// are echoed back, so responses vary by Origin; only "*" without
+// credentials is answered with "*".
+func ConfigurableCORS(cfg config.Config, config ...CORSConfig) gin.HandlerFunc {
+	corsConfig := DefaultCORSConfig(cfg)
 	if len(config) > 0 {
-		cfg = config[0]
+		corsConfig = config[0]
 	}
+	// Origins are compared in lower case; patterns are lowered once here
+	allowOrigins := make([]string, len(corsConfig.AllowOrigins))
+	for i, pattern := range corsConfig.AllowOrigins {
+		allowOrigins[i] = strings.ToLower(pattern)
+	}
+	corsConfig.AllowOrigins = allowOrigins
+	anyOrigin := contains(corsConfig.AllowOrigins, "*")
 
 	return func(c *gin.Context) {
-		// Set CORS headers
-		c.Header("Access-Control-Allow-Methods", joinStrings(cfg.AllowMethods, ", "))
-		c.Header("Access-Control-Allow-Headers", joinStrings(cfg.AllowHeaders, ", "))
-
-		if cfg.AllowCredentials {
-			c.Header("Access-Control-Allow-Credentials", "true")
-		}
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
 
-		if cfg.MaxAge > 0 {
-			c.Header("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
+		if !anyOrigin || corsConfig.AllowCredentials {
+			c.Writer.Header().Add("Vary", "Origin")
 		}
 
-		origin := c.GetHeader("Origin")
-		if origin != "" {
-			// Check if origin is allowed
-			if contains(cfg.AllowOrigins, "*") || contains(cfg.AllowOrigins, origin) {
+		if origin != "" && originAllowed(corsConfig.AllowOrigins, origin) {
+			if anyOrigin && !corsConfig.AllowCredentials {
+				c.Header("Access-Control-Allow-Origin", "*")
+			} else {
 				c.Header("Access-Control-Allow-Origin", origin)
 			}
-		} else if contains(cfg.AllowOrigins, "*") {
-			c.Header("Access-Control-Allow-Origin", "*")
+			if corsConfig.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+
+			if preflight {
+				c.Header("Access-Control-Allow-Methods", joinStrings(corsConfig.AllowMethods, ", "))
+				c.Header("Access-Control-Allow-Headers", joinStrings(corsConfig.AllowHeaders, ", "))
+				if corsConfig.MaxAge > 0 {
+					c.Header("Access-Control-Max-Age", strconv.Itoa(corsConfig.MaxAge))
+				}
+			} else if len(corsConfig.ExposeHeaders) > 0 {
+				c.Header("Access-Control-Expose-Headers", joinStrings(corsConfig.ExposeHeaders, ", "))
+			}
 		}
 
 		// Handle preflight requests
-		if c.Request.Method == "OPTIONS" {
+		if c.Request.Method == http.MethodOptions {
 			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
@@ -75,6 +92,32 @@ func ConfigurableCORS(config ...CORSConfig) gin.HandlerFunc {
 	}
 }
 
+// originAllowed matches origin against allowed origins and patterns. A
+// pattern such as "https://*.example.com" matches any subdomain of
+// example.com on https, but not example.com itself. Patterns must already
+// be lower case.
+func originAllowed(allowed []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range allowed {
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		scheme, host, ok := strings.Cut(pattern, "://*.")
+		if !ok {
+			continue
+		}
+		rest, ok := strings.CutPrefix(origin, scheme+"://")
+		if !ok {
+			continue
+		}
+		subdomain, ok := strings.CutSuffix(rest, "."+host)
+		if ok && subdomain != "" && !strings.ContainsAny(subdomain, "/:@") {
+			return true
+		}
+	}
+	return false
+}
+
 // Helper functions
 func joinStrings(slice []string, separator string) string {
 	if len(slice) == 0 {
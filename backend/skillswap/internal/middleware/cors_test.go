@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://app.example.com", "https://*.example.com", "http://*.dev.test:3000"}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.Example.com", true},
+		{"https://api.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://example.com", false},
+		{"https://.example.com", false},
+		{"http://api.example.com", false},
+		{"https://api.example.com:8443", false},
+		{"https://evil.com/.example.com", false},
+		{"https://user@evil.com:.example.com", false},
+		{"https://evilexample.com", false},
+		{"https://api.example.com.evil.com", false},
+		{"http://web.dev.test:3000", true},
+		{"http://web.dev.test", false},
+		{"http://dev.test:3000", false},
+	}
+
+	for _, tt := range tests {
+		if got := originAllowed(allowed, tt.origin); got != tt.want {
+			t.Errorf("originAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestConfigurableCORSLowersPatterns(t *testing.T) {
+	router := gin.New()
+	router.Use(ConfigurableCORS(config.Config{}, CORSConfig{AllowOrigins: []string{"https://*.Example.COM"}, AllowCredentials: true}))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for origin, want := range map[string]string{
+		"https://App.example.com": "https://App.example.com",
+		"https://example.com":     "",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("Access-Control-Allow-Origin for %s = %q, want %q", origin, got, want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,28 +17,30 @@ func Recovery() gin.HandlerFunc {
 	return ErrorRecovery()
 }
 
-// CORS returns the default CORS middleware
-func CORS() gin.HandlerFunc {
-	return ConfigurableCORS()
+// CORS returns the CORS middleware configured by cfg
+func CORS(cfg config.Config) gin.HandlerFunc {
+	return ConfigurableCORS(cfg)
 }
 
 // DefaultMiddleware returns a slice of commonly used middleware
-func DefaultMiddleware() []gin.HandlerFunc {
+func DefaultMiddleware(cfg config.Config) []gin.HandlerFunc {
 	return []gin.HandlerFunc{
 		RequestLogger(),
 		ErrorRecovery(),
-		ConfigurableCORS(),
-		SecurityHeaders(),
+		RequestTimeout(cfg),
+		ConfigurableCORS(cfg),
+		SecurityHeaders(cfg),
 	}
 }
 
-// ProductionMiddleware returns middleware suitable for production
-func ProductionMiddleware() []gin.HandlerFunc {
+// ProductionMiddleware returns middleware suitable for production. Rate
+// limits need a store, so they are added with a RateLimiter.
+func ProductionMiddleware(cfg config.Config) []gin.HandlerFunc {
 	return []gin.HandlerFunc{
 		RequestLogger(),
 		ErrorRecovery(),
-		RateLimit(), // Global rate limiting
-		ConfigurableCORS(),
-		SecurityHeaders(),
+		RequestTimeout(cfg),
+		ConfigurableCORS(cfg),
+		SecurityHeaders(cfg),
 	}
 }
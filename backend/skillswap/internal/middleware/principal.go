@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/authtoken"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const principalKey = "principal"
+
+// setPrincipal stores the authenticated user in the gin context, and in the
+// request context for code that only sees a context.Context
+func setPrincipal(c *gin.Context, p *authtoken.Principal) {
+	c.Set(principalKey, p)
+	c.Request = c.Request.WithContext(authtoken.NewContext(c.Request.Context(), p))
+}
+
+// CurrentPrincipal returns the authenticated user, if there is one
+func CurrentPrincipal(c *gin.Context) (*authtoken.Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return nil, false
+	}
+	p, ok := value.(*authtoken.Principal)
+	return p, ok
+}
+
+// CurrentUserID returns the authenticated user's ID. Without one it
+// responds with 401, and the handler should return.
+func CurrentUserID(c *gin.Context) (uuid.UUID, bool) {
+	p, ok := CurrentPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return uuid.Nil, false
+	}
+	return p.UserID, true
+}
+
+// OptionalUserID returns the authenticated user's ID on routes where
+// signing in is optional
+func OptionalUserID(c *gin.Context) (uuid.UUID, bool) {
+	p, ok := CurrentPrincipal(c)
+	if !ok {
+		return uuid.Nil, false
+	}
+	return p.UserID, true
+}
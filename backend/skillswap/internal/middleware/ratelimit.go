@@ -1,116 +1,91 @@
 package middleware
 
 import (
+	"fmt"
+	"log"
+	"math"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/authtoken"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/ratelimit"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/gin-gonic/gin"
 )
 
-// RateLimitConfig holds rate limiting configuration
-type RateLimitConfig struct {
-	Max      int                       // Maximum number of requests
-	Duration time.Duration             // Time window
-	Message  string                    // Error message when rate limit exceeded
-	KeyFunc  func(*gin.Context) string // Function to generate rate limit key
+// RateLimiter applies the configured rate limit policies. Requests with a
+// valid access token are counted per user, others per client IP.
+type RateLimiter struct {
+	store    ratelimit.Store
+	policies map[string]ratelimit.Policy
+	auth     AuthConfig
 }
 
-// DefaultRateLimitConfig returns default rate limiting configuration
-func DefaultRateLimitConfig() RateLimitConfig {
-	return RateLimitConfig{
-		Max:      100,         // 100 requests
-		Duration: time.Minute, // per minute
-		Message:  "Rate limit exceeded. Please try again later.",
-		KeyFunc:  func(c *gin.Context) string { return c.ClientIP() },
+// NewRateLimiter counts requests in store. auth verifies the tokens of
+// requests that reach it before JWTAuth.
+func NewRateLimiter(cfg config.Config, store ratelimit.Store, auth AuthConfig) *RateLimiter {
+	return &RateLimiter{
+		store:    store,
+		policies: cfg.RateLimit.Limits,
+		auth:     auth,
 	}
 }
 
-// RateLimit returns a rate limiting middleware
-func RateLimit(config ...RateLimitConfig) gin.HandlerFunc {
-	cfg := DefaultRateLimitConfig()
-	if len(config) > 0 {
-		cfg = config[0]
+// Limit returns middleware that applies the named policy. It sets the
+// RateLimit-* headers on every response, and Retry-After when it refuses a
+// request with 429.
+func (l *RateLimiter) Limit(name string) gin.HandlerFunc {
+	policy, ok := l.policies[name]
+	if !ok {
+		log.Fatalf("Unknown rate limit policy %q", name)
 	}
-
-	// Simple in-memory rate limiter
-	// For production, consider using Redis
-	type client struct {
-		count     int
-		resetTime time.Time
-		mutex     sync.Mutex
-	}
-
-	clients := make(map[string]*client)
-	mutex := sync.RWMutex{}
-
-	// Cleanup routine
-	go func() {
-		for {
-			time.Sleep(time.Minute)
-			mutex.Lock()
-			for key, c := range clients {
-				c.mutex.Lock()
-				if time.Now().After(c.resetTime) {
-					delete(clients, key)
-				}
-				c.mutex.Unlock()
-			}
-			mutex.Unlock()
-		}
-	}()
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Period))
 
 	return func(c *gin.Context) {
-		key := cfg.KeyFunc(c)
-
-		mutex.RLock()
-		clientData, exists := clients[key]
-		mutex.RUnlock()
-
-		if !exists {
-			mutex.Lock()
-			clientData = &client{
-				count:     1,
-				resetTime: time.Now().Add(cfg.Duration),
-			}
-			clients[key] = clientData
-			mutex.Unlock()
+		result, err := l.store.Take(c.Request.Context(), name+":"+l.clientKey(c), policy)
+		if err != nil {
+			// Rather serve unlimited than not at all
+			log.Printf("Warning: rate limit store failed: %v", err)
 			c.Next()
 			return
 		}
 
-		clientData.mutex.Lock()
-		defer clientData.mutex.Unlock()
+		header := c.Writer.Header()
+		header.Set("RateLimit-Policy", policyHeader)
+		header.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
 
-		// Reset if time window has passed
-		if time.Now().After(clientData.resetTime) {
-			clientData.count = 1
-			clientData.resetTime = time.Now().Add(cfg.Duration)
-			c.Next()
-			return
-		}
-
-		// Check if limit exceeded
-		if clientData.count >= cfg.Max {
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			header.Set("Retry-After", strconv.Itoa(retryAfter))
 			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":       cfg.Message,
-				"retry_after": int(time.Until(clientData.resetTime).Seconds()),
+				"error":       "Rate limit exceeded. Please try again later.",
+				"retry_after": retryAfter,
 			})
 			c.Abort()
 			return
 		}
-
-		clientData.count++
 		c.Next()
 	}
 }
 
-// AuthRateLimit returns a stricter rate limit for auth endpoints
-func AuthRateLimit() gin.HandlerFunc {
-	return RateLimit(RateLimitConfig{
-		Max:      5,           // 5 requests
-		Duration: time.Minute, // per minute
-		Message:  "Too many authentication attempts. Please try again later.",
-		KeyFunc:  func(c *gin.Context) string { return c.ClientIP() },
-	})
+// clientKey identifies who a request counts against. The limiter may run
+// before JWTAuth, so it reads the token itself when there is no principal
+// yet.
+func (l *RateLimiter) clientKey(c *gin.Context) string {
+	if p, ok := CurrentPrincipal(c); ok {
+		return "user:" + p.UserID.String()
+	}
+	if token, err := extractToken(c, l.auth); err == nil {
+		if claims, err := l.auth.Verifier.Verify(c.Request.Context(), token, authtoken.TypeAccess); err == nil {
+			return "user:" + claims.Principal().UserID.String()
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"log"
+	"net"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/clientip"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// RealIP replaces the request's peer address with the client address
+// resolved through the trusted proxies, so c.ClientIP(), the request log and
+// rate limits all see the client. It must run first, on an engine that
+// trusts no proxies itself (SetTrustedProxies(nil)).
+func RealIP(cfg config.Config) gin.HandlerFunc {
+	resolver, err := clientip.NewResolver(cfg.Server.TrustedPrefixes, cfg.Server.ClientIPHeader)
+	if err != nil {
+		log.Fatalf("Invalid client IP settings: %v", err)
+	}
+
+	return func(c *gin.Context) {
+		if addr := resolver.Resolve(c.Request); addr.IsValid() {
+			_, port, err := net.SplitHostPort(c.Request.RemoteAddr)
+			if err != nil {
+				port = "0"
+			}
+			c.Request.RemoteAddr = net.JoinHostPort(addr.String(), port)
+		}
+		c.Next()
+	}
+}
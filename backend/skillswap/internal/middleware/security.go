@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"fmt"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,22 +17,33 @@ type SecurityConfig struct {
 	StrictTransportSecurity string
 }
 
-// DefaultSecurityConfig returns default security configuration
-func DefaultSecurityConfig() SecurityConfig {
+// DefaultSecurityConfig returns the security headers, with CSP and HSTS
+// from cfg
+func DefaultSecurityConfig(cfg config.Config) SecurityConfig {
+	hsts := ""
+	if cfg.Security.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", int(cfg.Security.HSTSMaxAge.Seconds()))
+		if cfg.Security.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.Security.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
 	return SecurityConfig{
-		ContentTypeOptions: "nosniff",
-		FrameOptions:       "DENY",
-		XSSProtection:      "1; mode=block",
-		ReferrerPolicy:     "strict-origin-when-cross-origin",
-		// CSP and HSTS can be customized based on needs
-		ContentSecurityPolicy:   "",
-		StrictTransportSecurity: "",
+		ContentTypeOptions:      "nosniff",
+		FrameOptions:            "DENY",
+		XSSProtection:           "1; mode=block",
+		ReferrerPolicy:          "strict-origin-when-cross-origin",
+		ContentSecurityPolicy:   cfg.Security.ContentSecurityPolicy,
+		StrictTransportSecurity: hsts,
 	}
 }
 
 // SecurityHeaders returns a configurable security headers middleware
-func SecurityHeaders(config ...SecurityConfig) gin.HandlerFunc {
-	cfg := DefaultSecurityConfig()
+func SecurityHeaders(appConfig config.Config, config ...SecurityConfig) gin.HandlerFunc {
+	cfg := DefaultSecurityConfig(appConfig)
 	if len(config) > 0 {
 		cfg = config[0]
 	}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestTimeout gives every request the configured deadline
+func RequestTimeout(cfg config.Config) gin.HandlerFunc {
+	return Timeout(cfg.Server.RequestTimeout)
+}
+
+// Timeout cancels the request's context after d, which stops the database
+// queries made with it. It can only shorten an earlier deadline, so routes
+// may use it to fail faster. Handlers that return without responding once
+// the deadline has passed get a 503; a zero d sets no deadline.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "request timed out"})
+		}
+	}
+}
@@ -13,7 +13,9 @@ type AvailabilitySlot struct {
 	Label      string    `gorm:"not null"`
 	DayBitmask int32     `gorm:"column:day_bitmask;not null"`
 	StartTime  time.Time `gorm:"column:start_time;type:time;not null"`
-	EndTime    time.Time `gorm:"column:end_time;type:time;not null"`
+	EndTime    time.Time `gorm:"column:end_time;type:time;not null"` // Before StartTime for overnight slots
+	Timezone   *string   `gorm:"column:timezone"`                    // IANA zone; the user's timezone when empty
+	ICalUID    *string   `gorm:"column:ical_uid"`                    // UID of the imported calendar event, if any
 	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime"`
 
 	// Relations - restored
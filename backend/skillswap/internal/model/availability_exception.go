@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ExceptionKind string
+
+const (
+	// ExceptionAvailable adds one-off free time, e.g. an extra slot this Saturday
+	ExceptionAvailable ExceptionKind = "available"
+	// ExceptionBlackout removes free time, e.g. away 3 to 10 August
+	ExceptionBlackout ExceptionKind = "blackout"
+)
+
+// AvailabilityException overrides a user's weekly availability for a
+// concrete span of time
+type AvailabilityException struct {
+	ExceptionID uuid.UUID     `gorm:"type:uuid;primaryKey;column:exception_id;default:gen_random_uuid()"`
+	UserID      uuid.UUID     `gorm:"type:uuid;column:user_id;index"`
+	Kind        ExceptionKind `gorm:"type:text;not null"`
+	Label       string        `gorm:"not null;default:''"`
+	StartsAt    time.Time     `gorm:"column:starts_at;not null"`
+	EndsAt      time.Time     `gorm:"column:ends_at;not null"`
+	ICalUID     *string       `gorm:"column:ical_uid"` // UID of the imported calendar event, if any
+	CreatedAt   time.Time     `gorm:"column:created_at;autoCreateTime"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;references:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// BeforeCreate is called by GORM before creating an AvailabilityException record
+func (e *AvailabilityException) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ExceptionID == uuid.Nil {
+		e.ExceptionID = uuid.New()
+	}
+	return
+}
+
+func (AvailabilityException) TableName() string { return "availability_exceptions" }
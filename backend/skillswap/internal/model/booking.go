@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BookingStatus string
+
+const (
+	BookingConfirmed BookingStatus = "confirmed"
+	BookingCancelled BookingStatus = "cancelled"
+)
+
+// Booking reserves a concrete session for an accepted swap request
+type Booking struct {
+	BookingID    uuid.UUID     `gorm:"type:uuid;primaryKey;column:booking_id;default:gen_random_uuid()"`
+	SwapID       uuid.UUID     `gorm:"type:uuid;column:swap_id;index"`
+	CreatedBy    uuid.UUID     `gorm:"type:uuid;column:created_by"`
+	StartsAt     time.Time     `gorm:"column:starts_at;not null"`
+	EndsAt       time.Time     `gorm:"column:ends_at;not null"`
+	Timezone     string        `gorm:"column:timezone;not null"` // Zone the session was booked in
+	Status       BookingStatus `gorm:"type:text;not null;default:'confirmed'"`
+	CancelledBy  *uuid.UUID    `gorm:"type:uuid;column:cancelled_by"`
+	CancelReason *string       `gorm:"column:cancel_reason"`
+	CreatedAt    time.Time     `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt    time.Time     `gorm:"column:updated_at;autoUpdateTime"`
+
+	// Relations
+	Swap         SwapRequest          `gorm:"foreignKey:SwapID;references:SwapID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Participants []BookingParticipant `gorm:"foreignKey:BookingID;references:BookingID"`
+}
+
+// BeforeCreate is called by GORM before creating a Booking record
+func (b *Booking) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.BookingID == uuid.Nil {
+		b.BookingID = uuid.New()
+	}
+	return
+}
+
+func (Booking) TableName() string { return "bookings" }
+
+// BookingParticipant holds one user's claim on a booking's time. An
+// exclusion constraint keeps a user's active claims from overlapping.
+type BookingParticipant struct {
+	BookingID uuid.UUID `gorm:"type:uuid;primaryKey;column:booking_id"`
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey;column:user_id"`
+	StartsAt  time.Time `gorm:"column:starts_at;not null"`
+	EndsAt    time.Time `gorm:"column:ends_at;not null"`
+	Active    bool      `gorm:"column:active;not null;default:true"`
+}
+
+func (BookingParticipant) TableName() string { return "booking_participants" }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// LoginFailure counts recent failed logins for an email address, whether or
+// not an account uses it, to slow down and lock out password guessing
+type LoginFailure struct {
+	Email          string     `gorm:"primaryKey;column:email"` // Lowercased
+	FailedAttempts int        `gorm:"column:failed_attempts;not null"`
+	LastFailedAt   time.Time  `gorm:"column:last_failed_at;not null"`
+	LockedUntil    *time.Time `gorm:"column:locked_until"`
+}
+
+func (LoginFailure) TableName() string { return "login_failures" }
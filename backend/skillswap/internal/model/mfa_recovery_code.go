@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFARecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator is lost. Only the SHA-256 hash of the code is stored.
+type MFARecoveryCode struct {
+	CodeHash  string     `gorm:"primaryKey;column:code_hash"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (MFARecoveryCode) TableName() string { return "mfa_recovery_codes" }
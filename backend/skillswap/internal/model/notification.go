@@ -18,8 +18,58 @@ const (
 	NotificationTypeSkillMatched  NotificationType = "skill_matched"
 	NotificationTypeSystemAlert   NotificationType = "system_alert"
 	NotificationTypeAdminNotice   NotificationType = "admin_notice"
+
+	NotificationTypeBookingCreated     NotificationType = "booking_created"
+	NotificationTypeBookingRescheduled NotificationType = "booking_rescheduled"
+	NotificationTypeBookingCancelled   NotificationType = "booking_cancelled"
+	NotificationTypeSessionReminder    NotificationType = "session_reminder"
+
+	// NotificationTypeDigest summarises low-priority notifications held back
+	// for a daily or weekly digest
+	NotificationTypeDigest NotificationType = "digest"
 )
 
+// NotificationTypes lists every type users can set preferences for
+var NotificationTypes = []NotificationType{
+	NotificationTypeSwapRequest,
+	NotificationTypeSwapAccepted,
+	NotificationTypeSwapRejected,
+	NotificationTypeSwapCompleted,
+	NotificationTypeNewRating,
+	NotificationTypeSkillMatched,
+	NotificationTypeBookingCreated,
+	NotificationTypeBookingRescheduled,
+	NotificationTypeBookingCancelled,
+	NotificationTypeSessionReminder,
+	NotificationTypeSystemAlert,
+	NotificationTypeAdminNotice,
+	NotificationTypeDigest,
+}
+
+type NotificationPriority string
+
+const (
+	// PriorityHigh notifications ignore preferences and are always delivered
+	PriorityHigh NotificationPriority = "high"
+	// PriorityNormal notifications respect mutes, per-type settings and
+	// quiet hours
+	PriorityNormal NotificationPriority = "normal"
+	// PriorityLow notifications can also be batched into a digest
+	PriorityLow NotificationPriority = "low"
+)
+
+// Priority decides how far user preferences can hold back a notification
+func (t NotificationType) Priority() NotificationPriority {
+	switch t {
+	case NotificationTypeSystemAlert, NotificationTypeAdminNotice, NotificationTypeDigest:
+		return PriorityHigh
+	case NotificationTypeNewRating, NotificationTypeSkillMatched, NotificationTypeSwapCompleted:
+		return PriorityLow
+	default:
+		return PriorityNormal
+	}
+}
+
 type Notification struct {
 	NotificationID uuid.UUID        `gorm:"type:uuid;primaryKey;column:notification_id;default:gen_random_uuid()"`
 	UserID         uuid.UUID        `gorm:"type:uuid;not null;index"`
@@ -27,7 +77,9 @@ type Notification struct {
 	Title          string           `gorm:"column:title;not null"`
 	Message        string           `gorm:"column:message;not null"`
 	IsRead         bool             `gorm:"column:is_read;default:false"`
-	RelatedID      *uuid.UUID       `gorm:"type:uuid;column:related_id"` // ID of related entity (swap, rating, etc.)
+	RelatedID      *uuid.UUID       `gorm:"type:uuid;column:related_id"`         // ID of related entity (swap, rating, booking, etc.)
+	DeliverAt      *time.Time       `gorm:"column:deliver_at"`                   // Hidden until then, e.g. the end of quiet hours
+	DigestPending  bool             `gorm:"column:digest_pending;default:false"` // Hidden until the next digest
 	CreatedAt      time.Time        `gorm:"column:created_at;autoCreateTime"`
 	DeletedAt      gorm.DeletedAt   `gorm:"column:deleted_at;index"`
 
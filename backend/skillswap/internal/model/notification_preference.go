@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationChannel string
+
+const (
+	ChannelInApp NotificationChannel = "in_app"
+	ChannelEmail NotificationChannel = "email"
+)
+
+// NotificationChannels lists every delivery channel
+var NotificationChannels = []NotificationChannel{ChannelInApp, ChannelEmail}
+
+type DigestMode string
+
+const (
+	DigestOff    DigestMode = "off"
+	DigestDaily  DigestMode = "daily"
+	DigestWeekly DigestMode = "weekly"
+)
+
+// NotificationSettings holds a user's delivery settings that apply to all
+// notification types. Users without a row get the defaults: not muted, no
+// quiet hours, no digest.
+type NotificationSettings struct {
+	UserID          uuid.UUID  `gorm:"type:uuid;primaryKey;column:user_id"`
+	Muted           bool       `gorm:"column:muted;not null;default:false"`
+	QuietHoursStart *time.Time `gorm:"column:quiet_hours_start;type:time"` // Wall-clock time in the user's timezone
+	QuietHoursEnd   *time.Time `gorm:"column:quiet_hours_end;type:time"`   // Before QuietHoursStart for overnight quiet hours
+	Digest          DigestMode `gorm:"type:text;not null;default:'off'"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (NotificationSettings) TableName() string { return "notification_settings" }
+
+// NotificationPreference turns one notification type on or off for one
+// channel. Missing rows mean enabled.
+type NotificationPreference struct {
+	UserID    uuid.UUID           `gorm:"type:uuid;primaryKey;column:user_id"`
+	Type      NotificationType    `gorm:"primaryKey;column:type"`
+	Channel   NotificationChannel `gorm:"primaryKey;column:channel"`
+	Enabled   bool                `gorm:"column:enabled;not null"`
+	UpdatedAt time.Time           `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (NotificationPreference) TableName() string { return "notification_preferences" }
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EmailStatus string
+
+const (
+	EmailPending EmailStatus = "pending"
+	EmailSent    EmailStatus = "sent"
+	EmailFailed  EmailStatus = "failed"
+)
+
+// OutboundEmail is a rendered email waiting in the outbox, or the record of
+// one that was sent or given up on. Notification emails and account emails
+// such as verification links share the outbox.
+type OutboundEmail struct {
+	EmailID        uuid.UUID   `gorm:"type:uuid;primaryKey;column:email_id;default:gen_random_uuid()"`
+	UserID         uuid.UUID   `gorm:"type:uuid;not null;index"`
+	NotificationID *uuid.UUID  `gorm:"type:uuid;column:notification_id"` // Nil when the in-app notification was turned off
+	Type           string      `gorm:"column:type;not null"`             // Notification type, or the kind of account email
+	ToAddress      string      `gorm:"column:to_address;not null"`
+	Subject        string      `gorm:"column:subject;not null"`
+	TextBody       string      `gorm:"column:text_body;not null"`
+	HTMLBody       string      `gorm:"column:html_body;not null"`
+	UnsubscribeURL *string     `gorm:"column:unsubscribe_url"` // Sent as the List-Unsubscribe header
+	Status         EmailStatus `gorm:"type:text;not null;default:'pending'"`
+	Attempts       int         `gorm:"column:attempts;not null;default:0"`
+	LastError      *string     `gorm:"column:last_error"`
+	SendAfter      time.Time   `gorm:"column:send_after;not null"` // E.g. the end of the recipient's quiet hours
+	SentAt         *time.Time  `gorm:"column:sent_at"`
+	CreatedAt      time.Time   `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time   `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// BeforeCreate is called by GORM before creating an OutboundEmail record
+func (e *OutboundEmail) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.EmailID == uuid.Nil {
+		e.EmailID = uuid.New()
+	}
+	return
+}
+
+func (OutboundEmail) TableName() string { return "email_outbox" }
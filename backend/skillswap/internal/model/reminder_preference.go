@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReminderOffsets lists how many minutes before a session reminders are
+// sent, stored as a JSON array
+type ReminderOffsets []int
+
+func (o ReminderOffsets) Value() (driver.Value, error) {
+	if o == nil {
+		o = ReminderOffsets{}
+	}
+	b, err := json.Marshal([]int(o))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (o *ReminderOffsets) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*o = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into ReminderOffsets", value)
+	}
+	return json.Unmarshal(b, (*[]int)(o))
+}
+
+// ReminderPreference overrides the default session reminders for a user.
+// Users without a row get the configured defaults.
+type ReminderPreference struct {
+	UserID         uuid.UUID       `gorm:"type:uuid;primaryKey;column:user_id"`
+	Enabled        bool            `gorm:"column:enabled;not null;default:true"`
+	OffsetsMinutes ReminderOffsets `gorm:"column:offsets_minutes;type:jsonb;not null"`
+	UpdatedAt      time.Time       `gorm:"column:updated_at;autoUpdateTime"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;references:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (ReminderPreference) TableName() string { return "reminder_preferences" }
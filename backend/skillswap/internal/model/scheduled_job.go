@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// ScheduledJob is a unit of deferred work stored in the database so it
+// survives restarts and runs on exactly one instance
+type ScheduledJob struct {
+	JobID       uuid.UUID  `gorm:"type:uuid;primaryKey;column:job_id;default:gen_random_uuid()"`
+	Kind        string     `gorm:"column:kind;not null"`
+	Key         *string    `gorm:"column:key;uniqueIndex"`      // Deduplicates jobs, e.g. one reminder per booking and offset
+	SubjectID   *uuid.UUID `gorm:"type:uuid;column:subject_id"` // Entity the job is about, for cancelling
+	Payload     string     `gorm:"column:payload;type:jsonb;not null;default:'{}'"`
+	Status      JobStatus  `gorm:"type:text;not null;default:'pending'"`
+	RunAt       time.Time  `gorm:"column:run_at;not null"`
+	Attempts    int        `gorm:"column:attempts;not null;default:0"`
+	LockedUntil *time.Time `gorm:"column:locked_until"`
+	LastError   *string    `gorm:"column:last_error"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// BeforeCreate is called by GORM before creating a ScheduledJob record
+func (j *ScheduledJob) BeforeCreate(tx *gorm.DB) (err error) {
+	if j.JobID == uuid.Nil {
+		j.JobID = uuid.New()
+	}
+	return
+}
+
+func (ScheduledJob) TableName() string { return "scheduled_jobs" }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SkillAlias is an alternative name a skill can be found by, e.g. "k8s" for
+// Kubernetes. Aliases are stored lower-case.
+type SkillAlias struct {
+	AliasID   uuid.UUID `gorm:"type:uuid;primaryKey;column:alias_id;default:gen_random_uuid()"`
+	SkillID   uuid.UUID `gorm:"type:uuid;column:skill_id;index"`
+	Alias     string    `gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+
+	// Relations
+	Skill Skill `gorm:"foreignKey:SkillID;references:SkillID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// BeforeCreate is called by GORM before creating a SkillAlias record
+func (a *SkillAlias) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.AliasID == uuid.Nil {
+		a.AliasID = uuid.New()
+	}
+	return
+}
+
+func (SkillAlias) TableName() string { return "skill_aliases" }
@@ -8,25 +8,36 @@ import (
 )
 
 type User struct {
-	UserID        uuid.UUID `gorm:"type:uuid;primaryKey;column:user_id;default:gen_random_uuid()"`
-	Name          string    `gorm:"not null"`
-	Email         string    `gorm:"uniqueIndex;not null"`
-	PasswordHash  string    `gorm:"column:password_hash;not null"`
-	Location      *string
-	PhotoURL      *string        `gorm:"column:photo_url"`
-	PhotoData     []byte         `gorm:"column:photo_data;type:bytea"`
-	PhotoMimeType *string        `gorm:"column:photo_mime_type"`
-	IsPublic      bool           `gorm:"column:is_public;default:true"`
-	IsAdmin       bool           `gorm:"column:is_admin;default:false"`
-	IsBanned      bool           `gorm:"column:is_banned;default:false"`
-	CreatedAt     time.Time      `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt     time.Time      `gorm:"column:updated_at;autoUpdateTime"`
-	DeletedAt     gorm.DeletedAt `gorm:"column:deleted_at;index"`
+	UserID            uuid.UUID `gorm:"type:uuid;primaryKey;column:user_id;default:gen_random_uuid()"`
+	Name              string    `gorm:"not null"`
+	Email             string    `gorm:"uniqueIndex;not null"`
+	PasswordHash      string    `gorm:"column:password_hash;not null"`
+	Location          *string
+	LocationPlace     *string        `gorm:"column:location_place"` // Canonical place resolved from Location
+	Latitude          *float64       `gorm:"column:latitude"`
+	Longitude         *float64       `gorm:"column:longitude"`
+	Timezone          *string        `gorm:"column:timezone"`                // IANA zone, e.g. "Europe/Berlin"
+	IsRemote          bool           `gorm:"column:is_remote;default:false"` // Happy to swap over video
+	PhotoURL          *string        `gorm:"column:photo_url"`
+	PhotoData         []byte         `gorm:"column:photo_data;type:bytea"`
+	PhotoMimeType     *string        `gorm:"column:photo_mime_type"`
+	IsPublic          bool           `gorm:"column:is_public;default:true"`
+	IsBanned          bool           `gorm:"column:is_banned;default:false"`
+	CalendarTokenHash *string        `gorm:"column:calendar_token_hash;uniqueIndex" json:"-"` // SHA-256 of the calendar feed token
+	EmailVerifiedAt   *time.Time     `gorm:"column:email_verified_at"`
+	TokenVersion      int            `gorm:"column:token_version;not null;default:0" json:"-"` // Bumped to revoke all sessions
+	MFASecret         *string        `gorm:"column:mfa_secret" json:"-"`                       // Base32 TOTP secret, set during enrollment
+	MFAEnabledAt      *time.Time     `gorm:"column:mfa_enabled_at"`                            // Nil until enrollment is confirmed with a code
+	MFALastStep       int64          `gorm:"column:mfa_last_step;not null;default:0" json:"-"` // Last TOTP step used, so codes cannot be replayed
+	CreatedAt         time.Time      `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt         time.Time      `gorm:"column:updated_at;autoUpdateTime"`
+	DeletedAt         gorm.DeletedAt `gorm:"column:deleted_at;index"`
 
 	// Relations - restored
 	SkillsOffered     []UserSkillOffered `gorm:"foreignKey:UserID;references:UserID"`
 	SkillsWanted      []UserSkillWanted  `gorm:"foreignKey:UserID;references:UserID"`
 	AvailabilitySlots []AvailabilitySlot `gorm:"foreignKey:UserID;references:UserID"`
+	Roles             []UserRole         `gorm:"foreignKey:UserID;references:UserID" json:",omitempty"` // Staff roles, loaded for admins
 }
 
 // BeforeCreate is called by GORM before creating a User record
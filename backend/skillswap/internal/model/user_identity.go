@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links an account at an OpenID Connect provider to a user
+type UserIdentity struct {
+	Provider    string    `gorm:"primaryKey;column:provider"`
+	Subject     string    `gorm:"primaryKey;column:subject"` // The provider's stable user ID
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	Email       string    `gorm:"column:email"` // Address at the provider when last used
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
+	LastLoginAt time.Time `gorm:"column:last_login_at"`
+}
+
+func (UserIdentity) TableName() string { return "user_identities" }
+
+// OIDCLoginState is a sign-in started at a provider and not yet completed.
+// It is looked up by the hash of the state parameter.
+type OIDCLoginState struct {
+	StateHash    string    `gorm:"primaryKey;column:state_hash"`
+	Provider     string    `gorm:"column:provider;not null"`
+	Nonce        string    `gorm:"column:nonce;not null"`
+	CodeVerifier string    `gorm:"column:code_verifier;not null"` // PKCE
+	ExpiresAt    time.Time `gorm:"column:expires_at;not null"`
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (OIDCLoginState) TableName() string { return "oidc_login_states" }
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserRole grants a staff role to a user. Roles and their permissions are
+// defined in the rbac package.
+type UserRole struct {
+	UserID    uuid.UUID  `gorm:"type:uuid;primaryKey;column:user_id" json:"-"`
+	Role      string     `gorm:"primaryKey;column:role" json:"role"`
+	GrantedBy *uuid.UUID `gorm:"type:uuid;column:granted_by" json:"granted_by"` // Nil for roles from before roles existed
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (UserRole) TableName() string { return "user_roles" }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TokenPurpose string
+
+const (
+	TokenEmailVerification TokenPurpose = "email_verification"
+	TokenPasswordReset     TokenPurpose = "password_reset"
+	TokenEmailChange       TokenPurpose = "email_change"
+	TokenOIDCLogin         TokenPurpose = "oidc_login" // Exchanged by the app for tokens after a provider sign-in
+)
+
+// UserToken is a single-use token sent to a user, e.g. in a verification
+// link. Only the SHA-256 hash of the token is stored.
+type UserToken struct {
+	TokenHash string       `gorm:"primaryKey;column:token_hash"`
+	UserID    uuid.UUID    `gorm:"type:uuid;not null;index"`
+	Purpose   TokenPurpose `gorm:"type:text;not null"`
+	Email     *string      `gorm:"column:email"` // Address the token was sent to, e.g. the new address of an email change
+	ExpiresAt time.Time    `gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time   `gorm:"column:used_at"`
+	CreatedAt time.Time    `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (UserToken) TableName() string { return "user_tokens" }
@@ -1,10 +1,15 @@
 package notification
 
 import (
+	"bytes"
+	"html/template"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -36,15 +41,8 @@ func NewHandler(notificationService *service.NotificationService) *Handler {
 // @Failure 500 {object} map[string]string
 // @Router /api/notifications [get]
 func (h *Handler) GetUserNotifications(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	uid, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -65,7 +63,7 @@ func (h *Handler) GetUserNotifications(c *gin.Context) {
 
 	unreadOnly := c.Query("unread_only") == "true"
 
-	notifications, total, err := h.notificationService.GetUserNotifications(uid, page, limit, unreadOnly)
+	notifications, total, err := h.notificationService.GetUserNotifications(c.Request.Context(), uid, page, limit, unreadOnly)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notifications"})
 		return
@@ -109,15 +107,8 @@ func (h *Handler) GetUserNotifications(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/notifications/mark-read [put]
 func (h *Handler) MarkNotificationsAsRead(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	uid, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -132,7 +123,7 @@ func (h *Handler) MarkNotificationsAsRead(c *gin.Context) {
 		return
 	}
 
-	if err := h.notificationService.MarkNotificationsAsRead(uid, req.NotificationIDs); err != nil {
+	if err := h.notificationService.MarkNotificationsAsRead(c.Request.Context(), uid, req.NotificationIDs); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -152,19 +143,12 @@ func (h *Handler) MarkNotificationsAsRead(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/notifications/mark-all-read [put]
 func (h *Handler) MarkAllAsRead(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	uid, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	if err := h.notificationService.MarkAllAsRead(uid); err != nil {
+	if err := h.notificationService.MarkAllAsRead(c.Request.Context(), uid); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark all notifications as read"})
 		return
 	}
@@ -187,15 +171,8 @@ func (h *Handler) MarkAllAsRead(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/notifications/{id} [delete]
 func (h *Handler) DeleteNotification(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	uid, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -205,7 +182,7 @@ func (h *Handler) DeleteNotification(c *gin.Context) {
 		return
 	}
 
-	if err := h.notificationService.DeleteNotification(uid, notificationID); err != nil {
+	if err := h.notificationService.DeleteNotification(c.Request.Context(), uid, notificationID); err != nil {
 		if err.Error() == "notification not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
 			return
@@ -229,19 +206,12 @@ func (h *Handler) DeleteNotification(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/notifications/stats [get]
 func (h *Handler) GetNotificationStats(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	uid, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	stats, err := h.notificationService.GetNotificationStats(uid)
+	stats, err := h.notificationService.GetNotificationStats(c.Request.Context(), uid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification stats"})
 		return
@@ -250,25 +220,25 @@ func (h *Handler) GetNotificationStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
-// CreateNotification creates a new notification (admin only)
+// CreateNotification creates a new notification for a user
 // @Summary Create notification
-// @Description Create a new notification (admin only)
+// @Description Create a new notification (notifications:send permission)
 // @Tags notifications
 // @Accept json
 // @Produce json
 // @Param request body models.NotificationRequest true "Notification details"
 // @Security BearerAuth
 // @Success 201 {object} models.NotificationResponse
+// @Success 200 {object} map[string]string "Suppressed by the recipient's preferences"
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/notifications [post]
 func (h *Handler) CreateNotification(c *gin.Context) {
-	// Check if user is admin
-	isAdmin, exists := c.Get("is_admin")
-	if !exists || !isAdmin.(bool) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	principal, ok := middleware.CurrentPrincipal(c)
+	if !ok || !principal.Can(rbac.PermNotificationsSend) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
 		return
 	}
 
@@ -278,11 +248,16 @@ func (h *Handler) CreateNotification(c *gin.Context) {
 		return
 	}
 
-	notification, err := h.notificationService.CreateNotification(&req)
+	notification, err := h.notificationService.CreateNotification(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification"})
 		return
 	}
+	if notification == nil {
+		// The recipient has turned this type off
+		c.JSON(http.StatusOK, gin.H{"message": "Notification suppressed by the recipient's preferences"})
+		return
+	}
 
 	response := models.NotificationResponse{
 		NotificationID: notification.NotificationID,
@@ -312,15 +287,8 @@ func (h *Handler) CreateNotification(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/notifications/{id} [get]
 func (h *Handler) GetNotificationByID(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	uid, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -330,7 +298,7 @@ func (h *Handler) GetNotificationByID(c *gin.Context) {
 		return
 	}
 
-	notification, err := h.notificationService.GetNotificationByID(uid, notificationID)
+	notification, err := h.notificationService.GetNotificationByID(c.Request.Context(), uid, notificationID)
 	if err != nil {
 		if err.Error() == "notification not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
@@ -352,3 +320,156 @@ func (h *Handler) GetNotificationByID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// GetPreferences retrieves the authenticated user's notification preferences
+// @Summary Get notification preferences
+// @Description Get mute, quiet hours, digest and per-type, per-channel settings
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.NotificationPreferencesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/notifications/preferences [get]
+func (h *Handler) GetPreferences(c *gin.Context) {
+	uid, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.notificationService.GetPreferences(c.Request.Context(), uid)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences updates the authenticated user's notification preferences
+// @Summary Update notification preferences
+// @Description Update any of mute, quiet hours, digest mode and per-type, per-channel settings; omitted fields are unchanged
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param request body service.UpdateNotificationPreferencesDTO true "Preferences to change"
+// @Security BearerAuth
+// @Success 200 {object} service.NotificationPreferencesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/notifications/preferences [put]
+func (h *Handler) UpdatePreferences(c *gin.Context) {
+	uid, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req service.UpdateNotificationPreferencesDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := h.notificationService.UpdatePreferences(c.Request.Context(), uid, &req)
+	if err != nil {
+		msg := err.Error()
+		if strings.HasPrefix(msg, "invalid quiet hours") || strings.HasPrefix(msg, "unknown notification") ||
+			strings.HasSuffix(msg, "cannot be turned off") ||
+			msg == "quiet hours must not start and end at the same time" || msg == "digest must be off, daily or weekly" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		if msg == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// unsubscribePage is shown to people following an unsubscribe link
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width"><title>{{.Title}}</title></head>
+<body style="font-family:Helvetica,Arial,sans-serif;max-width:480px;margin:48px auto;padding:0 16px;color:#1f2933;">
+<h1 style="font-size:22px;">{{.Title}}</h1>
+<p>{{.Text}}</p>
+{{if .Confirm}}<form method="post"><button type="submit" style="padding:8px 16px;font-size:15px;">Unsubscribe</button></form>{{end}}
+</body>
+</html>
+`))
+
+// Unsubscribe shows what an unsubscribe link from an email turns off and
+// asks for confirmation, so link scanners that fetch it change nothing
+// @Summary Confirm email unsubscribe
+// @Description Public page for the unsubscribe links in notification emails; the signed token is the credential
+// @Tags notifications
+// @Produce html
+// @Param token query string true "Unsubscribe token"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Router /api/v1/notifications/unsubscribe [get]
+func (h *Handler) Unsubscribe(c *gin.Context) {
+	response, err := h.notificationService.DescribeUnsubscribe(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		h.unsubscribeError(c, err)
+		return
+	}
+
+	renderUnsubscribePage(c, http.StatusOK, "Unsubscribe", "Stop sending "+unsubscribeSubject(response)+" to "+response.Email+"?", true)
+}
+
+// ConfirmUnsubscribe turns off the emails an unsubscribe link is for. Mail
+// clients post here directly for one-click unsubscribe.
+// @Summary Unsubscribe from emails
+// @Description Turns off the email channel for the type in the token, or for all optional types
+// @Tags notifications
+// @Produce html
+// @Param token query string true "Unsubscribe token"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Router /api/v1/notifications/unsubscribe [post]
+func (h *Handler) ConfirmUnsubscribe(c *gin.Context) {
+	response, err := h.notificationService.Unsubscribe(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		h.unsubscribeError(c, err)
+		return
+	}
+
+	renderUnsubscribePage(c, http.StatusOK, "You have been unsubscribed", "We will no longer send "+unsubscribeSubject(response)+" to "+response.Email+". You can change this in your notification preferences.", false)
+}
+
+func (h *Handler) unsubscribeError(c *gin.Context, err error) {
+	if err.Error() == "invalid unsubscribe link" {
+		renderUnsubscribePage(c, http.StatusBadRequest, "Invalid link", "This unsubscribe link is invalid. You can change which emails you get in your notification preferences.", false)
+		return
+	}
+	renderUnsubscribePage(c, http.StatusInternalServerError, "Something went wrong", "Please try again later.", false)
+}
+
+func unsubscribeSubject(response *service.UnsubscribeResponse) string {
+	if response.Type == "" {
+		return "optional emails"
+	}
+	return strings.ReplaceAll(string(response.Type), "_", " ") + " emails"
+}
+
+func renderUnsubscribePage(c *gin.Context, status int, title, text string, confirm bool) {
+	var page bytes.Buffer
+	if err := unsubscribePage.Execute(&page, gin.H{"Title": title, "Text": text, "Confirm": confirm}); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
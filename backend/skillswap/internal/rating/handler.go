@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -41,15 +42,14 @@ func (h *Handler) CreateRating(c *gin.Context) {
 	}
 
 	// Get rater ID from JWT token
-	raterID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	raterID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
-	req.RaterID = raterID.(uuid.UUID)
+	req.RaterID = raterID
 
 	// Check if user can rate this swap
-	canRate, err := h.ratingService.CanUserRateSwap(req.SwapID, req.RaterID)
+	canRate, err := h.ratingService.CanUserRateSwap(c.Request.Context(), req.SwapID, req.RaterID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify rating permissions"})
 		return
@@ -60,7 +60,7 @@ func (h *Handler) CreateRating(c *gin.Context) {
 	}
 
 	// Check if user has already rated this swap
-	hasRated, err := h.ratingService.HasUserRatedSwap(req.SwapID, req.RaterID)
+	hasRated, err := h.ratingService.HasUserRatedSwap(c.Request.Context(), req.SwapID, req.RaterID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing ratings"})
 		return
@@ -70,7 +70,7 @@ func (h *Handler) CreateRating(c *gin.Context) {
 		return
 	}
 
-	rating, err := h.ratingService.CreateRating(&req)
+	rating, err := h.ratingService.CreateRating(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -99,7 +99,7 @@ func (h *Handler) GetRating(c *gin.Context) {
 		return
 	}
 
-	rating, err := h.ratingService.GetRatingByID(ratingID)
+	rating, err := h.ratingService.GetRatingByID(c.Request.Context(), ratingID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Rating not found"})
 		return
@@ -138,13 +138,12 @@ func (h *Handler) UpdateRating(c *gin.Context) {
 	}
 
 	// Get user ID from JWT token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	rating, err := h.ratingService.UpdateRating(ratingID, userID.(uuid.UUID), &req)
+	rating, err := h.ratingService.UpdateRating(c.Request.Context(), ratingID, userID, &req)
 	if err != nil {
 		if err.Error() == "rating not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Rating not found"})
@@ -184,13 +183,12 @@ func (h *Handler) DeleteRating(c *gin.Context) {
 	}
 
 	// Get user ID from JWT token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	err = h.ratingService.DeleteRating(ratingID, userID.(uuid.UUID))
+	err = h.ratingService.DeleteRating(c.Request.Context(), ratingID, userID)
 	if err != nil {
 		if err.Error() == "rating not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Rating not found"})
@@ -226,7 +224,7 @@ func (h *Handler) GetSwapRatings(c *gin.Context) {
 		return
 	}
 
-	ratings, err := h.ratingService.GetSwapRatings(swapID)
+	ratings, err := h.ratingService.GetSwapRatings(c.Request.Context(), swapID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -307,7 +305,7 @@ func (h *Handler) GetUserRatings(c *gin.Context) {
 		filter.AsRatee = true
 	}
 
-	ratings, err := h.ratingService.GetUserRatings(userID, filter)
+	ratings, err := h.ratingService.GetUserRatings(c.Request.Context(), userID, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -335,7 +333,7 @@ func (h *Handler) GetUserRatingStats(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.ratingService.GetUserRatingStats(userID)
+	stats, err := h.ratingService.GetUserRatingStats(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -2,21 +2,24 @@ package router
 
 import (
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/admin"
-	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/skill"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupAdminRoutes configures all admin-related routes
-func SetupAdminRoutes(api *gin.RouterGroup, cfg *config.Config, skillHandler *skill.Handler, adminHandler *admin.Handler) {
-	// Admin routes group with authentication and admin role check
+func SetupAdminRoutes(api *gin.RouterGroup, authConfig middleware.AuthConfig, skillHandler *skill.Handler, adminHandler *admin.Handler) {
+	// Admin routes group. Each route needs a permission, which staff roles
+	// grant; see package rbac.
 	adminGroup := api.Group("/admin")
-	adminGroup.Use(middleware.JWTAuth(*cfg))
-	adminGroup.Use(middleware.AdminAuth())
+	adminGroup.Use(middleware.JWTAuth(authConfig))
 	{
+		can := middleware.RequirePermission
+
 		// Admin skill management
 		skills := adminGroup.Group("/skills")
+		skills.Use(can(rbac.PermSkillsManage))
 		{
 			skills.POST("", skillHandler.CreateSkill)       // POST /api/v1/admin/skills
 			skills.PUT("/:id", skillHandler.UpdateSkill)    // PUT /api/v1/admin/skills/:id
@@ -26,24 +29,34 @@ func SetupAdminRoutes(api *gin.RouterGroup, cfg *config.Config, skillHandler *sk
 		// Admin user management
 		users := adminGroup.Group("/users")
 		{
-			users.GET("", adminHandler.GetAllUsers)                      // GET /api/v1/admin/users
-			users.PUT("/:id/ban", adminHandler.BanUser)                  // PUT /api/v1/admin/users/:id/ban
-			users.PUT("/:id/unban", adminHandler.UnbanUser)              // PUT /api/v1/admin/users/:id/unban
-			users.DELETE("/:id", adminHandler.DeleteUser)                // DELETE /api/v1/admin/users/:id
-			users.PUT("/:id/make-admin", adminHandler.MakeUserAdmin)     // PUT /api/v1/admin/users/:id/make-admin
-			users.PUT("/:id/remove-admin", adminHandler.RemoveUserAdmin) // PUT /api/v1/admin/users/:id/remove-admin
+			users.GET("", can(rbac.PermUsersRead), adminHandler.GetAllUsers)                        // GET /api/v1/admin/users
+			users.PUT("/:id/ban", can(rbac.PermUsersBan), adminHandler.BanUser)                     // PUT /api/v1/admin/users/:id/ban
+			users.PUT("/:id/unban", can(rbac.PermUsersBan), adminHandler.UnbanUser)                 // PUT /api/v1/admin/users/:id/unban
+			users.DELETE("/:id", can(rbac.PermUsersDelete), adminHandler.DeleteUser)                // DELETE /api/v1/admin/users/:id
+			users.PUT("/:id/make-admin", can(rbac.PermRolesManage), adminHandler.MakeUserAdmin)     // PUT /api/v1/admin/users/:id/make-admin
+			users.PUT("/:id/remove-admin", can(rbac.PermRolesManage), adminHandler.RemoveUserAdmin) // PUT /api/v1/admin/users/:id/remove-admin
+
+			// Role assignments
+			users.GET("/:id/roles", can(rbac.PermUsersRead), adminHandler.GetUserRoles)          // GET /api/v1/admin/users/:id/roles
+			users.PUT("/:id/roles/:role", can(rbac.PermRolesManage), adminHandler.GrantRole)     // PUT /api/v1/admin/users/:id/roles/:role
+			users.DELETE("/:id/roles/:role", can(rbac.PermRolesManage), adminHandler.RevokeRole) // DELETE /api/v1/admin/users/:id/roles/:role
 		}
+		adminGroup.GET("/roles", can(rbac.PermRolesManage), adminHandler.ListRoles) // GET /api/v1/admin/roles
+
+		// Login lockouts
+		adminGroup.GET("/lockouts", can(rbac.PermLockoutsManage), adminHandler.GetLockouts)              // GET /api/v1/admin/lockouts
+		adminGroup.DELETE("/users/:id/lockout", can(rbac.PermLockoutsManage), adminHandler.ClearLockout) // DELETE /api/v1/admin/users/:id/lockout
 
 		// Admin swap management
 		swaps := adminGroup.Group("/swaps")
 		{
-			swaps.GET("", adminHandler.GetAllSwaps)           // GET /api/v1/admin/swaps
-			swaps.PUT("/:id/cancel", adminHandler.CancelSwap) // PUT /api/v1/admin/swaps/:id/cancel
+			swaps.GET("", can(rbac.PermSwapsRead), adminHandler.GetAllSwaps)             // GET /api/v1/admin/swaps
+			swaps.PUT("/:id/cancel", can(rbac.PermSwapsCancel), adminHandler.CancelSwap) // PUT /api/v1/admin/swaps/:id/cancel
 		}
 
 		// Platform statistics and monitoring
-		adminGroup.GET("/stats", adminHandler.GetPlatformStats)     // GET /api/v1/admin/stats
-		adminGroup.GET("/reports", adminHandler.GetReportedContent) // GET /api/v1/admin/reports
+		adminGroup.GET("/stats", can(rbac.PermStatsRead), adminHandler.GetPlatformStats)         // GET /api/v1/admin/stats
+		adminGroup.GET("/reports", can(rbac.PermReportsManage), adminHandler.GetReportedContent) // GET /api/v1/admin/reports
 
 		// TODO: Additional admin features
 		// - GET /admin/audit-logs - View admin action logs
@@ -1,16 +1,17 @@
 package router
 
 import (
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/ratelimit"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/auth"
-	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupAuthRoutes configures all authentication-related routes
-func SetupAuthRoutes(api *gin.RouterGroup, authService service.AuthService, cfg *config.Config) {
+func SetupAuthRoutes(api *gin.RouterGroup, authService service.AuthService, authConfig middleware.AuthConfig, limiter *middleware.RateLimiter) {
 	authHandler := auth.NewHandler(authService)
+	authLimit := limiter.Limit(ratelimit.PolicyAuth)
 
 	// Public auth routes (no authentication required)
 	authGroup := api.Group("/auth")
@@ -18,13 +19,34 @@ func SetupAuthRoutes(api *gin.RouterGroup, authService service.AuthService, cfg
 		authGroup.POST("/register", authHandler.Register)
 		authGroup.POST("/login", authHandler.Login)
 		authGroup.POST("/refresh", authHandler.RefreshToken)
+		authGroup.POST("/verify-email", authHandler.VerifyEmail)
+		authGroup.POST("/forgot-password", authLimit, authHandler.ForgotPassword)
+		authGroup.POST("/reset-password", authHandler.ResetPassword)
+		authGroup.POST("/email/confirm", authHandler.ConfirmEmailChange)
+		authGroup.POST("/mfa/verify", authLimit, authHandler.VerifyMFA)
+
+		// Sign in with OpenID Connect identity providers
+		authGroup.GET("/oidc/providers", authHandler.GetOIDCProviders)
+		authGroup.GET("/oidc/:provider/login", authHandler.BeginOIDCLogin)
+		authGroup.GET("/oidc/:provider/callback", authHandler.OIDCCallback)
+		authGroup.POST("/oidc/exchange", authHandler.ExchangeOIDCLogin)
 	}
 
 	// Protected auth routes (authentication required)
 	authProtected := api.Group("/auth")
-	authProtected.Use(middleware.JWTAuth(*cfg))
+	authProtected.Use(middleware.JWTAuth(authConfig))
 	{
 		authProtected.POST("/logout", authHandler.Logout)
 		authProtected.GET("/me", authHandler.GetMe)
+		authProtected.POST("/verify-email/resend", authHandler.ResendVerification)
+		authProtected.PUT("/password", authHandler.ChangePassword)
+		authProtected.POST("/email", authHandler.ChangeEmail)
+
+		// Two-factor authentication
+		authProtected.GET("/mfa", authHandler.GetMFAStatus)
+		authProtected.POST("/mfa/setup", authHandler.SetupMFA)
+		authProtected.POST("/mfa/enable", authLimit, authHandler.EnableMFA)
+		authProtected.DELETE("/mfa", authLimit, authHandler.DisableMFA)
+		authProtected.POST("/mfa/recovery-codes", authLimit, authHandler.RegenerateRecoveryCodes)
 	}
 }
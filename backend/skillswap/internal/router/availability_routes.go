@@ -2,16 +2,18 @@ package router
 
 import (
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/availability"
-	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupAvailabilityRoutes sets up all availability-related routes
-func SetupAvailabilityRoutes(api *gin.RouterGroup, cfg *config.Config, availabilityHandler *availability.Handler) {
+func SetupAvailabilityRoutes(api *gin.RouterGroup, authConfig middleware.AuthConfig, availabilityHandler *availability.Handler) {
+	// Calendar feed; the token in the URL is the credential
+	api.GET("/calendar/:token", availabilityHandler.CalendarFeed) // GET /api/v1/calendar/:token.ics
+
 	// All availability routes require authentication
 	availabilityGroup := api.Group("/availability")
-	availabilityGroup.Use(middleware.JWTAuth(*cfg))
+	availabilityGroup.Use(middleware.JWTAuth(authConfig))
 	{
 		availabilityGroup.POST("", availabilityHandler.CreateAvailabilitySlot)       // POST /api/v1/availability
 		availabilityGroup.GET("", availabilityHandler.GetUserAvailabilitySlots)      // GET /api/v1/availability
@@ -19,7 +21,18 @@ func SetupAvailabilityRoutes(api *gin.RouterGroup, cfg *config.Config, availabil
 		availabilityGroup.PUT("/:id", availabilityHandler.UpdateAvailabilitySlot)    // PUT /api/v1/availability/:id
 		availabilityGroup.DELETE("/:id", availabilityHandler.DeleteAvailabilitySlot) // DELETE /api/v1/availability/:id
 
+		// One-off availability and blackouts
+		availabilityGroup.POST("/exceptions", availabilityHandler.CreateException)       // POST /api/v1/availability/exceptions
+		availabilityGroup.GET("/exceptions", availabilityHandler.GetUserExceptions)      // GET /api/v1/availability/exceptions
+		availabilityGroup.DELETE("/exceptions/:id", availabilityHandler.DeleteException) // DELETE /api/v1/availability/exceptions/:id
+
+		// Calendar sync
+		availabilityGroup.POST("/calendar/token", availabilityHandler.RotateCalendarToken)   // POST /api/v1/availability/calendar/token
+		availabilityGroup.DELETE("/calendar/token", availabilityHandler.RevokeCalendarToken) // DELETE /api/v1/availability/calendar/token
+		availabilityGroup.POST("/import", availabilityHandler.ImportCalendar)                // POST /api/v1/availability/import
+
 		// Advanced availability queries
+		availabilityGroup.GET("/free", availabilityHandler.GetFreeTime)                       // GET /api/v1/availability/free
 		availabilityGroup.GET("/search", availabilityHandler.GetAvailabilityByDayAndTime)     // GET /api/v1/availability/search
 		availabilityGroup.GET("/common/:user_id", availabilityHandler.FindCommonAvailability) // GET /api/v1/availability/common/:user_id
 	}
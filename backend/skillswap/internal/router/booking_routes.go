@@ -0,0 +1,23 @@
+package router
+
+import (
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/booking"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupBookingRoutes configures routes for booked swap sessions
+func SetupBookingRoutes(api *gin.RouterGroup, authConfig middleware.AuthConfig, bookingHandler *booking.Handler) {
+	// Protected booking routes (authentication required)
+	bookings := api.Group("/bookings")
+	bookings.Use(middleware.JWTAuth(authConfig))
+	{
+		bookings.POST("", bookingHandler.CreateBooking)                      // POST /api/v1/bookings
+		bookings.GET("", bookingHandler.GetUserBookings)                     // GET /api/v1/bookings
+		bookings.GET("/reminders", bookingHandler.GetReminderPreferences)    // GET /api/v1/bookings/reminders
+		bookings.PUT("/reminders", bookingHandler.UpdateReminderPreferences) // PUT /api/v1/bookings/reminders
+		bookings.GET("/:id", bookingHandler.GetBooking)                      // GET /api/v1/bookings/:id
+		bookings.PUT("/:id", bookingHandler.RescheduleBooking)               // PUT /api/v1/bookings/:id
+		bookings.POST("/:id/cancel", bookingHandler.CancelBooking)           // POST /api/v1/bookings/:id/cancel
+	}
+}
@@ -2,13 +2,12 @@ package router
 
 import (
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
-	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/file"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupFileRoutes(api *gin.RouterGroup, fileUploadService *service.FileUploadService, cfg *config.Config) {
+func SetupFileRoutes(api *gin.RouterGroup, fileUploadService *service.FileUploadService, authConfig middleware.AuthConfig) {
 	fileHandler := file.NewHandler(fileUploadService)
 
 	// File routes
@@ -19,7 +18,7 @@ func SetupFileRoutes(api *gin.RouterGroup, fileUploadService *service.FileUpload
 
 		// Protected file routes
 		protected := files.Group("/users")
-		protected.Use(middleware.JWTAuth(*cfg))
+		protected.Use(middleware.JWTAuth(authConfig))
 		{
 			protected.POST("/photo", fileHandler.UploadUserPhoto)         // POST /api/files/users/photo
 			protected.DELETE("/photo", fileHandler.DeleteUserPhoto)       // DELETE /api/files/users/photo
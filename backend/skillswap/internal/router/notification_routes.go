@@ -1,28 +1,34 @@
 package router
 
 import (
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
-	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/notification"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupNotificationRoutes(api *gin.RouterGroup, notificationService *service.NotificationService, cfg *config.Config) {
+func SetupNotificationRoutes(api *gin.RouterGroup, notificationService *service.NotificationService, authConfig middleware.AuthConfig) {
 	notificationHandler := notification.NewHandler(notificationService)
 
+	// Unsubscribe links in emails; the signed token is the credential
+	api.GET("/notifications/unsubscribe", notificationHandler.Unsubscribe)         // GET /api/v1/notifications/unsubscribe
+	api.POST("/notifications/unsubscribe", notificationHandler.ConfirmUnsubscribe) // POST /api/v1/notifications/unsubscribe
+
 	// Protected notification routes
 	notifications := api.Group("/notifications")
-	notifications.Use(middleware.JWTAuth(*cfg))
+	notifications.Use(middleware.JWTAuth(authConfig))
 	{
 		notifications.GET("", notificationHandler.GetUserNotifications)              // GET /api/notifications
 		notifications.GET("/stats", notificationHandler.GetNotificationStats)        // GET /api/notifications/stats
+		notifications.GET("/preferences", notificationHandler.GetPreferences)        // GET /api/notifications/preferences
+		notifications.PUT("/preferences", notificationHandler.UpdatePreferences)     // PUT /api/notifications/preferences
 		notifications.GET("/:id", notificationHandler.GetNotificationByID)           // GET /api/notifications/:id
 		notifications.PUT("/mark-read", notificationHandler.MarkNotificationsAsRead) // PUT /api/notifications/mark-read
 		notifications.PUT("/mark-all-read", notificationHandler.MarkAllAsRead)       // PUT /api/notifications/mark-all-read
 		notifications.DELETE("/:id", notificationHandler.DeleteNotification)         // DELETE /api/notifications/:id
 
 		// Admin only routes
-		notifications.POST("", notificationHandler.CreateNotification) // POST /api/notifications (admin only)
+		notifications.POST("", middleware.RequirePermission(rbac.PermNotificationsSend), notificationHandler.CreateNotification) // POST /api/notifications (notifications:send)
 	}
 }
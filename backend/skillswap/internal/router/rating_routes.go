@@ -1,14 +1,13 @@
 package router
 
 import (
-	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/rating"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRatingRoutes sets up all rating-related routes
-func SetupRatingRoutes(api *gin.RouterGroup, cfg *config.Config, ratingHandler *rating.Handler) {
+func SetupRatingRoutes(api *gin.RouterGroup, authConfig middleware.AuthConfig, ratingHandler *rating.Handler) {
 	// Public rating routes (viewing ratings)
 	ratingsGroup := api.Group("/ratings")
 	{
@@ -18,7 +17,7 @@ func SetupRatingRoutes(api *gin.RouterGroup, cfg *config.Config, ratingHandler *
 
 	// Protected rating routes (requires authentication)
 	protectedRatings := api.Group("/ratings")
-	protectedRatings.Use(middleware.JWTAuth(*cfg))
+	protectedRatings.Use(middleware.JWTAuth(authConfig))
 	{
 		protectedRatings.POST("", ratingHandler.CreateRating)
 		protectedRatings.PUT("/:id", ratingHandler.UpdateRating)
@@ -1,11 +1,23 @@
 package router
 
 import (
+	"context"
+	"log"
+
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/admin"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/authtoken"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/event"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/geo"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/jobs"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/mail"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/ratelimit"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/repository"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/searchindex"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/availability"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/booking"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/rating"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/skill"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/swap"
@@ -13,39 +25,115 @@ import (
 	"gorm.io/gorm"
 )
 
-// SetupRoutes configures all application routes by delegating to specific route files
-func SetupRoutes(api *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+// SetupRoutes configures all application routes by delegating to specific route files.
+// Search and statistics read from replica, which may be db itself.
+// Background work is registered on scheduler, which the caller starts.
+func SetupRoutes(api *gin.RouterGroup, db, replica *gorm.DB, cfg *config.Config, scheduler *jobs.Scheduler) {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 
+	// Access tokens stop working once their user's token version is bumped
+	authConfig := middleware.DefaultAuthConfig(*cfg, authtoken.NewVersionCache(userRepo))
+
+	// Rate limits for every API request, and stricter ones on some routes
+	rateLimitStore, err := ratelimit.New(cfg.RateLimit.Backend, db)
+	if err != nil {
+		log.Fatal("Failed to create rate limit store:", err)
+	}
+	limiter := middleware.NewRateLimiter(*cfg, rateLimitStore, authConfig)
+	api.Use(limiter.Limit(ratelimit.PolicyAPI))
+
+	// Resolves free-text locations to places and coordinates
+	geocoder := geo.DefaultGazetteer()
+
+	// Domain events and the search index kept in sync with them
+	events := event.NewBus()
+	indexer, err := searchindex.New(cfg.Search.Backend, replica)
+	if err != nil {
+		log.Fatal("Failed to create search index:", err)
+	}
+	searchSyncer := searchindex.NewSyncer(db, indexer)
+	if cfg.Search.Backend == searchindex.BackendMemory {
+		// The Postgres index maintains itself; the in-memory one starts empty
+		searchSyncer.Subscribe(events)
+		stats, err := searchSyncer.ReindexAll(context.Background())
+		if err != nil {
+			log.Fatal("Failed to build search index:", err)
+		}
+		log.Printf("✓ Search index built (%d users, %d skills, %d swaps in %s)", stats.Users, stats.Skills, stats.Swaps, stats.Duration)
+	}
+
+	// Outbound email for notifications
+	mailer, err := mail.New(mail.Settings{
+		Transport:    cfg.Mail.Transport,
+		From:         cfg.Mail.From,
+		SMTPHost:     cfg.Mail.SMTPHost,
+		SMTPPort:     cfg.Mail.SMTPPort,
+		SMTPUsername: cfg.Mail.SMTPUsername,
+		SMTPPassword: cfg.Mail.SMTPPassword,
+		Dir:          cfg.Mail.Dir,
+	})
+	if err != nil {
+		log.Fatal("Failed to create mailer:", err)
+	}
+
 	// Initialize services
-	userService := service.NewUserService(userRepo)
-	authService := service.NewAuthService(userRepo, *cfg)
-	skillService := service.NewSkillService(db)
-	swapService := service.NewSwapService(db)
+	notificationService := service.NewNotificationService(db, scheduler, mailer, *cfg)
+	notificationService.RegisterJobs(scheduler)
+	if n, err := notificationService.ResumeEmails(context.Background()); err != nil {
+		log.Printf("Warning: failed to resume outbox emails: %v", err)
+	} else if n > 0 {
+		log.Printf("✓ Resumed %d pending outbox emails", n)
+	}
+	userService := service.NewUserService(userRepo, geocoder, events)
+	authService := service.NewAuthService(db, userRepo, *cfg, geocoder, events, notificationService)
+	if err := authService.RegisterJobs(context.Background(), scheduler); err != nil {
+		log.Printf("Warning: failed to schedule the login failure purge: %v", err)
+	}
+	skillService := service.NewSkillService(db, events)
+	swapService := service.NewSwapService(db, events)
 	ratingService := service.NewRatingService(db)
-	adminService := service.NewAdminService(db)
-	availabilityService := service.NewAvailabilityService(db)
-	notificationService := service.NewNotificationService(db)
-	searchService := service.NewSearchService(db)
+	adminService := service.NewAdminService(db, replica, events)
+	scheduleService := service.NewScheduleService(db)
+	availabilityService := service.NewAvailabilityService(db, scheduleService)
+	calendarService := service.NewCalendarService(db, *cfg)
+	searchService := service.NewSearchService(replica, indexer, geocoder)
+	suggestionService := service.NewSuggestionService(db, events)
 	fileUploadService := service.NewFileUploadService(db, *cfg)
 
+	// Booked sessions are busy time and show up in calendar feeds
+	bookingService := service.NewBookingService(db, scheduleService, notificationService, events, *cfg)
+	scheduleService.AddBusySource(bookingService)
+	calendarService.AddEventSource(bookingService)
+
+	// Session reminders are durable scheduled jobs
+	reminderService := service.NewReminderService(db, scheduler, notificationService, cfg.Jobs.ReminderOffsets)
+	reminderService.Register(scheduler)
+	reminderService.Subscribe(events)
+	if n, err := reminderService.ScheduleUpcoming(context.Background()); err != nil {
+		log.Printf("Warning: failed to schedule session reminders: %v", err)
+	} else {
+		log.Printf("✓ Session reminders scheduled for %d upcoming bookings", n)
+	}
+
 	// Initialize handlers
 	skillHandler := skill.NewHandler(skillService)
 	swapHandler := swap.NewHandler(swapService)
 	ratingHandler := rating.NewHandler(ratingService)
 	adminHandler := admin.NewHandler(adminService)
-	availabilityHandler := availability.NewHandler(availabilityService)
+	availabilityHandler := availability.NewHandler(availabilityService, calendarService)
+	bookingHandler := booking.NewHandler(bookingService, reminderService)
 
 	// Setup route groups
-	SetupAuthRoutes(api, authService, cfg)
-	SetupUserRoutes(api, userService, cfg)
-	SetupSkillRoutes(api, cfg, skillHandler)
-	SetupSwapRoutes(api, cfg, swapHandler)
-	SetupRatingRoutes(api, cfg, ratingHandler)
-	SetupAvailabilityRoutes(api, cfg, availabilityHandler)
-	SetupAdminRoutes(api, cfg, skillHandler, adminHandler)
-	SetupNotificationRoutes(api, notificationService, cfg)
-	SetupSearchRoutes(api, searchService, cfg)
-	SetupFileRoutes(api, fileUploadService, cfg)
+	SetupAuthRoutes(api, authService, authConfig, limiter)
+	SetupUserRoutes(api, userService, authConfig)
+	SetupSkillRoutes(api, authConfig, skillHandler)
+	SetupSwapRoutes(api, authConfig, swapHandler)
+	SetupRatingRoutes(api, authConfig, ratingHandler)
+	SetupAvailabilityRoutes(api, authConfig, availabilityHandler)
+	SetupBookingRoutes(api, authConfig, bookingHandler)
+	SetupAdminRoutes(api, authConfig, skillHandler, adminHandler)
+	SetupNotificationRoutes(api, notificationService, authConfig)
+	SetupSearchRoutes(api, searchService, suggestionService, searchSyncer, authConfig)
+	SetupFileRoutes(api, fileUploadService, authConfig)
 }
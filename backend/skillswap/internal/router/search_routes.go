@@ -1,15 +1,16 @@
 package router
 
 import (
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/rbac"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/searchindex"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
-	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/search"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupSearchRoutes(api *gin.RouterGroup, searchService service.SearchService, cfg *config.Config) {
-	searchHandler := search.NewHandler(searchService)
+func SetupSearchRoutes(api *gin.RouterGroup, searchService service.SearchService, suggestionService service.SuggestionService, syncer *searchindex.Syncer, authConfig middleware.AuthConfig) {
+	searchHandler := search.NewHandler(searchService, suggestionService, syncer)
 
 	// Public search routes
 	public := api.Group("/search")
@@ -20,10 +21,18 @@ func SetupSearchRoutes(api *gin.RouterGroup, searchService service.SearchService
 
 	// Protected search routes (for more detailed searches)
 	protected := api.Group("/search")
-	protected.Use(middleware.JWTAuth(*cfg))
+	protected.Use(middleware.JWTAuth(authConfig))
 	{
 		protected.GET("/users", searchHandler.SearchUsers)   // GET /api/search/users
 		protected.GET("/swaps", searchHandler.SearchSwaps)   // GET /api/search/swaps
 		protected.GET("/skills", searchHandler.SearchSkills) // GET /api/search/skills
 	}
+
+	// Admin index maintenance
+	admin := api.Group("/admin/search")
+	admin.Use(middleware.JWTAuth(authConfig))
+	admin.Use(middleware.RequirePermission(rbac.PermSearchReindex))
+	{
+		admin.POST("/reindex", searchHandler.Reindex) // POST /api/v1/admin/search/reindex
+	}
 }
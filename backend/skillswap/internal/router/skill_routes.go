@@ -1,14 +1,13 @@
 package router
 
 import (
-	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/skill"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupSkillRoutes configures all skill-related routes
-func SetupSkillRoutes(api *gin.RouterGroup, cfg *config.Config, skillHandler *skill.Handler) {
+func SetupSkillRoutes(api *gin.RouterGroup, authConfig middleware.AuthConfig, skillHandler *skill.Handler) {
 	// Public skill routes (no authentication required)
 	skills := api.Group("/skills")
 	{
@@ -18,7 +17,7 @@ func SetupSkillRoutes(api *gin.RouterGroup, cfg *config.Config, skillHandler *sk
 
 	// Protected user skill routes (authentication required)
 	userSkills := api.Group("/users/skills")
-	userSkills.Use(middleware.JWTAuth(*cfg))
+	userSkills.Use(middleware.JWTAuth(authConfig))
 	{
 		// Offered skills
 		userSkills.GET("/offered", skillHandler.GetUserOfferedSkills)      // GET /api/v1/users/skills/offered
@@ -1,17 +1,16 @@
 package router
 
 import (
-	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/swap"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupSwapRoutes configures all swap request related routes
-func SetupSwapRoutes(api *gin.RouterGroup, cfg *config.Config, swapHandler *swap.Handler) {
+func SetupSwapRoutes(api *gin.RouterGroup, authConfig middleware.AuthConfig, swapHandler *swap.Handler) {
 	// Protected swap routes (authentication required)
 	swaps := api.Group("/swaps")
-	swaps.Use(middleware.JWTAuth(*cfg))
+	swaps.Use(middleware.JWTAuth(authConfig))
 	{
 		swaps.POST("", swapHandler.CreateSwapRequest)          // POST /api/v1/swaps
 		swaps.GET("", swapHandler.GetUserSwapRequests)         // GET /api/v1/swaps
@@ -2,14 +2,13 @@ package router
 
 import (
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
-	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/user"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupUserRoutes configures all user-related routes
-func SetupUserRoutes(api *gin.RouterGroup, userService service.UserService, cfg *config.Config) {
+func SetupUserRoutes(api *gin.RouterGroup, userService service.UserService, authConfig middleware.AuthConfig) {
 	userHandler := user.NewHandler(userService)
 
 	// Public user routes (no authentication required)
@@ -20,7 +19,7 @@ func SetupUserRoutes(api *gin.RouterGroup, userService service.UserService, cfg
 
 	// Protected user routes (authentication required)
 	protected := api.Group("/users")
-	protected.Use(middleware.JWTAuth(*cfg))
+	protected.Use(middleware.JWTAuth(authConfig))
 	{
 		protected.GET("/profile", userHandler.GetProfile)
 		protected.PUT("/profile", userHandler.UpdateProfile)
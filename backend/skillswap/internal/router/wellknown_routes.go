@@ -0,0 +1,19 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupWellKnownRoutes configures metadata that other services discover
+// under /.well-known
+func SetupWellKnownRoutes(router *gin.Engine, cfg *config.Config) {
+	// Public keys that verify our access tokens
+	router.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		// Short enough that a rotated key is picked up within the overlap
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, cfg.Auth.Keys.JWKS())
+	})
+}
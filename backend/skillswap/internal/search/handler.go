@@ -1,22 +1,29 @@
 package search
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/searchindex"
 	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type Handler struct {
-	searchService service.SearchService
+	searchService     service.SearchService
+	suggestionService service.SuggestionService
+	syncer            *searchindex.Syncer
 }
 
-func NewHandler(searchService service.SearchService) *Handler {
+func NewHandler(searchService service.SearchService, suggestionService service.SuggestionService, syncer *searchindex.Syncer) *Handler {
 	return &Handler{
-		searchService: searchService,
+		searchService:     searchService,
+		suggestionService: suggestionService,
+		syncer:            syncer,
 	}
 }
 
@@ -26,13 +33,19 @@ func NewHandler(searchService service.SearchService) *Handler {
 // @Tags search
 // @Accept json
 // @Produce json
-// @Param q query string false "Search query (name, location)"
-// @Param location query string false "Filter by location"
+// @Param q query string false "Search query (name, location); supports name:, location:, skill:, offers:, wants:"
+// @Param location query string false "Filter by location; aliases such as NYC resolve to the same place"
+// @Param near query string false "Center of a radius search, as a place name"
+// @Param lat query number false "Center latitude"
+// @Param lng query number false "Center longitude"
+// @Param radius_km query number false "Only users within this distance of the center (defaults to your own location)"
+// @Param remote query bool false "Only users who do (true) or don't (false) swap remotely"
+// @Param include_remote query bool false "Also include remote users outside the radius"
 // @Param skills_offered query string false "Comma-separated skill IDs offered"
 // @Param skills_wanted query string false "Comma-separated skill IDs wanted"
 // @Param min_rating query number false "Minimum average rating"
 // @Param is_public query bool false "Public profiles only"
-// @Param sort_by query string false "Sort by (created_at, name, rating)"
+// @Param sort_by query string false "Sort by (relevance, created_at, name, rating, distance)"
 // @Param sort_order query string false "Sort order (asc, desc)"
 // @Param limit query int false "Limit results"
 // @Param offset query int false "Offset for pagination"
@@ -44,10 +57,52 @@ func (h *Handler) SearchUsers(c *gin.Context) {
 	filter := service.UserSearchFilter{
 		Query:     c.Query("q"),
 		Location:  c.Query("location"),
+		Near:      c.Query("near"),
 		SortBy:    c.Query("sort_by"),
 		SortOrder: c.Query("sort_order"),
 	}
 
+	// Parse location filters
+	if latStr := c.Query("lat"); latStr != "" {
+		lat, err := strconv.ParseFloat(latStr, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid latitude"})
+			return
+		}
+		filter.Latitude = &lat
+	}
+	if lngStr := c.Query("lng"); lngStr != "" {
+		lng, err := strconv.ParseFloat(lngStr, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid longitude"})
+			return
+		}
+		filter.Longitude = &lng
+	}
+	if radiusStr := c.Query("radius_km"); radiusStr != "" {
+		radius, err := strconv.ParseFloat(radiusStr, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid radius"})
+			return
+		}
+		filter.RadiusKm = &radius
+	}
+	if remoteStr := c.Query("remote"); remoteStr != "" {
+		if remote, err := strconv.ParseBool(remoteStr); err == nil {
+			filter.IsRemote = &remote
+		}
+	}
+	if includeRemote, err := strconv.ParseBool(c.Query("include_remote")); err == nil {
+		filter.IncludeRemote = includeRemote
+	}
+
+	// Without an explicit center, distances are measured from the caller
+	if filter.Near == "" && filter.Latitude == nil && filter.Longitude == nil {
+		if userID, ok := middleware.OptionalUserID(c); ok {
+			filter.Origin = &userID
+		}
+	}
+
 	// Parse skills offered
 	if skillsOfferedStr := c.Query("skills_offered"); skillsOfferedStr != "" {
 		skillIDs := strings.Split(skillsOfferedStr, ",")
@@ -94,9 +149,15 @@ func (h *Handler) SearchUsers(c *gin.Context) {
 		}
 	}
 
-	users, total, err := h.searchService.SearchUsers(filter)
+	users, total, err := h.searchService.SearchUsers(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch err.Error() {
+		case "invalid radius", "invalid coordinates", "unknown location",
+			"radius search requires a location", "distance sort requires a location":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -114,7 +175,7 @@ func (h *Handler) SearchUsers(c *gin.Context) {
 // @Tags search
 // @Accept json
 // @Produce json
-// @Param q query string false "Search query (description)"
+// @Param q query string false "Search query (skill and participant names); supports skill:, offered:, wanted:, user:, status:"
 // @Param status query string false "Filter by status"
 // @Param offered_skill_id query string false "Filter by offered skill ID"
 // @Param wanted_skill_id query string false "Filter by wanted skill ID"
@@ -122,7 +183,7 @@ func (h *Handler) SearchUsers(c *gin.Context) {
 // @Param responder_id query string false "Filter by responder ID"
 // @Param created_after query string false "Created after date (ISO format)"
 // @Param created_before query string false "Created before date (ISO format)"
-// @Param sort_by query string false "Sort by (created_at, updated_at)"
+// @Param sort_by query string false "Sort by (relevance, created_at, updated_at)"
 // @Param sort_order query string false "Sort order (asc, desc)"
 // @Param limit query int false "Limit results"
 // @Param offset query int false "Offset for pagination"
@@ -186,7 +247,7 @@ func (h *Handler) SearchSwaps(c *gin.Context) {
 		}
 	}
 
-	swaps, total, err := h.searchService.SearchSwaps(filter)
+	swaps, total, err := h.searchService.SearchSwaps(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -206,9 +267,8 @@ func (h *Handler) SearchSwaps(c *gin.Context) {
 // @Tags search
 // @Accept json
 // @Produce json
-// @Param q query string false "Search query (name, description)"
-// @Param category query string false "Filter by category"
-// @Param sort_by query string false "Sort by (name, created_at, popularity)"
+// @Param q query string false "Search query (name); supports name:"
+// @Param sort_by query string false "Sort by (relevance, name, created_at, popularity)"
 // @Param sort_order query string false "Sort order (asc, desc)"
 // @Param limit query int false "Limit results"
 // @Param offset query int false "Offset for pagination"
@@ -219,7 +279,6 @@ func (h *Handler) SearchSwaps(c *gin.Context) {
 func (h *Handler) SearchSkills(c *gin.Context) {
 	filter := service.SkillSearchFilter{
 		Query:     c.Query("q"),
-		Category:  c.Query("category"),
 		SortBy:    c.Query("sort_by"),
 		SortOrder: c.Query("sort_order"),
 	}
@@ -236,7 +295,7 @@ func (h *Handler) SearchSkills(c *gin.Context) {
 		}
 	}
 
-	skills, total, err := h.searchService.SearchSkills(filter)
+	skills, total, err := h.searchService.SearchSkills(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -287,7 +346,7 @@ func (h *Handler) GlobalSearch(c *gin.Context) {
 		}
 	}
 
-	results, err := h.searchService.GlobalSearch(query, entityTypes, limit)
+	results, err := h.searchService.GlobalSearch(c.Request.Context(), query, entityTypes, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -298,19 +357,19 @@ func (h *Handler) GlobalSearch(c *gin.Context) {
 
 // SearchSuggestions provides search suggestions/autocomplete
 // @Summary Search suggestions
-// @Description Get search suggestions for autocomplete
+// @Description Get prefix completions for skill names, skill aliases and public user names, most popular first
 // @Tags search
 // @Accept json
 // @Produce json
-// @Param q query string true "Search query"
-// @Param type query string false "Entity type (users,skills,swaps)"
+// @Param q query string true "Prefix to complete"
+// @Param type query string false "Entity type (skills, users, all)"
+// @Param limit query int false "Maximum suggestions (default 10, max 20)"
 // @Success 200 {object} gin.H
 // @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
 // @Router /api/v1/search/suggestions [get]
 func (h *Handler) SearchSuggestions(c *gin.Context) {
 	query := c.Query("q")
-	if query == "" {
+	if strings.TrimSpace(query) == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
 		return
 	}
@@ -319,48 +378,54 @@ func (h *Handler) SearchSuggestions(c *gin.Context) {
 	if entityType == "" {
 		entityType = "skills" // Default to skills for suggestions
 	}
+	if entityType != "skills" && entityType != "users" && entityType != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entity type"})
+		return
+	}
 
-	var suggestions []string
-
-	switch entityType {
-	case "skills":
-		// Get skill name suggestions
-		results, _, err := h.searchService.SearchSkills(service.SkillSearchFilter{
-			Query: query,
-			Limit: 10,
-		})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		for _, skill := range results {
-			suggestions = append(suggestions, skill.Name)
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
 		}
+	}
 
-	case "users":
-		// Get user name suggestions (public users only)
-		isPublic := true
-		results, _, err := h.searchService.SearchUsers(service.UserSearchFilter{
-			Query:    query,
-			IsPublic: &isPublic,
-			Limit:    10,
-		})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		for _, user := range results {
-			suggestions = append(suggestions, user.Name)
-		}
+	results := h.suggestionService.Suggest(query, entityType, limit)
 
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entity type"})
-		return
+	suggestions := make([]string, len(results))
+	for i, result := range results {
+		suggestions[i] = result.Text
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"suggestions": suggestions,
+		"results":     results,
 		"query":       query,
 		"type":        entityType,
 	})
 }
+
+// Reindex rebuilds the search index from the database
+// @Summary Rebuild search index (search:reindex permission)
+// @Description Drop and re-add every user, skill and swap in the search index
+// @Tags admin
+// @Produce json
+// @Success 200 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/admin/search/reindex [post]
+func (h *Handler) Reindex(c *gin.Context) {
+	// Stopping halfway would leave the index incomplete, so the rebuild
+	// outlives the request
+	stats, err := h.syncer.ReindexAll(context.WithoutCancel(c.Request.Context()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Search index rebuilt successfully",
+		"stats":   stats,
+	})
+}
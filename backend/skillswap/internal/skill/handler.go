@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	appservice "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -51,7 +52,7 @@ type UserSkillRequest struct {
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/skills [get]
 func (h *Handler) GetAllSkills(c *gin.Context) {
-	skills, err := h.skillService.GetAllSkills()
+	skills, err := h.skillService.GetAllSkills(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch skills"})
 		return
@@ -88,7 +89,7 @@ func (h *Handler) GetSkill(c *gin.Context) {
 		return
 	}
 
-	skill, err := h.skillService.GetSkillByID(skillID)
+	skill, err := h.skillService.GetSkillByID(c.Request.Context(), skillID)
 	if err != nil {
 		if err.Error() == "skill not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Skill not found"})
@@ -109,7 +110,7 @@ func (h *Handler) GetSkill(c *gin.Context) {
 
 // CreateSkill godoc
 // @Summary Create new skill
-// @Description Create a new skill (admin only)
+// @Description Create a new skill (skills:manage permission)
 // @Tags skills
 // @Accept json
 // @Produce json
@@ -121,14 +122,23 @@ func (h *Handler) GetSkill(c *gin.Context) {
 // @Failure 403 {object} ErrorResponse
 // @Router /api/v1/admin/skills [post]
 func (h *Handler) CreateSkill(c *gin.Context) {
+	actorID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
 	var req CreateSkillRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	skill, err := h.skillService.CreateSkill(req.Name)
+	skill, err := h.skillService.CreateSkill(c.Request.Context(), actorID, req.Name)
 	if err != nil {
+		if err.Error() == "permission denied" {
+			c.JSON(http.StatusForbidden, ErrorResponse{Error: "Permission denied"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create skill"})
 		return
 	}
@@ -144,7 +154,7 @@ func (h *Handler) CreateSkill(c *gin.Context) {
 
 // UpdateSkill godoc
 // @Summary Update skill
-// @Description Update an existing skill (admin only)
+// @Description Update an existing skill (skills:manage permission)
 // @Tags skills
 // @Accept json
 // @Produce json
@@ -165,14 +175,23 @@ func (h *Handler) UpdateSkill(c *gin.Context) {
 		return
 	}
 
+	actorID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
 	var req UpdateSkillRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	skill, err := h.skillService.UpdateSkill(skillID, req.Name)
+	skill, err := h.skillService.UpdateSkill(c.Request.Context(), actorID, skillID, req.Name)
 	if err != nil {
+		if err.Error() == "permission denied" {
+			c.JSON(http.StatusForbidden, ErrorResponse{Error: "Permission denied"})
+			return
+		}
 		if err.Error() == "skill not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Skill not found"})
 			return
@@ -192,7 +211,7 @@ func (h *Handler) UpdateSkill(c *gin.Context) {
 
 // DeleteSkill godoc
 // @Summary Delete skill
-// @Description Delete a skill (admin only)
+// @Description Delete a skill (skills:manage permission)
 // @Tags skills
 // @Accept json
 // @Produce json
@@ -212,8 +231,17 @@ func (h *Handler) DeleteSkill(c *gin.Context) {
 		return
 	}
 
-	err = h.skillService.DeleteSkill(skillID)
+	actorID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	err = h.skillService.DeleteSkill(c.Request.Context(), actorID, skillID)
 	if err != nil {
+		if err.Error() == "permission denied" {
+			c.JSON(http.StatusForbidden, ErrorResponse{Error: "Permission denied"})
+			return
+		}
 		if err.Error() == "skill not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Skill not found"})
 			return
@@ -242,15 +270,8 @@ func (h *Handler) DeleteSkill(c *gin.Context) {
 // @Failure 401 {object} ErrorResponse
 // @Router /api/v1/users/skills/offered [post]
 func (h *Handler) AddOfferedSkill(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -266,7 +287,7 @@ func (h *Handler) AddOfferedSkill(c *gin.Context) {
 		return
 	}
 
-	err = h.skillService.AddOfferedSkill(userID, skillID)
+	err = h.skillService.AddOfferedSkill(c.Request.Context(), userID, skillID)
 	if err != nil {
 		if err.Error() == "skill not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Skill not found"})
@@ -297,15 +318,8 @@ func (h *Handler) AddOfferedSkill(c *gin.Context) {
 // @Failure 404 {object} ErrorResponse
 // @Router /api/v1/users/skills/offered/{id} [delete]
 func (h *Handler) RemoveOfferedSkill(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -316,7 +330,7 @@ func (h *Handler) RemoveOfferedSkill(c *gin.Context) {
 		return
 	}
 
-	err = h.skillService.RemoveOfferedSkill(userID, skillID)
+	err = h.skillService.RemoveOfferedSkill(c.Request.Context(), userID, skillID)
 	if err != nil {
 		if err.Error() == "offered skill not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Offered skill not found"})
@@ -342,15 +356,8 @@ func (h *Handler) RemoveOfferedSkill(c *gin.Context) {
 // @Failure 401 {object} ErrorResponse
 // @Router /api/v1/users/skills/wanted [post]
 func (h *Handler) AddWantedSkill(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -366,7 +373,7 @@ func (h *Handler) AddWantedSkill(c *gin.Context) {
 		return
 	}
 
-	err = h.skillService.AddWantedSkill(userID, skillID)
+	err = h.skillService.AddWantedSkill(c.Request.Context(), userID, skillID)
 	if err != nil {
 		if err.Error() == "skill not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Skill not found"})
@@ -397,15 +404,8 @@ func (h *Handler) AddWantedSkill(c *gin.Context) {
 // @Failure 404 {object} ErrorResponse
 // @Router /api/v1/users/skills/wanted/{id} [delete]
 func (h *Handler) RemoveWantedSkill(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -416,7 +416,7 @@ func (h *Handler) RemoveWantedSkill(c *gin.Context) {
 		return
 	}
 
-	err = h.skillService.RemoveWantedSkill(userID, skillID)
+	err = h.skillService.RemoveWantedSkill(c.Request.Context(), userID, skillID)
 	if err != nil {
 		if err.Error() == "wanted skill not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Wanted skill not found"})
@@ -440,19 +440,12 @@ func (h *Handler) RemoveWantedSkill(c *gin.Context) {
 // @Failure 401 {object} ErrorResponse
 // @Router /api/v1/users/skills/offered [get]
 func (h *Handler) GetUserOfferedSkills(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	skills, err := h.skillService.GetUserOfferedSkills(userID)
+	skills, err := h.skillService.GetUserOfferedSkills(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch offered skills"})
 		return
@@ -481,19 +474,12 @@ func (h *Handler) GetUserOfferedSkills(c *gin.Context) {
 // @Failure 401 {object} ErrorResponse
 // @Router /api/v1/users/skills/wanted [get]
 func (h *Handler) GetUserWantedSkills(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	skills, err := h.skillService.GetUserWantedSkills(userID)
+	skills, err := h.skillService.GetUserWantedSkills(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch wanted skills"})
 		return
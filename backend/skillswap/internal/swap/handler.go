@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	appservice "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	models "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/model"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -69,6 +70,8 @@ type MatchResponse struct {
 	OfferedSkill SkillResponse `json:"offered_skill"`
 	WantedSkill  SkillResponse `json:"wanted_skill"`
 	MatchScore   int           `json:"match_score"`
+	DistanceKm   *float64      `json:"distance_km,omitempty"`
+	IsRemote     bool          `json:"is_remote"`
 }
 
 type ErrorResponse struct {
@@ -143,17 +146,11 @@ func (h *Handler) getStringValue(ptr *string) string {
 // @Success 201 {object} SwapRequestResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "Email not verified"
 // @Router /api/v1/swaps [post]
 func (h *Handler) CreateSwapRequest(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -190,8 +187,12 @@ func (h *Handler) CreateSwapRequest(c *gin.Context) {
 		WantedSkillID:  wantedSkillID,
 	}
 
-	swap, err := h.swapService.CreateSwapRequest(swapDTO)
+	swap, err := h.swapService.CreateSwapRequest(c.Request.Context(), swapDTO)
 	if err != nil {
+		if err.Error() == "verify your email address before requesting swaps" {
+			c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -215,15 +216,8 @@ func (h *Handler) CreateSwapRequest(c *gin.Context) {
 // @Failure 404 {object} ErrorResponse
 // @Router /api/v1/swaps/{id} [get]
 func (h *Handler) GetSwapRequest(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -234,7 +228,7 @@ func (h *Handler) GetSwapRequest(c *gin.Context) {
 		return
 	}
 
-	swap, err := h.swapService.GetSwapRequestByID(swapID)
+	swap, err := h.swapService.GetSwapRequestByID(c.Request.Context(), swapID)
 	if err != nil {
 		if err.Error() == "swap request not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Swap request not found"})
@@ -270,15 +264,8 @@ func (h *Handler) GetSwapRequest(c *gin.Context) {
 // @Failure 401 {object} ErrorResponse
 // @Router /api/v1/swaps [get]
 func (h *Handler) GetUserSwapRequests(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -312,7 +299,7 @@ func (h *Handler) GetUserSwapRequests(c *gin.Context) {
 
 	// If neither sent nor received specified, show organized view
 	if !filter.Sent && !filter.Received {
-		swapRequests, err := h.swapService.GetSwapRequestsForUser(userID)
+		swapRequests, err := h.swapService.GetSwapRequestsForUser(c.Request.Context(), userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch swap requests"})
 			return
@@ -336,7 +323,7 @@ func (h *Handler) GetUserSwapRequests(c *gin.Context) {
 	}
 
 	// Get filtered requests
-	swaps, err := h.swapService.GetUserSwapRequests(userID, filter)
+	swaps, err := h.swapService.GetUserSwapRequests(c.Request.Context(), userID, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch swap requests"})
 		return
@@ -366,15 +353,8 @@ func (h *Handler) GetUserSwapRequests(c *gin.Context) {
 // @Failure 404 {object} ErrorResponse
 // @Router /api/v1/swaps/{id}/status [put]
 func (h *Handler) UpdateSwapStatus(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -392,7 +372,7 @@ func (h *Handler) UpdateSwapStatus(c *gin.Context) {
 	}
 
 	status := models.SwapStatus(req.Status)
-	swap, err := h.swapService.UpdateSwapStatus(swapID, userID, status)
+	swap, err := h.swapService.UpdateSwapStatus(c.Request.Context(), swapID, userID, status)
 	if err != nil {
 		if err.Error() == "swap request not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Swap request not found"})
@@ -426,15 +406,8 @@ func (h *Handler) UpdateSwapStatus(c *gin.Context) {
 // @Failure 404 {object} ErrorResponse
 // @Router /api/v1/swaps/{id} [delete]
 func (h *Handler) DeleteSwapRequest(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -445,7 +418,7 @@ func (h *Handler) DeleteSwapRequest(c *gin.Context) {
 		return
 	}
 
-	err = h.swapService.DeleteSwapRequest(swapID, userID)
+	err = h.swapService.DeleteSwapRequest(c.Request.Context(), swapID, userID)
 	if err != nil {
 		if err.Error() == "swap request not found" {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Swap request not found"})
@@ -469,25 +442,39 @@ func (h *Handler) DeleteSwapRequest(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param radius_km query number false "Only users within this distance of you"
+// @Param include_remote query bool false "Also include remote users outside the radius"
 // @Success 200 {array} MatchResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Router /api/v1/swaps/matches [get]
 func (h *Handler) GetPotentialMatches(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
-		return
+	var opts appservice.MatchOptions
+	if radiusStr := c.Query("radius_km"); radiusStr != "" {
+		radius, err := strconv.ParseFloat(radiusStr, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid radius"})
+			return
+		}
+		opts.RadiusKm = &radius
+	}
+	if includeRemote, err := strconv.ParseBool(c.Query("include_remote")); err == nil {
+		opts.IncludeRemote = includeRemote
 	}
 
-	matches, err := h.swapService.FindPotentialMatches(userID)
+	matches, err := h.swapService.FindPotentialMatches(c.Request.Context(), userID, opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to find matches"})
+		switch err.Error() {
+		case "invalid radius", "your profile has no resolved location":
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to find matches"})
+		}
 		return
 	}
 
@@ -509,6 +496,8 @@ func (h *Handler) GetPotentialMatches(c *gin.Context) {
 				Name:    match.WantedSkill.Name,
 			},
 			MatchScore: match.MatchScore,
+			DistanceKm: match.DistanceKm,
+			IsRemote:   match.User.IsRemote,
 		})
 	}
 
@@ -5,8 +5,8 @@ import (
 	"strconv"
 
 	appservice "github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/app/service"
+	"github.com/Sky-walkerX/Skill-swap/backend/skillswap/internal/middleware"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 type Handler struct {
@@ -32,19 +32,12 @@ func NewHandler(userService appservice.UserService) *Handler {
 // @Failure 500 {object} ErrorResponse
 // @Router /users/profile [get]
 func (h *Handler) GetProfile(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
-	profile, err := h.userService.GetProfile(userID)
+	profile, err := h.userService.GetProfile(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -68,15 +61,8 @@ func (h *Handler) GetProfile(c *gin.Context) {
 // @Failure 500 {object} ErrorResponse
 // @Router /users/profile [put]
 func (h *Handler) UpdateProfile(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -86,7 +72,11 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.UpdateProfile(userID, &req); err != nil {
+	if err := h.userService.UpdateProfile(c.Request.Context(), userID, &req); err != nil {
+		if err.Error() == "invalid timezone" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -128,7 +118,7 @@ func (h *Handler) SearchUsers(c *gin.Context) {
 		}
 	}
 
-	result, err := h.userService.SearchUsers(&req)
+	result, err := h.userService.SearchUsers(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,25 @@
+-- Migration: Full-text and trigram search
+-- Description: Add tsvector columns and pg_trgm indexes used by the search service
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Generated search vectors (name weighted above location)
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(location, '')), 'B')
+    ) STORED;
+
+ALTER TABLE skills
+ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(name, ''))) STORED;
+
+-- Full-text indexes
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_skills_search_vector ON skills USING GIN (search_vector);
+
+-- Trigram indexes for typo tolerance and substring (ILIKE) matches
+CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_location_trgm ON users USING GIN (location gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_skills_name_trgm ON skills USING GIN (name gin_trgm_ops);
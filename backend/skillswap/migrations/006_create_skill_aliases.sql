@@ -0,0 +1,34 @@
+-- Migration: Add skill aliases
+-- Description: Alternative skill names used by autocomplete suggestions (e.g. "k8s" for Kubernetes)
+
+CREATE TABLE IF NOT EXISTS skill_aliases (
+    alias_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    skill_id UUID NOT NULL REFERENCES skills(skill_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    alias TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_skill_aliases_skill_id ON skill_aliases(skill_id);
+
+-- Seed aliases for the default skills
+INSERT INTO skill_aliases (skill_id, alias)
+SELECT s.skill_id, a.alias
+FROM (VALUES
+    ('JavaScript', 'js'), ('JavaScript', 'ecmascript'),
+    ('TypeScript', 'ts'),
+    ('Python', 'py'),
+    ('Go', 'golang'),
+    ('Kubernetes', 'k8s'),
+    ('PostgreSQL', 'postgres'), ('PostgreSQL', 'psql'),
+    ('Node.js', 'node'), ('Node.js', 'nodejs'),
+    ('Vue.js', 'vue'), ('Vue.js', 'vuejs'),
+    ('Machine Learning', 'ml'),
+    ('UI/UX Design', 'user experience'), ('UI/UX Design', 'user interface'),
+    ('HTML/CSS', 'html'), ('HTML/CSS', 'css'),
+    ('C++', 'cpp'),
+    ('AWS', 'amazon web services'),
+    ('MongoDB', 'mongo'),
+    ('Cybersecurity', 'infosec'), ('Cybersecurity', 'security')
+) AS a(skill_name, alias)
+JOIN skills s ON s.name = a.skill_name
+ON CONFLICT (alias) DO NOTHING;
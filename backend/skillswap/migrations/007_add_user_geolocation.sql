@@ -0,0 +1,25 @@
+-- Migration: Structured user location
+-- Description: Resolved place, coordinates, timezone and remote flag for distance-based search
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS location_place TEXT,
+ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS timezone TEXT,
+ADD COLUMN IF NOT EXISTS is_remote BOOLEAN DEFAULT FALSE;
+
+-- Bounding-box prefilter for radius search
+CREATE INDEX IF NOT EXISTS idx_users_lat_lng ON users(latitude, longitude) WHERE latitude IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_location_place ON users(location_place);
+
+-- Rebuild the search vector so the resolved place is searchable too
+ALTER TABLE users DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE users
+ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(location, '') || ' ' || COALESCE(location_place, '')), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+
+-- Existing free-text locations are resolved by the application on startup
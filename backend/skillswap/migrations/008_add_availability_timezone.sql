@@ -0,0 +1,7 @@
+-- Migration: Timezone-aware availability
+-- Description: Optional per-slot IANA timezone; slots without one use the owner's timezone
+
+ALTER TABLE availability_slots
+ADD COLUMN IF NOT EXISTS timezone TEXT;
+
+-- end_time may now be earlier than start_time for slots that run past midnight
@@ -0,0 +1,15 @@
+-- Migration: Availability exceptions
+-- Description: One-off free time and blackouts that override weekly availability slots
+
+CREATE TABLE IF NOT EXISTS availability_exceptions (
+    exception_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('available', 'blackout')),
+    label TEXT NOT NULL DEFAULT '',
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_availability_exceptions_user_time ON availability_exceptions(user_id, starts_at, ends_at);
@@ -0,0 +1,15 @@
+-- Migration: iCalendar feed and import
+-- Description: Per-user feed token (stored hashed) and the source UID of imported slots and blackouts
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS calendar_token_hash TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_calendar_token_hash ON users(calendar_token_hash);
+
+-- Re-importing the same calendar updates entries instead of duplicating them
+ALTER TABLE availability_slots
+ADD COLUMN IF NOT EXISTS ical_uid TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_availability_slots_ical_uid ON availability_slots(user_id, ical_uid);
+
+ALTER TABLE availability_exceptions
+ADD COLUMN IF NOT EXISTS ical_uid TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_availability_exceptions_ical_uid ON availability_exceptions(user_id, ical_uid);
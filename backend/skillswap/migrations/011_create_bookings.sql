@@ -0,0 +1,36 @@
+-- Migration: Swap session bookings
+-- Description: Concrete sessions for accepted swaps; an exclusion constraint stops a user being booked twice for the same time
+
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE IF NOT EXISTS bookings (
+    booking_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    swap_id UUID NOT NULL REFERENCES swap_requests(swap_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    created_by UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    timezone TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'confirmed' CHECK (status IN ('confirmed', 'cancelled')),
+    cancelled_by UUID REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE SET NULL,
+    cancel_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookings_swap_id ON bookings(swap_id);
+
+-- One row per participant so the constraint covers both sides of a booking
+CREATE TABLE IF NOT EXISTS booking_participants (
+    booking_id UUID NOT NULL REFERENCES bookings(booking_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    PRIMARY KEY (booking_id, user_id),
+    CHECK (ends_at > starts_at),
+    CONSTRAINT booking_participants_no_overlap EXCLUDE USING gist (
+        user_id WITH =,
+        tstzrange(starts_at, ends_at, '[)') WITH &&
+    ) WHERE (active)
+);
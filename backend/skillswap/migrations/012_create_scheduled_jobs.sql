@@ -0,0 +1,28 @@
+-- Migration: Scheduled jobs and session reminders
+-- Description: Durable job queue polled with FOR UPDATE SKIP LOCKED, and per-user reminder offsets
+
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    job_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind TEXT NOT NULL,
+    key TEXT UNIQUE, -- Deduplicates jobs, e.g. one reminder per booking, user and offset
+    subject_id UUID, -- Entity the job is about, used to cancel pending jobs
+    payload JSONB NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE, -- Lease of the instance running the job
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_due ON scheduled_jobs(run_at) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_subject ON scheduled_jobs(kind, subject_id) WHERE status = 'pending';
+
+-- Users without a row get the server's default offsets
+CREATE TABLE IF NOT EXISTS reminder_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    offsets_minutes JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
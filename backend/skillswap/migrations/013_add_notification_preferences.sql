@@ -0,0 +1,27 @@
+-- Migration: Notification preferences and digests
+-- Description: Per-type, per-channel preferences, mute, quiet hours and digest mode; notifications held back until quiet hours end or the next digest
+
+ALTER TABLE notifications
+ADD COLUMN IF NOT EXISTS deliver_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS digest_pending BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_notifications_digest_pending ON notifications(user_id) WHERE digest_pending;
+
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    quiet_hours_start TIME,
+    quiet_hours_end TIME,
+    digest TEXT NOT NULL DEFAULT 'off' CHECK (digest IN ('off', 'daily', 'weekly')),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((quiet_hours_start IS NULL) = (quiet_hours_end IS NULL))
+);
+
+-- Missing rows mean enabled
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    channel TEXT NOT NULL CHECK (channel IN ('in_app', 'email')),
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, type, channel)
+);
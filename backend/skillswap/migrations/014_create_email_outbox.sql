@@ -0,0 +1,24 @@
+-- Migration: Email outbox
+-- Description: Rendered notification emails; delivery and retries are driven by scheduled jobs
+
+CREATE TABLE IF NOT EXISTS email_outbox (
+    email_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    notification_id UUID REFERENCES notifications(notification_id) ON DELETE SET NULL,
+    type VARCHAR(50) NOT NULL,
+    to_address TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL,
+    unsubscribe_url TEXT, -- Sent as the List-Unsubscribe header
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    send_after TIMESTAMP WITH TIME ZONE NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_user_id ON email_outbox(user_id);
+CREATE INDEX IF NOT EXISTS idx_email_outbox_pending ON email_outbox(send_after) WHERE status = 'pending';
@@ -0,0 +1,22 @@
+-- Migration: Email verification and password reset
+-- Description: Verified-email flag, session revocation counter and single-use emailed tokens
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0; -- Bumped to revoke all sessions
+
+-- Accounts that existed before verification are trusted
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+-- Only the SHA-256 hash of each token is stored
+CREATE TABLE IF NOT EXISTS user_tokens (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    purpose TEXT NOT NULL CHECK (purpose IN ('email_verification', 'password_reset')),
+    email TEXT, -- Address the token was sent to
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_purpose ON user_tokens(user_id, purpose);
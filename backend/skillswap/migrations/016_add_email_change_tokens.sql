@@ -0,0 +1,6 @@
+-- Migration: Email change tokens
+-- Description: Allow single-use tokens that confirm a new email address
+
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+    CHECK (purpose IN ('email_verification', 'password_reset', 'email_change'));
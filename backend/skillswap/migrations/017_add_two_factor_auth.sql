@@ -0,0 +1,17 @@
+-- Migration: Two-factor authentication
+-- Description: TOTP secrets and single-use recovery codes
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS mfa_secret TEXT, -- Base32 TOTP secret, set during enrollment
+ADD COLUMN IF NOT EXISTS mfa_enabled_at TIMESTAMP WITH TIME ZONE, -- NULL until enrollment is confirmed
+ADD COLUMN IF NOT EXISTS mfa_last_step BIGINT NOT NULL DEFAULT 0; -- Last TOTP step used, against replay
+
+-- Only the SHA-256 hash of each code is stored
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    code_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
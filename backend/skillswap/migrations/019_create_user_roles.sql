@@ -0,0 +1,18 @@
+-- Migration: Staff roles
+-- Description: Role assignments replace users.is_admin. The roles and their
+-- permissions are defined in code (package rbac).
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id UUID NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    granted_by UUID REFERENCES users(user_id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role ON user_roles(role);
+
+-- Existing admins keep their access. is_admin is no longer read.
+INSERT INTO user_roles (user_id, role)
+SELECT user_id, 'admin' FROM users WHERE is_admin = TRUE
+ON CONFLICT DO NOTHING;
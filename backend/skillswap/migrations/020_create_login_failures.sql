@@ -0,0 +1,13 @@
+-- Migration: Login failures
+-- Description: Failed logins per lowercased email address, used to slow down
+-- and temporarily lock out password guessing. Rows are kept for unknown
+-- addresses too, so the responses do not reveal which accounts exist.
+
+CREATE TABLE IF NOT EXISTS login_failures (
+    email TEXT PRIMARY KEY,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_last_failed_at ON login_failures(last_failed_at);
@@ -0,0 +1,12 @@
+-- Migration: Rate limit buckets
+-- Description: Token buckets for RATE_LIMIT_BACKEND=postgres, shared by
+-- every server. Rows whose bucket is full again (expires_at) are deleted.
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_expires_at ON rate_limit_buckets(expires_at);